@@ -0,0 +1,478 @@
+// Package bigwighttp 把 bigWig 区间查询封装为 http.Handler，可以挂载到已有的 Go 服务中
+//
+// 路由（坐标从 0 开始、左闭右开，region 形如 chr1:0-1000）：
+//
+//	GET /tracks                                         已注册的轨道
+//	GET /track/{name}/info                              文件头、zoom 层级和染色体列表
+//	GET /track/{name}/intervals?region=...              区间内的原始记录
+//	GET /track/{name}/values?region=...[&bins=N]        逐碱基的值，或分箱后的 RegionTrack
+//	GET /track/{name}/stats?region=...[&type=mean&bins=N&exact=1&zoom=K]
+//	GET /api/v1/tileset_info/?d=name[&d=...]            HiGlass 的 tileset_info
+//	GET /api/v1/tiles/?d=name.z.x[.aggregation[.range]] HiGlass 的 tiles
+//
+// 后两个接口与 higlass-server 相同，HiGlass 可以直接把 Server 当作 tile 服务器使用，
+// 轨道名即 tileset uuid（因此不能包含点）；单个 tileset 或 tile 出错时在对应条目中返回 {"error": ...}
+// values 默认返回 JSON（NaN 为 null），format=f32 时返回小端 float32 数组；
+// 出错时返回 {"error": ...}：未知轨道为 404，参数错误为 400，超时为 504
+//
+// 挂载到子路径时配合 http.StripPrefix 使用：
+//
+//	h := bigwighttp.Handler(pool)
+//	h.Register("h3k27ac", "/data/h3k27ac.bw")
+//	mux.Handle("/bigwig/", http.StripPrefix("/bigwig", h))
+package bigwighttp
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// 默认的单个请求超时和不分箱时单次请求的最大碱基数
+const (
+	DefaultTimeout  = 30 * time.Second
+	DefaultMaxBases = 1000000
+)
+
+// Server 是 Handler 返回的 http.Handler，持有轨道表和共享的句柄池，可以并发使用
+type Server struct {
+	pool     *gb.Pool
+	timeout  time.Duration
+	maxBases int
+	mux      *http.ServeMux
+
+	mu     sync.RWMutex
+	tracks map[string]string // 轨道名到文件路径/URL
+}
+
+// Option 配置 Server
+type Option func(*Server)
+
+// WithTimeout 设置单个请求的超时时间，<=0 表示不限制
+func WithTimeout(d time.Duration) Option {
+	return func(s *Server) { s.timeout = d }
+}
+
+// WithMaxBases 设置不分箱的 values 和 intervals 请求允许的最大区间长度，
+// 同时也是 values 和 stats 请求的最大分箱数
+func WithMaxBases(n int) Option {
+	return func(s *Server) { s.maxBases = n }
+}
+
+// Handler 返回从 pool 读取文件的 Server，轨道需通过 Register 注册后才能访问
+func Handler(pool *gb.Pool, opts ...Option) *Server {
+	s := &Server{
+		pool:     pool,
+		timeout:  DefaultTimeout,
+		maxBases: DefaultMaxBases,
+		mux:      http.NewServeMux(),
+		tracks:   make(map[string]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux.HandleFunc("GET /tracks", s.handleTracks)
+	s.mux.Handle("GET /track/{name}/info", s.route(s.info))
+	s.mux.Handle("GET /track/{name}/intervals", s.route(s.intervals))
+	s.mux.Handle("GET /track/{name}/values", s.route(s.values))
+	s.mux.Handle("GET /track/{name}/stats", s.route(s.stats))
+	s.mux.HandleFunc("GET /api/v1/tileset_info/", s.handleTilesetInfo)
+	s.mux.HandleFunc("GET /api/v1/tiles/", s.handleTiles)
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Register 以 name 注册 path 处的 bigWig 文件（本地路径或 URL），name 已存在时返回错误；
+// 注册时会读取文件头确认文件类型
+func (s *Server) Register(name, path string) error {
+	ftype, _, err := gb.DetectType(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if ftype != gb.FileTypeBigWig {
+		return fmt.Errorf("%s: %s files are not supported", path, ftype)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.tracks[name]; dup {
+		return fmt.Errorf("duplicate track name %q", name)
+	}
+	s.tracks[name] = path
+	return nil
+}
+
+// Unregister 移除 name，已打开的句柄仍由 pool 管理
+func (s *Server) Unregister(name string) {
+	s.mu.Lock()
+	delete(s.tracks, name)
+	s.mu.Unlock()
+}
+
+// Len 返回已注册的轨道数
+func (s *Server) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tracks)
+}
+
+// httpError 是带 HTTP 状态码的错误
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+// trackFunc 处理针对单个轨道的请求，出错时不应写出任何内容
+type trackFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, fp *gb.Bigwig_file_out) error
+
+// route 为 fn 取得轨道句柄、设置超时并统一处理错误
+func (s *Server) route(fn trackFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := s.requestContext(r)
+		defer cancel()
+		fp, release, err := s.acquire(r.PathValue("name"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer release()
+		if err := fn(ctx, w, r, fp); err != nil {
+			writeError(w, err)
+		}
+	})
+}
+
+// acquire 取得名为 name 的轨道的句柄，未注册时返回 404 错误
+func (s *Server) acquire(name string) (*gb.Bigwig_file_out, func(), error) {
+	s.mu.RLock()
+	path, ok := s.tracks[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil, &httpError{http.StatusNotFound, fmt.Errorf("unknown track %q", name)}
+	}
+	return s.pool.Acquire(path)
+}
+
+// requestContext 返回带有请求超时的 ctx
+func (s *Server) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(r.Context(), s.timeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
+// writeError 以 {"error": ...} 的形式返回错误，客户端已断开时不再写出
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var he *httpError
+	switch {
+	case errors.As(err, &he):
+		status = he.status
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return
+	case errors.Is(err, gb.ErrInvalidRange), errors.Is(err, gb.ErrOutOfRange):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("bigwighttp: write response: %v", err)
+	}
+}
+
+// writeFloat32 以小端 float32 数组返回 values，NaN 保持为 NaN
+func writeFloat32(w http.ResponseWriter, values []float32) error {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(4*len(values)))
+	return binary.Write(w, binary.LittleEndian, values)
+}
+
+// nullable 把 NaN 转换为 JSON 的 null
+func nullable[T float32 | float64](values []T) []*T {
+	out := make([]*T, len(values))
+	for i := range values {
+		if !math.IsNaN(float64(values[i])) {
+			out[i] = &values[i]
+		}
+	}
+	return out
+}
+
+// region 解析 region 参数；limit 为 true 时检查区间长度不超过 maxBases
+func (s *Server) region(r *http.Request, limit bool) (gb.Region, error) {
+	region, err := gb.ParseRegion(r.URL.Query().Get("region"))
+	if err != nil {
+		return region, badRequest("%v", err)
+	}
+	if limit && s.maxBases > 0 && region.End-region.Start > s.maxBases {
+		return region, badRequest("region spans more than %d bases; pass bins", s.maxBases)
+	}
+	return region, nil
+}
+
+// checkBins 检查分箱数不超过区间长度和 maxBases，分箱结果的大小与分箱数成正比
+func (s *Server) checkBins(bins int, region gb.Region) error {
+	if bins > region.End-region.Start {
+		return badRequest("%d bins exceed the %d bases of the region", bins, region.End-region.Start)
+	}
+	if s.maxBases > 0 && bins > s.maxBases {
+		return badRequest("%d bins exceed the limit of %d", bins, s.maxBases)
+	}
+	return nil
+}
+
+// intParam 解析正整数参数，未给出时返回 def
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, badRequest("invalid %s %q", name, v)
+	}
+	return n, nil
+}
+
+func (s *Server) handleTracks(w http.ResponseWriter, r *http.Request) {
+	type track struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	}
+	s.mu.RLock()
+	out := make([]track, 0, len(s.tracks))
+	for name, path := range s.tracks {
+		out = append(out, track{name, path})
+	}
+	s.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) info(ctx context.Context, w http.ResponseWriter, r *http.Request, fp *gb.Bigwig_file_out) error {
+	chroms, err := fp.Chroms()
+	if err != nil {
+		return err
+	}
+	type chrom struct {
+		Name   string `json:"name"`
+		Length uint32 `json:"length"`
+	}
+	hdr := fp.Header()
+	info := struct {
+		Version      uint16   `json:"version"`
+		ZoomLevels   []uint32 `json:"zoomLevels"`
+		Chroms       []chrom  `json:"chroms"`
+		BasesCovered uint64   `json:"basesCovered"`
+		Min          float64  `json:"min"`
+		Max          float64  `json:"max"`
+		Mean         float64  `json:"mean"`
+	}{Version: hdr.Version, ZoomLevels: []uint32{}, BasesCovered: hdr.NBasesCovered, Min: hdr.MinVal, Max: hdr.MaxVal}
+	for _, z := range hdr.ZoomLevels {
+		info.ZoomLevels = append(info.ZoomLevels, z.Reduction)
+	}
+	for _, c := range chroms {
+		info.Chroms = append(info.Chroms, chrom{c.Name, c.Length})
+	}
+	if hdr.NBasesCovered > 0 {
+		info.Mean = hdr.SumData / float64(hdr.NBasesCovered)
+	}
+	writeJSON(w, http.StatusOK, info)
+	return nil
+}
+
+func (s *Server) intervals(ctx context.Context, w http.ResponseWriter, r *http.Request, fp *gb.Bigwig_file_out) error {
+	region, err := s.region(r, true)
+	if err != nil {
+		return err
+	}
+	items, err := fp.IntervalsContext(ctx, region.Chrom, region.Start, region.End)
+	if err != nil {
+		return err
+	}
+	type interval struct {
+		Start uint32  `json:"start"`
+		End   uint32  `json:"end"`
+		Value float32 `json:"value"`
+	}
+	out := make([]interval, len(items))
+	for i, it := range items {
+		out[i] = interval{it.Start, it.End, it.Value}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"chrom":     region.Chrom,
+		"intervals": out,
+	})
+	return nil
+}
+
+func (s *Server) values(ctx context.Context, w http.ResponseWriter, r *http.Request, fp *gb.Bigwig_file_out) error {
+	bins, err := intParam(r, "bins", 0)
+	if err != nil {
+		return err
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "f32" {
+		return badRequest("unknown format %q", format)
+	}
+	region, err := s.region(r, bins == 0)
+	if err != nil {
+		return err
+	}
+	if bins > 0 {
+		if err := s.checkBins(bins, region); err != nil {
+			return err
+		}
+		t, err := fp.RegionTrackContext(ctx, region.Chrom, region.Start, region.End, bins)
+		if err != nil {
+			return err
+		}
+		if format == "f32" {
+			values := make([]float32, len(t.Values))
+			for i, v := range t.Values {
+				values[i] = float32(v)
+			}
+			return writeFloat32(w, values)
+		}
+		writeJSON(w, http.StatusOK, t)
+		return nil
+	}
+	values, err := fp.GetValuesContext(ctx, region.Chrom, region.Start, region.End)
+	if err != nil {
+		return err
+	}
+	if format == "f32" {
+		return writeFloat32(w, values)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"chrom":  region.Chrom,
+		"start":  region.Start,
+		"end":    region.Start + len(values),
+		"values": nullable(values),
+	})
+	return nil
+}
+
+func (s *Server) stats(ctx context.Context, w http.ResponseWriter, r *http.Request, fp *gb.Bigwig_file_out) error {
+	region, err := s.region(r, false)
+	if err != nil {
+		return err
+	}
+	q := r.URL.Query()
+	opts := gb.StatsOptions{Type: q.Get("type")}
+	if opts.Type == "" {
+		opts.Type = "mean"
+	}
+	if opts.NBins, err = intParam(r, "bins", 1); err != nil {
+		return err
+	}
+	if opts.ZoomLevel, err = intParam(r, "zoom", 0); err != nil {
+		return err
+	}
+	if v := q.Get("exact"); v != "" {
+		if opts.Exact, err = strconv.ParseBool(v); err != nil {
+			return badRequest("invalid exact %q", v)
+		}
+	}
+	if err := s.checkBins(opts.NBins, region); err != nil {
+		return err
+	}
+	if n := len(fp.Header().ZoomLevels); opts.ZoomLevel > n {
+		return badRequest("zoom level %d does not exist (file has %d)", opts.ZoomLevel, n)
+	}
+	values, err := fp.Stats(ctx, region.Chrom, region.Start, region.End, opts)
+	if errors.Is(err, gb.ErrUnknownStat) {
+		return badRequest("%v", err)
+	}
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"chrom":  region.Chrom,
+		"start":  region.Start,
+		"end":    region.End,
+		"type":   opts.Type,
+		"values": nullable(values),
+	})
+	return nil
+}
+
+// errorEntry 是 HiGlass 接口中单个条目的错误
+func errorEntry(err error) map[string]string {
+	return map[string]string{"error": err.Error()}
+}
+
+func (s *Server) handleTilesetInfo(w http.ResponseWriter, r *http.Request) {
+	out := make(map[string]any)
+	for _, name := range r.URL.Query()["d"] {
+		fp, release, err := s.acquire(name)
+		if err != nil {
+			out[name] = errorEntry(err)
+			continue
+		}
+		info, err := fp.HiGlassTilesetInfo()
+		release()
+		if err != nil {
+			out[name] = errorEntry(err)
+			continue
+		}
+		out[name] = info
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleTiles(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+	out := make(map[string]any)
+	for _, id := range r.URL.Query()["d"] {
+		name, zoom, x, agg, rangeMode, err := gb.ParseHiGlassTileID(id)
+		if err != nil {
+			out[id] = errorEntry(err)
+			continue
+		}
+		fp, release, err := s.acquire(name)
+		if err != nil {
+			out[id] = errorEntry(err)
+			continue
+		}
+		tile, err := fp.HiGlassTileContext(ctx, zoom, x, agg, rangeMode)
+		release()
+		if ctx.Err() != nil {
+			writeError(w, ctx.Err())
+			return
+		}
+		if err != nil {
+			out[id] = errorEntry(err)
+			continue
+		}
+		out[id] = tile
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,48 @@
+package bigwighttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"go-bigwig/bigwighttp"
+	gb "go-bigwig/gobigwig"
+)
+
+// 分箱数超过 maxBases 时返回 400，不按分箱数分配结果
+func TestBinsLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.bw")
+	w, err := gb.CreateBigWig(path, []string{"chr1"}, []uint32{100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddIntervals("chr1", []uint32{0}, []uint32{1000}, []float32{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pool := gb.NewPool(4, 0)
+	defer pool.Close()
+	h := bigwighttp.Handler(pool, bigwighttp.WithMaxBases(100))
+	if err := h.Register("t", path); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		url  string
+		want int
+	}{
+		{"/track/t/values?region=chr1:0-100000&bins=100", http.StatusOK},
+		{"/track/t/values?region=chr1:0-100000&bins=101", http.StatusBadRequest},
+		{"/track/t/values?region=chr1:0-10&bins=20", http.StatusBadRequest},
+		{"/track/t/stats?region=chr1:0-100000&bins=100", http.StatusOK},
+		{"/track/t/stats?region=chr1:0-100000&bins=101", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.url, nil))
+		if rec.Code != c.want {
+			t.Errorf("GET %s: status %d, want %d: %s", c.url, rec.Code, c.want, rec.Body)
+		}
+	}
+}
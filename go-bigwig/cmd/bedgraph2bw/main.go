@@ -0,0 +1,53 @@
+// bedgraph2bw 把 bedGraph 转换为 bigWig，相当于 UCSC bedGraphToBigWig
+//
+//	bedgraph2bw [选项] in.bedGraph[.gz]|- chrom.sizes out.bw
+//	bedgraph2bw [选项] -genome hg38 in.bedGraph[.gz]|- out.bw
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	wf := cliutil.AddConvertFlags(flag.CommandLine)
+	sortInput := flag.Bool("sort", false, "输入未排序时先做外部排序")
+	tmpDir := flag.String("tmp-dir", "", "外部排序的临时目录，默认为系统临时目录")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bedgraph2bw [选项] in.bedGraph chrom.sizes out.bw\n      bedgraph2bw [选项] -genome NAME in.bedGraph out.bw")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	in, sizesPath, out, ok := wf.ConvertArgs(flag.Args())
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts, err := wf.Options()
+	if err != nil {
+		cliutil.Fatal("bedgraph2bw", err)
+	}
+	if *sortInput {
+		opts = append(opts, gb.WithExternalSort(*tmpDir))
+	}
+	if err := run(in, sizesPath, out, wf, opts); err != nil {
+		cliutil.Fatal("bedgraph2bw", err)
+	}
+}
+
+func run(in, sizesPath, out string, wf *cliutil.WriteFlags, opts []gb.WriteOption) error {
+	sizes, err := wf.ChromSizes(sizesPath)
+	if err != nil {
+		return err
+	}
+	r, err := cliutil.Open(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return gb.ConvertBedGraph(r, sizes, out, opts...)
+}
@@ -0,0 +1,65 @@
+// bw2bedgraph 把 bigWig 的全部或部分区间导出为 bedGraph，相当于 UCSC bigWigToBedGraph
+//
+//	bw2bedgraph [-merge-adjacent] [-bed regions.bed] [-o out.bedGraph[.gz]] file.bw|URL [chrom[:start-end] ...]
+//
+// 逐块流式读取和写出，内存占用与文件大小无关；区间坐标从 0 开始、左闭右开
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	var specs cliutil.Strings
+	flag.Var(&specs, "region", "只导出该区间 chrom:start-end，可重复；也可以写在文件名之后")
+	bed := flag.String("bed", "", "只导出 BED 文件中的区间（前三列）")
+	merge := flag.Bool("merge-adjacent", false, "把首尾相接且值相同的记录合并为一行")
+	output := flag.String("o", "-", "输出文件，- 表示标准输出，以 .gz 结尾时压缩")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bw2bedgraph [选项] file.bw|URL [chrom[:start-end] ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	specs = append(specs, flag.Args()[1:]...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, flag.Arg(0), specs, *bed, *merge, *output); err != nil {
+		cliutil.Fatal("bw2bedgraph", err)
+	}
+}
+
+func run(ctx context.Context, path string, specs []string, bed string, merge bool, output string) error {
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		return err
+	}
+	defer gb.CloseBigWig(fp)
+	regions, err := cliutil.Regions(fp, specs, bed)
+	if err != nil {
+		return err
+	}
+	out, err := cliutil.Create(output)
+	if err != nil {
+		return err
+	}
+	err = fp.WriteBedGraphContext(ctx, out, merge, regions...)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil && output != "" && output != "-" {
+		os.Remove(output)
+	}
+	return err
+}
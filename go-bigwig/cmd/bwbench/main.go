@@ -0,0 +1,99 @@
+// bwbench 生成合成 bigWig 文件并运行 internal/bwtest 中的基准测试，输出与 go test -bench 相同的格式，
+// 可以把两次运行的结果交给 benchstat 比较以发现性能回归；与 go test -bench ./internal/bwtest 相比，
+// 可以调整合成文件的密度、块大小和远程延迟
+//
+//	bwbench [-density 0.5] [-span 10] [-block N] [-chroms 3] [-len 10000000] [-latency 2ms] [-run regexp] [-count N]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"go-bigwig/internal/bwtest"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	var spec bwtest.Spec
+	flag.Float64Var(&spec.Density, "density", 0.5, "有数据的碱基比例")
+	span := flag.Uint("span", 10, "每条记录覆盖的碱基数")
+	block := flag.Uint("block", 0, "每个数据块的记录数，0 表示写入器的默认值")
+	flag.IntVar(&spec.Chroms, "chroms", 3, "染色体数")
+	length := flag.Uint("len", 10_000_000, "每条染色体的长度")
+	flag.Uint64Var(&spec.Seed, "seed", 1, "生成文件使用的随机数种子")
+	latency := flag.Duration("latency", 0, "模拟远程文件时每个请求的延迟")
+	run := flag.String("run", "", "只运行名称匹配该正则表达式的基准测试")
+	count := flag.Int("count", 1, "每个基准测试的运行次数")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwbench [选项]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	spec.Span, spec.BlockSize, spec.ChromLen = uint32(*span), uint32(*block), uint32(*length)
+	match, err := regexp.Compile(*run)
+	if err != nil {
+		cliutil.Fatal("bwbench", err)
+	}
+	if err := bench(spec, *latency, match, *count); err != nil {
+		cliutil.Fatal("bwbench", err)
+	}
+}
+
+// bench 在临时目录中生成文件，按 go test -bench 的格式输出每个匹配的基准测试的结果
+func bench(spec bwtest.Spec, latency time.Duration, match *regexp.Regexp, count int) error {
+	dir, err := os.MkdirTemp("", "bwbench-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	files, err := bwtest.Prepare(dir, spec)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("goos: %s\ngoarch: %s\npkg: go-bigwig/internal/bwtest\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("# %s, %d bytes, latency %v\n", files.Spec, len(files.Data), latency)
+	suffix := ""
+	if p := runtime.GOMAXPROCS(0); p > 1 {
+		suffix = fmt.Sprintf("-%d", p)
+	}
+	for _, bm := range bwtest.Suite(files, latency) {
+		if !match.MatchString(bm.Name) {
+			continue
+		}
+		for i := 0; i < count; i++ {
+			var failure error
+			r := testing.Benchmark(func(b *testing.B) {
+				if failure = run(b, bm); failure != nil {
+					b.Fail()
+				}
+			})
+			if failure != nil {
+				return fmt.Errorf("benchmark %s: %w", bm.Name, failure)
+			}
+			fmt.Printf("Benchmark%s%s\t%s\t%s\n", bm.Name, suffix, r.String(), r.MemString())
+		}
+	}
+	return nil
+}
+
+// run 与 internal/bwtest 的 Benchmark* 函数相同：bm.Setup 之后计时，调用 b.N 次 run
+func run(b *testing.B, bm bwtest.Benchmark) error {
+	iter, cleanup, err := bm.Setup()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := iter(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// bwcompare 按 bin 比较两个 bigWig（log2 比值、比值、差值等）并写出新的 bigWig，相当于 deeptools bigwigCompare
+//
+//	bwcompare [-op log2] [-bin-size 50] [-pseudocount 1] [-skip-missing] [-scale ses|median] a.bw b.bw out.bw
+//
+// -scale 先在全基因组抽样估计缩放系数（见 EstimateScaling），把系数输出到标准错误后再比较
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	wf := cliutil.AddWriteFlags(flag.CommandLine)
+	opName := flag.String("op", "log2", "运算：log2、ratio、reciprocal_ratio、subtract、add、mean、first、second")
+	binSize := flag.Uint("bin-size", 50, "bin 宽度（碱基）")
+	pseudocount := flag.Float64("pseudocount", 1, "比值类运算中加到分子和分母上的伪计数")
+	skipMissing := flag.Bool("skip-missing", false, "跳过任一文件没有数据的 bin，默认缺失按 0 计算")
+	scale := flag.String("scale", "", "比较前估计并应用缩放系数：ses 或 median，默认不缩放")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwcompare [选项] a.bw b.bw out.bw")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	op, err := gb.ParseCompareOp(*opName)
+	if err != nil {
+		cliutil.Fatal("bwcompare", err)
+	}
+	wopts, err := wf.Options()
+	if err != nil {
+		cliutil.Fatal("bwcompare", err)
+	}
+	opts := []gb.CompareOption{gb.WithCompareWriteOptions(wopts...)}
+	if *skipMissing {
+		opts = append(opts, gb.WithMissing(gb.MissingSkip))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	a, b, out := flag.Arg(0), flag.Arg(1), flag.Arg(2)
+	if *scale != "" {
+		method, err := gb.ParseScalingMethod(*scale)
+		if err != nil {
+			cliutil.Fatal("bwcompare", err)
+		}
+		f, err := gb.EstimateScaling(ctx, a, b, method, gb.ScalingOptions{})
+		if err != nil {
+			cliutil.Fatal("bwcompare", err)
+		}
+		fmt.Fprintf(os.Stderr, "bwcompare: %s scaling from %d bins: a x %g, b x %g\n", method, f.Bins, f.A, f.B)
+		opts = append(opts, f.CompareOption())
+	}
+	if err := gb.CompareContext(ctx, a, b, out, op, *pseudocount, uint32(*binSize), opts...); err != nil {
+		cliutil.Fatal("bwcompare", err)
+	}
+}
@@ -0,0 +1,43 @@
+// bwconform 执行 internal/bwtest 的一致性测试：对目录中的每个 *.expected.json 运行其中的查询，
+// 与随仓库提交的期望结果逐位比较，有不一致时逐条输出并以状态 1 退出
+//
+//	bwconform [dir]
+//
+// dir 默认为 internal/bwtest/testdata/conformance，用例由该目录下的 make_fixtures.sh 生成
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"go-bigwig/internal/bwtest"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwconform [dir]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	dir := "internal/bwtest/testdata/conformance"
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	mismatches, n, err := bwtest.CheckConformance(context.Background(), dir)
+	if err != nil {
+		cliutil.Fatal("bwconform", err)
+	}
+	if n == 0 {
+		cliutil.Fatal("bwconform", fmt.Errorf("%s: no *.expected.json fixtures (run make_fixtures.sh)", dir))
+	}
+	for _, m := range mismatches {
+		fmt.Println(m)
+	}
+	fmt.Printf("%d queries, %d mismatches\n", n, len(mismatches))
+	if len(mismatches) > 0 {
+		os.Exit(1)
+	}
+}
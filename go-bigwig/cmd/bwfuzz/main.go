@@ -0,0 +1,143 @@
+// bwfuzz 对 internal/bwtest 中的解析阶段目标（文件头、chrom 树、R 树、数据块）做变异模糊测试：
+// 从合成文件得到的种子出发，随机翻转、覆盖、插入、删除字节和截断，把结果交给目标；
+// 解析器 panic（返回 gobigwig.ErrParsePanic）的输入保存到 -crashers 目录，以状态 1 退出
+//
+//	bwfuzz [-run regexp] [-duration 30s] [-seed N] [-crashers dir]
+//
+// 保存的输入可以再次交给 bwfuzz -replay file 复现
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/bwtest"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	run := flag.String("run", "", "只运行名称匹配该正则表达式的目标")
+	duration := flag.Duration("duration", 30*time.Second, "每个目标的运行时间")
+	seed := flag.Uint64("seed", uint64(time.Now().UnixNano()), "随机数种子")
+	crashers := flag.String("crashers", "bwfuzz-crashers", "保存导致 panic 的输入的目录")
+	replay := flag.String("replay", "", "只把该文件的内容交给匹配 -run 的目标并报告结果")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwfuzz [选项]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	match, err := regexp.Compile(*run)
+	if err != nil {
+		cliutil.Fatal("bwfuzz", err)
+	}
+	failed := false
+	for _, t := range bwtest.FuzzTargets() {
+		if !match.MatchString(t.Name) {
+			continue
+		}
+		var ok bool
+		if *replay != "" {
+			ok, err = replayInput(t, *replay)
+		} else {
+			ok, err = fuzz(t, *duration, *seed, *crashers)
+		}
+		if err != nil {
+			cliutil.Fatal("bwfuzz", err)
+		}
+		failed = failed || !ok
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// fuzz 在 d 时间内反复变异种子并运行 t，发现 panic 时保存输入并返回 false
+func fuzz(t bwtest.FuzzTarget, d time.Duration, seed uint64, dir string) (bool, error) {
+	corpus, err := bwtest.FuzzSeeds(t.Name)
+	if err != nil {
+		return false, err
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	deadline := time.Now().Add(d)
+	execs, crashes := 0, 0
+	for time.Now().Before(deadline) {
+		data := mutate(rng, corpus[rng.IntN(len(corpus))])
+		err := t.Fn(data)
+		execs++
+		if !errors.Is(err, gb.ErrParsePanic) {
+			continue
+		}
+		crashes++
+		path, werr := save(dir, t.Name, data)
+		if werr != nil {
+			return false, werr
+		}
+		fmt.Printf("%s: %v\n\tinput saved to %s\n", t.Name, err, path)
+	}
+	fmt.Printf("%s: %d execs, %d crashes\n", t.Name, execs, crashes)
+	return crashes == 0, nil
+}
+
+// replayInput 把文件 path 的内容交给 t 一次
+func replayInput(t bwtest.FuzzTarget, path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	err = t.Fn(data)
+	fmt.Printf("%s: %v\n", t.Name, err)
+	return !errors.Is(err, gb.ErrParsePanic), nil
+}
+
+// mutate 返回 in 的副本经过 1 到 8 次随机变异的结果
+func mutate(rng *rand.Rand, in []byte) []byte {
+	b := append([]byte(nil), in...)
+	for n := 1 + rng.IntN(8); n > 0; n-- {
+		if len(b) == 0 {
+			b = append(b, byte(rng.Uint32()))
+			continue
+		}
+		i := rng.IntN(len(b))
+		switch rng.IntN(6) {
+		case 0: // 翻转一位
+			b[i] ^= 1 << rng.IntN(8)
+		case 1: // 覆盖一个字节
+			b[i] = byte(rng.Uint32())
+		case 2: // 覆盖 4 或 8 字节为边界值，命中偏移和计数字段
+			v := []uint64{0, 1, 0xff, 0xffff, 0xffffffff, 1<<63 - 1, 1<<64 - 1, uint64(len(b))}[rng.IntN(8)]
+			width := 4 + 4*rng.IntN(2)
+			for j := 0; j < width && i+j < len(b); j++ {
+				b[i+j] = byte(v >> (8 * j))
+			}
+		case 3: // 插入若干随机字节
+			ins := make([]byte, 1+rng.IntN(16))
+			for j := range ins {
+				ins[j] = byte(rng.Uint32())
+			}
+			b = append(b[:i], append(ins, b[i:]...)...)
+		case 4: // 删除一段
+			b = append(b[:i], b[min(len(b), i+1+rng.IntN(16)):]...)
+		case 5: // 截断
+			b = b[:i]
+		}
+	}
+	return b
+}
+
+// save 把导致 panic 的输入保存为 dir/name-<sha256 前缀>，返回路径
+func save(dir, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%x", name, sum[:8]))
+	return path, os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,168 @@
+// bwinfo 打印 bigWig 文件的文件头、zoom 层级、染色体列表和全局统计，相当于 UCSC bigWigInfo
+//
+//	bwinfo [-chroms] [-zooms] [-json] file.bw|URL
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// info 是 -json 的输出
+type info struct {
+	Path             string      `json:"path"`
+	Version          uint16      `json:"version"`
+	Compressed       bool        `json:"isCompressed"`
+	Endianness       string      `json:"endianness"`
+	BufSize          uint32      `json:"bufSize"`
+	PrimaryDataSize  uint64      `json:"primaryDataSize"`
+	PrimaryIndexSize uint64      `json:"primaryIndexSize"`
+	ZoomLevels       []zoomLevel `json:"zoomLevels"`
+	Chroms           []chromInfo `json:"chroms"`
+	BasesCovered     uint64      `json:"basesCovered"`
+	Mean             float64     `json:"mean"`
+	Min              float64     `json:"min"`
+	Max              float64     `json:"max"`
+	Std              float64     `json:"std"`
+}
+
+type chromInfo struct {
+	ID     uint32 `json:"id"`
+	Name   string `json:"name"`
+	Length uint32 `json:"length"`
+}
+
+type zoomLevel struct {
+	Level      int    `json:"level"`
+	Reduction  uint32 `json:"reductionLevel"`
+	DataSize   uint64 `json:"dataSize"`
+	IndexSize  uint64 `json:"indexSize"`
+	DataOffset uint64 `json:"dataOffset"`
+}
+
+func main() {
+	chroms := flag.Bool("chroms", false, "列出每条染色体的名称、ID 和长度")
+	zooms := flag.Bool("zooms", false, "列出每个 zoom 层级的缩放倍数和大小")
+	asJSON := flag.Bool("json", false, "以 JSON 输出全部信息")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwinfo [-chroms] [-zooms] [-json] file.bw|URL")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *chroms, *zooms, *asJSON); err != nil {
+		fmt.Fprintln(os.Stderr, "bwinfo:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, chroms, zooms, asJSON bool) error {
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		return err
+	}
+	defer gb.CloseBigWig(fp)
+
+	in, err := collect(fp, path)
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(in)
+	}
+
+	fmt.Printf("version: %d\n", in.Version)
+	fmt.Printf("isCompressed: %s\n", yesNo(in.Compressed))
+	fmt.Printf("isSwapped: %d\n", boolInt(in.Endianness == gb.BigEndian.String()))
+	fmt.Printf("primaryDataSize: %d\n", in.PrimaryDataSize)
+	fmt.Printf("primaryIndexSize: %d\n", in.PrimaryIndexSize)
+	fmt.Printf("zoomLevels: %d\n", len(in.ZoomLevels))
+	if zooms {
+		for _, z := range in.ZoomLevels {
+			fmt.Printf("\t%d\t%d\n", z.Reduction, z.DataSize+z.IndexSize)
+		}
+	}
+	fmt.Printf("chromCount: %d\n", len(in.Chroms))
+	if chroms {
+		for _, c := range in.Chroms {
+			fmt.Printf("\t%s %d %d\n", c.Name, c.ID, c.Length)
+		}
+	}
+	fmt.Printf("basesCovered: %d\n", in.BasesCovered)
+	fmt.Printf("mean: %f\n", in.Mean)
+	fmt.Printf("min: %f\n", in.Min)
+	fmt.Printf("max: %f\n", in.Max)
+	fmt.Printf("std: %f\n", in.Std)
+	return nil
+}
+
+// collect 汇总文件头、布局和染色体列表
+func collect(fp *gb.Bigwig_file_out, path string) (*info, error) {
+	hdr := fp.Header()
+	chroms, err := fp.Chroms()
+	if err != nil {
+		return nil, err
+	}
+	in := &info{
+		Path:         path,
+		Version:      hdr.Version,
+		Compressed:   hdr.BufSize > 0,
+		Endianness:   hdr.Endianness.String(),
+		BufSize:      hdr.BufSize,
+		ZoomLevels:   []zoomLevel{},
+		Chroms:       make([]chromInfo, len(chroms)),
+		BasesCovered: hdr.NBasesCovered,
+		Min:          hdr.MinVal,
+		Max:          hdr.MaxVal,
+	}
+	for i, c := range chroms {
+		in.Chroms[i] = chromInfo{c.ID, c.Name, c.Length}
+	}
+	for i, z := range hdr.ZoomLevels {
+		in.ZoomLevels = append(in.ZoomLevels, zoomLevel{Level: i, Reduction: z.Reduction, DataOffset: z.DataOffset})
+	}
+	for _, s := range fp.Layout().Sections {
+		switch s.Name {
+		case "data":
+			in.PrimaryDataSize = s.Size
+		case "index":
+			in.PrimaryIndexSize = s.Size
+		case "zoomData":
+			in.ZoomLevels[s.Level].DataSize = s.Size
+		case "zoomIndex":
+			in.ZoomLevels[s.Level].IndexSize = s.Size
+		}
+	}
+	// 与 bigWigInfo 一致，标准差使用样本方差
+	if n := float64(hdr.NBasesCovered); n > 0 {
+		in.Mean = hdr.SumData / n
+		if n > 1 {
+			in.Std = math.Sqrt(math.Max((hdr.SumSquared-hdr.SumData*hdr.SumData/n)/(n-1), 0))
+		}
+	}
+	return in, nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+// bwmerge 把多个 bigWig 按位置合并为一个（平均、求和、最大或最小），相当于 wiggletools mean/sum
+//
+//	bwmerge [-op mean|sum|max|min] -o out.bw in1.bw in2.bw ...
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	wf := cliutil.AddWriteFlags(flag.CommandLine)
+	opName := flag.String("op", "mean", "合并方式：mean、sum、max、min")
+	output := flag.String("o", "", "输出 bigWig 文件（必需）")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwmerge [选项] -o out.bw in1.bw in2.bw ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *output == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, *output, flag.Args(), *opName, wf); err != nil {
+		cliutil.Fatal("bwmerge", err)
+	}
+}
+
+func run(ctx context.Context, output string, inputs []string, opName string, wf *cliutil.WriteFlags) error {
+	op, err := gb.ParseAggOp(opName)
+	if err != nil {
+		return err
+	}
+	opts, err := wf.Options()
+	if err != nil {
+		return err
+	}
+	if len(inputs) < 2 {
+		return errors.New("at least two input files are required")
+	}
+	return gb.MergeContext(ctx, output, inputs, op, opts...)
+}
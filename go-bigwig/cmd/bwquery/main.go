@@ -0,0 +1,141 @@
+// bwquery 从 bigWig 文件或 URL 中取出若干区间的数据，输出 bedGraph、TSV、JSON 或原始值，便于在管道中使用
+//
+//	bwquery [-format bedgraph|tsv|json|values] [-bins N] [-bed regions.bed] [-o out] file.bw|URL [chrom[:start-end] ...]
+//
+// 区间坐标从 0 开始、左闭右开；只写染色体名表示整条染色体；不给出区间时 bedgraph 格式导出整个文件
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	var specs cliutil.Strings
+	flag.Var(&specs, "region", "查询区间 chrom:start-end，可重复；也可以写在文件名之后")
+	bed := flag.String("bed", "", "从 BED 文件读取查询区间（前三列）")
+	format := flag.String("format", "bedgraph", "输出格式：bedgraph（区间内的记录）、tsv（每个区间一行的分箱矩阵）、json（每个区间的分箱轨道）、values（每个区间一行的原始记录值）")
+	bins := flag.Int("bins", 0, "tsv/json 格式每个区间的 bin 数，默认 tsv 为 1、json 为 100")
+	workers := flag.Int("workers", 0, "values 格式并行查询的句柄数，0 表示 CPU 核数")
+	stats := flag.Bool("stats", false, "结束时向标准错误输出本次查询的 IO 统计（JSON）")
+	output := flag.String("o", "-", "输出文件，- 表示标准输出，以 .gz 结尾时压缩")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwquery [选项] file.bw|URL [chrom[:start-end] ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	specs = append(specs, flag.Args()[1:]...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, flag.Arg(0), specs, *bed, strings.ToLower(*format), *bins, *workers, *output, *stats); err != nil {
+		cliutil.Fatal("bwquery", err)
+	}
+}
+
+func run(ctx context.Context, path string, specs []string, bed, format string, bins, workers int, output string, stats bool) error {
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		return err
+	}
+	defer gb.CloseBigWig(fp)
+	if stats {
+		var qs gb.QueryStats
+		stop := fp.CollectStats(&qs)
+		defer func() {
+			stop()
+			fmt.Fprintln(os.Stderr, "bwquery: io stats", qs.String())
+		}()
+	}
+	regions, err := cliutil.Regions(fp, specs, bed)
+	if err != nil {
+		return err
+	}
+	if len(regions) == 0 && format != "bedgraph" {
+		return errors.New("no regions given")
+	}
+	switch format {
+	case "bedgraph", "tsv", "json", "values":
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	out, err := cliutil.Create(output)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "bedgraph":
+		err = fp.WriteBedGraphContext(ctx, out, false, regions...)
+	case "tsv":
+		if bins <= 0 {
+			bins = 1
+		}
+		err = fp.WriteMatrixTable(ctx, out, regions, bins)
+	case "json":
+		if bins <= 0 {
+			bins = 100
+		}
+		err = writeJSON(out, fp, regions, bins)
+	case "values":
+		err = writeValues(ctx, out, fp, regions, workers)
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeJSON 以 JSON 数组输出每个区间的分箱轨道（见 MarshalRegionJSON）
+func writeJSON(out *cliutil.Output, fp *gb.Bigwig_file_out, regions []gb.Region, bins int) error {
+	out.WriteString("[")
+	for i, r := range regions {
+		b, err := fp.MarshalRegionJSON(r.Chrom, r.Start, r.End, bins)
+		if err != nil {
+			return fmt.Errorf("%s:%d-%d: %w", r.Chrom, r.Start, r.End, err)
+		}
+		if i > 0 {
+			out.WriteString(",\n")
+		}
+		out.Write(b)
+	}
+	_, err := out.WriteString("]\n")
+	return err
+}
+
+// writeValues 并行查询所有区间，每个区间输出一行：chrom、start、end 和以逗号分隔的记录值
+func writeValues(ctx context.Context, out *cliutil.Output, fp *gb.Bigwig_file_out, regions []gb.Region, workers int) error {
+	results, err := fp.QueryManyContext(ctx, regions, workers)
+	if err != nil {
+		return err
+	}
+	var line bytes.Buffer
+	for _, r := range results {
+		line.Reset()
+		fmt.Fprintf(&line, "%s\t%d\t%d\t", r.Region.Chrom, r.Region.Start, r.Region.End)
+		for j, v := range r.Values {
+			if j > 0 {
+				line.WriteByte(',')
+			}
+			fmt.Fprint(&line, v)
+		}
+		line.WriteByte('\n')
+		if _, err := out.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// bwserve 通过 HTTP 提供已注册 bigWig 轨道的查询，可作为网页基因组浏览器的后端
+//
+//	bwserve [-addr :8080] [-timeout 30s] [-max-open 256] [-memory 512] name=file.bw|URL ...
+//
+// 接口见 bigwighttp 包；所有文件共享一个句柄池和内存预算，每个请求在 -timeout 后取消
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-bigwig/bigwighttp"
+	gb "go-bigwig/gobigwig"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "监听地址")
+	timeout := flag.Duration("timeout", bigwighttp.DefaultTimeout, "单个请求的超时时间")
+	maxOpen := flag.Int("max-open", 256, "同时打开的文件数上限")
+	memory := flag.Int64("memory", 512, "所有文件共享的缓存大小（MB）")
+	maxBases := flag.Int("max-bases", bigwighttp.DefaultMaxBases, "不分箱的 values/intervals 请求允许的最大区间长度")
+	resultCache := flag.Int64("result-cache", 0, "stats、分箱 values 和 HiGlass tile 结果的缓存大小（MB），0 表示不缓存")
+	reopen := flag.Duration("reopen-check", 0, "检查文件是否被替换（重新上传）的最小间隔，被替换时自动重新打开；0 表示不检查")
+	eagerZoom := flag.Bool("eager-zoom", false, "本地文件打开时读取全部 zoom 索引并常驻内存")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwserve [选项] [name=]file.bw|URL ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var opts []gb.OpenOption
+	if *eagerZoom {
+		opts = append(opts, gb.WithEagerZoomIndex())
+	}
+	if *reopen > 0 {
+		opts = append(opts, gb.WithReopenCheck(*reopen))
+	}
+	if *resultCache > 0 {
+		opts = append(opts, gb.WithResultCache(gb.NewResultCache(*resultCache<<20)))
+	}
+	pool := gb.NewPool(*maxOpen, *memory<<20, opts...)
+	defer pool.Close()
+	h := bigwighttp.Handler(pool, bigwighttp.WithTimeout(*timeout), bigwighttp.WithMaxBases(*maxBases))
+	for _, arg := range flag.Args() {
+		if err := register(h, arg); err != nil {
+			log.Fatalf("bwserve: %v", err)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           h,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      *timeout + 10*time.Second,
+	}
+	log.Printf("bwserve: serving %d tracks on %s", h.Len(), *addr)
+	log.Fatal(srv.ListenAndServe())
+}
+
+// register 注册 [name=]path 形式的轨道，省略 name 时使用去掉扩展名的文件名
+func register(h *bigwighttp.Server, arg string) error {
+	name, path, ok := strings.Cut(arg, "=")
+	if !ok {
+		path = arg
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return h.Register(name, path)
+}
@@ -0,0 +1,109 @@
+// bwstats 计算区间或 bin 上的 mean/min/max/sum/coverage/std 并输出表格，相当于 pyBigWig 的 bw.stats
+//
+//	bwstats [-type mean,max] [-bins N] [-exact] [-zoom-level L] [-bed regions.bed] file.bw|URL [chrom[:start-end] ...]
+//
+// 区间坐标从 0 开始、左闭右开；只写染色体名表示整条染色体；不给出区间时统计每条染色体
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	var specs cliutil.Strings
+	flag.Var(&specs, "region", "统计区间 chrom:start-end，可重复；也可以写在文件名之后")
+	bed := flag.String("bed", "", "从 BED 文件读取统计区间（前三列）")
+	types := flag.String("type", "mean", "统计量，多个用逗号分隔：mean、min、max、sum、coverage、std")
+	bins := flag.Int("bins", 1, "把每个区间均分为 N 个 bin，每个 bin 输出一行")
+	exact := flag.Bool("exact", false, "使用原始记录计算精确值，不使用 zoom 层级")
+	zoom := flag.Int("zoom-level", -1, "使用指定的 zoom 层级（从 0 开始，顺序与 bwinfo -zooms 一致），-1 表示自动选择")
+	na := flag.String("na", "NaN", "没有数据时的输出")
+	output := flag.String("o", "-", "输出文件，- 表示标准输出")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwstats [选项] file.bw|URL [chrom[:start-end] ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	specs = append(specs, flag.Args()[1:]...)
+	if *exact && *zoom >= 0 {
+		cliutil.Fatal("bwstats", fmt.Errorf("-exact and -zoom-level are mutually exclusive"))
+	}
+	opts := gb.StatsOptions{NBins: *bins, Exact: *exact, ZoomLevel: *zoom + 1}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, flag.Arg(0), specs, *bed, strings.Split(*types, ","), opts, *na, *output); err != nil {
+		cliutil.Fatal("bwstats", err)
+	}
+}
+
+func run(ctx context.Context, path string, specs []string, bed string, types []string, opts gb.StatsOptions, na, output string) error {
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		return err
+	}
+	defer gb.CloseBigWig(fp)
+	regions, err := cliutil.Regions(fp, specs, bed)
+	if err != nil {
+		return err
+	}
+	if len(regions) == 0 {
+		chroms, err := fp.Chroms()
+		if err != nil {
+			return err
+		}
+		for _, c := range chroms {
+			regions = append(regions, gb.Region{Chrom: c.Name, Start: 0, End: int(c.Length)})
+		}
+	}
+	if opts.NBins <= 0 {
+		opts.NBins = 1
+	}
+
+	var rows []gb.TableRow
+	for _, r := range regions {
+		cols := make([][]float64, len(types))
+		for i, t := range types {
+			opts.Type = strings.TrimSpace(t)
+			if cols[i], err = fp.Stats(ctx, r.Chrom, r.Start, r.End, opts); err != nil {
+				return err
+			}
+		}
+		for j := 0; j < opts.NBins; j++ {
+			s, e := binEdges(r.Start, r.End, opts.NBins, j)
+			row := gb.TableRow{Chrom: r.Chrom, Start: s, End: e, Values: make([]float64, len(types))}
+			for i := range types {
+				row.Values[i] = cols[i][j]
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	out, err := cliutil.Create(output)
+	if err != nil {
+		return err
+	}
+	err = gb.WriteTable(out, types, rows, gb.WithNA(na))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// binEdges 返回 [start, end) 均分为 n 份时第 i 个 bin 的边界，与 Stats 的分箱方式一致
+func binEdges(start, end, n, i int) (int, int) {
+	size := float64(end-start) / float64(n)
+	return start + int(uint32(float64(i)*size)), start + int(uint32(float64(i+1)*size))
+}
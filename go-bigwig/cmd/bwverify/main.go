@@ -0,0 +1,126 @@
+// bwverify 检查 bigWig 文件的完整性并输出 JSON 报告，用退出码区分问题类别，便于在数据入库流程中拦截坏文件
+//
+//	bwverify [-level header|index|data] [-q] [-fix-summary] file.bw|URL
+//
+// -fix-summary 在发现的问题全部是 summary 与数据不符时就地改写本地文件的 summary 段，并输出改写后重新检查的报告；
+// 同时存在其他类别的问题（或问题数达到上限、检查提前结束）时不改写文件，只输出原来的报告
+//
+// 退出码：
+//
+//	0 没有发现问题
+//	1 文件无法打开
+//	2 参数错误
+//	3 文件被截断
+//	4 文件头、zoom 头或染色体树不合法
+//	5 索引损坏
+//	6 数据块损坏
+//	7 summary 与数据不符
+//
+// 同时存在多类问题时按上面的顺序取第一个
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	gb "go-bigwig/gobigwig"
+)
+
+const (
+	exitOK = iota
+	exitOpen
+	exitUsage
+	exitTruncated
+	exitFormat
+	exitIndex
+	exitData
+	exitSummary
+)
+
+// exitCodes 按优先级排列的问题类别与退出码
+var exitCodes = []struct {
+	kind gb.IssueKind
+	code int
+}{
+	{gb.IssueTruncated, exitTruncated},
+	{gb.IssueFormat, exitFormat},
+	{gb.IssueIndex, exitIndex},
+	{gb.IssueData, exitData},
+	{gb.IssueSummary, exitSummary},
+}
+
+var levels = map[string]gb.VerifyLevel{
+	"header": gb.VerifyHeader,
+	"index":  gb.VerifyIndex,
+	"data":   gb.VerifyData,
+}
+
+// result 是输出的 JSON 报告
+type result struct {
+	gb.Report
+	OK       bool   `json:"ok"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exitCode"`
+}
+
+func main() {
+	levelName := flag.String("level", "data", "检查深度：header（文件头与染色体树）、index（加上全部索引）、data（加上解压每个数据块并重算 summary）")
+	quiet := flag.Bool("q", false, "不输出报告，只设置退出码")
+	fix := flag.Bool("fix-summary", false, "只有 summary 与数据不符时就地改写 summary 段并重新检查（仅本地文件，需要 -level data）")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: bwverify [-level header|index|data] [-q] [-fix-summary] file.bw|URL")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	level, ok := levels[*levelName]
+	if flag.NArg() != 1 || !ok || *fix && level != gb.VerifyData {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+
+	report, err := gb.Verify(flag.Arg(0), level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bwverify:", err)
+		os.Exit(exitOpen)
+	}
+	if *fix && report.Has(gb.IssueSummary) && !onlySummary(report) {
+		fmt.Fprintln(os.Stderr, "bwverify: not rewriting the summary: the file has other problems")
+	} else if *fix && report.Has(gb.IssueSummary) {
+		if _, err := gb.RecomputeSummary(flag.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, "bwverify:", err)
+		} else if report, err = gb.Verify(flag.Arg(0), level); err != nil {
+			fmt.Fprintln(os.Stderr, "bwverify:", err)
+			os.Exit(exitOpen)
+		}
+	}
+	res := result{Report: report, OK: report.OK(), Status: "ok", ExitCode: exitOK}
+	for _, c := range exitCodes {
+		if report.Has(c.kind) {
+			res.Status, res.ExitCode = c.kind.String(), c.code
+			break
+		}
+	}
+	if !*quiet {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(res); err != nil {
+			fmt.Fprintln(os.Stderr, "bwverify:", err)
+		}
+	}
+	os.Exit(res.ExitCode)
+}
+
+// onlySummary 判断 report 列出了全部问题且它们都是 summary 与数据不符
+func onlySummary(report gb.Report) bool {
+	if report.IssuesCapped {
+		return false
+	}
+	for _, i := range report.Issues {
+		if i.Kind != gb.IssueSummary {
+			return false
+		}
+	}
+	return true
+}
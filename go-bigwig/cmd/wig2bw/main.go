@@ -0,0 +1,48 @@
+// wig2bw 把 wiggle（fixedStep/variableStep/bedGraph 段）转换为 bigWig，相当于 UCSC wigToBigWig
+//
+//	wig2bw [选项] in.wig[.gz]|- chrom.sizes out.bw
+//	wig2bw [选项] -genome hg38 in.wig[.gz]|- out.bw
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/cliutil"
+)
+
+func main() {
+	wf := cliutil.AddConvertFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "用法: wig2bw [选项] in.wig chrom.sizes out.bw\n      wig2bw [选项] -genome NAME in.wig out.bw")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	in, sizesPath, out, ok := wf.ConvertArgs(flag.Args())
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts, err := wf.Options()
+	if err != nil {
+		cliutil.Fatal("wig2bw", err)
+	}
+	if err := run(in, sizesPath, out, wf, opts); err != nil {
+		cliutil.Fatal("wig2bw", err)
+	}
+}
+
+func run(in, sizesPath, out string, wf *cliutil.WriteFlags, opts []gb.WriteOption) error {
+	sizes, err := wf.ChromSizes(sizesPath)
+	if err != nil {
+		return err
+	}
+	r, err := cliutil.Open(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return gb.ConvertWiggle(r, sizes, out, opts...)
+}
@@ -1,35 +1,13 @@
 package gobigwig
 
-/*
-#cgo CFLAGS: -I.
-#cgo LDFLAGS: -lm
-#include <stdint.h>
-#include <stdlib.h>
-#include <windows.h>  // 引入Windows头文件，使用Windows原生类型
-
-// Windows兼容：用Windows原生类型替代C99类型（避免uint64_t未定义）
-struct CBWFileInfo {
-    WORD    Version;           // 对应uint16_t（2字节）
-    WORD    NLevels;           // 对应uint16_t
-    WORD    FieldCount;        // 对应uint16_t
-    WORD    DefinedFieldCount; // 对应uint16_t
-    DWORD   Bufsize;           // 对应uint32_t（4字节）
-    ULONGLONG Extensionoffset; // 对应uint64_t（8字节，Windows原生）
-    ULONGLONG NBasesCovered;   // 对应uint64_t
-    double  MinVal;            // 保持不变（8字节）
-    double  MaxVal;
-    double  SumData;
-    double  SumSquared;
-};
-*/
-import "C"
-
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math"
+	"os"
 	"runtime"
 	"sync"
-	"unsafe"
 )
 
 // -------------------------- 你原有结构体（保持不变） --------------------------
@@ -53,17 +31,20 @@ type FileInfo_bw_out struct {
 }
 
 // -------------------------- 你原有核心方法（仅修正1行错误） --------------------------
-func OpenBigWig(fname string) (*Bigwig_file_out, error) {
+func OpenBigWig(fname string, opts ...OpenOption) (*Bigwig_file_out, error) {
 	// 1. 检查是否是 BigWig 文件
-	isBw, err := bwisBigWig(fname)
+	ftype, endian, err := DetectType(fname, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("检查文件格式失败: %w", err)
 	}
-	if !isBw {
+	switch {
+	case ftype == FileTypeUnknown:
 		return nil, fmt.Errorf("不是有效的 BigWig 文件")
+	case ftype != FileTypeBigWig:
+		return nil, fmt.Errorf("不是 BigWig 文件（检测到 %s 的 %s 文件）", endian, ftype)
 	}
 	// 2. 打开文件
-	url, err := Open(fname)
+	url, err := Open(fname, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("打开文件失败: %w", err)
 	}
@@ -71,26 +52,40 @@ func OpenBigWig(fname string) (*Bigwig_file_out, error) {
 		URL:     url,
 		IsWrite: false,
 		Type:    0, // 0 = BigWig
+		Opts:    newOpenOptions(opts),
+	}
+	fp.budget = fp.Opts.MemoryBudget
+	if fp.budget == nil && fp.Opts.MemoryLimit > 0 {
+		fp.budget = NewMemoryBudget(fp.Opts.MemoryLimit)
+	}
+	if fp.budget != nil {
+		fp.cacheOwner = bwNextCacheOwner()
 	}
+	fp.shared = &bwShared{}
+	fp.shared.refs.Store(1)
 	// 3. 读取文件头
 	if err := bwHdrRead(fp); err != nil {
 		url.Close()
 		return nil, fmt.Errorf("读取文件头失败: %w", err)
 	}
-	// 4. 读取染色体列表
-	cl, err := bwReadchromList(fp)
-	if err != nil {
-		url.Close()
-		return nil, fmt.Errorf("读取染色体列表失败: %w", err)
-	}
-	fp.Cl = cl
-	// 5. 读取索引
-	idx := bwReadIndex(fp, 0)
-	if idx == nil {
-		url.Close()
-		return nil, fmt.Errorf("读取索引失败")
+	fp.progress = newBWProgress(fp.Opts.Progress, bwDataSectionSize(fp))
+	// 4. 读取染色体列表和索引（延迟模式下推迟到首次使用）
+	if !fp.Opts.Lazy {
+		if err := bwLoadChromList(fp); err != nil {
+			url.Close()
+			return nil, fmt.Errorf("读取染色体列表失败: %w", err)
+		}
+		if err := bwLoadIndex(fp); err != nil {
+			url.Close()
+			return nil, fmt.Errorf("读取索引失败: %w", err)
+		}
+		if fp.Opts.EagerZoomIndex && url.isLocal() {
+			if err := bwLoadZoomIndexes(fp); err != nil {
+				url.Close()
+				return nil, fmt.Errorf("读取 zoom 索引失败: %w", err)
+			}
+		}
 	}
-	fp.Idx = idx
 
 	fbo := FileInfo_bw_out{
 		Version:           fp.Hdr.version,
@@ -115,28 +110,99 @@ func OpenBigWig(fname string) (*Bigwig_file_out, error) {
 func CloseBigWig(fp *Bigwig_file_out) {
 	if fp.bf_fp != nil && fp.bf_fp.URL != nil {
 		fp.bf_fp.URL.Close()
+		// 克隆的句柄共享缓存，最后一个句柄关闭时才清除
+		if sh := fp.bf_fp.shared; sh == nil || sh.refs.Add(-1) == 0 {
+			fp.bf_fp.budget.dropOwner(fp.bf_fp.cacheOwner)
+			if sh != nil && sh.tmp != "" {
+				os.Remove(sh.tmp)
+			}
+		}
 	}
 }
 
+// ReadBigWigSignal 返回与 chrom:[start, end) 重叠的每条记录的值，每个区间一个值而不是每个碱基一个值
+// （逐碱基的值见 GetValuesContext），出错时返回 nil；需要错误信息时使用 ReadBigWigSignalContext
 func (fp *Bigwig_file_out) ReadBigWigSignal(chrom string, start int, end int) []float32 {
+	values, err := fp.ReadBigWigSignalContext(context.Background(), chrom, start, end)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// ReadBigWigSignalContext 与 ReadBigWigSignal 相同，但在每个数据块的读取、解压之间
+// 以及块内解码过程中检查 ctx；ctx 被取消时尽快停止并返回 ctx.Err()
+// 开启 SortResults（默认）时，返回值按区间 start 排序且不含重复区间
+// 区间超出染色体末端时按 RangePolicy 截断或返回 ErrOutOfRange；索引或数据块读取失败时返回错误
+func (fp *Bigwig_file_out) ReadBigWigSignalContext(ctx context.Context, chrom string, start int, end int) ([]float32, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
 	start_uint32 := uint32(start)
 	end_uint32 := uint32(end)
 	blocksPerIteration := uint32(10) // 每次处理10个块
-	iter := bwOverlappingIntervalsIterator(fp.bf_fp, chrom, start_uint32, end_uint32, blocksPerIteration)
+	iter := bwOverlappingIntervalsIterator(ctx, fp.bf_fp, chrom, start_uint32, end_uint32, blocksPerIteration)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if iter == nil {
-		fmt.Println("创建迭代器失败")
-		return nil
+		return nil, fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	defer bwIteratorDestroy(iter)
+	if iter.Err != nil {
+		return nil, iter.Err
 	}
 	output_float32 := []float32{}
+	sorted := fp.bf_fp.Opts.SortResults
+	blacklist := fp.bf_fp.Opts.Blacklist
+	all := &bwOverlappingIntervals_t{}
 	// 迭代所有数据块
 	for iter.Data != nil {
 		intervals := iter.Intervals
 		if intervals != nil {
-			output_float32 = append(output_float32, intervals.Value...)
+			if sorted {
+				for i := uint32(0); i < intervals.L; i++ {
+					all = pushIntervals(all, intervals.Start[i], intervals.End[i], intervals.Value[i])
+				}
+			} else if blacklist != nil {
+				for i := uint32(0); i < intervals.L; i++ {
+					output_float32 = append(output_float32, bwMaskInterval(blacklist, chrom, intervals.Start[i], intervals.End[i], intervals.Value[i]))
+				}
+			} else {
+				output_float32 = append(output_float32, intervals.Value[:intervals.L]...)
+			}
+		}
+		next := bwIteratorNext(iter)
+		if next == nil {
+			if iter.Err != nil {
+				return nil, iter.Err
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("failed to read data blocks")
+		}
+		iter = next
+	}
+	if sorted {
+		sortIntervals(all)
+		output_float32 = append(output_float32, all.Value[:all.L]...)
+		if blacklist != nil {
+			for i := range output_float32 {
+				output_float32[i] = bwMaskInterval(blacklist, chrom, all.Start[i], all.End[i], output_float32[i])
+			}
 		}
-		iter = bwIteratorNext(iter)
 	}
-	return output_float32
+	return output_float32, nil
+}
+
+// bwMaskInterval 区间与黑名单重叠时返回 NaN，否则返回 v
+func bwMaskInterval(b *Blacklist, chrom string, start, end uint32, v float32) float32 {
+	if b.Overlaps(chrom, start, end) {
+		return float32(math.NaN())
+	}
+	return v
 }
 
 func (fp *Bigwig_file_out) Getmeta_hdr() {
@@ -157,6 +223,9 @@ func (fp *Bigwig_file_out) GetMaxVal() float64          { return fp.Info.MaxVal
 func (fp *Bigwig_file_out) GetSumData() float64         { return fp.Info.SumData }
 func (fp *Bigwig_file_out) GetSumSquared() float64      { return fp.Info.SumSquared }
 
+// Metrics 返回打开时通过 WithMetrics 设置的统计接收者，未设置时返回 nil
+func (fp *Bigwig_file_out) Metrics() Metrics { return fp.bf_fp.Opts.Metrics }
+
 func (fp *Bigwig_file_out) PrintZoomInfo() {
 	if fp.bf_fp.Hdr == nil || len(fp.bf_fp.Hdr.ZoomHdrs) == 0 {
 		fmt.Println("No zoom levels available")
@@ -181,6 +250,8 @@ type BWOptions_Zoom struct {
 	IndexZoomModel ZoomSelector // zoom选择策略函数
 }
 
+// GetZoomValues 返回 chrom:[start, end) 上 numBins 个 bin 的 zoom 汇总值，出错时返回 nil；
+// 需要错误信息时使用 GetZoomValuesContext
 func (fp *Bigwig_file_out) GetZoomValues(
 	chrom string,
 	start int,
@@ -189,9 +260,44 @@ func (fp *Bigwig_file_out) GetZoomValues(
 	useClosest bool,
 	desiredReduction int,
 ) []float32 {
+	values, err := fp.GetZoomValuesContext(context.Background(), chrom, start, end, numBins, useClosest, desiredReduction)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// GetZoomValuesContext 与 GetZoomValues 相同，但在每个 zoom 块之间、块内解码和分箱过程中检查 ctx，
+// 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) GetZoomValuesContext(
+	ctx context.Context,
+	chrom string,
+	start int,
+	end int,
+	numBins int,
+	useClosest bool,
+	desiredReduction int,
+) ([]float32, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return fp.zoomValues(ctx, chrom, start, end, numBins, useClosest, desiredReduction)
+}
+
+// zoomValues 在已校验、从 0 开始的区间 [start, end) 上执行 GetZoomValuesContext 的查询
+func (fp *Bigwig_file_out) zoomValues(ctx context.Context, chrom string, start, end, numBins int, useClosest bool, desiredReduction int) ([]float32, error) {
+	zoomIdx, err := fp.selectZoom(useClosest, desiredReduction)
+	if err != nil {
+		return nil, err
+	}
+	return fp.zoomLevelValues(ctx, chrom, start, end, numBins, zoomIdx)
+}
 
+// selectZoom 按 GetZoomValues 的规则选择 zoom 层级：useClosest 时取最接近 desiredReduction 的层级，
+// 否则取不超过它的最粗层级
+func (fp *Bigwig_file_out) selectZoom(useClosest bool, desiredReduction int) (int, error) {
 	opts := BWOptions_Zoom{
-		NumBins:     numBins,
 		SummaryType: "mean",
 	}
 
@@ -202,32 +308,96 @@ func (fp *Bigwig_file_out) GetZoomValues(
 	}
 
 	if len(fp.bf_fp.Hdr.ZoomHdrs) == 0 {
-		fmt.Println("no zoom headers available")
-		return nil
+		return -1, errors.New("no zoom headers available")
 	}
 
 	zhdr := fp.bf_fp.Hdr.ZoomHdrs[0]
 	// 核心修正：删除 &zhdr 中的 &，直接传入 zhdr（单层指针）
 	zoomIdx := opts.IndexZoomModel(zhdr, uint32(desiredReduction))
 	if zoomIdx < 0 {
-		fmt.Printf("no suitable zoom level found for desiredReduction=%d\n", desiredReduction)
+		return -1, fmt.Errorf("no suitable zoom level found for desiredReduction=%d", desiredReduction)
+	}
+	return zoomIdx, nil
+}
+
+// GetZoomValues64Context 与 GetZoomValuesContext 相同，但 zoom 汇总的分摊与求值全程使用 float64 并返回 float64，
+// 适用于深度测序等需要高精度统计的场景
+func (fp *Bigwig_file_out) GetZoomValues64Context(ctx context.Context, chrom string, start, end, numBins int, useClosest bool, desiredReduction int) ([]float64, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	zoomIdx, err := fp.selectZoom(useClosest, desiredReduction)
+	if err != nil {
+		return nil, err
+	}
+	return fp.zoomLevelValues64(ctx, chrom, start, end, numBins, zoomIdx)
+}
+
+// ZoomLevels 按文件中的顺序（通常从细到粗）返回各 zoom 层级的 reduction，下标即 GetZoomLevelValuesContext 的 zoomIdx
+func (fp *Bigwig_file_out) ZoomLevels() []uint32 {
+	if len(fp.bf_fp.Hdr.ZoomHdrs) == 0 {
 		return nil
 	}
+	return append([]uint32(nil), fp.bf_fp.Hdr.ZoomHdrs[0].Level...)
+}
+
+// GetZoomLevelValuesContext 与 GetZoomValuesContext 相同，但直接读取第 zoomIdx 个 zoom 层级
+// （ZoomLevels 中的下标），由调用方决定分辨率；zoomIdx 超出范围时返回 ErrOutOfRange
+func (fp *Bigwig_file_out) GetZoomLevelValuesContext(ctx context.Context, chrom string, start, end, numBins, zoomIdx int) ([]float32, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(fp.ZoomLevels()); zoomIdx < 0 || zoomIdx >= n {
+		return nil, fmt.Errorf("%w: zoom level %d (file has %d)", ErrOutOfRange, zoomIdx, n)
+	}
+	return fp.zoomLevelValues(ctx, chrom, start, end, numBins, zoomIdx)
+}
+
+// GetZoomLevelValues64Context 与 GetZoomLevelValuesContext 相同，但全程使用 float64 并返回 float64
+func (fp *Bigwig_file_out) GetZoomLevelValues64Context(ctx context.Context, chrom string, start, end, numBins, zoomIdx int) ([]float64, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(fp.ZoomLevels()); zoomIdx < 0 || zoomIdx >= n {
+		return nil, fmt.Errorf("%w: zoom level %d (file has %d)", ErrOutOfRange, zoomIdx, n)
+	}
+	return fp.zoomLevelValues64(ctx, chrom, start, end, numBins, zoomIdx)
+}
+
+// zoomLevelValues 读取第 zoomIdx 个 zoom 层级上 [start, end) 的 numBins 个平均值，没有数据的 bin 为 0
+func (fp *Bigwig_file_out) zoomLevelValues(ctx context.Context, chrom string, start, end, numBins, zoomIdx int) ([]float32, error) {
+	values64, err := fp.zoomLevelValues64(ctx, chrom, start, end, numBins, zoomIdx)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float32, len(values64))
+	for i, v := range values64 {
+		values[i] = float32(v)
+	}
+	return values, nil
+}
 
-	values, err := bwGetValuesFromZoom(
-		fp.bf_fp, zoomIdx, chrom,
+// zoomLevelValues64 与 zoomLevelValues 相同，但全程以 float64 计算并返回
+func (fp *Bigwig_file_out) zoomLevelValues64(ctx context.Context, chrom string, start, end, numBins, zoomIdx int) ([]float64, error) {
+	values, err := bwGetValuesFromZoom64(
+		ctx, fp.bf_fp, zoomIdx, chrom,
 		uint32(start), uint32(end),
-		opts.NumBins, opts.SummaryType,
+		numBins, "mean",
 	)
 	if err != nil {
-		fmt.Printf("failed to read zoom data: %v\n", err)
-		return nil
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("failed to read zoom data: %v", err)
 	}
 
 	// 并行替换 NaN 为 0
 	n := len(values)
 	if n == 0 {
-		return values
+		return values, nil
 	}
 
 	numCPU := runtime.NumCPU()
@@ -249,7 +419,7 @@ func (fp *Bigwig_file_out) GetZoomValues(
 		go func(s, e int) {
 			defer wg.Done()
 			for j := s; j < e; j++ {
-				if math.IsNaN(float64(values[j])) {
+				if math.IsNaN(values[j]) {
 					values[j] = 0
 				}
 			}
@@ -257,162 +427,133 @@ func (fp *Bigwig_file_out) GetZoomValues(
 	}
 
 	wg.Wait()
-	return values
+	if b := fp.bf_fp.Opts.Blacklist; b != nil {
+		b.maskBins(chrom, uint32(start), uint32(end), values)
+	}
+	return values, nil
 }
 
-// -------------------------- C绑定接口（Windows兼容，无变化） --------------------------
-
-// 1. 打开文件（返回句柄，失败返回0）
-//export BigWigOpen
-func BigWigOpen(fname *C.char) C.uintptr_t {
-	if fname == nil {
-		fmt.Println("BigWigOpen: 文件名不能为空")
-		return 0
+// Preload 立即读取延迟打开时推迟的染色体列表、主索引以及所有 zoom 层级的索引
+// 对本地文件或需要稳定查询延迟的场景，在打开后调用一次即可
+func (fp *Bigwig_file_out) Preload() error {
+	bw := fp.bf_fp
+	if err := bwLoadChromList(bw); err != nil {
+		return fmt.Errorf("读取染色体列表失败: %w", err)
 	}
-	goFname := C.GoString(fname)
-	fp, err := OpenBigWig(goFname)
-	if err != nil {
-		fmt.Printf("BigWigOpen: 打开失败: %v\n", err)
-		return 0
+	if err := bwLoadIndex(bw); err != nil {
+		return fmt.Errorf("读取索引失败: %w", err)
 	}
-	// Go指针转句柄返回给Python
-	return C.uintptr_t(uintptr(unsafe.Pointer(fp)))
-}
-
-// 2. 关闭文件（释放资源）
-//export BigWigClose
-func BigWigClose(handle C.uintptr_t) {
-	if handle == 0 {
-		return
+	if len(bw.Hdr.ZoomHdrs) == 0 {
+		return nil
 	}
-	fp := (*Bigwig_file_out)(unsafe.Pointer(uintptr(handle)))
-	CloseBigWig(fp)
-}
-
-// 3. 读取原始信号（返回float数组指针+长度，Python侧需释放内存）
-//export BigWigReadSignal
-func BigWigReadSignal(
-	handle C.uintptr_t,
-	chrom *C.char,
-	start C.int,
-	end C.int,
-	outLen *C.int,
-) *C.float {
-	// 参数校验
-	if handle == 0 || chrom == nil || outLen == nil || start < 0 || end <= start {
-		if outLen != nil {
-			*outLen = 0
+	zhdr := bw.Hdr.ZoomHdrs[0]
+	for i := range zhdr.Level {
+		if _, err := bwZoomIndex(bw, i); err != nil {
+			return fmt.Errorf("读取 zoom 索引 %d 失败: %w", i, err)
 		}
-		return nil
 	}
+	return nil
+}
 
-	// 类型转换
-	fp := (*Bigwig_file_out)(unsafe.Pointer(uintptr(handle)))
-	goChrom := C.GoString(chrom)
-	goVals := fp.ReadBigWigSignal(goChrom, int(start), int(end))
-
-	// 处理返回结果
-	if goVals == nil || len(goVals) == 0 {
-		*outLen = 0
-		return nil
-	}
-	*outLen = C.int(len(goVals))
+// Header 返回文件头、zoom 层级和全局 summary
+func (fp *Bigwig_file_out) Header() *Header {
+	return bwExportHeader(fp.bf_fp)
+}
 
-	// 分配C内存并拷贝数据（Python侧需调用BigWigFree释放）
-	cVals := (*C.float)(C.malloc(C.size_t(len(goVals)) * C.sizeof_float))
-	if cVals == nil {
-		fmt.Println("BigWigReadSignal: 内存分配失败")
-		*outLen = 0
-		return nil
+// Chroms 返回文件中的染色体列表，顺序与染色体 ID 一致；延迟打开时会先读取染色体树
+func (fp *Bigwig_file_out) Chroms() ([]ChromInfo, error) {
+	bw := fp.bf_fp
+	if err := bwLoadChromList(bw); err != nil {
+		return nil, err
 	}
-	goBuf := (*[1 << 30]C.float)(unsafe.Pointer(cVals))[:len(goVals):len(goVals)]
-	for i, v := range goVals {
-		goBuf[i] = C.float(v)
+	out := make([]ChromInfo, len(bw.Cl.Chrom))
+	for i := range bw.Cl.Chrom {
+		out[i] = ChromInfo{ID: uint32(i), Name: bw.Cl.Chrom[i], Length: bw.Cl.Len[i]}
 	}
-
-	return cVals
+	return out, nil
 }
 
-// 4. 获取Zoom缩放数据（并行NaN转0）
-//export BigWigGetZoomValues
-func BigWigGetZoomValues(
-	handle C.uintptr_t,
-	chrom *C.char,
-	start C.int,
-	end C.int,
-	numBins C.int,
-	useClosest C.int,
-	desiredReduction C.int,
-	outLen *C.int,
-) *C.float {
-	// 参数校验
-	if handle == 0 || chrom == nil || outLen == nil || start < 0 || end <= start || numBins <= 0 || desiredReduction < 1 {
-		if outLen != nil {
-			*outLen = 0
-		}
-		return nil
-	}
+// ChromLength 返回染色体长度，通过 WithChromLengths 覆盖过的染色体返回覆盖后的长度
+func (fp *Bigwig_file_out) ChromLength(chrom string) (uint32, bool) {
+	return bwChromLength(fp.bf_fp, chrom)
+}
 
-	// 类型转换
-	fp := (*Bigwig_file_out)(unsafe.Pointer(uintptr(handle)))
-	goChrom := C.GoString(chrom)
-	goVals := fp.GetZoomValues(
-		goChrom, int(start), int(end),
-		int(numBins), useClosest != 0, int(desiredReduction),
-	)
+// GetValuesContext 返回 [start, end) 中每个碱基的值，没有数据的位置为 NaN
+// end 超过染色体长度时按 RangePolicy 截断（返回值相应变短）或返回 ErrOutOfRange；
+// 通过 WithChromLengths 加长的染色体，记录长度之后的尾部全部为 NaN；
+// 重叠区间按 WithOverlapPolicy 设置的方式合并
+func (fp *Bigwig_file_out) GetValuesContext(ctx context.Context, chrom string, start, end int) ([]float32, error) {
+	return fp.GetValuesPolicyContext(ctx, chrom, start, end, fp.bf_fp.Opts.OverlapPolicy)
+}
 
-	// 处理返回结果
-	if goVals == nil || len(goVals) == 0 {
-		*outLen = 0
-		return nil
+// GetValuesPolicyContext 与 GetValuesContext 相同，但本次查询的重叠区间按 policy 合并
+func (fp *Bigwig_file_out) GetValuesPolicyContext(ctx context.Context, chrom string, start, end int, policy OverlapPolicy) ([]float32, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
 	}
-	*outLen = C.int(len(goVals))
-
-	// 分配C内存并拷贝数据
-	cVals := (*C.float)(C.malloc(C.size_t(len(goVals)) * C.sizeof_float))
-	if cVals == nil {
-		fmt.Println("BigWigGetZoomValues: 内存分配失败")
-		*outLen = 0
-		return nil
+	values := make([]float32, end-start)
+	for i := range values {
+		values[i] = float32(math.NaN())
+	}
+	// 只查询文件中实际记录的部分，其余保持 NaN
+	recorded, _ := bwRecordedChromLength(fp.bf_fp, chrom)
+	qend := uint32(end)
+	if qend > recorded {
+		qend = recorded
 	}
-	goBuf := (*[1 << 30]C.float)(unsafe.Pointer(cVals))[:len(goVals):len(goVals)]
-	for i, v := range goVals {
-		goBuf[i] = C.float(v)
+	if uint32(start) >= qend {
+		return values, nil
 	}
+	out, err := bwGetValues(ctx, fp.bf_fp, chrom, uint32(start), qend, true, policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if out != nil {
+		copy(values, out.Value[:out.L])
+	}
+	if b := fp.bf_fp.Opts.Blacklist; b != nil {
+		b.maskValues(chrom, uint32(start), values)
+	}
+	return values, nil
+}
+
+// ReadBigWigSignalInt16 读取区间的逐碱基值（与 GetValuesContext 相同，没有数据的碱基为 NaN）
+// 并按每 window 个碱基一组量化为 int16，每组的 scale/offset 在返回值的 Windows 中，用于缩小机器学习特征的存储体积
+func (fp *Bigwig_file_out) ReadBigWigSignalInt16(ctx context.Context, chrom string, start, end, window int) (*QuantizedInt16, error) {
+	values, err := fp.GetValuesContext(ctx, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return QuantizeInt16(values, window), nil
+}
 
-	return cVals
+// ReadBigWigSignalUint8 与 ReadBigWigSignalInt16 相同，但量化为 uint8
+func (fp *Bigwig_file_out) ReadBigWigSignalUint8(ctx context.Context, chrom string, start, end, window int) (*QuantizedUint8, error) {
+	values, err := fp.GetValuesContext(ctx, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return QuantizeUint8(values, window), nil
 }
 
-// 5. 获取文件元信息（Windows兼容：使用Windows原生结构体类型）
-//export BigWigGetInfo
-func BigWigGetInfo(handle C.uintptr_t, info *C.struct_CBWFileInfo) C.int {
-	if handle == 0 || info == nil {
-		return -1 // 失败返回-1
-	}
-	// 句柄转回Go结构体
-	fp := (*Bigwig_file_out)(unsafe.Pointer(uintptr(handle)))
-	goInfo := fp.Info
-
-	// 赋值到Windows兼容的C结构体（类型对应：Go → Windows C类型）
-	info.Version = C.WORD(goInfo.Version)
-	info.NLevels = C.WORD(goInfo.NLevels)
-	info.FieldCount = C.WORD(goInfo.FieldCount)
-	info.DefinedFieldCount = C.WORD(goInfo.DefinedFieldCount)
-	info.Bufsize = C.DWORD(goInfo.Bufsize)
-	info.Extensionoffset = C.ULONGLONG(goInfo.Extensionoffset)
-	info.NBasesCovered = C.ULONGLONG(goInfo.NBasesCovered)
-	info.MinVal = C.double(goInfo.MinVal)
-	info.MaxVal = C.double(goInfo.MaxVal)
-	info.SumData = C.double(goInfo.SumData)
-	info.SumSquared = C.double(goInfo.SumSquared)
-
-	return 0 // 成功返回0
+// GetZoomValuesInt16 获取 zoom 分箱值并按每 window 个 bin 一组量化为 int16
+func (fp *Bigwig_file_out) GetZoomValuesInt16(ctx context.Context, chrom string, start, end, numBins int, useClosest bool, desiredReduction, window int) (*QuantizedInt16, error) {
+	values, err := fp.GetZoomValuesContext(ctx, chrom, start, end, numBins, useClosest, desiredReduction)
+	if err != nil {
+		return nil, err
+	}
+	return QuantizeInt16(values, window), nil
 }
 
-// 6. 释放C内存（Python侧必须调用，避免内存泄漏）
-//export BigWigFree
-func BigWigFree(ptr unsafe.Pointer) {
-	if ptr != nil {
-		C.free(ptr)
+// GetZoomValuesUint8 获取 zoom 分箱值并按每 window 个 bin 一组量化为 uint8
+func (fp *Bigwig_file_out) GetZoomValuesUint8(ctx context.Context, chrom string, start, end, numBins int, useClosest bool, desiredReduction, window int) (*QuantizedUint8, error) {
+	values, err := fp.GetZoomValuesContext(ctx, chrom, start, end, numBins, useClosest, desiredReduction)
+	if err != nil {
+		return nil, err
 	}
-}
\ No newline at end of file
+	return QuantizeUint8(values, window), nil
+}
@@ -0,0 +1,1180 @@
+//go:build cgo_export
+
+// C 接口只在 cgo_export 标签下编译（共享库构建见 gobigwig_wrapper.go），
+// 不带该标签时 gobigwig 是纯 Go 包，可以在 CGO_ENABLED=0 下使用
+
+package gobigwig
+
+/*
+#cgo CFLAGS: -I.
+#cgo LDFLAGS: -lm
+#include <stdint.h>
+#include <stdlib.h>
+#include <wchar.h>
+
+// 线程安全：所有导出函数都可以在多个线程中并发调用。同一句柄上的调用按到达顺序串行执行
+// （它们共用一个文件读取位置），不同句柄之间可以并行；BigWigClose会等待该句柄上
+// 进行中的调用结束，之后再使用该句柄的调用返回失败。需要在同一文件上并行读取时，
+// 对同一路径多次调用BigWigOpen，每个线程使用自己的句柄。BigWigCreate返回的写入句柄同样
+// 按句柄串行化，BigWigWriterClose等待进行中的调用结束后再收尾
+//
+// 错误处理：导出函数失败时不向标准输出打印任何内容，只返回NULL/0/-1；
+// 失败原因保存在调用线程中，用BigWigLastError(handle)或BigWigLastGlobalError()读取
+
+// 结构体只使用<stdint.h>中的定长类型，同一份导出在Windows（.dll）、Linux（.so）和
+// macOS（.dylib）上都能编译，布局与之前的WORD/DWORD/ULONGLONG版本一致，已有调用方不受影响
+struct CBWFileInfo {
+    uint16_t Version;
+    uint16_t NLevels;
+    uint16_t FieldCount;
+    uint16_t DefinedFieldCount;
+    uint32_t Bufsize;
+    uint64_t Extensionoffset;
+    uint64_t NBasesCovered;
+    double  MinVal;
+    double  MaxVal;
+    double  SumData;
+    double  SumSquared;
+};
+
+// BigWigOpenEx的打开参数，全部字段为0（或NULL）时与BigWigOpen相同
+struct BWOpenOptions {
+    uint32_t    StructSize;         // 必须为sizeof(struct BWOpenOptions)，用于发现头文件与DLL不匹配
+    int32_t     TimeoutMs;          // 单个远程请求的超时时间（毫秒），0表示不限制
+    const char *Headers;            // 远程请求附加的请求头，每行一个"Name: value"，NULL表示不附加
+    const char *CacheDir;           // 远程小文件整体下载到该目录下的临时文件，NULL表示保存在内存中
+    int64_t     WholeFileThreshold; // 远程文件小于该字节数时整体下载，0为默认值（50 MB），<0表示禁用
+    int64_t     MemoryLimit;        // 块缓存等使用的内存上限（字节），0表示不缓存
+    int32_t     Prefetch;           // 远程顺序扫描时后台预读的范围数，0为默认值，<0表示禁用
+    int32_t     Lazy;               // 非0时打开时只读文件头，染色体列表和索引在首次使用时加载
+};
+
+// BigWigFeatures返回的功能位
+#define BW_FEATURE_WRITER 0x1 // BigWigCreate等写入接口
+#define BW_FEATURE_BIGBED 0x2 // bigBed读取（尚不支持，始终为0）
+#define BW_FEATURE_REMOTE 0x4 // BigWigOpen接受http(s)://地址
+
+// BigWigGetIntervalRecords返回的单条记录，坐标从0开始、左闭右开
+struct CBWInterval {
+    uint32_t Start;
+    uint32_t End;
+    float    Value;
+};
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// -------------------------- C绑定接口（Windows/Linux/macOS通用） --------------------------
+
+// 1. 打开文件（返回句柄，失败返回0）
+//export BigWigOpen
+func BigWigOpen(fname *C.char) C.uintptr_t {
+	bwClearError()
+	if fname == nil {
+		bwSetError(0, "BigWigOpen: 文件名不能为空")
+		return 0
+	}
+	goFname := C.GoString(fname)
+	fp, err := OpenBigWig(goFname)
+	if err != nil {
+		bwSetError(0, "BigWigOpen: 打开失败: %v", err)
+		return 0
+	}
+	// 返回句柄表中的编号，Go指针不经过C侧
+	return C.uintptr_t(bwRegisterHandle(fp))
+}
+
+// 2. 关闭文件（释放资源）
+//export BigWigClose
+func BigWigClose(handle C.uintptr_t) {
+	// 先从句柄表移除，重复关闭或关闭无效句柄时什么也不做
+	if fp := bwReleaseHandle(uint64(handle)); fp != nil {
+		CloseBigWig(fp)
+	}
+}
+
+// 3. 读取原始信号（返回float数组指针+长度，Python侧需释放内存）
+//export BigWigReadSignal
+func BigWigReadSignal(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	outLen *C.int,
+) *C.float {
+	// 参数校验
+	bwClearError()
+	if handle == 0 || chrom == nil || outLen == nil || start < 0 || end <= start {
+		if outLen != nil {
+			*outLen = 0
+		}
+		bwSetError(handle, "BigWigReadSignal: 参数无效")
+		return nil
+	}
+
+	// 从句柄表取回文件并独占它（句柄无效或已关闭时失败）
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		*outLen = 0
+		bwSetError(handle, "BigWigReadSignal: 句柄无效或已关闭")
+		return nil
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	goChrom := C.GoString(chrom)
+	goVals, err := fp.ReadBigWigSignalContext(ctx, goChrom, int(start), int(end))
+	if err != nil {
+		*outLen = 0
+		bwSetError(handle, "BigWigReadSignal: %v", err)
+		return nil
+	}
+
+	// 处理返回结果（区间内没有记录时返回NULL，*outLen为0，不算失败）
+	if len(goVals) == 0 {
+		*outLen = 0
+		return nil
+	}
+	*outLen = C.int(len(goVals))
+
+	// 分配C内存并拷贝数据（Python侧需调用BigWigFree释放）
+	cVals := (*C.float)(C.malloc(C.size_t(len(goVals)) * C.sizeof_float))
+	if cVals == nil {
+		bwSetError(handle, "BigWigReadSignal: 内存分配失败")
+		*outLen = 0
+		return nil
+	}
+	goBuf := unsafe.Slice(cVals, len(goVals))
+	for i, v := range goVals {
+		goBuf[i] = C.float(v)
+	}
+
+	return cVals
+}
+
+// 4. 获取Zoom缩放数据（并行NaN转0）
+//export BigWigGetZoomValues
+func BigWigGetZoomValues(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	numBins C.int,
+	useClosest C.int,
+	desiredReduction C.int,
+	outLen *C.int,
+) *C.float {
+	// 参数校验
+	bwClearError()
+	if handle == 0 || chrom == nil || outLen == nil || start < 0 || end <= start || numBins <= 0 || desiredReduction < 1 {
+		if outLen != nil {
+			*outLen = 0
+		}
+		bwSetError(handle, "BigWigGetZoomValues: 参数无效")
+		return nil
+	}
+
+	// 从句柄表取回文件并独占它（句柄无效或已关闭时失败）
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		*outLen = 0
+		bwSetError(handle, "BigWigGetZoomValues: 句柄无效或已关闭")
+		return nil
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	goChrom := C.GoString(chrom)
+	goVals, err := fp.GetZoomValuesContext(
+		ctx, goChrom, int(start), int(end),
+		int(numBins), useClosest != 0, int(desiredReduction),
+	)
+	if err != nil {
+		*outLen = 0
+		bwSetError(handle, "BigWigGetZoomValues: %v", err)
+		return nil
+	}
+
+	// 处理返回结果
+	if len(goVals) == 0 {
+		*outLen = 0
+		return nil
+	}
+	*outLen = C.int(len(goVals))
+
+	// 分配C内存并拷贝数据
+	cVals := (*C.float)(C.malloc(C.size_t(len(goVals)) * C.sizeof_float))
+	if cVals == nil {
+		bwSetError(handle, "BigWigGetZoomValues: 内存分配失败")
+		*outLen = 0
+		return nil
+	}
+	goBuf := unsafe.Slice(cVals, len(goVals))
+	for i, v := range goVals {
+		goBuf[i] = C.float(v)
+	}
+
+	return cVals
+}
+
+// 5. 获取文件元信息（填入调用方提供的CBWFileInfo）
+//export BigWigGetInfo
+func BigWigGetInfo(handle C.uintptr_t, info *C.struct_CBWFileInfo) C.int {
+	bwClearError()
+	if handle == 0 || info == nil {
+		bwSetError(handle, "BigWigGetInfo: 参数无效")
+		return -1 // 失败返回-1
+	}
+	// 从句柄表取回文件并独占它
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigGetInfo: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	goInfo := fp.Info
+
+	// 赋值到C结构体（类型对应：Go → <stdint.h>定长类型）
+	info.Version = C.uint16_t(goInfo.Version)
+	info.NLevels = C.uint16_t(goInfo.NLevels)
+	info.FieldCount = C.uint16_t(goInfo.FieldCount)
+	info.DefinedFieldCount = C.uint16_t(goInfo.DefinedFieldCount)
+	info.Bufsize = C.uint32_t(goInfo.Bufsize)
+	info.Extensionoffset = C.uint64_t(goInfo.Extensionoffset)
+	info.NBasesCovered = C.uint64_t(goInfo.NBasesCovered)
+	info.MinVal = C.double(goInfo.MinVal)
+	info.MaxVal = C.double(goInfo.MaxVal)
+	info.SumData = C.double(goInfo.SumData)
+	info.SumSquared = C.double(goInfo.SumSquared)
+
+	return 0 // 成功返回0
+}
+
+// 6. 释放C内存（Python侧必须调用，避免内存泄漏）
+//export BigWigFree
+func BigWigFree(ptr unsafe.Pointer) {
+	if ptr != nil {
+		C.free(ptr)
+	}
+}
+// 7. 批量取zoom分箱矩阵并直接保存为.npy（数据不经过cgo边界逐个拷贝，Python侧用np.load读取）
+// chroms/starts/ends为n个区间，成功返回0，失败返回-1
+//export BigWigSaveZoomMatrixNpy
+func BigWigSaveZoomMatrixNpy(
+	handle C.uintptr_t,
+	chroms **C.char,
+	starts *C.int,
+	ends *C.int,
+	n C.int,
+	numBins C.int,
+	path *C.char,
+) C.int {
+	bwClearError()
+	if handle == 0 || chroms == nil || starts == nil || ends == nil || path == nil || n <= 0 || numBins <= 0 {
+		bwSetError(handle, "BigWigSaveZoomMatrixNpy: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigSaveZoomMatrixNpy: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	cChroms := unsafe.Slice(chroms, int(n))
+	cStarts := unsafe.Slice(starts, int(n))
+	cEnds := unsafe.Slice(ends, int(n))
+	regions := make([]Region, int(n))
+	for i := range regions {
+		if cChroms[i] == nil {
+			bwSetError(handle, "BigWigSaveZoomMatrixNpy: 第%d个染色体名为NULL", i)
+			return -1
+		}
+		regions[i] = Region{Chrom: C.GoString(cChroms[i]), Start: int(cStarts[i]), End: int(cEnds[i])}
+	}
+
+	f, err := os.Create(C.GoString(path))
+	if err != nil {
+		bwSetError(handle, "BigWigSaveZoomMatrixNpy: 创建文件失败: %v", err)
+		return -1
+	}
+	if err := fp.WriteMatrixNpy(ctx, f, regions, int(numBins)); err != nil {
+		f.Close()
+		bwSetError(handle, "BigWigSaveZoomMatrixNpy: 写入失败: %v", err)
+		return -1
+	}
+	if err := f.Close(); err != nil {
+		bwSetError(handle, "BigWigSaveZoomMatrixNpy: 写入失败: %v", err)
+		return -1
+	}
+	return 0
+}
+
+// 8. 区间分箱统计（每个bin一个double，没有数据的bin为NaN，coverage为有数据碱基的比例）
+// statType为mean/min/max/sum/std/coverage，NULL或空串表示mean；结果须用BigWigFreeStats释放
+//export BigWigStats
+func BigWigStats(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	nBins C.int,
+	statType *C.char,
+	outLen *C.int,
+) *C.double {
+	bwClearError()
+	if outLen == nil {
+		bwSetError(handle, "BigWigStats: 参数无效")
+		return nil
+	}
+	*outLen = 0
+	if handle == 0 || chrom == nil || start < 0 || end <= start || nBins <= 0 {
+		bwSetError(handle, "BigWigStats: 参数无效")
+		return nil
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigStats: 句柄无效或已关闭")
+		return nil
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	opts := StatsOptions{NBins: int(nBins)}
+	if statType != nil {
+		opts.Type = C.GoString(statType)
+	}
+	goVals, err := fp.Stats(ctx, C.GoString(chrom), int(start), int(end), opts)
+	if err != nil {
+		bwSetError(handle, "BigWigStats: %v", err)
+		return nil
+	}
+
+	cVals := (*C.double)(C.malloc(C.size_t(len(goVals)) * C.sizeof_double))
+	if cVals == nil {
+		bwSetError(handle, "BigWigStats: 内存分配失败")
+		return nil
+	}
+	copy(unsafe.Slice((*float64)(unsafe.Pointer(cVals)), len(goVals)), goVals)
+	*outLen = C.int(len(goVals))
+	return cVals
+}
+
+// 9. 释放BigWigStats返回的数组
+//export BigWigFreeStats
+func BigWigFreeStats(ptr *C.double) {
+	if ptr != nil {
+		C.free(unsafe.Pointer(ptr))
+	}
+}
+
+// 10. 区间内的原始记录，以三个平行数组返回（记录截断到[start, end)内，坐标从0开始）
+// 成功返回0并设置*n（没有记录时三个数组为NULL），失败返回-1；数组须用BigWigFreeIntervals释放
+//export BigWigGetIntervals
+func BigWigGetIntervals(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	starts **C.uint32_t,
+	ends **C.uint32_t,
+	values **C.float,
+	n *C.int,
+) C.int {
+	bwClearError()
+	if starts == nil || ends == nil || values == nil || n == nil {
+		bwSetError(handle, "BigWigGetIntervals: 参数无效")
+		return -1
+	}
+	*starts, *ends, *values, *n = nil, nil, nil, 0
+	items, ok := bwExportIntervals("BigWigGetIntervals", handle, chrom, start, end)
+	if !ok {
+		return -1
+	}
+	if len(items) == 0 {
+		return 0
+	}
+	size := C.size_t(len(items))
+	cStarts := (*C.uint32_t)(C.malloc(size * C.sizeof_uint32_t))
+	cEnds := (*C.uint32_t)(C.malloc(size * C.sizeof_uint32_t))
+	cValues := (*C.float)(C.malloc(size * C.sizeof_float))
+	if cStarts == nil || cEnds == nil || cValues == nil {
+		bwSetError(handle, "BigWigGetIntervals: 内存分配失败")
+		BigWigFreeIntervals(cStarts, cEnds, cValues)
+		return -1
+	}
+	goStarts := unsafe.Slice((*uint32)(unsafe.Pointer(cStarts)), len(items))
+	goEnds := unsafe.Slice((*uint32)(unsafe.Pointer(cEnds)), len(items))
+	goValues := unsafe.Slice((*float32)(unsafe.Pointer(cValues)), len(items))
+	for i, it := range items {
+		goStarts[i], goEnds[i], goValues[i] = it.Start, it.End, it.Value
+	}
+	*starts, *ends, *values, *n = cStarts, cEnds, cValues, C.int(len(items))
+	return 0
+}
+
+// 11. 释放BigWigGetIntervals返回的三个数组（NULL会被忽略）
+//export BigWigFreeIntervals
+func BigWigFreeIntervals(starts *C.uint32_t, ends *C.uint32_t, values *C.float) {
+	C.free(unsafe.Pointer(starts))
+	C.free(unsafe.Pointer(ends))
+	C.free(unsafe.Pointer(values))
+}
+
+// 12. 与BigWigGetIntervals相同，但返回CBWInterval数组（方便按结构体读取的调用方），
+// 没有记录或失败时返回NULL，二者以*outLen区分（失败为-1）；数组须用BigWigFree释放
+//export BigWigGetIntervalRecords
+func BigWigGetIntervalRecords(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	outLen *C.int,
+) *C.struct_CBWInterval {
+	bwClearError()
+	if outLen == nil {
+		bwSetError(handle, "BigWigGetIntervalRecords: 参数无效")
+		return nil
+	}
+	*outLen = -1
+	items, ok := bwExportIntervals("BigWigGetIntervalRecords", handle, chrom, start, end)
+	if !ok {
+		return nil
+	}
+	*outLen = 0
+	if len(items) == 0 {
+		return nil
+	}
+	cItems := (*C.struct_CBWInterval)(C.malloc(C.size_t(len(items)) * C.sizeof_struct_CBWInterval))
+	if cItems == nil {
+		bwSetError(handle, "BigWigGetIntervalRecords: 内存分配失败")
+		*outLen = -1
+		return nil
+	}
+	recs := unsafe.Slice(cItems, len(items))
+	for i, it := range items {
+		recs[i].Start, recs[i].End, recs[i].Value = C.uint32_t(it.Start), C.uint32_t(it.End), C.float(it.Value)
+	}
+	*outLen = C.int(len(items))
+	return cItems
+}
+
+// bwExportIntervals 是 BigWigGetIntervals 系列的公共部分：校验参数并读取记录，失败时记录原因
+func bwExportIntervals(name string, handle C.uintptr_t, chrom *C.char, start, end C.int) ([]Interval, bool) {
+	if handle == 0 || chrom == nil || start < 0 || end <= start {
+		bwSetError(handle, "%s: 参数无效", name)
+		return nil, false
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "%s: 句柄无效或已关闭", name)
+		return nil, false
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	items, err := fp.IntervalsContext(ctx, C.GoString(chrom), int(start), int(end))
+	if err != nil {
+		bwSetError(handle, "%s: %v", name, err)
+		return nil, false
+	}
+	return items, true
+}
+
+// 13. 染色体列表（按文件中的顺序）：*names为以NUL分隔的名字缓冲区（每个名字后跟一个NUL，
+// 依次读取*n个即可），*lengths为对应的长度；成功返回0，失败返回-1，须用BigWigFreeChroms释放
+//export BigWigGetChroms
+func BigWigGetChroms(handle C.uintptr_t, names **C.char, lengths **C.uint32_t, n *C.int) C.int {
+	bwClearError()
+	if names == nil || lengths == nil || n == nil {
+		bwSetError(handle, "BigWigGetChroms: 参数无效")
+		return -1
+	}
+	*names, *lengths, *n = nil, nil, 0
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigGetChroms: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	chroms, err := fp.Chroms()
+	if err != nil {
+		bwSetError(handle, "BigWigGetChroms: %v", err)
+		return -1
+	}
+	if len(chroms) == 0 {
+		return 0
+	}
+	size := 0
+	for _, c := range chroms {
+		size += len(c.Name) + 1
+	}
+	cNames := (*C.char)(C.malloc(C.size_t(size)))
+	cLengths := (*C.uint32_t)(C.malloc(C.size_t(len(chroms)) * C.sizeof_uint32_t))
+	if cNames == nil || cLengths == nil {
+		bwSetError(handle, "BigWigGetChroms: 内存分配失败")
+		BigWigFreeChroms(cNames, cLengths)
+		return -1
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(cNames)), size)
+	goLengths := unsafe.Slice((*uint32)(unsafe.Pointer(cLengths)), len(chroms))
+	pos := 0
+	for i, c := range chroms {
+		pos += copy(buf[pos:], c.Name)
+		buf[pos] = 0
+		pos++
+		goLengths[i] = c.Length
+	}
+	*names, *lengths, *n = cNames, cLengths, C.int(len(chroms))
+	return 0
+}
+
+// 14. 释放BigWigGetChroms返回的缓冲区（NULL会被忽略）
+//export BigWigFreeChroms
+func BigWigFreeChroms(names *C.char, lengths *C.uint32_t) {
+	C.free(unsafe.Pointer(names))
+	C.free(unsafe.Pointer(lengths))
+}
+
+// 15. 调用线程最近一次在handle上失败的原因（UTF-8），handle上没有失败时返回空串
+// 每个导出函数开始时清除本线程的错误信息，所以应在失败的调用之后立即读取；
+// 返回的字符串归DLL所有，不要释放，在本线程下一次调用导出函数前有效
+//export BigWigLastError
+func BigWigLastError(handle C.uintptr_t) *C.char {
+	return bwGetError(handle, false)
+}
+
+// 16. 与BigWigLastError相同，但不区分句柄，用于BigWigOpen等没有句柄的失败
+//export BigWigLastGlobalError
+func BigWigLastGlobalError() *C.char {
+	return bwGetError(0, true)
+}
+
+// 17. 与BigWigReadSignal相同，但写入调用方提供的buf（容量cap个float），不需要BigWigFree
+// 返回值n为结果的个数：n<=cap时已写入buf；n>cap时不写入，调用方按n分配后重试；失败返回-1
+//export BigWigReadSignalInto
+func BigWigReadSignalInto(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	buf *C.float,
+	cap C.int,
+) C.int {
+	bwClearError()
+	if handle == 0 || chrom == nil || start < 0 || end <= start || cap < 0 || (buf == nil && cap > 0) {
+		bwSetError(handle, "BigWigReadSignalInto: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigReadSignalInto: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	goVals, err := fp.ReadBigWigSignalContext(ctx, C.GoString(chrom), int(start), int(end))
+	if err != nil {
+		bwSetError(handle, "BigWigReadSignalInto: %v", err)
+		return -1
+	}
+	if len(goVals) <= int(cap) && len(goVals) > 0 {
+		copy(unsafe.Slice((*float32)(unsafe.Pointer(buf)), len(goVals)), goVals)
+	}
+	return C.int(len(goVals))
+}
+
+// 18. 与BigWigGetZoomValues相同，但写入调用方提供的buf，buf至少要有numBins个float
+// 成功返回numBins，失败返回-1
+//export BigWigGetZoomValuesInto
+func BigWigGetZoomValuesInto(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	numBins C.int,
+	useClosest C.int,
+	desiredReduction C.int,
+	buf *C.float,
+) C.int {
+	bwClearError()
+	if handle == 0 || chrom == nil || buf == nil || start < 0 || end <= start || numBins <= 0 || desiredReduction < 1 {
+		bwSetError(handle, "BigWigGetZoomValuesInto: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigGetZoomValuesInto: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	goVals, err := fp.GetZoomValuesContext(
+		ctx, C.GoString(chrom), int(start), int(end),
+		int(numBins), useClosest != 0, int(desiredReduction),
+	)
+	if err != nil {
+		bwSetError(handle, "BigWigGetZoomValuesInto: %v", err)
+		return -1
+	}
+	copy(unsafe.Slice((*float32)(unsafe.Pointer(buf)), int(numBins)), goVals)
+	return C.int(len(goVals))
+}
+
+// 19. 批量取zoom分箱矩阵：第i行为chromIdx[i]号染色体（BigWigGetChroms中的顺序，从0开始）上
+// [starts[i], ends[i])的numBins个分箱值，按行优先写入buf（至少n*numBins个float），
+// 一次调用取回全部区间；成功返回0，任一区间失败时返回-1（buf内容不确定）
+//export BigWigGetZoomMatrix
+func BigWigGetZoomMatrix(
+	handle C.uintptr_t,
+	chromIdx *C.int,
+	starts *C.int,
+	ends *C.int,
+	n C.int,
+	numBins C.int,
+	buf *C.float,
+) C.int {
+	bwClearError()
+	if handle == 0 || chromIdx == nil || starts == nil || ends == nil || buf == nil || n <= 0 || numBins <= 0 {
+		bwSetError(handle, "BigWigGetZoomMatrix: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigGetZoomMatrix: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	chroms, err := fp.Chroms()
+	if err != nil {
+		bwSetError(handle, "BigWigGetZoomMatrix: %v", err)
+		return -1
+	}
+	cIdx := unsafe.Slice(chromIdx, int(n))
+	cStarts := unsafe.Slice(starts, int(n))
+	cEnds := unsafe.Slice(ends, int(n))
+	bins := int(numBins)
+	out := unsafe.Slice((*float32)(unsafe.Pointer(buf)), int(n)*bins)
+	for i := range cIdx {
+		idx := int(cIdx[i])
+		if idx < 0 || idx >= len(chroms) {
+			bwSetError(handle, "BigWigGetZoomMatrix: 第%d行的染色体编号%d超出范围（共%d条）", i, idx, len(chroms))
+			return -1
+		}
+		r := Region{Chrom: chroms[idx].Name, Start: int(cStarts[i]), End: int(cEnds[i])}
+		values, _, _, err := fp.binnedRows(ctx, r, bins)
+		if err != nil {
+			bwSetError(handle, "BigWigGetZoomMatrix: 第%d行 %s:%d-%d: %v", i, r.Chrom, r.Start, r.End, err)
+			return -1
+		}
+		copy(out[i*bins:(i+1)*bins], values)
+	}
+	return 0
+}
+
+// 20. 创建bigWig文件并返回写入句柄（失败返回0）：chroms/lengths为n条染色体的名字和长度，
+// 顺序即写入顺序；zoomLevels为最多生成的缩放层级数，小于0时使用默认值，0表示不生成。
+// 写入句柄只能用于BigWigAddIntervals、BigWigAddSpans和BigWigWriterClose
+//export BigWigCreate
+func BigWigCreate(
+	fname *C.char,
+	chroms **C.char,
+	lengths *C.uint32_t,
+	n C.int,
+	zoomLevels C.int,
+) C.uintptr_t {
+	bwClearError()
+	if fname == nil || chroms == nil || lengths == nil || n <= 0 {
+		bwSetError(0, "BigWigCreate: 参数无效")
+		return 0
+	}
+	cChroms := unsafe.Slice(chroms, int(n))
+	goChroms := make([]string, len(cChroms))
+	for i, c := range cChroms {
+		if c == nil {
+			bwSetError(0, "BigWigCreate: 第%d条染色体名为空", i)
+			return 0
+		}
+		goChroms[i] = C.GoString(c)
+	}
+	goLengths := append([]uint32(nil), unsafe.Slice((*uint32)(unsafe.Pointer(lengths)), int(n))...)
+	var opts []WriteOption
+	if zoomLevels >= 0 {
+		opts = append(opts, WithMaxZoomLevels(int(zoomLevels)))
+	}
+	w, err := CreateBigWig(C.GoString(fname), goChroms, goLengths, opts...)
+	if err != nil {
+		bwSetError(0, "BigWigCreate: 创建失败: %v", err)
+		return 0
+	}
+	return C.uintptr_t(bwRegisterWriter(w))
+}
+
+// 21. 添加n条bedGraph形式的记录[starts[i], ends[i]) = values[i]；成功返回0，失败返回-1
+// 记录须按BigWigCreate中的染色体顺序、同一染色体内按位置有序且互不重叠地添加
+//export BigWigAddIntervals
+func BigWigAddIntervals(
+	handle C.uintptr_t,
+	chrom *C.char,
+	starts *C.uint32_t,
+	ends *C.uint32_t,
+	values *C.float,
+	n C.int,
+) C.int {
+	bwClearError()
+	if chrom == nil || starts == nil || ends == nil || values == nil || n <= 0 {
+		bwSetError(handle, "BigWigAddIntervals: 参数无效")
+		return -1
+	}
+	w, unlock := bwAcquireWriter(uint64(handle))
+	if w == nil {
+		bwSetError(handle, "BigWigAddIntervals: 写入句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	err := w.AddIntervals(C.GoString(chrom),
+		unsafe.Slice((*uint32)(unsafe.Pointer(starts)), int(n)),
+		unsafe.Slice((*uint32)(unsafe.Pointer(ends)), int(n)),
+		unsafe.Slice((*float32)(unsafe.Pointer(values)), int(n)))
+	if err != nil {
+		bwSetError(handle, "BigWigAddIntervals: %v", err)
+		return -1
+	}
+	return 0
+}
+
+// 22. 添加n条variableStep形式的记录，第i条为[starts[i], starts[i]+span) = values[i]；
+// 成功返回0，失败返回-1，顺序要求与BigWigAddIntervals相同
+//export BigWigAddSpans
+func BigWigAddSpans(
+	handle C.uintptr_t,
+	chrom *C.char,
+	starts *C.uint32_t,
+	span C.uint32_t,
+	values *C.float,
+	n C.int,
+) C.int {
+	bwClearError()
+	if chrom == nil || starts == nil || values == nil || n <= 0 || span == 0 {
+		bwSetError(handle, "BigWigAddSpans: 参数无效")
+		return -1
+	}
+	w, unlock := bwAcquireWriter(uint64(handle))
+	if w == nil {
+		bwSetError(handle, "BigWigAddSpans: 写入句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	err := w.AddIntervalSpans(C.GoString(chrom),
+		unsafe.Slice((*uint32)(unsafe.Pointer(starts)), int(n)), uint32(span),
+		unsafe.Slice((*float32)(unsafe.Pointer(values)), int(n)))
+	if err != nil {
+		bwSetError(handle, "BigWigAddSpans: %v", err)
+		return -1
+	}
+	return 0
+}
+
+// 23. 写出索引和缩放层级并关闭文件；成功返回0，失败返回-1（文件不完整，不应使用）
+// 无论成功与否句柄都会被释放，之后不再有效；等待该句柄上进行中的调用结束后才开始收尾
+//export BigWigWriterClose
+func BigWigWriterClose(handle C.uintptr_t) C.int {
+	bwClearError()
+	w := bwReleaseWriter(uint64(handle))
+	if w == nil {
+		bwSetError(handle, "BigWigWriterClose: 写入句柄无效或已关闭")
+		return -1
+	}
+	if err := w.Close(); err != nil {
+		bwSetError(handle, "BigWigWriterClose: %v", err)
+		return -1
+	}
+	return 0
+}
+
+// 24. zoom层级列表：返回层级数n（没有zoom数据时为0），n<=cap时把各层级的reduction
+// 按文件中的顺序写入reductions，下标即BigWigGetZoomValuesAt的zoomIdx；
+// 可以先以reductions=NULL、cap=0查询个数；失败返回-1
+//export BigWigZoomLevels
+func BigWigZoomLevels(handle C.uintptr_t, reductions *C.uint32_t, cap C.int) C.int {
+	bwClearError()
+	if handle == 0 || cap < 0 || (reductions == nil && cap > 0) {
+		bwSetError(handle, "BigWigZoomLevels: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigZoomLevels: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	levels := fp.ZoomLevels()
+	if len(levels) <= int(cap) {
+		copy(unsafe.Slice((*uint32)(unsafe.Pointer(reductions)), int(cap)), levels)
+	}
+	return C.int(len(levels))
+}
+
+// 25. 与BigWigGetZoomValues相同，但直接读取第zoomIdx个zoom层级（BigWigZoomLevels中的下标），
+// 不按desiredReduction选择层级，调用方可以自行决定分辨率；数组须用BigWigFree释放
+//export BigWigGetZoomValuesAt
+func BigWigGetZoomValuesAt(
+	handle C.uintptr_t,
+	chrom *C.char,
+	start C.int,
+	end C.int,
+	numBins C.int,
+	zoomIdx C.int,
+	outLen *C.int,
+) *C.float {
+	bwClearError()
+	if outLen == nil {
+		bwSetError(handle, "BigWigGetZoomValuesAt: 参数无效")
+		return nil
+	}
+	*outLen = 0
+	if handle == 0 || chrom == nil || start < 0 || end <= start || numBins <= 0 || zoomIdx < 0 {
+		bwSetError(handle, "BigWigGetZoomValuesAt: 参数无效")
+		return nil
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigGetZoomValuesAt: 句柄无效或已关闭")
+		return nil
+	}
+	defer unlock()
+	ctx, cancel := bwHandleContext(uint64(handle))
+	defer cancel()
+	goVals, err := fp.GetZoomLevelValuesContext(
+		ctx, C.GoString(chrom), int(start), int(end),
+		int(numBins), int(zoomIdx),
+	)
+	if err != nil {
+		bwSetError(handle, "BigWigGetZoomValuesAt: %v", err)
+		return nil
+	}
+	if len(goVals) == 0 {
+		return nil
+	}
+	cVals := (*C.float)(C.malloc(C.size_t(len(goVals)) * C.sizeof_float))
+	if cVals == nil {
+		bwSetError(handle, "BigWigGetZoomValuesAt: 内存分配失败")
+		return nil
+	}
+	copy(unsafe.Slice((*float32)(unsafe.Pointer(cVals)), len(goVals)), goVals)
+	*outLen = C.int(len(goVals))
+	return cVals
+}
+
+// bwLibVersion 是 BigWigLibVersion 返回的字符串，只分配一次，随共享库一直存在
+var bwLibVersion = C.CString(LibVersion)
+
+// 26. 库版本号（如"0.2.0"），字符串归DLL所有，不要释放；
+// 次版本号增加表示新增了导出函数，调用方可据此在调用新接口前确认DLL足够新
+//export BigWigLibVersion
+func BigWigLibVersion() *C.char {
+	return bwLibVersion
+}
+
+// 27. 功能位掩码（BW_FEATURE_*的组合），用于在运行时检查DLL支持的功能
+//export BigWigFeatures
+func BigWigFeatures() C.uint32_t {
+	return C.BW_FEATURE_WRITER | C.BW_FEATURE_REMOTE
+}
+
+// 28. 与BigWigOpen相同，但按opts设置超时、请求头、缓存目录等参数（opts为NULL时与BigWigOpen相同）；
+// fname可以是本地路径或http(s)://地址，返回句柄，失败返回0
+//export BigWigOpenEx
+func BigWigOpenEx(fname *C.char, opts *C.struct_BWOpenOptions) C.uintptr_t {
+	bwClearError()
+	if fname == nil {
+		bwSetError(0, "BigWigOpenEx: 文件名不能为空")
+		return 0
+	}
+	goOpts, err := bwOpenOptionsFromC(opts)
+	if err != nil {
+		bwSetError(0, "BigWigOpenEx: %v", err)
+		return 0
+	}
+	fp, err := OpenBigWig(C.GoString(fname), goOpts...)
+	if err != nil {
+		bwSetError(0, "BigWigOpenEx: 打开失败: %v", err)
+		return 0
+	}
+	return C.uintptr_t(bwRegisterHandle(fp))
+}
+
+// bwOpenOptionsFromC 把 BWOpenOptions 转换为函数式选项，opts 为 nil 时不设置任何选项
+func bwOpenOptionsFromC(opts *C.struct_BWOpenOptions) ([]OpenOption, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	if opts.StructSize != C.sizeof_struct_BWOpenOptions {
+		return nil, fmt.Errorf("StructSize为%d，应为%d（头文件与DLL版本不一致）", opts.StructSize, C.sizeof_struct_BWOpenOptions)
+	}
+	var out []OpenOption
+	if opts.TimeoutMs < 0 {
+		return nil, fmt.Errorf("TimeoutMs不能为负数: %d", opts.TimeoutMs)
+	}
+	if opts.TimeoutMs > 0 {
+		out = append(out, WithHTTPTimeout(time.Duration(opts.TimeoutMs)*time.Millisecond))
+	}
+	if opts.Headers != nil {
+		header, err := bwParseHeaders(C.GoString(opts.Headers))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, WithRequestHook(func(req *http.Request, offset, length int64) error {
+			for k, v := range header {
+				req.Header[k] = v
+			}
+			return nil
+		}))
+	}
+	if opts.CacheDir != nil {
+		out = append(out, WithWholeFileDir(C.GoString(opts.CacheDir)))
+	}
+	switch {
+	case opts.WholeFileThreshold < 0:
+		out = append(out, WithWholeFileThreshold(0))
+	case opts.WholeFileThreshold > 0:
+		out = append(out, WithWholeFileThreshold(int64(opts.WholeFileThreshold)))
+	}
+	if opts.MemoryLimit < 0 {
+		return nil, fmt.Errorf("MemoryLimit不能为负数: %d", opts.MemoryLimit)
+	}
+	if opts.MemoryLimit > 0 {
+		out = append(out, WithMemoryLimit(int64(opts.MemoryLimit)))
+	}
+	switch {
+	case opts.Prefetch < 0:
+		out = append(out, WithPrefetch(0))
+	case opts.Prefetch > 0:
+		out = append(out, WithPrefetch(int(opts.Prefetch)))
+	}
+	if opts.Lazy != 0 {
+		out = append(out, WithLazyLoad())
+	}
+	return out, nil
+}
+
+// bwParseHeaders 解析每行一个 "Name: value" 的请求头，忽略空行
+func bwParseHeaders(s string) (http.Header, error) {
+	header := make(http.Header)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("无效的请求头 %q，应为\"Name: value\"", line)
+		}
+		header.Add(name, strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// 29. 为handle设置取消标志：flag指向调用方的int32_t，调用方（例如GUI的取消按钮）把它置为非0后，
+// 该句柄上进行中的读取在下一个数据块或分箱检查点停止并返回失败，之后的调用在标志清零前立即失败；
+// flag为NULL表示取消设置。flag在取消设置或BigWigClose之前必须一直有效，读写应使用原子操作
+// （或volatile）。成功返回0，句柄无效时返回-1
+//export BigWigSetCancelFlag
+func BigWigSetCancelFlag(handle C.uintptr_t, flag *C.int32_t) C.int {
+	bwClearError()
+	if !bwSetCancelFlag(uint64(handle), (*int32)(unsafe.Pointer(flag))) {
+		bwSetError(handle, "BigWigSetCancelFlag: 句柄无效或已关闭")
+		return -1
+	}
+	return 0
+}
+
+// 30. 与BigWigOpen相同，但文件名为以0结尾的宽字符串：Windows上wchar_t为UTF-16
+// （Excel/VBA、Delphi等宿主传入的路径），其他平台为UTF-32；在内部转换为UTF-8后打开
+//export BigWigOpenW
+func BigWigOpenW(fname *C.wchar_t) C.uintptr_t {
+	bwClearError()
+	if fname == nil {
+		bwSetError(0, "BigWigOpenW: 文件名不能为空")
+		return 0
+	}
+	fp, err := OpenBigWig(bwGoStringW(fname))
+	if err != nil {
+		bwSetError(0, "BigWigOpenW: 打开失败: %v", err)
+		return 0
+	}
+	return C.uintptr_t(bwRegisterHandle(fp))
+}
+
+// bwGoStringW 把以 0 结尾的 wchar_t 字符串转换为 Go 字符串，
+// 2 字节的 wchar_t 按 UTF-16（含代理对）解码，4 字节的按 UTF-32 解码，无效的编码替换为 U+FFFD
+func bwGoStringW(p *C.wchar_t) string {
+	n := 0
+	for *(*C.wchar_t)(unsafe.Add(unsafe.Pointer(p), n*C.sizeof_wchar_t)) != 0 {
+		n++
+	}
+	if C.sizeof_wchar_t == 2 {
+		return string(utf16.Decode(unsafe.Slice((*uint16)(unsafe.Pointer(p)), n)))
+	}
+	runes := make([]rune, n)
+	for i, c := range unsafe.Slice((*uint32)(unsafe.Pointer(p)), n) {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// 扁平接口（31~33）：参数和返回值只有整数、double、字符串和指向它们的输出参数，没有结构体，
+// 适合ctypes/cffi直接绑定。文件信息按名字读取，以后新增的信息只增加新的名字，
+// 不会改变这些函数的签名，也不会像CBWFileInfo那样因为字段变化而要求调用方同步修改结构体定义
+
+// 31. 读取整数类型的文件信息，key为：version、zoom_levels、field_count、defined_field_count、
+// bufsize、extension_offset、bases_covered、chrom_count；成功返回0并写入*out，key未知或失败返回-1
+//export BigWigInfoInt
+func BigWigInfoInt(handle C.uintptr_t, key *C.char, out *C.int64_t) C.int {
+	bwClearError()
+	if key == nil || out == nil {
+		bwSetError(handle, "BigWigInfoInt: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigInfoInt: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	info := fp.Info
+	var v int64
+	switch k := C.GoString(key); k {
+	case "version":
+		v = int64(info.Version)
+	case "zoom_levels":
+		v = int64(info.NLevels)
+	case "field_count":
+		v = int64(info.FieldCount)
+	case "defined_field_count":
+		v = int64(info.DefinedFieldCount)
+	case "bufsize":
+		v = int64(info.Bufsize)
+	case "extension_offset":
+		v = int64(info.Extensionoffset)
+	case "bases_covered":
+		v = int64(info.NBasesCovered)
+	case "chrom_count":
+		chroms, err := fp.Chroms()
+		if err != nil {
+			bwSetError(handle, "BigWigInfoInt: %v", err)
+			return -1
+		}
+		v = int64(len(chroms))
+	default:
+		bwSetError(handle, "BigWigInfoInt: 未知的信息名 %q", k)
+		return -1
+	}
+	*out = C.int64_t(v)
+	return 0
+}
+
+// 32. 读取浮点类型的文件信息（来自文件的全局summary），key为：min、max、sum、sum_squares、
+// mean、std（样本标准差，与BigWigStats相同除以n-1）；没有数据时mean和std为NaN，只覆盖一个碱基时std为0。
+// 成功返回0并写入*out，key未知或失败返回-1
+//export BigWigInfoDouble
+func BigWigInfoDouble(handle C.uintptr_t, key *C.char, out *C.double) C.int {
+	bwClearError()
+	if key == nil || out == nil {
+		bwSetError(handle, "BigWigInfoDouble: 参数无效")
+		return -1
+	}
+	fp, unlock := bwAcquireHandle(uint64(handle))
+	if fp == nil {
+		bwSetError(handle, "BigWigInfoDouble: 句柄无效或已关闭")
+		return -1
+	}
+	defer unlock()
+	info := fp.Info
+	n := float64(info.NBasesCovered)
+	var v float64
+	switch k := C.GoString(key); k {
+	case "min":
+		v = info.MinVal
+	case "max":
+		v = info.MaxVal
+	case "sum":
+		v = info.SumData
+	case "sum_squares":
+		v = info.SumSquared
+	case "mean":
+		v = info.SumData / n
+	case "std":
+		switch {
+		case n == 0:
+			v = math.NaN()
+		case n == 1:
+			v = 0
+		default:
+			mean := info.SumData / n
+			v = math.Sqrt(math.Max((info.SumSquared-n*mean*mean)/(n-1), 0))
+		}
+	default:
+		bwSetError(handle, "BigWigInfoDouble: 未知的信息名 %q", k)
+		return -1
+	}
+	*out = C.double(v)
+	return 0
+}
+
+// 33. 与BigWigOpenEx相同，但打开参数逐个传入而不是通过BWOpenOptions结构体，
+// 各参数的含义和取0时的默认行为与BWOpenOptions的同名字段一致（headers、cacheDir可以为NULL）
+//export BigWigOpenFlat
+func BigWigOpenFlat(
+	fname *C.char,
+	timeoutMs C.int32_t,
+	headers *C.char,
+	cacheDir *C.char,
+	wholeFileThreshold C.int64_t,
+	memoryLimit C.int64_t,
+	prefetch C.int32_t,
+	lazy C.int32_t,
+) C.uintptr_t {
+	bwClearError()
+	if fname == nil {
+		bwSetError(0, "BigWigOpenFlat: 文件名不能为空")
+		return 0
+	}
+	opts := C.struct_BWOpenOptions{
+		StructSize:         C.sizeof_struct_BWOpenOptions,
+		TimeoutMs:          timeoutMs,
+		Headers:            headers,
+		CacheDir:           cacheDir,
+		WholeFileThreshold: wholeFileThreshold,
+		MemoryLimit:        memoryLimit,
+		Prefetch:           prefetch,
+		Lazy:               lazy,
+	}
+	goOpts, err := bwOpenOptionsFromC(&opts)
+	if err != nil {
+		bwSetError(0, "BigWigOpenFlat: %v", err)
+		return 0
+	}
+	fp, err := OpenBigWig(C.GoString(fname), goOpts...)
+	if err != nil {
+		bwSetError(0, "BigWigOpenFlat: 打开失败: %v", err)
+		return 0
+	}
+	return C.uintptr_t(bwRegisterHandle(fp))
+}
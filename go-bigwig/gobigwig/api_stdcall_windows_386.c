@@ -0,0 +1,42 @@
+//go:build cgo_export
+
+// 32 位 Windows 上的 stdcall 版本导出：Go 的 //export 只生成 cdecl 函数，而 VB6/VBA、
+// 部分 Delphi 程序等 32 位宿主只能调用 stdcall 函数。这里为每个导出函数提供一个
+// 名字后加 Std 的包装（BigWigOpen → BigWigOpenStd），参数、返回值和语义与原函数相同；
+// 链接时使用 --kill-at，导出名不带 @N 修饰。64 位 Windows 只有一种调用约定，不需要这些包装
+
+#include "_cgo_export.h"
+
+__declspec(dllexport) uintptr_t __stdcall BigWigOpenStd(char* fname) { return BigWigOpen(fname); }
+__declspec(dllexport) void __stdcall BigWigCloseStd(uintptr_t handle) { BigWigClose(handle); }
+__declspec(dllexport) float* __stdcall BigWigReadSignalStd(uintptr_t handle, char* chrom, int start, int end, int* outLen) { return BigWigReadSignal(handle, chrom, start, end, outLen); }
+__declspec(dllexport) float* __stdcall BigWigGetZoomValuesStd(uintptr_t handle, char* chrom, int start, int end, int numBins, int useClosest, int desiredReduction, int* outLen) { return BigWigGetZoomValues(handle, chrom, start, end, numBins, useClosest, desiredReduction, outLen); }
+__declspec(dllexport) int __stdcall BigWigGetInfoStd(uintptr_t handle, struct CBWFileInfo* info) { return BigWigGetInfo(handle, info); }
+__declspec(dllexport) void __stdcall BigWigFreeStd(void* ptr) { BigWigFree(ptr); }
+__declspec(dllexport) int __stdcall BigWigSaveZoomMatrixNpyStd(uintptr_t handle, char** chroms, int* starts, int* ends, int n, int numBins, char* path) { return BigWigSaveZoomMatrixNpy(handle, chroms, starts, ends, n, numBins, path); }
+__declspec(dllexport) double* __stdcall BigWigStatsStd(uintptr_t handle, char* chrom, int start, int end, int nBins, char* statType, int* outLen) { return BigWigStats(handle, chrom, start, end, nBins, statType, outLen); }
+__declspec(dllexport) void __stdcall BigWigFreeStatsStd(double* ptr) { BigWigFreeStats(ptr); }
+__declspec(dllexport) int __stdcall BigWigGetIntervalsStd(uintptr_t handle, char* chrom, int start, int end, uint32_t** starts, uint32_t** ends, float** values, int* n) { return BigWigGetIntervals(handle, chrom, start, end, starts, ends, values, n); }
+__declspec(dllexport) void __stdcall BigWigFreeIntervalsStd(uint32_t* starts, uint32_t* ends, float* values) { BigWigFreeIntervals(starts, ends, values); }
+__declspec(dllexport) struct CBWInterval* __stdcall BigWigGetIntervalRecordsStd(uintptr_t handle, char* chrom, int start, int end, int* outLen) { return BigWigGetIntervalRecords(handle, chrom, start, end, outLen); }
+__declspec(dllexport) int __stdcall BigWigGetChromsStd(uintptr_t handle, char** names, uint32_t** lengths, int* n) { return BigWigGetChroms(handle, names, lengths, n); }
+__declspec(dllexport) void __stdcall BigWigFreeChromsStd(char* names, uint32_t* lengths) { BigWigFreeChroms(names, lengths); }
+__declspec(dllexport) char* __stdcall BigWigLastErrorStd(uintptr_t handle) { return BigWigLastError(handle); }
+__declspec(dllexport) char* __stdcall BigWigLastGlobalErrorStd(void) { return BigWigLastGlobalError(); }
+__declspec(dllexport) int __stdcall BigWigReadSignalIntoStd(uintptr_t handle, char* chrom, int start, int end, float* buf, int cap) { return BigWigReadSignalInto(handle, chrom, start, end, buf, cap); }
+__declspec(dllexport) int __stdcall BigWigGetZoomValuesIntoStd(uintptr_t handle, char* chrom, int start, int end, int numBins, int useClosest, int desiredReduction, float* buf) { return BigWigGetZoomValuesInto(handle, chrom, start, end, numBins, useClosest, desiredReduction, buf); }
+__declspec(dllexport) int __stdcall BigWigGetZoomMatrixStd(uintptr_t handle, int* chromIdx, int* starts, int* ends, int n, int numBins, float* buf) { return BigWigGetZoomMatrix(handle, chromIdx, starts, ends, n, numBins, buf); }
+__declspec(dllexport) uintptr_t __stdcall BigWigCreateStd(char* fname, char** chroms, uint32_t* lengths, int n, int zoomLevels) { return BigWigCreate(fname, chroms, lengths, n, zoomLevels); }
+__declspec(dllexport) int __stdcall BigWigAddIntervalsStd(uintptr_t handle, char* chrom, uint32_t* starts, uint32_t* ends, float* values, int n) { return BigWigAddIntervals(handle, chrom, starts, ends, values, n); }
+__declspec(dllexport) int __stdcall BigWigAddSpansStd(uintptr_t handle, char* chrom, uint32_t* starts, uint32_t span, float* values, int n) { return BigWigAddSpans(handle, chrom, starts, span, values, n); }
+__declspec(dllexport) int __stdcall BigWigWriterCloseStd(uintptr_t handle) { return BigWigWriterClose(handle); }
+__declspec(dllexport) int __stdcall BigWigZoomLevelsStd(uintptr_t handle, uint32_t* reductions, int cap) { return BigWigZoomLevels(handle, reductions, cap); }
+__declspec(dllexport) float* __stdcall BigWigGetZoomValuesAtStd(uintptr_t handle, char* chrom, int start, int end, int numBins, int zoomIdx, int* outLen) { return BigWigGetZoomValuesAt(handle, chrom, start, end, numBins, zoomIdx, outLen); }
+__declspec(dllexport) char* __stdcall BigWigLibVersionStd(void) { return BigWigLibVersion(); }
+__declspec(dllexport) uint32_t __stdcall BigWigFeaturesStd(void) { return BigWigFeatures(); }
+__declspec(dllexport) uintptr_t __stdcall BigWigOpenExStd(char* fname, struct BWOpenOptions* opts) { return BigWigOpenEx(fname, opts); }
+__declspec(dllexport) int __stdcall BigWigSetCancelFlagStd(uintptr_t handle, int32_t* flag) { return BigWigSetCancelFlag(handle, flag); }
+__declspec(dllexport) uintptr_t __stdcall BigWigOpenWStd(wchar_t* fname) { return BigWigOpenW(fname); }
+__declspec(dllexport) int __stdcall BigWigInfoIntStd(uintptr_t handle, char* key, int64_t* out) { return BigWigInfoInt(handle, key, out); }
+__declspec(dllexport) int __stdcall BigWigInfoDoubleStd(uintptr_t handle, char* key, double* out) { return BigWigInfoDouble(handle, key, out); }
+__declspec(dllexport) uintptr_t __stdcall BigWigOpenFlatStd(char* fname, int32_t timeoutMs, char* headers, char* cacheDir, int64_t wholeFileThreshold, int64_t memoryLimit, int32_t prefetch, int32_t lazy) { return BigWigOpenFlat(fname, timeoutMs, headers, cacheDir, wholeFileThreshold, memoryLimit, prefetch, lazy); }
@@ -0,0 +1,9 @@
+//go:build cgo_export
+
+package gobigwig
+
+// stdcall 包装见 api_stdcall_windows_386.c；--kill-at 去掉 stdcall 导出名的 @N 后缀，
+// 宿主按 BigWigOpenStd 这样的原名查找函数
+
+// #cgo LDFLAGS: -Wl,--kill-at
+import "C"
@@ -2,22 +2,40 @@ package gobigwig
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
-	"unsafe"
+	"math"
+	"sync"
 )
 
 const (
 	GOBIGWIG_VERSION  = 0.1
 	BIGWIG_MAGIC      = 0x888FFC26
+	BIGBED_MAGIC      = 0x8789F2EB
 	CIRTREE_MAGIC     = 0x78CA8C91
 	IDX_MAGIC         = 0x2468ace0
 	DEFAULT_nCHILDREN = 64
 	DEFAULT_BLOCKSIZE = 32768
 )
 
+// ctxCheckInterval 块内解码时每处理多少条记录检查一次 ctx 是否已取消
+const ctxCheckInterval = 1024
+
+// 支持读取的文件头版本范围：版本 3 起 bufsize 字段表示块解压缓冲区大小（之前为保留字段），
+// 版本 4 起 extensionoffset 指向扩展头（bigBed 的扩展字段等）
+const (
+	bwMinVersion         = 1
+	bwMaxVersion         = 4
+	bwCompressionVersion = 3
+	bwExtensionVersion   = 4
+)
+
+// ErrUnsupportedVersion 在文件头版本不在支持范围内时返回（错误信息中包含实际版本号）
+var ErrUnsupportedVersion = errors.New("unsupported bigWig version")
+
 
 type bwStatsType struct {
 	doesNotExist int
@@ -67,6 +85,12 @@ type bwZoomHdr_t struct {
 
 	// 每个缩放层级对应的一棵 R 树索引
 	Idx []*bwRTree_t
+
+	// 每个层级加载索引时持有的锁，保证同一层级只被加载一次（与 Clone 出的句柄共享）
+	loadMu []sync.Mutex
+
+	// 每个层级 R 树头部记录的数据范围，受 loadMu 保护
+	extents []bwZoomExtent
 }
 
 /*!
@@ -104,20 +128,27 @@ type chromList struct {
 	NKeys int64    // The number of chromosomes
 	Chrom []string // A list of chromosome names
 	Len   []uint32 // The lengths of each chromosome
+
+	// 读取 chrom B+ 树时使用：条目按读到的顺序暂存，ids 为对应的染色体编号；
+	// seen 记录访问过的节点偏移，防止损坏文件中的环
+	ids  []uint32
+	seen map[uint64]bool
 }
 
+// bwMaxChromKeySize chrom B+ 树键（染色体名）的最大字节数，防止损坏文件触发超大分配
+const bwMaxChromKeySize = 1024
+
 // bwLL is a linked list of R-tree nodes
 type bwLL struct {
 	Node *bwRTreeNode_t // pointer to R-tree node
 	Next *bwLL          // pointer to next linked list element
 }
 
-// BwZoomBuffer represents a zoom buffer linked list
+// bwZoomBuffer_t 保存写入时某个缩放层级累积的 summary 记录
 type bwZoomBuffer_t struct {
-	P    unsafe.Pointer  // pointer to any type (void* equivalent)
-	L    uint32          // length or some value
-	M    uint32          // another value
-	Next *bwZoomBuffer_t // pointer to next buffer
+	Reduction uint32      // 该层级每条 summary 覆盖的碱基数（按 Reduction 对齐分窗）
+	Records   []bwSummary // 已完成的 summary，按 (ChromId, Start) 有序
+	Cur       *bwSummary  // 正在累积的 summary
 }
 
 
@@ -129,28 +160,50 @@ type bigWigFile_t struct {
 	WriteBuffer *bwWriteBuffer_t // 写入时使用的缓冲区
 	IsWrite     bool             // false: 以读取模式打开，true: 以写入模式打开
 	Type        int              // 0: bigWig 文件，1: bigBed 文件
+	Opts        BWOptions_Open   // 打开时传入的选项
+	budget      *MemoryBudget    // 块缓存与 R 树节点的内存预算，nil 表示不缓存数据块
+	cacheOwner  uint64           // 在共享的 MemoryBudget 中区分本文件的缓存条目
+	shared      *bwShared        // 与 Clone 出的句柄共享的状态，nil 表示未共享（如写入时）
+	order       binary.ByteOrder // 文件的字节序，由文件头魔数决定；nil 表示小端
+	progress    *bwProgress      // 数据区数据块的读取进度，nil 表示不报告
+}
+
+// bwOrder 返回读取 fp 时使用的字节序
+func bwOrder(fp *bigWigFile_t) binary.ByteOrder {
+	if fp.order == nil {
+		return binary.LittleEndian
+	}
+	return fp.order
+}
+
+// bwWriteItem 写入缓冲中的一条记录（编码方式由块类型决定）
+type bwWriteItem struct {
+	Start uint32
+	End   uint32
+	Value float32
 }
 
 type bwWriteBuffer_t struct {
-	NBlocks          uint64           // 已写入的块数
-	BlockSize        uint32           // 最大子节点数
-	NEntries         uint64           // 已处理的条目数，用于第一个 contig 和计算缩放级别
-	RunningWidthSum  uint64           // 第一个 contig 条目宽度的累计和，用于计算缩放级别
-	Tid              uint32           // 当前处理的 TID
-	Start            uint32           // 块的起始位置
-	End              uint32           // 块的结束位置
-	Span             uint32           // 条目的跨度（如适用）
-	Step             uint32           // 步长（如适用）
-	LType            uint8            // 最近添加条目的类型
-	L                uint32           // 当前 p 的大小，结合类型决定包含条目数
-	P                unsafe.Pointer   // 大小为 hdr.bufSize 的缓冲区
-	FirstIndexNode   *bwLL            // 链表中的第一个索引节点
-	CurrentIndexNode *bwLL            // 链表中的最后一个索引节点
-	FirstZoomBuffer  **bwZoomBuffer_t // 叶子节点链表的第一个节点
-	LastZoomBuffer   **bwZoomBuffer_t // 叶子节点链表的最后一个节点
-	NNodes           *uint64          // 每个缩放级别的叶子节点数，用于确定重复级别
-	CompressPsz      uint32           // 压缩缓冲区大小（uLongf 一般映射为 uint32）
-	CompressP        unsafe.Pointer   // 压缩缓冲区，大小为 CompressPsz
+	NBlocks          uint64          // 已写入的块数
+	BlockSize        uint32          // R 树节点的最大子节点数
+	BufSize          uint32          // 数据块未压缩时的最大字节数
+	NEntries         uint64          // 已处理的条目数，用于计算缩放级别
+	RunningWidthSum  uint64          // 条目宽度的累计和，用于计算缩放级别
+	Tid              uint32          // 当前块的 TID
+	Start            uint32          // 块的起始位置
+	End              uint32          // 块的结束位置
+	Span             uint32          // 条目的跨度（如适用）
+	Step             uint32          // 步长（如适用）
+	LType            uint8           // 当前块的类型：1=bedGraph，2=variableStep，3=fixedStep，0=空
+	L                uint32          // 当前块中的条目数
+	P                []bwWriteItem   // 当前块的条目，写出时再编码
+	LastTid          uint32          // 上一个条目的 TID，用于检查输入顺序
+	LastEnd          uint32          // 上一个条目的结束位置，用于检查输入顺序
+	FirstIndexNode   *bwLL           // 链表中的第一个索引节点
+	CurrentIndexNode *bwLL           // 链表中的最后一个索引节点
+	Zoom             *bwZoomBuffer_t // 最细缩放层级的累积缓冲，更粗的层级在关闭时由它合并得到
+	BlocksSinceSync  int             // 上次 fsync 之后写入的块数
+	MaxBlockSize     uint32          // 最大的未压缩块大小，写入文件头的 bufsize
 }
 
 type bwOverlappingIntervals_t struct {
@@ -172,6 +225,7 @@ type bbOverlappingEntries_t struct {
 
 // bwOverlapIterator_t 用于迭代 bigWig 或 bigBed 文件的记录
 type bwOverlapIterator_t struct {
+	Ctx                context.Context           // 控制迭代取消，每个块读取/解码前检查
 	Bw                 *bigWigFile_t             // 指向 bigWig/bigBed 文件
 	Tid                uint32                    // 染色体/contig ID
 	Start              uint32                    // 查询区间的起始位置
@@ -183,6 +237,7 @@ type bwOverlapIterator_t struct {
 	Intervals          *bwOverlappingIntervals_t // 重叠的区间（或 nil）
 	Entries            *bbOverlappingEntries_t   // 重叠的条目（或 nil）
 	Data               interface{}               // 指向 Intervals 或 Entries，用于判断是否继续迭代
+	Err                error                     // 读取或解码数据块失败（或 ctx 被取消）时的错误，此时迭代结束
 }
 
 type bwRTreeNode_t struct {
@@ -219,6 +274,13 @@ type bwOverlapBlock_t struct {
 	Size   []uint64 // 每个数据块在文件中的大小（字节）
 }
 
+// 数据块类型
+const (
+	bwTypeBedGraph     = 1
+	bwTypeVariableStep = 2
+	bwTypeFixedStep    = 3
+)
+
 // BwDataHeader 表示某个数据块的头部信息
 type bwDataHeader_t struct {
 	Tid    uint32 // 染色体 ID
@@ -266,8 +328,8 @@ func bwRead(data any, sz, nmemb int, fp *bigWigFile_t) (int, error) {
 	if n != len(buf) {
 		return 0, io.ErrUnexpectedEOF
 	}
-	// 按小端解析
-	return nmemb, binary.Read(bytes.NewReader(buf), binary.LittleEndian, data)
+	// 按文件的字节序解析
+	return nmemb, binary.Read(bytes.NewReader(buf), bwOrder(fp), data)
 }
 
 
@@ -282,31 +344,84 @@ func (hdr *bigWigHdr_t) setIndexOffset(offset uint64) {
     hdr.indexoffset = offset
 }
 
-// IsBigWig 检查文件是否为 BigWig 文件
-func bwisBigWig(fname string) (bool, error) {
-	url, err := Open(fname)
+// FileType 是 DetectType 识别出的文件类型
+type FileType int
+
+const (
+	FileTypeUnknown FileType = iota
+	FileTypeBigWig
+	FileTypeBigBed
+)
+
+func (t FileType) String() string {
+	switch t {
+	case FileTypeBigWig:
+		return "bigWig"
+	case FileTypeBigBed:
+		return "bigBed"
+	}
+	return "unknown"
+}
+
+// Endianness 表示文件写入时使用的字节序
+type Endianness int
+
+const (
+	LittleEndian Endianness = iota
+	BigEndian
+)
+
+func (e Endianness) String() string {
+	if e == BigEndian {
+		return "big-endian"
+	}
+	return "little-endian"
+}
+
+// ByteOrder 返回对应的 binary.ByteOrder
+func (e Endianness) ByteOrder() binary.ByteOrder {
+	if e == BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// bwDetectMagic 按两种字节序匹配 bigWig 与 bigBed 的魔数
+func bwDetectMagic(b []byte) (FileType, Endianness) {
+	if len(b) < 4 {
+		return FileTypeUnknown, LittleEndian
+	}
+	for _, e := range []Endianness{LittleEndian, BigEndian} {
+		switch e.ByteOrder().Uint32(b) {
+		case BIGWIG_MAGIC:
+			return FileTypeBigWig, e
+		case BIGBED_MAGIC:
+			return FileTypeBigBed, e
+		}
+	}
+	return FileTypeUnknown, LittleEndian
+}
+
+// DetectType 读取文件开头的魔数，判断是 bigWig、bigBed 还是未知格式，以及文件的字节序
+// 只读取 4 个字节，远程文件不会整体下载；文件不足 4 字节时返回 FileTypeUnknown
+func DetectType(path string, opts ...OpenOption) (FileType, Endianness, error) {
+	url, err := Open(path, append(opts[:len(opts):len(opts)], WithWholeFileThreshold(0))...)
 	if err != nil {
-		return false, err
+		return FileTypeUnknown, LittleEndian, err
 	}
 	defer url.Close()
-	var magic uint32
+	url.readAhead = statReadAhead
 	buf := make([]byte, 4)
-	n, err := url.Read(buf)
-	if err != nil && err != io.EOF {
-		return false, err
-	}
-	if n != 4 {
-		return false, nil
+	n, err := io.ReadFull(url, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return FileTypeUnknown, LittleEndian, err
 	}
-	// 按小端解析
-	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &magic); err != nil {
-		return false, err
-	}
-	return magic == BIGWIG_MAGIC, nil
+	t, e := bwDetectMagic(buf[:n])
+	return t, e, nil
 }
 
 
-func bwReadZoomHdrs(r io.Reader, nLevels uint16) (*bwZoomHdr_t, error) {
+func bwReadZoomHdrs(r io.Reader, order binary.ByteOrder, nLevels uint16) (*bwZoomHdr_t, error) {
 	if nLevels == 0 {
 		return nil, nil
 	}
@@ -315,23 +430,25 @@ func bwReadZoomHdrs(r io.Reader, nLevels uint16) (*bwZoomHdr_t, error) {
 		DataOffset:  make([]uint64, nLevels),
 		IndexOffset: make([]uint64, nLevels),
 		Idx:         make([]*bwRTree_t, nLevels),
+		loadMu:      make([]sync.Mutex, nLevels),
+		extents:     make([]bwZoomExtent, nLevels),
 	}
 	var padding uint32
 	for i := uint16(0); i < nLevels; i++ {
 		// 读取 level
-		if err := binary.Read(r, binary.LittleEndian, &zhdr.Level[i]); err != nil {
+		if err := binary.Read(r, order, &zhdr.Level[i]); err != nil {
 			return nil, err
 		}
 		// 读取 padding
-		if err := binary.Read(r, binary.LittleEndian, &padding); err != nil {
+		if err := binary.Read(r, order, &padding); err != nil {
 			return nil, err
 		}
 		// 读取 dataOffset
-		if err := binary.Read(r, binary.LittleEndian, &zhdr.DataOffset[i]); err != nil {
+		if err := binary.Read(r, order, &zhdr.DataOffset[i]); err != nil {
 			return nil, err
 		}
 		// 读取 indexOffset
-		if err := binary.Read(r, binary.LittleEndian, &zhdr.IndexOffset[i]); err != nil {
+		if err := binary.Read(r, order, &zhdr.IndexOffset[i]); err != nil {
 			return nil, err
 		}
 	}
@@ -346,14 +463,17 @@ func bwHdrRead(bw *bigWigFile_t) error {
 
 	bw.Hdr = &bigWigHdr_t{}
 
-	// 读取 magic
-	var magic uint32
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &magic); err != nil {
+	// 读取 magic，并由它的字节顺序确定整个文件的字节序
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(bw.URL.rs, magic); err != nil {
 		return fmt.Errorf("[bwHdrRead] failed to read magic: %w", err)
 	}
-	if magic != BIGWIG_MAGIC {
+	ftype, endian := bwDetectMagic(magic)
+	if ftype != FileTypeBigWig {
 		return errors.New("[bwHdrRead] invalid magic number")
 	}
+	bw.order = endian.ByteOrder()
+	order := bw.order
 
 	// 顺序读取文件头字段
 	fields := []interface{}{
@@ -371,15 +491,27 @@ func bwHdrRead(bw *bigWigFile_t) error {
 	}
 
 	for _, f := range fields {
-		if err := binary.Read(bw.URL.rs, binary.LittleEndian, f); err != nil {
+		if err := binary.Read(bw.URL.rs, order, f); err != nil {
 			bw.Hdr = nil
 			return fmt.Errorf("[bwHdrRead] failed to read header field: %w", err)
 		}
 	}
 
+	if v := bw.Hdr.version; v < bwMinVersion || v > bwMaxVersion {
+		bw.Hdr = nil
+		return fmt.Errorf("[bwHdrRead] %w %d (supported: %d-%d)", ErrUnsupportedVersion, v, bwMinVersion, bwMaxVersion)
+	}
+	// 旧版本中这些位置是保留字段，内容没有意义，不能当作压缩缓冲区或扩展头解析
+	if bw.Hdr.version < bwCompressionVersion {
+		bw.Hdr.bufsize = 0
+	}
+	if bw.Hdr.version < bwExtensionVersion {
+		bw.Hdr.extensionoffset = 0
+	}
+
 	// 读取 zoom headers
 	if bw.Hdr.nLevels > 0 {
-		zoomHdrs, err := bwReadZoomHdrs(bw.URL.rs, bw.Hdr.nLevels)
+		zoomHdrs, err := bwReadZoomHdrs(bw.URL.rs, order, bw.Hdr.nLevels)
 		if err != nil {
 			bw.Hdr = nil
 			return fmt.Errorf("[bwHdrRead] failed to read zoom headers: %w", err)
@@ -402,7 +534,7 @@ func bwHdrRead(bw *bigWigFile_t) error {
 			&bw.Hdr.SumSquared,
 		}
 		for _, f := range summaryFields {
-			if err := binary.Read(bw.URL.rs, binary.LittleEndian, f); err != nil {
+			if err := binary.Read(bw.URL.rs, order, f); err != nil {
 				bw.Hdr = nil
 				return fmt.Errorf("[bwHdrRead] failed to read summary: %w", err)
 			}
@@ -475,16 +607,13 @@ func readChromLeaf(bw *bigWigFile_t, cl *chromList, valueSize uint32) (uint64, e
 		if _, err := bwRead(&length, 4, 1, bw); err != nil {
 			return 0, fmt.Errorf("failed to read chrom length: %w", err)
 		}
-		// 存入 chromList
-		name := string(bytes.Trim(chrom, "\x00"))
-		if int(idx) >= len(cl.Chrom) {
-			// 如果 chromList 还不够大，扩容
-			newSize := int(idx) + 1
-			cl.Chrom = append(cl.Chrom, make([]string, newSize-len(cl.Chrom))...)
-			cl.Len = append(cl.Len, make([]uint32, newSize-len(cl.Len))...)
+		// 按读取顺序暂存，全部读完后再按 idx 排列（分配大小只取决于实际读到的条目数）
+		if uint64(len(cl.ids)) >= uint64(cl.NKeys) {
+			return 0, errors.New("more chromosomes than declared in chrom tree header")
 		}
-		cl.Chrom[idx] = name
-		cl.Len[idx] = length
+		cl.Chrom = append(cl.Chrom, string(bytes.Trim(chrom, "\x00")))
+		cl.Len = append(cl.Len, length)
+		cl.ids = append(cl.ids, idx)
 	}
 	return uint64(nVals), nil
 }
@@ -508,6 +637,10 @@ func readChromNonLeaf(bw *bigWigFile_t, cl *chromList, keySize uint32) (uint64,
 		if _, err := bwRead(&offset, 8, 1, bw); err != nil {
 			return 0, fmt.Errorf("failed to read offset: %w", err)
 		}
+		if cl.seen[offset] {
+			return 0, fmt.Errorf("chrom tree contains a cycle at offset %d", offset)
+		}
+		cl.seen[offset] = true
 
 		if bwSetPos(bw, offset) != 0 {
 			return 0, fmt.Errorf("failed to seek to child offset %d", offset)
@@ -546,10 +679,62 @@ func readChromBlock(bw *bigWigFile_t, cl *chromList, keySize uint32) (uint64, er
 	return readChromNonLeaf(bw, cl, keySize)
 }
 
+// bwLoadChromList 在染色体列表尚未读取时读取它（延迟打开模式）
+func bwLoadChromList(bw *bigWigFile_t) error {
+	if bw.Cl != nil {
+		return nil
+	}
+	cl, err := bwReadchromList(bw)
+	if err != nil {
+		return err
+	}
+	bw.Cl = cl
+	return nil
+}
+
+// bwLoadIndex 在主 R 树索引或其根节点尚未读取时读取它们
+func bwLoadIndex(bw *bigWigFile_t) error {
+	if bw.Idx == nil {
+		idx, err := readRTreeIdx(bw, bw.Hdr.indexoffset)
+		if err != nil {
+			return err
+		}
+		bw.Idx = idx
+	}
+	if bw.Idx.Root == nil {
+		root, err := bwGetRTreeNode(bw, bw.Idx.RootOffset)
+		if err != nil {
+			return err
+		}
+		bw.Idx.Root = root
+	}
+	return nil
+}
+
+// bwRecordedChromLength 返回文件中记录的染色体长度，未找到时 ok 为 false
+func bwRecordedChromLength(bw *bigWigFile_t, chrom string) (uint32, bool) {
+	tid := bwGetTid(bw, chrom)
+	if tid == ^uint32(0) || int(tid) >= len(bw.Cl.Len) {
+		return 0, false
+	}
+	return bw.Cl.Len[tid], true
+}
+
+// bwChromLength 返回查询时使用的染色体长度，WithChromLengths 中的覆盖值优先
+func bwChromLength(bw *bigWigFile_t, chrom string) (uint32, bool) {
+	if l, ok := bw.Opts.ChromLengths[chrom]; ok {
+		return l, true
+	}
+	return bwRecordedChromLength(bw, chrom)
+}
+
 func ShowChromosomes(bw *bigWigFile_t) error {
-	if bw == nil || bw.Cl == nil {
+	if bw == nil {
 		return fmt.Errorf("invalid BigWig file or chromosome list is nil")
 	}
+	if err := bwLoadChromList(bw); err != nil {
+		return err
+	}
 
 	fmt.Println("=== Chromosome Information ===")
 	for i := 0; i < len(bw.Cl.Chrom); i++ {
@@ -583,7 +768,7 @@ func bwReadchromList(bw *bigWigFile_t) (*chromList, error) {
 	var itemCount uint64
 
 	// 读取魔数
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &magic); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &magic); err != nil {
 		return nil, err
 	}
 	if magic != CIRTREE_MAGIC {
@@ -591,28 +776,33 @@ func bwReadchromList(bw *bigWigFile_t) (*chromList, error) {
 	}
 
 	// 顺序读取字段
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &itemsPerBlock); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &itemsPerBlock); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &keySize); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &keySize); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &valueSize); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &valueSize); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &itemCount); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &itemCount); err != nil {
 		return nil, err
 	}
 
+	if keySize == 0 || keySize > bwMaxChromKeySize {
+		return nil, fmt.Errorf("invalid chrom tree key size %d", keySize)
+	}
+	if itemCount > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid chromosome count %d", itemCount)
+	}
 	cl.NKeys = int64(itemCount)
-	cl.Chrom = make([]string, itemCount)
-	cl.Len = make([]uint32, itemCount)
+	cl.seen = map[uint64]bool{}
 
 	// 跳过两个 magic（占位）
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &magic); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &magic); err != nil {
 		return nil, err
 	}
-	if err := binary.Read(bw.URL.rs, binary.LittleEndian, &magic); err != nil {
+	if err := binary.Read(bw.URL.rs, bwOrder(bw), &magic); err != nil {
 		return nil, err
 	}
 
@@ -621,10 +811,24 @@ func bwReadchromList(bw *bigWigFile_t) (*chromList, error) {
 	if err != nil {
 		return nil, err
 	}
-	if rv != itemCount {
+	if rv != itemCount || uint64(len(cl.ids)) != itemCount {
 		return nil, errors.New("chromosome count mismatch")
 	}
 
+	// 按染色体编号重新排列，编号必须恰好覆盖 0..itemCount-1
+	chroms := make([]string, itemCount)
+	lens := make([]uint32, itemCount)
+	filled := make([]bool, itemCount)
+	for i, id := range cl.ids {
+		if uint64(id) >= itemCount || filled[id] {
+			return nil, fmt.Errorf("invalid or duplicate chromosome id %d", id)
+		}
+		filled[id] = true
+		chroms[id] = cl.Chrom[i]
+		lens[id] = cl.Len[i]
+	}
+	cl.Chrom, cl.Len = chroms, lens
+	cl.ids, cl.seen = nil, nil
 	return cl, nil
 }
 
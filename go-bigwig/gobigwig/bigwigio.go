@@ -2,11 +2,14 @@ package gobigwig
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 )
 
 // bigWigFileType 表示文件类型
@@ -20,55 +23,298 @@ const (
 )
 type size_t =int64
 
+// DEFAULT_WHOLE_FILE_THRESHOLD 远程文件小于该字节数时，打开时整体下载（默认 50 MB）
+const DEFAULT_WHOLE_FILE_THRESHOLD = 50 << 20
+
+// BWOptions_Open 表示打开文件时的可选参数
+type BWOptions_Open struct {
+	WholeFileThreshold int64             // 远程文件小于该字节数时整体下载后在本地读取，<=0 表示禁用
+	WholeFileDir       string            // 非空时整体下载到该目录下的临时文件，否则保存在内存中
+	SortResults        bool              // 查询结果按 start 排序并去除重复区间（默认开启）
+	PrefetchDepth      int               // 远程顺序扫描时后台预读的范围数，<=0 表示禁用
+	Metrics            Metrics           // IO 与缓存统计，nil 表示不统计
+	ChromLengths       map[string]uint32 // 覆盖文件中记录的染色体长度，超出记录长度的部分视为无数据
+	MemoryBudget       *MemoryBudget     // 多个文件共享的内存预算，优先于 MemoryLimit
+	MemoryLimit        int64             // 本文件独占的内存预算（字节），<=0 且未设置 MemoryBudget 时不限制也不缓存数据块
+	RequestHooks       []RequestHook     // 每个远程请求发出前按顺序调用
+	Lazy               bool              // 打开时只读文件头，染色体列表和索引在首次使用时加载
+	EagerZoomIndex     bool              // 本地文件打开时读取全部 zoom 层级的完整 R 树并常驻内存
+	RangePolicy        RangePolicy       // 查询区间超出染色体末端时的处理方式（默认截断）
+	ClampHook          ClampHook         // RangeClamp 模式下截断查询区间时调用，nil 表示不通知
+	OverlapPolicy      OverlapPolicy     // 逐碱基取值和分箱时重叠区间的合并方式（默认 OverlapRaw）
+	Coordinates        Coordinates       // 查询接口的坐标约定（默认 ZeroBased）
+	Blacklist          *Blacklist        // 查询时屏蔽为 NaN 的区域，nil 表示不屏蔽
+	HTTPTimeout        time.Duration     // 单个远程请求（含读取响应体）的超时时间，0 表示不限制
+	Progress           ProgressFunc      // 数据区数据块的读取进度，nil 表示不报告
+	Weighting          Weighting         // 统计与分箱时没有数据的碱基是否按 0 计入（默认 CoverageWeighted）
+	ResultCache        *ResultCache      // 统计与分箱结果的缓存，可在多个文件之间共享，nil 表示不缓存
+	ReopenInterval     time.Duration     // Pool 检查文件是否被替换的最小间隔，0 表示不检查
+}
+
+// RequestHook 在每个远程请求发出前调用，可以修改 req 的请求头、记录日志或否决请求
+// offset/length 是请求的字节范围，HEAD 和整体下载时 length 为 -1
+// 返回非 nil 错误表示否决，请求不会发出，该错误会（包装后）返回给读取方
+type RequestHook func(req *http.Request, offset, length int64) error
+
+// OpenOption 用于修改 BWOptions_Open 的函数式选项
+type OpenOption func(*BWOptions_Open)
+
+// WithWholeFileThreshold 设置远程文件整体下载的大小阈值，n<=0 表示始终使用 Range 请求
+func WithWholeFileThreshold(n int64) OpenOption {
+	return func(o *BWOptions_Open) { o.WholeFileThreshold = n }
+}
+
+// WithWholeFileDir 让整体下载的远程文件保存到 dir 下的临时文件（关闭时删除）
+func WithWholeFileDir(dir string) OpenOption {
+	return func(o *BWOptions_Open) { o.WholeFileDir = dir }
+}
+
+// WithSortedResults 控制查询结果是否保证按 start 排序且不重复
+// 关闭后结果保持数据块的读取顺序，索引叶子重叠时可能出现重复区间
+func WithSortedResults(enabled bool) OpenOption {
+	return func(o *BWOptions_Open) { o.SortResults = enabled }
+}
+
+// WithPrefetch 设置远程文件扫描数据块时最多提前下载的范围数，0 表示禁用预读
+func WithPrefetch(depth int) OpenOption {
+	return func(o *BWOptions_Open) { o.PrefetchDepth = depth }
+}
+
+// WithChromLengths 覆盖染色体长度，用于坐标所用的组装版本与建库时略有差异的情况
+// 覆盖长度可以大于文件中记录的长度（多出的尾部按无数据处理），
+// 也可以给文件中不存在的染色体指定长度（整条视为无数据）
+func WithChromLengths(lengths map[string]uint32) OpenOption {
+	return func(o *BWOptions_Open) { o.ChromLengths = lengths }
+}
+
+// WithMemoryBudget 让文件使用共享的内存预算，块缓存、R 树节点和查询临时缓冲区都计入其中
+func WithMemoryBudget(b *MemoryBudget) OpenOption {
+	return func(o *BWOptions_Open) { o.MemoryBudget = b }
+}
+
+// WithMemoryLimit 为文件创建独占的内存预算，上限为 n 字节
+func WithMemoryLimit(n int64) OpenOption {
+	return func(o *BWOptions_Open) { o.MemoryLimit = n }
+}
+
+// WithRequestHook 追加一个远程请求钩子，可多次使用，按添加顺序调用
+func WithRequestHook(h RequestHook) OpenOption {
+	return func(o *BWOptions_Open) { o.RequestHooks = append(o.RequestHooks, h) }
+}
+
+// WithLazyLoad 打开时只读取文件头，染色体列表和 R 树索引推迟到第一次查询时加载
+// 适合只需要元数据的远程文件；需要一次性加载时调用 Preload
+func WithLazyLoad() OpenOption {
+	return func(o *BWOptions_Open) { o.Lazy = true }
+}
+
+// WithEagerZoomIndex 让本地文件（以及整体下载的远程文件）在打开时读取每个 zoom 层级的完整 R 树并一直保留，
+// 之后的 zoom 查询不再逐个节点读取索引；zoom 索引通常很小，适合长时间运行、查询分散的服务。
+// 使用 Range 请求的远程文件不受影响，仍在首次使用时按需加载；与 WithLazyLoad 同时使用时以后者为准
+func WithEagerZoomIndex() OpenOption {
+	return func(o *BWOptions_Open) { o.EagerZoomIndex = true }
+}
+
+// WithRangePolicy 设置查询区间超出染色体末端时是截断（RangeClamp）还是返回 ErrOutOfRange（RangeError）
+func WithRangePolicy(p RangePolicy) OpenOption {
+	return func(o *BWOptions_Open) { o.RangePolicy = p }
+}
+
+// WithClampHook 设置查询区间被截断到染色体末端时调用的钩子，可用于记录警告
+func WithClampHook(h ClampHook) OpenOption {
+	return func(o *BWOptions_Open) { o.ClampHook = h }
+}
+
+// WithOverlapPolicy 设置逐碱基取值和分箱时重叠区间的默认合并方式，
+// 单次查询可以用 GetValuesPolicyContext 指定其他方式
+func WithOverlapPolicy(p OverlapPolicy) OpenOption {
+	return func(o *BWOptions_Open) { o.OverlapPolicy = p }
+}
+
+// WithWeighting 设置统计与分箱接口（Stats、GetZoomValues 系列、Profile、SampleValues、MarshalRegionJSON）
+// 对没有数据的碱基的默认处理方式，单次 Stats 调用可以用 StatsOptions.Weighting 指定其他方式
+func WithWeighting(w Weighting) OpenOption {
+	return func(o *BWOptions_Open) { o.Weighting = w }
+}
+
+// WithCoordinates 设置所有查询接口（包括 Region、Pool 和 QueryMany）输入输出坐标的约定，
+// 例如 WithCoordinates(OneBased) 后 chr1:1-10 表示前 10 个碱基
+func WithCoordinates(c Coordinates) OpenOption {
+	return func(o *BWOptions_Open) { o.Coordinates = c }
+}
+
+// WithMetrics 设置接收 IO 与缓存统计的 Metrics，可在多个文件之间共享
+func WithMetrics(m Metrics) OpenOption {
+	return func(o *BWOptions_Open) { o.Metrics = m }
+}
+
+// WithBlacklist 让查询结果中落在 b 内的值变为 NaN：逐碱基取值按位置屏蔽，
+// 区间查询屏蔽与 b 重叠的区间，zoom 分箱屏蔽一半以上碱基在 b 内的 bin
+func WithBlacklist(b *Blacklist) OpenOption {
+	return func(o *BWOptions_Open) { o.Blacklist = b }
+}
+
+// WithHTTPTimeout 设置单个远程请求的超时时间（从发出请求到读完响应体），0 表示不限制
+func WithHTTPTimeout(d time.Duration) OpenOption {
+	return func(o *BWOptions_Open) { o.HTTPTimeout = d }
+}
+
+func newOpenOptions(opts []OpenOption) BWOptions_Open {
+	o := BWOptions_Open{
+		WholeFileThreshold: DEFAULT_WHOLE_FILE_THRESHOLD,
+		SortResults:        true,
+		PrefetchDepth:      DEFAULT_PREFETCH,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
 
 type URL struct {
 	rs io.ReadSeeker // 实际用于 Read/Seek 的接口
 	// 远程文件专用
 	client *http.Client
 	url    string
-	buf    *bytes.Buffer
+	buf      []byte        // 最近一次 Range 请求取回的数据
+	bufStart int64         // buf 第一个字节在文件中的偏移
+	pf       *bwPrefetcher // 当前查询的预读器，nil 表示未启用
+	whole    bool          // 远程文件已整体下载，rs 指向内存或临时文件
+	tmp      string        // 整体下载使用的临时文件路径，关闭时删除
+	data     []byte        // 整体下载到内存中的文件内容
+	metrics  *bwTracer     // IO 与缓存统计（未设置时为 nopMetrics），以及 CollectStats 的单次查询统计
+	hooks    []RequestHook // 远程请求发出前调用的钩子
+	readAhead int          // 远程读取缓冲区不足时每次至少下载的字节数，0 表示 64 KB
+	version  bwRemoteVersion // 远程文件最近一次响应的 ETag 或 Last-Modified，用于结果缓存失效
+	checked  time.Time       // 上次检查文件是否被替换的时间
 	Type         bigWigFileType
 	FName        string
 	IsCompressed bool
-	FilePos      int64
+	FilePos      int64 // 远程文件的当前读取位置
 }
 
-// Open 打开本地文件或远程 URL
-func Open(fname string) (*URL, error) {
+// Open 打开本地文件（路径或 file:// URL）或远程 URL
+// 远程文件小于 WholeFileThreshold 时会在打开时整体下载，之后的读取不再发出 Range 请求
+func Open(fname string, opts ...OpenOption) (*URL, error) {
+	o := newOpenOptions(opts)
 	u := &URL{
-		FName: fname,
+		FName:   fname,
+		metrics: newBWTracer(o.Metrics),
+		hooks:   o.RequestHooks,
 	}
 	switch {
 	case len(fname) >= 7 && fname[:7] == "http://":
 		u.Type = BWG_HTTP
-		u.client = &http.Client{}
+		u.client = bwNewHTTPClient(o.HTTPTimeout)
 		u.url = fname
-		u.buf = bytes.NewBuffer(nil)
 		u.rs = u // 使用自定义 ReadSeeker
 	case len(fname) >= 8 && fname[:8] == "https://":
 		u.Type = BWG_HTTPS
-		u.client = &http.Client{}
+		u.client = bwNewHTTPClient(o.HTTPTimeout)
 		u.url = fname
-		u.buf = bytes.NewBuffer(nil)
 		u.rs = u
 	default:
-		// 本地文件
-		f, err := os.Open(fname)
+		// 本地文件，file:// URL 先转换为路径
+		path, err := bwLocalPath(fname)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Open(path)
 		if err != nil {
 			return nil, err
 		}
+		u.FName = path
 		u.Type = BWG_FILE
 		u.rs = f
 	}
 
+	if u.Type != BWG_FILE && o.WholeFileThreshold > 0 {
+		if err := u.fetchWhole(o.WholeFileThreshold, o.WholeFileDir); err != nil {
+			return nil, err
+		}
+	}
 	return u, nil
 }
 
+// isLocal 判断读取是否直接由 rs 完成（本地文件或已整体下载的远程文件）
+func (u *URL) isLocal() bool {
+	return u.Type == BWG_FILE || u.whole
+}
+
+// fetchWhole 通过 HEAD 请求获取远程文件大小，小于 threshold 时整体下载
+// 服务器不返回长度或 HEAD 失败时保持 Range 请求模式
+func (u *URL) fetchWhole(threshold int64, dir string) error {
+	req, err := http.NewRequest("HEAD", u.url, nil)
+	if err != nil {
+		return err
+	}
+	head, err := u.do(req, 0, -1)
+	if err != nil {
+		return nil
+	}
+	head.Body.Close()
+	if head.StatusCode != http.StatusOK || head.ContentLength < 0 || head.ContentLength >= threshold {
+		return nil
+	}
+
+	req, err = http.NewRequest("GET", u.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.do(req, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("failed to download remote file: " + resp.Status)
+	}
+
+	if dir == "" {
+		data, err := io.ReadAll(resp.Body)
+		u.metrics.BytesRead(int64(len(data)))
+		if err != nil {
+			return err
+		}
+		u.rs = bytes.NewReader(data)
+		u.data = data
+	} else {
+		f, err := os.CreateTemp(dir, "gobigwig-*.bw")
+		if err != nil {
+			return err
+		}
+		n, err := io.Copy(f, resp.Body)
+		u.metrics.BytesRead(n)
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		u.rs = f
+		u.tmp = f.Name()
+	}
+	u.whole = true
+	return nil
+}
+
 // Close 关闭文件
 func (u *URL) Close() error {
-	if u.Type == BWG_FILE {
+	u.stopPrefetch()
+	if u.isLocal() {
 		if f, ok := u.rs.(*os.File); ok {
-			return f.Close()
+			err := f.Close()
+			if u.tmp != "" {
+				os.Remove(u.tmp)
+			}
+			return err
 		}
 	}
 	// 远程文件没有长连接需要关闭
@@ -76,23 +322,53 @@ func (u *URL) Close() error {
 }
 
 // Read 实现 io.Reader
+// 远程文件优先从预读器和缓冲区读取，不足时发出新的 Range 请求，直到读满 p 或到达文件末尾
 func (u *URL) Read(p []byte) (int, error) {
-	if u.Type == BWG_FILE {
-		return u.rs.Read(p)
+	if u.isLocal() {
+		n, err := u.rs.Read(p)
+		if !u.whole {
+			u.metrics.BytesRead(int64(n))
+		}
+		return n, err
 	}
-	// 远程文件，缓冲区读取
-	if u.buf.Len() == 0 {
-		err := u.fillBuffer()
-		if err != nil {
-			return 0, err
+	total := 0
+	for total < len(p) {
+		if u.pf != nil {
+			n, ok, err := u.pf.read(u.FilePos, p[total:])
+			if err != nil {
+				return total, err
+			}
+			if ok {
+				u.metrics.CacheHit()
+				u.FilePos += int64(n)
+				total += n
+				continue
+			}
 		}
+		if u.FilePos < u.bufStart || u.FilePos >= u.bufStart+int64(len(u.buf)) {
+			u.metrics.CacheMiss()
+			if err := u.fillBuffer(len(p) - total); err != nil {
+				return total, err
+			}
+			if len(u.buf) == 0 {
+				if total == 0 {
+					return 0, io.EOF
+				}
+				break
+			}
+		} else {
+			u.metrics.CacheHit()
+		}
+		n := copy(p[total:], u.buf[u.FilePos-u.bufStart:])
+		u.FilePos += int64(n)
+		total += n
 	}
-	return u.buf.Read(p)
+	return total, nil
 }
 
 // Seek 实现 io.Seeker
 func (u *URL) Seek(offset int64, whence int) (int64, error) {
-	if u.Type == BWG_FILE {
+	if u.isLocal() {
 		return u.rs.Seek(offset, whence)
 	}
 	// 远程文件，通过 Range 请求实现
@@ -108,37 +384,90 @@ func (u *URL) Seek(offset int64, whence int) (int64, error) {
 	default:
 		return 0, errors.New("invalid whence")
 	}
+	if absPos < 0 {
+		return 0, errors.New("negative position")
+	}
+	// 只移动读取位置，缓冲区中的数据仍可复用
 	u.FilePos = absPos
-	u.buf.Reset() // 清空缓冲，下次读取会重新请求
 	return u.FilePos, nil
 }
 
-// fillBuffer 从远程文件下载数据
-func (u *URL) fillBuffer() error {
-	if u.client == nil {
-		return errors.New("http client not initialized")
+// fillBuffer 从 FilePos 开始下载至少 want 字节（最少 readAhead，默认 64 KB）到缓冲区
+func (u *URL) fillBuffer(want int) error {
+	size := int64(65536)
+	if u.readAhead > 0 {
+		size = int64(u.readAhead)
+	}
+	if int64(want) > size {
+		size = int64(want)
 	}
-	req, err := http.NewRequest("GET", u.url, nil)
+	data, err := u.fetchRange(context.Background(), u.FilePos, size)
 	if err != nil {
 		return err
 	}
+	u.buf = data
+	u.bufStart = u.FilePos
+	return nil
+}
+
+// do 依次调用请求钩子后发出请求，任一钩子返回错误时请求被否决
+func (u *URL) do(req *http.Request, offset, length int64) (*http.Response, error) {
+	for _, h := range u.hooks {
+		if h == nil {
+			continue
+		}
+		if err := h(req, offset, length); err != nil {
+			return nil, fmt.Errorf("remote request vetoed: %w", err)
+		}
+	}
+	u.metrics.Request()
+	resp, err := u.client.Do(req)
+	// HEAD 只用于检查文件，不更新已读取内容对应的版本
+	if err == nil && req.Method != "HEAD" {
+		u.version.note(resp)
+	}
+	return resp, err
+}
+
+// fetchRange 通过 Range 请求下载 [start, start+size) 范围的数据，可被并发调用
+// 到达文件末尾时返回的数据可能少于 size
+func (u *URL) fetchRange(ctx context.Context, start, size int64) ([]byte, error) {
+	if u.client == nil {
+		return nil, errors.New("http client not initialized")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.url, nil)
+	if err != nil {
+		return nil, err
+	}
 	// 支持 Range 请求
-	rangeHeader := "bytes=" + strconv.FormatInt(u.FilePos, 10) + "-" + strconv.FormatInt(u.FilePos+65535, 10)
+	rangeHeader := "bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(start+size-1, 10)
 	req.Header.Set("Range", rangeHeader)
 
-	resp, err := u.client.Do(req)
+	resp, err := u.do(req, start, size)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	buf := new(bytes.Buffer)
-	n, err := buf.ReadFrom(resp.Body)
-	if err != nil && err != io.EOF {
-		return err
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		data, err := io.ReadAll(resp.Body)
+		u.metrics.BytesRead(int64(len(data)))
+		return data, err
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil, nil
+	case http.StatusOK:
+		// 服务器忽略了 Range，返回的是整个文件
+		data, err := io.ReadAll(resp.Body)
+		u.metrics.BytesRead(int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		if start >= int64(len(data)) {
+			return nil, nil
+		}
+		return data[start:min(int(start+size), len(data))], nil
+	default:
+		return nil, errors.New("unexpected HTTP status: " + resp.Status)
 	}
-	u.FilePos += n
-	u.buf = buf
-	return nil
 }
-
@@ -0,0 +1,123 @@
+package gobigwig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+// bwNewHTTPClient 在 js/wasm 上返回直接调用 fetch API 的客户端：Range 请求由浏览器发出，
+// 整个 bigWig 轨道可以在客户端读取和渲染，不需要后端服务；在 Node.js（18 及以上）中同样可用。
+// 远程服务器需要通过 CORS 允许 Range 请求头
+func bwNewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: bwFetchTransport{}}
+}
+
+// bwFetchTransport 用 fetch 实现 http.RoundTripper，只用于不带请求体的 GET/HEAD 请求，
+// 响应体在返回前整体读入内存（Range 请求的数据块都不大）
+type bwFetchTransport struct{}
+
+func (bwFetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fetch := js.Global().Get("fetch")
+	if fetch.Type() != js.TypeFunction {
+		return nil, errors.New("fetch API is not available")
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return nil, errors.New("fetch transport does not support request bodies")
+	}
+	headers := js.Global().Get("Headers").New()
+	for k, vs := range req.Header {
+		for _, v := range vs {
+			headers.Call("append", k, v)
+		}
+	}
+	init := js.Global().Get("Object").New()
+	init.Set("method", req.Method)
+	init.Set("headers", headers)
+	abort := func() {}
+	if ac := js.Global().Get("AbortController"); ac.Type() == js.TypeFunction {
+		ctrl := ac.New()
+		init.Set("signal", ctrl.Get("signal"))
+		abort = func() { ctrl.Call("abort") }
+	}
+
+	ctx := req.Context()
+	jsResp, err := bwAwait(ctx, fetch.Invoke(req.URL.String(), init), abort)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", req.URL, err)
+	}
+	resp := &http.Response{
+		Status:        strconv.Itoa(jsResp.Get("status").Int()) + " " + jsResp.Get("statusText").String(),
+		StatusCode:    jsResp.Get("status").Int(),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		ContentLength: -1,
+		Request:       req,
+	}
+	forEach := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		resp.Header.Add(args[1].String(), args[0].String())
+		return nil
+	})
+	jsResp.Get("headers").Call("forEach", forEach)
+	forEach.Release()
+	if n, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		resp.ContentLength = n
+	}
+
+	var data []byte
+	if req.Method != http.MethodHead {
+		buf, err := bwAwait(ctx, jsResp.Call("arrayBuffer"), abort)
+		if err != nil {
+			return nil, fmt.Errorf("fetch %s: %w", req.URL, err)
+		}
+		u8 := js.Global().Get("Uint8Array").New(buf)
+		data = make([]byte, u8.Get("length").Int())
+		js.CopyBytesToGo(data, u8)
+		resp.ContentLength = int64(len(data))
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// bwAwait 等待 JS Promise 完成并返回结果；ctx 先结束时调用 abort 并返回 ctx.Err()，
+// 回调在 Promise 最终完成后才释放
+func bwAwait(ctx context.Context, promise js.Value, abort func()) (js.Value, error) {
+	type result struct {
+		v   js.Value
+		err error
+	}
+	ch := make(chan result, 1)
+	onOK := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- result{v: args[0]}
+		return nil
+	})
+	onErr := js.FuncOf(func(_ js.Value, args []js.Value) any {
+		ch <- result{err: errors.New(args[0].Call("toString").String())}
+		return nil
+	})
+	release := func() {
+		onOK.Release()
+		onErr.Release()
+	}
+	promise.Call("then", onOK, onErr)
+	select {
+	case r := <-ch:
+		release()
+		return r.v, r.err
+	case <-ctx.Done():
+		abort()
+		go func() {
+			<-ch
+			release()
+		}()
+		return js.Value{}, ctx.Err()
+	}
+}
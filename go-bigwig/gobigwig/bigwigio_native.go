@@ -0,0 +1,13 @@
+//go:build !js && !wasip1
+
+package gobigwig
+
+import (
+	"net/http"
+	"time"
+)
+
+// bwNewHTTPClient 返回读取远程文件使用的客户端，timeout 为 0 表示不限制
+func bwNewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout}
+}
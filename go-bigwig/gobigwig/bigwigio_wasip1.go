@@ -0,0 +1,19 @@
+package gobigwig
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// bwNewHTTPClient 在 wasip1 上返回总是失败的客户端：WASI 预览 1 没有网络接口，
+// 只能读取宿主通过预打开目录提供的本地文件
+func bwNewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: bwNoNetTransport{}}
+}
+
+type bwNoNetTransport struct{}
+
+func (bwNoNetTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("remote files are not supported on wasip1")
+}
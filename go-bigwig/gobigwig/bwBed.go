@@ -0,0 +1,50 @@
+package gobigwig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadBED 读取 BED 文件的前三列（chrom、start、end，坐标从 0 开始）作为区间列表，顺序与文件一致
+// 第 6 列为 + 或 - 时记为区间的链方向；空行、# 开头的行和 track/browser 行被跳过，其余列被忽略；r 可以是 gzip 压缩的
+func ReadBED(r io.Reader) ([]Region, error) {
+	r, err := bwTextInput(r)
+	if err != nil {
+		return nil, err
+	}
+	var regions []Region
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if bwSkipTextLine(line) {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least 3 fields, got %d", lineNo, len(f))
+		}
+		start, err := strconv.ParseUint(f[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid start %q", lineNo, f[1])
+		}
+		end, err := strconv.ParseUint(f[2], 10, 32)
+		if err != nil || end < start {
+			return nil, fmt.Errorf("line %d: invalid end %q", lineNo, f[2])
+		}
+		reg := Region{Chrom: f[0], Start: int(start), End: int(end)}
+		if len(f) >= 6 && (f[5] == "+" || f[5] == "-") {
+			reg.Strand = f[5][0]
+		}
+		regions = append(regions, reg)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return regions, nil
+}
@@ -0,0 +1,130 @@
+package gobigwig
+
+import (
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Blacklist 是一组需要屏蔽的区域（例如 ENCODE blacklist），坐标从 0 开始
+// 每条染色体上的区域合并重叠部分后按起点排序，互不相交的区间集合上的区间树退化为有序数组，
+// 查询用二分查找，复杂度为 O(log n + 命中的区域数)
+type Blacklist struct {
+	chroms map[string][]bwMaskRange
+}
+
+// bwMaskRange 是一个屏蔽区域 [Start, End)
+type bwMaskRange struct {
+	Start, End uint32
+}
+
+// NewBlacklist 用 regions 创建黑名单，重叠或首尾相接的区域被合并，空区域被忽略
+func NewBlacklist(regions []Region) *Blacklist {
+	b := &Blacklist{chroms: make(map[string][]bwMaskRange)}
+	for _, r := range regions {
+		if r.Start < 0 || r.End <= r.Start {
+			continue
+		}
+		b.chroms[r.Chrom] = append(b.chroms[r.Chrom], bwMaskRange{uint32(r.Start), uint32(min64(uint64(r.End), math.MaxUint32))})
+	}
+	for chrom, rs := range b.chroms {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].Start < rs[j].Start })
+		out := rs[:1]
+		for _, r := range rs[1:] {
+			last := &out[len(out)-1]
+			if r.Start <= last.End {
+				last.End = max32(last.End, r.End)
+				continue
+			}
+			out = append(out, r)
+		}
+		b.chroms[chrom] = out
+	}
+	return b
+}
+
+// ReadBlacklist 从 BED 格式的 r 读取黑名单，只使用前三列，见 ReadBED
+func ReadBlacklist(r io.Reader) (*Blacklist, error) {
+	regions, err := ReadBED(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlacklist(regions), nil
+}
+
+// LoadBlacklist 读取 BED 文件 path 作为黑名单，文件可以是 gzip 压缩的
+func LoadBlacklist(path string) (*Blacklist, error) {
+	path, err := bwLocalPath(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBlacklist(f)
+}
+
+// Regions 返回合并后的区域，按染色体名和起点排序
+func (b *Blacklist) Regions() []Region {
+	chroms := make([]string, 0, len(b.chroms))
+	for chrom := range b.chroms {
+		chroms = append(chroms, chrom)
+	}
+	sort.Strings(chroms)
+	var out []Region
+	for _, chrom := range chroms {
+		for _, r := range b.chroms[chrom] {
+			out = append(out, Region{Chrom: chrom, Start: int(r.Start), End: int(r.End)})
+		}
+	}
+	return out
+}
+
+// each 对 chrom:[start, end) 内被屏蔽的每一段（已截断到查询区间）按位置顺序调用 fn
+func (b *Blacklist) each(chrom string, start, end uint32, fn func(s, e uint32)) {
+	rs := b.chroms[chrom]
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].End > start })
+	for ; i < len(rs) && rs[i].Start < end; i++ {
+		fn(max32(rs[i].Start, start), min32(rs[i].End, end))
+	}
+}
+
+// Covered 返回 chrom:[start, end) 中被屏蔽的碱基数
+func (b *Blacklist) Covered(chrom string, start, end uint32) uint32 {
+	var n uint32
+	b.each(chrom, start, end, func(s, e uint32) { n += e - s })
+	return n
+}
+
+// Overlaps 判断 chrom:[start, end) 是否与任一屏蔽区域重叠
+func (b *Blacklist) Overlaps(chrom string, start, end uint32) bool {
+	rs := b.chroms[chrom]
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].End > start })
+	return i < len(rs) && rs[i].Start < end
+}
+
+// maskValues 把从 start 开始的逐碱基值 values 中被屏蔽的位置置为 NaN
+func (b *Blacklist) maskValues(chrom string, start uint32, values []float32) {
+	nan := float32(math.NaN())
+	b.each(chrom, start, start+uint32(len(values)), func(s, e uint32) {
+		for i := s - start; i < e-start; i++ {
+			values[i] = nan
+		}
+	})
+}
+
+// maskBins 把 [start, end) 上 len(values) 个 bin（划分方式与 bwBinSummaries 一致）中
+// 一半以上碱基被屏蔽的 bin 置为 NaN
+func (b *Blacklist) maskBins(chrom string, start, end uint32, values []float64) {
+	binSize := float64(end-start) / float64(len(values))
+	for j := range values {
+		s := start + uint32(float64(j)*binSize)
+		e := start + uint32(float64(j+1)*binSize)
+		if e > s && 2*b.Covered(chrom, s, e) > e-s {
+			values[j] = math.NaN()
+		}
+	}
+}
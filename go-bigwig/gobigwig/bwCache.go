@@ -0,0 +1,229 @@
+package gobigwig
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 缓存条目的种类
+const (
+	bwCacheBlock uint8 = iota // 解压后的数据块（含 zoom 数据块）
+	bwCacheNode               // R 树节点
+	bwCacheTile               // Tile 的结果，offset 为 tile 编号
+)
+
+// rTreeNodeOverhead 估算一个 R 树节点除子项数组以外占用的字节数
+const rTreeNodeOverhead = 96
+
+// bwCacheKey 唯一标识一个缓存条目，owner 区分共享同一预算的不同文件句柄
+type bwCacheKey struct {
+	owner  uint64
+	kind   uint8
+	offset uint64
+	tile   bwTileKey // 仅用于 bwCacheTile
+}
+
+type bwCacheEntry struct {
+	key   bwCacheKey
+	value any
+	size  int64
+}
+
+// MemoryBudget 限制 LRU 块缓存、R 树节点和查询临时缓冲区的总内存
+// 可以只给一个文件句柄使用，也可以在很多句柄之间共享（例如一个进程打开数百个 bigWig 时）
+// 超出预算时按最近最少使用的顺序淘汰缓存；查询临时缓冲区不能被淘汰，
+// 它们只会挤占缓存的空间，不会导致查询失败
+type MemoryBudget struct {
+	mu      sync.Mutex
+	limit   int64
+	cached  int64 // LRU 中条目的字节数
+	scratch int64 // 进行中的查询临时缓冲区字节数
+	lru     *list.List
+	items   map[bwCacheKey]*list.Element
+}
+
+// NewMemoryBudget 创建上限为 limit 字节的内存预算
+func NewMemoryBudget(limit int64) *MemoryBudget {
+	return &MemoryBudget{
+		limit: limit,
+		lru:   list.New(),
+		items: make(map[bwCacheKey]*list.Element),
+	}
+}
+
+// Limit 返回预算上限（字节）
+func (b *MemoryBudget) Limit() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.limit
+}
+
+// Used 返回当前缓存与查询临时缓冲区占用的总字节数
+func (b *MemoryBudget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cached + b.scratch
+}
+
+// get 查找缓存条目并将其移到 LRU 头部
+func (b *MemoryBudget) get(key bwCacheKey) (any, bool) {
+	if b == nil {
+		return nil, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	el, ok := b.items[key]
+	if !ok {
+		return nil, false
+	}
+	b.lru.MoveToFront(el)
+	return el.Value.(*bwCacheEntry).value, true
+}
+
+// put 加入缓存条目，超出预算时淘汰最久未使用的条目；大于整个预算的条目不缓存
+func (b *MemoryBudget) put(key bwCacheKey, value any, size int64) {
+	if b == nil || size > b.limit {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if el, ok := b.items[key]; ok {
+		e := el.Value.(*bwCacheEntry)
+		b.cached += size - e.size
+		e.value, e.size = value, size
+		b.lru.MoveToFront(el)
+	} else {
+		b.items[key] = b.lru.PushFront(&bwCacheEntry{key: key, value: value, size: size})
+		b.cached += size
+	}
+	b.evictLocked()
+}
+
+// reserve 为查询临时缓冲区占用 n 字节，必要时淘汰缓存腾出空间
+func (b *MemoryBudget) reserve(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scratch += n
+	b.evictLocked()
+}
+
+// release 归还 reserve 占用的字节
+func (b *MemoryBudget) release(n int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.scratch -= n
+	b.mu.Unlock()
+}
+
+func (b *MemoryBudget) evictLocked() {
+	for b.cached+b.scratch > b.limit {
+		el := b.lru.Back()
+		if el == nil {
+			return
+		}
+		e := b.lru.Remove(el).(*bwCacheEntry)
+		delete(b.items, e.key)
+		b.cached -= e.size
+	}
+}
+
+// dropOwner 删除某个文件句柄的全部缓存条目，在关闭文件时调用
+func (b *MemoryBudget) dropOwner(owner uint64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, el := range b.items {
+		if key.owner == owner {
+			b.cached -= el.Value.(*bwCacheEntry).size
+			b.lru.Remove(el)
+			delete(b.items, key)
+		}
+	}
+}
+
+var bwCacheOwnerSeq atomic.Uint64
+
+// bwNextCacheOwner 为新打开的文件句柄分配缓存 owner 编号
+func bwNextCacheOwner() uint64 {
+	return bwCacheOwnerSeq.Add(1)
+}
+
+// bwGetRTreeNodeCached 读取 R 树节点；设置了内存预算时经由 LRU 缓存
+func bwGetRTreeNodeCached(fp *bigWigFile_t, offset uint64) (*bwRTreeNode_t, error) {
+	key := bwCacheKey{owner: fp.cacheOwner, kind: bwCacheNode, offset: offset}
+	if v, ok := fp.budget.get(key); ok {
+		fp.URL.metrics.CacheHit()
+		return v.(*bwRTreeNode_t), nil
+	}
+	if fp.budget != nil {
+		fp.URL.metrics.CacheMiss()
+	}
+	node, err := bwGetRTreeNode(fp, offset)
+	if err != nil {
+		return nil, err
+	}
+	fp.budget.put(key, node, rTreeNodeOverhead+int64(node.NChildren)*32)
+	return node, nil
+}
+
+// bwReadBlock 读取 offset 处大小为 size 的数据块并在需要时解压
+// 设置了内存预算时解压结果进入 LRU 缓存，读取用的临时缓冲区计入预算
+func bwReadBlock(fp *bigWigFile_t, offset, size uint64) ([]byte, error) {
+	fp.URL.metrics.block()
+	key := bwCacheKey{owner: fp.cacheOwner, kind: bwCacheBlock, offset: offset}
+	if v, ok := fp.budget.get(key); ok {
+		fp.URL.metrics.CacheHit()
+		return v.([]byte), nil
+	}
+
+	if fp.budget != nil {
+		fp.URL.metrics.CacheMiss()
+	}
+	// 压缩后的块不会比上限大太多，更大的 size 只能来自损坏的索引
+	limit := bwBlockLimit(fp)
+	if size > uint64(limit)+1024 {
+		return nil, fmt.Errorf("data block size %d exceeds limit", size)
+	}
+	fp.budget.reserve(int64(size))
+	defer fp.budget.release(int64(size))
+
+	if bwSetPos(fp, offset) != 0 {
+		return nil, errors.New("failed to seek to data block")
+	}
+	compBuf := make([]byte, size)
+	n, err := fp.URL.Read(compBuf)
+	if err != nil || n != int(size) {
+		return nil, fmt.Errorf("failed to read data block: %v", err)
+	}
+
+	data := compBuf
+	if fp.Hdr.bufsize > 0 {
+		t0 := time.Now()
+		data, err = bwInflate(compBuf, limit)
+		fp.URL.metrics.Decompress(time.Since(t0))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %v", err)
+		}
+		fp.URL.metrics.decompressed(len(data))
+	}
+	fp.budget.put(key, data, int64(cap(data)))
+	if bwInDataSection(fp, offset) {
+		fp.progress.add(size)
+	}
+	return data, nil
+}
@@ -0,0 +1,149 @@
+package gobigwig
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bwBuiltinSizes 内置的常用基因组 chrom.sizes，只包含主要染色体和 chrM，
+// 不含 unplaced/random/alt 等片段（需要时使用 FetchChromSizes）
+//
+//go:embed chromsizes/*.chrom.sizes
+var bwBuiltinSizes embed.FS
+
+// ChromSizesURL 是 FetchChromSizes 下载 chrom.sizes 的地址，%[1]s 为基因组名；
+// 可改为内网镜像地址
+var ChromSizesURL = "https://hgdownload.soe.ucsc.edu/goldenPath/%[1]s/bigZips/%[1]s.chrom.sizes"
+
+// ReadChromSizes 读取 chrom.sizes 格式（每行染色体名和长度，以空白分隔，多余的列被忽略），
+// 空行和 # 开头的行被跳过；r 可以是 gzip 压缩的。同名染色体重复出现且长度不同时返回错误
+func ReadChromSizes(r io.Reader) (map[string]uint32, error) {
+	r, err := bwTextInput(r)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]uint32)
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			return nil, fmt.Errorf("line %d: expected chromosome name and size", lineNo)
+		}
+		n, err := strconv.ParseUint(f[1], 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("line %d: invalid size %q for %s", lineNo, f[1], f[0])
+		}
+		if old, ok := sizes[f[0]]; ok && old != uint32(n) {
+			return nil, fmt.Errorf("line %d: %s listed with sizes %d and %d", lineNo, f[0], old, n)
+		}
+		sizes[f[0]] = uint32(n)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(sizes) == 0 {
+		return nil, errors.New("no chromosome sizes found")
+	}
+	return sizes, nil
+}
+
+// FetchChromSizes 从 UCSC（ChromSizesURL）下载 genome（如 hg38、mm10）的 chrom.sizes
+func FetchChromSizes(genome string) (map[string]uint32, error) {
+	return FetchChromSizesContext(context.Background(), genome)
+}
+
+// FetchChromSizesContext 与 FetchChromSizes 相同，ctx 用于取消下载
+func FetchChromSizesContext(ctx context.Context, genome string) (map[string]uint32, error) {
+	if genome == "" || strings.IndexFunc(genome, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.')
+	}) >= 0 {
+		return nil, fmt.Errorf("invalid genome name %q", genome)
+	}
+	url := fmt.Sprintf(ChromSizesURL, genome)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch chrom sizes for %s: %w", genome, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch chrom sizes for %s: %s returned %s", genome, url, resp.Status)
+	}
+	sizes, err := ReadChromSizes(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fetch chrom sizes for %s: %w", genome, err)
+	}
+	return sizes, nil
+}
+
+// BuiltinGenomes 返回内置 chrom.sizes 的基因组名（hg19、hg38、mm10、mm39、dm6）
+func BuiltinGenomes() []string {
+	entries, _ := bwBuiltinSizes.ReadDir("chromsizes")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".chrom.sizes"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuiltinChromSizes 返回内置的 genome 染色体长度，无需网络；
+// 只包含主要染色体和 chrM，输入中含其他片段时应改用 FetchChromSizes
+func BuiltinChromSizes(genome string) (map[string]uint32, error) {
+	f, err := bwBuiltinSizes.Open("chromsizes/" + genome + ".chrom.sizes")
+	if err != nil {
+		return nil, fmt.Errorf("no built-in chrom sizes for %q (available: %s)", genome, strings.Join(BuiltinGenomes(), ", "))
+	}
+	defer f.Close()
+	return ReadChromSizes(f)
+}
+
+// LoadChromSizes 按名称取染色体长度：name 为内置基因组名时使用内置表，否则作为 chrom.sizes 文件路径读取
+func LoadChromSizes(name string) (map[string]uint32, error) {
+	if sizes, err := BuiltinChromSizes(name); err == nil {
+		return sizes, nil
+	}
+	path, err := bwLocalPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a built-in genome (%s) nor a readable file: %w", name, strings.Join(BuiltinGenomes(), ", "), err)
+	}
+	defer f.Close()
+	return ReadChromSizes(f)
+}
+
+// CreateBigWigFromSizes 与 CreateBigWig 相同，但染色体由 sizes 给出（例如 BuiltinChromSizes 的结果），
+// tid 按染色体名排序分配
+func CreateBigWigFromSizes(fname string, sizes map[string]uint32, opts ...WriteOption) (*BigWigWriter, error) {
+	chroms := make([]string, 0, len(sizes))
+	for c := range sizes {
+		chroms = append(chroms, c)
+	}
+	sort.Strings(chroms)
+	lengths := make([]uint32, len(chroms))
+	for i, c := range chroms {
+		lengths[i] = sizes[c]
+	}
+	return CreateBigWig(fname, chroms, lengths, opts...)
+}
@@ -0,0 +1,103 @@
+package gobigwig
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// bwShared 保存原句柄与 Clone 出的句柄之间共享的状态
+type bwShared struct {
+	mu   sync.Mutex   // 保护索引的延迟加载（R 树子节点、zoom 索引）
+	refs atomic.Int32 // 共享解析结果与缓存的句柄数
+	tmp  string       // 克隆后由最后一个关闭的句柄删除的临时文件
+}
+
+// bwLockIndex/bwUnlockIndex 在读写延迟加载的索引指针前后调用
+func bwLockIndex(fp *bigWigFile_t) {
+	if fp.shared != nil {
+		fp.shared.mu.Lock()
+	}
+}
+
+func bwUnlockIndex(fp *bigWigFile_t) {
+	if fp.shared != nil {
+		fp.shared.mu.Unlock()
+	}
+}
+
+// clone 打开一个独立的读取器，指向同一个文件，读取位置和缓冲区互不影响
+func (u *URL) clone() (*URL, error) {
+	c := &URL{
+		client:       u.client,
+		url:          u.url,
+		whole:        u.whole,
+		data:         u.data,
+		metrics:      u.metrics.fork(),
+		hooks:        u.hooks,
+		Type:         u.Type,
+		FName:        u.FName,
+		IsCompressed: u.IsCompressed,
+	}
+	switch {
+	case u.Type == BWG_FILE:
+		f, err := os.Open(u.FName)
+		if err != nil {
+			return nil, err
+		}
+		c.rs = f
+	case u.whole && u.data != nil:
+		c.rs = bytes.NewReader(u.data)
+	case u.whole:
+		f, ok := u.rs.(*os.File)
+		if !ok {
+			return nil, errors.New("cannot clone downloaded file")
+		}
+		f2, err := os.Open(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		c.rs = f2
+	default:
+		c.rs = c
+	}
+	return c, nil
+}
+
+// Clone 返回一个共享文件头、染色体列表、索引和缓存的新句柄（延迟打开的句柄会先加载它们），
+// 只复制文件描述符（远程文件则是独立的 HTTP 读取状态），开销很小
+// 同一个句柄不能被多个 goroutine 同时使用，但每个 goroutine 可以各自持有一个 Clone 并行查询
+// 每个 Clone 都需要用 CloseBigWig 关闭，共享的缓存在最后一个句柄关闭时释放
+func (fp *Bigwig_file_out) Clone() (*Bigwig_file_out, error) {
+	src := fp.bf_fp
+	if src == nil || src.URL == nil || src.shared == nil {
+		return nil, errors.New("cannot clone a closed or write-mode file")
+	}
+	// 延迟打开的句柄先加载染色体列表和主索引，使克隆之间共享同一份
+	if err := bwLoadChromList(src); err != nil {
+		return nil, err
+	}
+	if err := bwLoadIndex(src); err != nil {
+		return nil, err
+	}
+	u, err := src.URL.clone()
+	if err != nil {
+		return nil, err
+	}
+	bw := *src
+	bw.URL = u
+	bw.WriteBuffer = nil
+	src.shared.refs.Add(1)
+
+	// 临时文件改由最后一个关闭的句柄删除
+	src.shared.mu.Lock()
+	if src.URL.tmp != "" {
+		src.shared.tmp = src.URL.tmp
+		src.URL.tmp = ""
+	}
+	src.shared.mu.Unlock()
+
+	return &Bigwig_file_out{bf_fp: &bw, Info: fp.Info}, nil
+}
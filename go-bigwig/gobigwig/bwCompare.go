@@ -0,0 +1,243 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// CompareOp 表示 Compare 在每个 bin 上对两个文件的值 a、b 做的运算，与 deeptools bigwigCompare 的 --operation 对应
+type CompareOp int
+
+const (
+	CompareLog2            CompareOp = iota // log2((a+pseudocount)/(b+pseudocount))
+	CompareRatio                            // (a+pseudocount)/(b+pseudocount)
+	CompareReciprocalRatio                  // 比值不小于 1 时为比值，否则为 -1/比值
+	CompareSubtract                         // a-b
+	CompareAdd                              // a+b
+	CompareMean                             // (a+b)/2
+	CompareFirst                            // a
+	CompareSecond                           // b
+)
+
+func (op CompareOp) String() string {
+	switch op {
+	case CompareLog2:
+		return "log2"
+	case CompareRatio:
+		return "ratio"
+	case CompareReciprocalRatio:
+		return "reciprocal_ratio"
+	case CompareSubtract:
+		return "subtract"
+	case CompareAdd:
+		return "add"
+	case CompareMean:
+		return "mean"
+	case CompareFirst:
+		return "first"
+	case CompareSecond:
+		return "second"
+	}
+	return fmt.Sprintf("CompareOp(%d)", int(op))
+}
+
+// ParseCompareOp 按名称（log2、ratio、reciprocal_ratio、subtract、add、mean、first、second，
+// 与 String 的结果一致）返回 CompareOp
+func ParseCompareOp(s string) (CompareOp, error) {
+	for op := CompareLog2; op <= CompareSecond; op++ {
+		if s == op.String() {
+			return op, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compare operation %q", s)
+}
+
+// apply 计算一个 bin 的结果，结果不是有限值（例如除以 0）时返回 false
+func (op CompareOp) apply(a, b, pseudocount float64) (float64, bool) {
+	var r float64
+	switch op {
+	case CompareLog2, CompareRatio, CompareReciprocalRatio:
+		r = (a + pseudocount) / (b + pseudocount)
+		switch op {
+		case CompareLog2:
+			r = math.Log2(r)
+		case CompareReciprocalRatio:
+			if r < 1 {
+				r = -1 / r
+			}
+		}
+	case CompareSubtract:
+		r = a - b
+	case CompareAdd:
+		r = a + b
+	case CompareMean:
+		r = (a + b) / 2
+	case CompareFirst:
+		r = a
+	case CompareSecond:
+		r = b
+	}
+	return r, !math.IsNaN(r) && !math.IsInf(r, 0)
+}
+
+// MissingPolicy 表示 Compare 如何处理某个文件在 bin 内没有数据的情况
+type MissingPolicy int
+
+const (
+	MissingAsZero MissingPolicy = iota // 缺失按 0 计算；两个文件都没有数据的 bin 不输出
+	MissingSkip                        // 任一文件没有数据的 bin 不输出
+)
+
+// CompareOptions 是 Compare 的可选参数
+type CompareOptions struct {
+	Missing        MissingPolicy
+	Write          []WriteOption // 用于创建输出文件
+	ScaleA, ScaleB float64       // 运算前分别乘到两个文件每个 bin 的值上，0 表示不缩放
+	Weighting      Weighting     // bin 内没有数据的碱基是否按 0 计入，WeightDefault 即 CoverageWeighted
+}
+
+// CompareOption 用于修改 CompareOptions 的函数式选项
+type CompareOption func(*CompareOptions)
+
+// WithMissing 设置缺失数据的处理方式
+func WithMissing(p MissingPolicy) CompareOption {
+	return func(o *CompareOptions) { o.Missing = p }
+}
+
+// WithScale 设置运算前乘到两个文件上的缩放系数，例如 EstimateScaling 的结果（见 ScalingFactors.CompareOption）
+func WithScale(a, b float64) CompareOption {
+	return func(o *CompareOptions) { o.ScaleA, o.ScaleB = a, b }
+}
+
+// WithCompareWeighting 设置每个 bin 取平均值时没有数据的碱基是否按 0 计入
+// RegionWeighted 时只要 bin 内有一个文件有数据，两个文件都按 sum/bin 宽度取值
+func WithCompareWeighting(w Weighting) CompareOption {
+	return func(o *CompareOptions) { o.Weighting = w }
+}
+
+// WithCompareWriteOptions 设置创建输出文件时的参数
+func WithCompareWriteOptions(opts ...WriteOption) CompareOption {
+	return func(o *CompareOptions) { o.Write = append(o.Write, opts...) }
+}
+
+// Compare 把两个 bigWig 按 binSize 分箱（每个 bin 取有数据碱基的平均值），逐 bin 计算 op 后写入 out，
+// 相当于 deeptools bigwigCompare；pseudocount 只用于比值类运算，结果不是有限值的 bin 不输出
+// 缺失数据默认按 0 计算（见 MissingPolicy），相邻且值相同的 bin 合并为一条记录
+func Compare(a, b, out string, op CompareOp, pseudocount float64, binSize uint32, opts ...CompareOption) error {
+	return CompareContext(context.Background(), a, b, out, op, pseudocount, binSize, opts...)
+}
+
+// CompareContext 与 Compare 相同，ctx 被取消时返回 ctx.Err() 并删除 out
+func CompareContext(ctx context.Context, a, b, out string, op CompareOp, pseudocount float64, binSize uint32, opts ...CompareOption) error {
+	if binSize == 0 {
+		return errors.New("bin size must be positive")
+	}
+	if op < CompareLog2 || op > CompareSecond {
+		return fmt.Errorf("unknown compare operation %v", op)
+	}
+	var o CompareOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	files, sizes, err := bwOpenInputs([]string{a, b})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			CloseBigWig(f)
+		}
+	}()
+	c, err := newBWConverter(sizes, out, o.Write)
+	if err != nil {
+		return err
+	}
+	c.progress = bwTrackProgress(c.w.opts.Progress, files...)
+	return c.finish(c.compare(ctx, files[0].bf_fp, files[1].bf_fp, op, pseudocount, binSize, o))
+}
+
+// compare 逐条染色体按 bin 写出 op(a, b)
+func (c *bwConverter) compare(ctx context.Context, fa, fb *bigWigFile_t, op CompareOp, pseudocount float64, binSize uint32, o CompareOptions) error {
+	scaleA, scaleB := o.ScaleA, o.ScaleB
+	if scaleA == 0 {
+		scaleA = 1
+	}
+	if scaleB == 0 {
+		scaleB = 1
+	}
+	w := CoverageWeighted
+	if o.Weighting == RegionWeighted {
+		w = RegionWeighted
+	}
+	for tid, chrom := range c.w.bf_fp.Cl.Chrom {
+		length := c.w.bf_fp.Cl.Len[tid]
+		ca := newBWIntervalCursor(ctx, fa, chrom, 0, length)
+		cb := newBWIntervalCursor(ctx, fb, chrom, 0, length)
+		out := &bwPendingRecord{c: c, chrom: chrom}
+		err := func() error {
+			for s := uint32(0); s < length; {
+				if ca.err != nil {
+					return ca.err
+				}
+				if cb.err != nil {
+					return cb.err
+				}
+				// 两个文件都已读完，或下一条记录之前的 bin 都没有数据，直接跳过
+				next := uint32(math.MaxUint32)
+				if !ca.done {
+					next = ca.cur.Start
+				}
+				if !cb.done && cb.cur.Start < next {
+					next = cb.cur.Start
+				}
+				if next == math.MaxUint32 {
+					break
+				}
+				if next/binSize > s/binSize {
+					s = next / binSize * binSize
+				}
+				e := s + binSize
+				if e > length || e < s {
+					e = length
+				}
+				// 每个 bin 取有数据碱基的平均值，缺失按 0
+				var accA, accB bwStatAcc
+				ca.accumulate(&accA, s, e)
+				cb.accumulate(&accB, s, e)
+				oka, okb := accA.n > 0, accB.n > 0
+				if (oka || okb) && (o.Missing != MissingSkip || (oka && okb)) {
+					var va, vb float64
+					if oka || o.Weighting == RegionWeighted {
+						va = accA.result("mean", e-s, w) * scaleA
+					}
+					if okb || o.Weighting == RegionWeighted {
+						vb = accB.result("mean", e-s, w) * scaleB
+					}
+					if v, ok := op.apply(va, vb, pseudocount); ok {
+						if err := out.add(s, e, float32(v)); err != nil {
+							return err
+						}
+					}
+				}
+				s = e
+			}
+			if ca.err != nil {
+				return ca.err
+			}
+			if cb.err != nil {
+				return cb.err
+			}
+			return out.flush()
+		}()
+		ca.close()
+		cb.close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", chrom, err)
+		}
+	}
+	return nil
+}
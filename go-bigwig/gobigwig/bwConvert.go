@@ -0,0 +1,224 @@
+package gobigwig
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bwConvertBatch 转换时每次交给 BigWigWriter 的记录数
+const bwConvertBatch = 4096
+
+// bwConverter 把文本格式的记录按批写入 BigWigWriter，同时检查染色体是否存在、
+// 坐标是否越界以及记录是否按 sort -k1,1 -k2,2n 的顺序排列且互不重叠
+type bwConverter struct {
+	w        *BigWigWriter
+	sizes    map[string]uint32
+	chrom    string // 当前染色体，空表示尚未读到记录
+	lastEnd  uint32
+	line     int             // 当前记录所在的输入行号，由读取方设置
+	prev     bwConvertRecord // 上一条记录，用于排序错误的提示
+	typ      uint8           // 缓存记录的块类型（bwTypeBedGraph/VariableStep/FixedStep）
+	span     uint32          // variableStep/fixedStep 的 span
+	step     uint32          // fixedStep 的 step
+	starts   []uint32
+	ends     []uint32
+	values   []float32
+	progress *bwProgress // 读取输入的进度，成功结束时报告完成
+}
+
+// newBWConverter 以 chromSizes 中的染色体创建 out，tid 按染色体名排序分配
+func newBWConverter(chromSizes map[string]uint32, out string, opts []WriteOption) (*bwConverter, error) {
+	w, err := CreateBigWigFromSizes(out, chromSizes, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &bwConverter{w: w, sizes: chromSizes}, nil
+}
+
+// add 追加一条 bedGraph 记录 chrom:[start, end) = value
+func (c *bwConverter) add(chrom string, start, end uint32, value float32) error {
+	return c.addItem(bwTypeBedGraph, 0, 0, chrom, start, end, value)
+}
+
+// addItem 追加一条 typ 类型的记录；类型、span 或 step 改变（或 fixedStep 不再连续）时先写出缓存的记录
+func (c *bwConverter) addItem(typ uint8, span, step uint32, chrom string, start, end uint32, value float32) error {
+	if chrom != c.chrom {
+		if _, ok := c.sizes[chrom]; !ok {
+			return fmt.Errorf("chromosome %s is not in the chromosome sizes", chrom)
+		}
+		if c.chrom != "" && chrom < c.chrom {
+			return c.unsorted(chrom, start, end, value, false)
+		}
+		if err := c.flush(); err != nil {
+			return err
+		}
+		c.chrom, c.lastEnd = chrom, 0
+	}
+	if start >= end {
+		return fmt.Errorf("invalid interval %s:%d-%d", chrom, start, end)
+	}
+	if size := c.sizes[chrom]; end > size {
+		return fmt.Errorf("interval %s:%d-%d exceeds chromosome length %d", chrom, start, end, size)
+	}
+	if start < c.lastEnd {
+		return c.unsorted(chrom, start, end, value, start >= c.prev.start)
+	}
+	if n := len(c.starts); n > 0 && (typ != c.typ || span != c.span || step != c.step ||
+		(typ == bwTypeFixedStep && start != c.starts[0]+uint32(n)*step)) {
+		if err := c.flush(); err != nil {
+			return err
+		}
+	}
+	c.typ, c.span, c.step = typ, span, step
+	c.lastEnd = end
+	c.prev = bwConvertRecord{chrom, start, end, value, c.line}
+	c.starts = append(c.starts, start)
+	c.ends = append(c.ends, end)
+	c.values = append(c.values, value)
+	if len(c.starts) >= bwConvertBatch {
+		return c.flush()
+	}
+	return nil
+}
+
+// unsorted 返回当前记录与上一条记录构成的 UnsortedError
+func (c *bwConverter) unsorted(chrom string, start, end uint32, value float32, overlap bool) error {
+	return &UnsortedError{
+		Line:       c.line,
+		Record:     bwConvertRecord{chrom, start, end, value, c.line}.String(),
+		PrevLine:   c.prev.line,
+		PrevRecord: c.prev.String(),
+		Overlap:    overlap,
+	}
+}
+
+// flush 把缓存的记录写入 writer
+func (c *bwConverter) flush() error {
+	if len(c.starts) == 0 {
+		return nil
+	}
+	var err error
+	switch c.typ {
+	case bwTypeVariableStep:
+		err = c.w.AddIntervalSpans(c.chrom, c.starts, c.span, c.values)
+	case bwTypeFixedStep:
+		err = c.w.AddIntervalSpanSteps(c.chrom, c.starts[0], c.span, c.step, c.values)
+	default:
+		err = c.w.AddIntervals(c.chrom, c.starts, c.ends, c.values)
+	}
+	c.starts, c.ends, c.values = c.starts[:0], c.ends[:0], c.values[:0]
+	return err
+}
+
+// finish 写出剩余记录并关闭文件；err 非 nil 时删除未完成的输出文件
+func (c *bwConverter) finish(err error) error {
+	if err == nil {
+		err = c.flush()
+	}
+	if cerr := c.w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(c.w.bf_fp.URL.FName)
+		return err
+	}
+	c.progress.finish()
+	return nil
+}
+
+// bwTextInput 根据魔数判断 r 是否为 gzip 压缩（bgzip 生成的 BGZF 也是多段 gzip），
+// 是则返回解压后的流，否则原样返回
+func bwTextInput(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip input: %w", err)
+		}
+		return zr, nil
+	}
+	return br, nil
+}
+
+// bwSkipTextLine 判断是否为空行、注释或 track/browser 行
+func bwSkipTextLine(line string) bool {
+	return line == "" || line[0] == '#' || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser")
+}
+
+// ConvertBedGraph 读取 r 中的 bedGraph（chrom、start、end、value，坐标从 0 开始），写成 bigWig 文件 out，
+// 并按 opts 生成 zoom 层级，可替代 UCSC 的 bedGraphToBigWig
+// chromSizes 给出染色体长度（可取自 LoadChromSizes/BuiltinChromSizes）；输入必须按 sort -k1,1 -k2,2n 排序（或使用 WithExternalSort）且记录互不重叠，
+// 顺序颠倒或重叠时返回 *UnsortedError，其中给出两条记录及各自的行号
+// r 可以是 gzip 或 bgzip 压缩的，按魔数自动识别；出错时返回带行号的错误并删除 out
+func ConvertBedGraph(r io.Reader, chromSizes map[string]uint32, out string, opts ...WriteOption) error {
+	if len(chromSizes) == 0 {
+		return errors.New("chromosome sizes are empty")
+	}
+	c, err := newBWConverter(chromSizes, out, opts)
+	if err != nil {
+		return err
+	}
+	r, c.progress = bwTrackReader(c.w.opts.Progress, r)
+	if c.w.opts.SortInput {
+		return c.finish(c.readBedGraphSorted(r, c.w.opts.SortTempDir))
+	}
+	return c.finish(c.readBedGraph(r))
+}
+
+// readBedGraph 逐行解析 bedGraph 并写入
+func (c *bwConverter) readBedGraph(r io.Reader) error {
+	r, err := bwTextInput(r)
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if bwSkipTextLine(line) {
+			continue
+		}
+		chrom, start, end, value, err := bwParseBedGraphLine(line)
+		c.line = lineNo
+		if err == nil {
+			err = c.add(chrom, start, end, value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return sc.Err()
+}
+
+// bwParseBedGraphLine 解析一行 bedGraph，字段以制表符或空格分隔
+func bwParseBedGraphLine(line string) (string, uint32, uint32, float32, error) {
+	f := strings.Fields(line)
+	if len(f) != 4 {
+		return "", 0, 0, 0, fmt.Errorf("expected 4 fields, got %d", len(f))
+	}
+	start, err := strconv.ParseUint(f[1], 10, 32)
+	if err != nil {
+		return "", 0, 0, 0, fmt.Errorf("invalid start %q", f[1])
+	}
+	end, err := strconv.ParseUint(f[2], 10, 32)
+	if err != nil {
+		return "", 0, 0, 0, fmt.Errorf("invalid end %q", f[2])
+	}
+	value, err := strconv.ParseFloat(f[3], 32)
+	if err != nil {
+		return "", 0, 0, 0, fmt.Errorf("invalid value %q", f[3])
+	}
+	return f[0], uint32(start), uint32(end), float32(value), nil
+}
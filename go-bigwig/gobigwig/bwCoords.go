@@ -0,0 +1,60 @@
+package gobigwig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Coordinates 表示查询接口使用的坐标约定
+type Coordinates int
+
+const (
+	ZeroBased Coordinates = iota // 从 0 开始的半开区间 [start, end)，与 BED/bigWig 文件一致（默认）
+	OneBased                     // 从 1 开始的闭区间 [start, end]，与 R/Bioconductor、VCF、SAM 区域写法一致
+)
+
+func (c Coordinates) String() string {
+	if c == OneBased {
+		return "1-based"
+	}
+	return "0-based"
+}
+
+// ToZeroBased 把约定 c 下的区间转换为从 0 开始的半开区间
+func (c Coordinates) ToZeroBased(start, end int) (int, int) {
+	if c == OneBased {
+		return start - 1, end
+	}
+	return start, end
+}
+
+// FromZeroBased 把从 0 开始的半开区间转换为约定 c 下的区间
+func (c Coordinates) FromZeroBased(start, end int) (int, int) {
+	if c == OneBased {
+		return start + 1, end
+	}
+	return start, end
+}
+
+// ParseRegion 解析 chrom:start-end 形式的区间，数字中的逗号（如 1,000,000）被忽略
+// 坐标原样返回，按打开文件时设置的约定解释；染色体名本身可以包含冒号
+func ParseRegion(s string) (Region, error) {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 {
+		return Region{}, fmt.Errorf("invalid region %q: expected chrom:start-end", s)
+	}
+	from, to, ok := strings.Cut(strings.ReplaceAll(s[i+1:], ",", ""), "-")
+	if !ok {
+		return Region{}, fmt.Errorf("invalid region %q: expected chrom:start-end", s)
+	}
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return Region{}, fmt.Errorf("invalid region %q: bad start", s)
+	}
+	end, err := strconv.Atoi(to)
+	if err != nil {
+		return Region{}, fmt.Errorf("invalid region %q: bad end", s)
+	}
+	return Region{Chrom: s[:i], Start: start, End: end}, nil
+}
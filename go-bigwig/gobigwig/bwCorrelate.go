@@ -0,0 +1,129 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CorrelationMethod 表示 Correlate 使用的相关系数
+type CorrelationMethod int
+
+const (
+	Pearson  CorrelationMethod = iota // 皮尔逊相关系数
+	Spearman                          // 斯皮尔曼秩相关系数（并列值取平均秩）
+)
+
+func (m CorrelationMethod) String() string {
+	switch m {
+	case Pearson:
+		return "pearson"
+	case Spearman:
+		return "spearman"
+	}
+	return fmt.Sprintf("CorrelationMethod(%d)", int(m))
+}
+
+// Correlation 是 Correlate 的结果
+type Correlation struct {
+	Method      CorrelationMethod
+	Coefficient float64  // 有效 bin 少于 2 个或某一侧方差为 0 时为 NaN
+	Bins        []Region // 参与计算的 bin，坐标从 0 开始
+	A, B        []float64
+}
+
+// Correlate 把两个 bigWig 按 binSize 分箱（每个 bin 取平均值，见 MultiBigwigSummary），
+// 返回两者的相关系数以及参与计算的成对 bin 值；缺失数据按 WithMissing 处理，
+// 默认缺失按 0 计算、两个文件都没有数据的 bin 被跳过
+func Correlate(a, b string, binSize uint32, method CorrelationMethod, opts ...CompareOption) (*Correlation, error) {
+	return CorrelateContext(context.Background(), a, b, binSize, method, opts...)
+}
+
+// CorrelateContext 与 Correlate 相同，ctx 被取消时返回 ctx.Err()
+func CorrelateContext(ctx context.Context, a, b string, binSize uint32, method CorrelationMethod, opts ...CompareOption) (*Correlation, error) {
+	if method != Pearson && method != Spearman {
+		return nil, fmt.Errorf("unknown correlation method %v", method)
+	}
+	var o CompareOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	s, err := MultiBigwigSummary(ctx, []string{a, b}, MultiSummaryOptions{BinSize: binSize, Stat: "mean", Weighting: o.Weighting})
+	if err != nil {
+		return nil, err
+	}
+	c := &Correlation{Method: method}
+	for j, bin := range s.Bins {
+		va, vb := s.Values[0][j], s.Values[1][j]
+		na, nb := math.IsNaN(va), math.IsNaN(vb)
+		if (na && nb) || (o.Missing == MissingSkip && (na || nb)) {
+			continue
+		}
+		if na {
+			va = 0
+		}
+		if nb {
+			vb = 0
+		}
+		c.Bins = append(c.Bins, bin)
+		c.A = append(c.A, va)
+		c.B = append(c.B, vb)
+	}
+	if method == Spearman {
+		c.Coefficient = bwPearson(bwRanks(c.A), bwRanks(c.B))
+	} else {
+		c.Coefficient = bwPearson(c.A, c.B)
+	}
+	return c, nil
+}
+
+// bwPearson 返回 x、y 的皮尔逊相关系数
+func bwPearson(x, y []float64) float64 {
+	n := len(x)
+	if n < 2 {
+		return math.NaN()
+	}
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(n)
+	my /= float64(n)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// bwRanks 返回 x 中各值的秩（从 1 开始），并列值取平均秩
+func bwRanks(x []float64) []float64 {
+	idx := make([]int, len(x))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return x[idx[a]] < x[idx[b]] })
+	ranks := make([]float64, len(x))
+	for i := 0; i < len(idx); {
+		j := i + 1
+		for j < len(idx) && x[idx[j]] == x[idx[i]] {
+			j++
+		}
+		r := float64(i+j+1) / 2 // 第 i+1 到第 j 名的平均
+		for k := i; k < j; k++ {
+			ranks[idx[k]] = r
+		}
+		i = j
+	}
+	return ranks
+}
@@ -0,0 +1,94 @@
+package gobigwig
+
+import "context"
+
+// bwCountZoomDiv 控制 CoveredBases 选择的 zoom 层级：缩放倍数不超过区间长度的 1/bwCountZoomDiv，
+// 这样两端需要读取原始记录的部分最多约占区间的 2/bwCountZoomDiv
+const bwCountZoomDiv = 16
+
+// CoveredBases 返回 chrom:[start, end) 中有数据的碱基数
+// 完全落在区间内的 zoom 汇总直接累加其 ValidCount，只有跨越区间端点的汇总才读取原始记录，
+// 因此大区间只需要解压少量数据块；结果与逐条读取原始记录相同。文件没有 zoom 层级时读取原始记录
+func (fp *Bigwig_file_out) CoveredBases(ctx context.Context, chrom string, start, end int) (uint64, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return 0, err
+	}
+	bw := fp.bf_fp
+	zoomIdx := -1
+	if len(bw.Hdr.ZoomHdrs) > 0 {
+		zoomIdx = bwSelectBestZoomLevel(bw.Hdr.ZoomHdrs[0], uint32((end-start)/bwCountZoomDiv))
+	}
+	if zoomIdx < 0 {
+		return bwRawCoveredBases(ctx, bw, chrom, uint32(start), uint32(end))
+	}
+	summaries, err := bwGetSummariesInRegion(ctx, bw, zoomIdx, chrom, uint32(start), uint32(end))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, s := range summaries {
+		if s.Start >= uint32(start) && s.End <= uint32(end) {
+			n += uint64(s.ValidCount)
+			continue
+		}
+		c, err := bwRawCoveredBases(ctx, bw, chrom, max32(s.Start, uint32(start)), min32(s.End, uint32(end)))
+		if err != nil {
+			return 0, err
+		}
+		n += c
+	}
+	return n, nil
+}
+
+// CountIntervals 返回与 chrom:[start, end) 重叠的原始记录数
+// zoom 汇总不保存记录条数，所以只有区间内没有任何汇总（即没有数据）时才能不读取原始数据块直接返回 0，
+// 其余情况逐条计数
+func (fp *Bigwig_file_out) CountIntervals(ctx context.Context, chrom string, start, end int) (uint64, error) {
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return 0, err
+	}
+	bw := fp.bf_fp
+	if zhdrs := bw.Hdr.ZoomHdrs; len(zhdrs) > 0 && len(zhdrs[0].Level) > 0 {
+		// 最粗的层级汇总最少，判断有无数据最快
+		coarsest := 0
+		for i, r := range zhdrs[0].Level {
+			if r > zhdrs[0].Level[coarsest] {
+				coarsest = i
+			}
+		}
+		summaries, err := bwGetSummariesInRegion(ctx, bw, coarsest, chrom, uint32(start), uint32(end))
+		if err != nil {
+			return 0, err
+		}
+		if len(summaries) == 0 {
+			return 0, nil
+		}
+	}
+	cur := newBWIntervalCursor(ctx, bw, chrom, uint32(start), uint32(end))
+	defer cur.close()
+	var n uint64
+	for ; !cur.done && cur.cur.Start < uint32(end); cur.advance() {
+		if cur.cur.End > uint32(start) {
+			n++
+		}
+	}
+	if cur.err != nil {
+		return 0, cur.err
+	}
+	return n, nil
+}
+
+// bwRawCoveredBases 读取原始记录，返回 chrom:[start, end) 中有数据的碱基数
+func bwRawCoveredBases(ctx context.Context, bw *bigWigFile_t, chrom string, start, end uint32) (uint64, error) {
+	cur := newBWIntervalCursor(ctx, bw, chrom, start, end)
+	defer cur.close()
+	var n uint64
+	for ; !cur.done && cur.cur.Start < end; cur.advance() {
+		if cur.cur.End > start {
+			n += uint64(min32(cur.cur.End, end) - max32(cur.cur.Start, start))
+		}
+	}
+	return n, cur.err
+}
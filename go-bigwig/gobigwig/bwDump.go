@@ -0,0 +1,174 @@
+package gobigwig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// IndexDump 是 DumpIndex 以 JSON 输出的 R 树结构
+type IndexDump struct {
+	IndexOffset uint64       `json:"indexOffset"`
+	BlockSize   uint32       `json:"blockSize"`
+	NItems      uint64       `json:"nItems"`
+	RootOffset  uint64       `json:"rootOffset"`
+	Levels      []IndexLevel `json:"levels"`
+	Root        *IndexNode   `json:"root"`
+}
+
+// IndexLevel 汇总 R 树某一层的节点数、子项数和叶子指向的数据量，用于调整写入时的 block size
+type IndexLevel struct {
+	Depth     int    `json:"depth"`
+	Nodes     int    `json:"nodes"`
+	Children  int    `json:"children"`
+	Leaves    int    `json:"leaves"`              // 该层叶子节点数
+	DataBytes uint64 `json:"dataBytes,omitempty"` // 该层叶子指向的数据块总字节数
+}
+
+// IndexNode 是 R 树中的一个节点
+type IndexNode struct {
+	Offset   uint64       `json:"offset"`
+	Depth    int          `json:"depth"`
+	IsLeaf   bool         `json:"isLeaf"`
+	Children []IndexChild `json:"children"`
+}
+
+// IndexChild 是节点中的一项；叶子项的 Offset/Size 指向数据块，枝节点项的 Node 为子节点
+type IndexChild struct {
+	Span   string     `json:"span"` // chrom:start-chrom:end，坐标从 0 开始
+	Offset uint64     `json:"offset"`
+	Size   uint64     `json:"size,omitempty"`
+	Node   *IndexNode `json:"node,omitempty"`
+}
+
+// DumpIndex 遍历主 R 树索引，把节点结构（层级、子项数、覆盖范围和偏移）写入 w，
+// format 为 "json" 或 "dot"（Graphviz），用于分析某个区间查询为何需要读取大量数据块
+func (fp *Bigwig_file_out) DumpIndex(w io.Writer, format string) error {
+	bw := fp.bf_fp
+	if err := bwLoadChromList(bw); err != nil {
+		return err
+	}
+	if err := bwLoadIndex(bw); err != nil {
+		return err
+	}
+	return bwDumpIndex(bw, bw.Hdr.indexoffset, w, format)
+}
+
+// DumpZoomIndex 与 DumpIndex 相同，但输出第 level 个 zoom 层级的索引
+func (fp *Bigwig_file_out) DumpZoomIndex(w io.Writer, level int, format string) error {
+	bw := fp.bf_fp
+	if len(bw.Hdr.ZoomHdrs) == 0 || level < 0 || level >= len(bw.Hdr.ZoomHdrs[0].Level) {
+		return fmt.Errorf("zoom level %d not found", level)
+	}
+	if err := bwLoadChromList(bw); err != nil {
+		return err
+	}
+	return bwDumpIndex(bw, bw.Hdr.ZoomHdrs[0].IndexOffset[level], w, format)
+}
+
+// bwDumpIndex 读取 offset 处的 R 树并按 format 输出
+func bwDumpIndex(bw *bigWigFile_t, offset uint64, w io.Writer, format string) error {
+	if format != "json" && format != "dot" {
+		return fmt.Errorf("unknown index dump format %q (want json or dot)", format)
+	}
+	idx, err := readRTreeIdx(bw, offset)
+	if err != nil {
+		return err
+	}
+	d := &IndexDump{
+		IndexOffset: offset,
+		BlockSize:   idx.BlockSize,
+		NItems:      idx.NItems,
+		RootOffset:  idx.RootOffset,
+	}
+	visited := make(map[uint64]bool)
+	if d.Root, err = bwDumpNode(bw, d, idx.RootOffset, 0, visited); err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d)
+	}
+	return bwWriteDot(w, d)
+}
+
+// bwDumpNode 递归读取 offset 处的节点，同时累计每层的统计
+func bwDumpNode(bw *bigWigFile_t, d *IndexDump, offset uint64, depth int, visited map[uint64]bool) (*IndexNode, error) {
+	if visited[offset] || depth > 64 {
+		return nil, fmt.Errorf("R-tree node %d is reachable more than once or nested too deep", offset)
+	}
+	visited[offset] = true
+	node, err := bwGetRTreeNode(bw, offset)
+	if err != nil {
+		return nil, err
+	}
+	for len(d.Levels) <= depth {
+		d.Levels = append(d.Levels, IndexLevel{Depth: len(d.Levels)})
+	}
+	lv := &d.Levels[depth]
+	lv.Nodes++
+	lv.Children += int(node.NChildren)
+
+	n := &IndexNode{Offset: offset, Depth: depth, IsLeaf: node.IsLeaf != 0}
+	if n.IsLeaf {
+		lv.Leaves++
+	}
+	for i := 0; i < int(node.NChildren); i++ {
+		c := IndexChild{
+			Span: fmt.Sprintf("%s:%d-%s:%d", bwChromName(bw, node.ChrIdxStart[i]), node.BaseStart[i],
+				bwChromName(bw, node.ChrIdxEnd[i]), node.BaseEnd[i]),
+			Offset: node.DataOffset[i],
+		}
+		if n.IsLeaf {
+			c.Size = node.Size[i]
+			d.Levels[depth].DataBytes += c.Size
+		} else if c.Node, err = bwDumpNode(bw, d, c.Offset, depth+1, visited); err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, c)
+	}
+	return n, nil
+}
+
+// bwChromName 返回 tid 对应的染色体名，越界时返回编号
+func bwChromName(bw *bigWigFile_t, tid uint32) string {
+	if bw.Cl != nil && int(tid) < len(bw.Cl.Chrom) {
+		return bw.Cl.Chrom[tid]
+	}
+	return fmt.Sprintf("#%d", tid)
+}
+
+// bwWriteDot 以 Graphviz 格式输出 R 树，数据块画成叶子下的方框
+func bwWriteDot(w io.Writer, d *IndexDump) error {
+	var err error
+	p := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	p("digraph rtree {\n")
+	p("  label=\"R-tree at %d: blockSize=%d nItems=%d\";\n", d.IndexOffset, d.BlockSize, d.NItems)
+	p("  node [shape=record, fontsize=10];\n")
+	var walk func(n *IndexNode)
+	walk = func(n *IndexNode) {
+		kind := "node"
+		if n.IsLeaf {
+			kind = "leaf"
+		}
+		p("  n%d [label=\"%s @%d|depth %d|%d children\"];\n", n.Offset, kind, n.Offset, n.Depth, len(n.Children))
+		for _, c := range n.Children {
+			if c.Node != nil {
+				p("  n%d -> n%d [label=\"%s\"];\n", n.Offset, c.Node.Offset, c.Span)
+				walk(c.Node)
+				continue
+			}
+			p("  b%d [shape=box, label=\"block @%d\\n%d bytes\\n%s\"];\n", c.Offset, c.Offset, c.Size, c.Span)
+			p("  n%d -> b%d;\n", n.Offset, c.Offset)
+		}
+	}
+	walk(d.Root)
+	p("}\n")
+	return err
+}
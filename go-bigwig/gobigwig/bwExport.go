@@ -0,0 +1,211 @@
+package gobigwig
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// bwExportBlocksPerIteration 导出时迭代器每次读取的数据块数
+const bwExportBlocksPerIteration = 64
+
+// bwEachInterval 按文件顺序逐块读取 chrom:[start, end) 内的记录并调用 fn，
+// 记录被截断到查询区间内；内存占用与区间大小无关
+func bwEachInterval(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32, fn func(s, e uint32, v float32) error) error {
+	iter := bwOverlappingIntervalsIterator(ctx, fp, chrom, start, end, bwExportBlocksPerIteration)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if iter == nil {
+		return fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	defer bwIteratorDestroy(iter)
+	if iter.Err != nil {
+		return iter.Err
+	}
+	for iter.Data != nil {
+		o := iter.Intervals
+		if o == nil {
+			// Data 保存的是 nil 的 *bwOverlappingIntervals_t，即没有读到数据块索引
+			return fmt.Errorf("failed to read data blocks of %s", chrom)
+		}
+		if fp.Opts.SortResults {
+			sortIntervals(o)
+		}
+		for i := uint32(0); i < o.L; i++ {
+			s, e := max32(o.Start[i], start), min32(o.End[i], end)
+			if s >= e {
+				continue
+			}
+			if err := fn(s, e, o.Value[i]); err != nil {
+				return err
+			}
+		}
+		next := bwIteratorNext(iter)
+		if next == nil {
+			if iter.Err != nil {
+				return iter.Err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return errors.New("failed to read data blocks")
+		}
+		iter = next
+	}
+	return nil
+}
+
+// IntervalsContext 返回 chrom:[start, end) 内的记录，记录被截断到区间边界内；
+// 输入与返回的记录坐标都按打开文件时的 Coordinates 约定（WithCoordinates(OneBased) 时为从 1 开始的闭区间）
+// 与黑名单重叠的记录被丢弃，ctx 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) IntervalsContext(ctx context.Context, chrom string, start, end int) ([]Interval, error) {
+	bw := fp.bf_fp
+	start, end, err := bwCheckRange(bw, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	blacklist := bw.Opts.Blacklist
+	coords := bw.Opts.Coordinates
+	out := []Interval{}
+	err = bwEachInterval(ctx, bw, chrom, uint32(start), uint32(end), func(s, e uint32, v float32) error {
+		if blacklist == nil || !blacklist.Overlaps(chrom, s, e) {
+			is, ie := coords.FromZeroBased(int(s), int(e))
+			out = append(out, Interval{uint32(is), uint32(ie), v})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// bwExportRegions 把 regions 转换为从 0 开始的查询区间；regions 为空时返回所有染色体的全长
+func bwExportRegions(fp *bigWigFile_t, regions []Region) ([]Region, error) {
+	if err := bwLoadChromList(fp); err != nil {
+		return nil, err
+	}
+	if len(regions) == 0 {
+		out := make([]Region, len(fp.Cl.Chrom))
+		for i, c := range fp.Cl.Chrom {
+			out[i] = Region{Chrom: c, Start: 0, End: int(fp.Cl.Len[i])}
+		}
+		return out, nil
+	}
+	out := make([]Region, len(regions))
+	for i, r := range regions {
+		s, e, err := bwCheckRange(fp, r.Chrom, r.Start, r.End)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = Region{Chrom: r.Chrom, Start: s, End: e}
+	}
+	return out, nil
+}
+
+// bwFormatValue 以能精确还原 float32 的最短形式格式化数值
+func bwFormatValue(v float32) string {
+	return strconv.FormatFloat(float64(v), 'g', -1, 32)
+}
+
+// WriteBedGraph 把 regions 内的记录以 bedGraph 格式（chrom、start、end、value，坐标从 0 开始）写入 w，
+// 不给出 regions 时导出整个文件；记录被截断到区间边界内，可替代 UCSC 的 bigWigToBedGraph
+func (fp *Bigwig_file_out) WriteBedGraph(w io.Writer, regions ...Region) error {
+	return fp.WriteBedGraphContext(context.Background(), w, false, regions...)
+}
+
+// WriteBedGraphMerged 与 WriteBedGraph 相同，但把首尾相接且值相同的记录合并为一行
+func (fp *Bigwig_file_out) WriteBedGraphMerged(w io.Writer, regions ...Region) error {
+	return fp.WriteBedGraphContext(context.Background(), w, true, regions...)
+}
+
+// WriteBedGraphContext 是 WriteBedGraph/WriteBedGraphMerged 的通用形式，ctx 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) WriteBedGraphContext(ctx context.Context, w io.Writer, merge bool, regions ...Region) error {
+	bw := fp.bf_fp
+	rs, err := bwExportRegions(bw, regions)
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(w)
+	for _, r := range rs {
+		var pending bool
+		var ps, pe uint32
+		var pv float32
+		flush := func() error {
+			if !pending {
+				return nil
+			}
+			pending = false
+			_, err := fmt.Fprintf(out, "%s\t%d\t%d\t%s\n", r.Chrom, ps, pe, bwFormatValue(pv))
+			return err
+		}
+		err := bwEachInterval(ctx, bw, r.Chrom, uint32(r.Start), uint32(r.End), func(s, e uint32, v float32) error {
+			if merge && pending && s == pe && v == pv {
+				pe = e
+				return nil
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			pending, ps, pe, pv = true, s, e, v
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// bwEachBlock 按文件顺序读取与 chrom:[start, end) 重叠的每个数据块，
+// 以块头和块内属于该染色体且与区间重叠的记录（不截断）调用 fn
+func bwEachBlock(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32, fn func(hdr BlockHeader, items []Interval) error) error {
+	tid := bwGetTid(fp, chrom)
+	if tid == ^uint32(0) {
+		return fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	blocks, err := bwGetOverlappingBlocks(ctx, fp, chrom, start, end)
+	if err != nil {
+		return err
+	}
+	fp.URL.startPrefetch(ctx, coalesceBlocks(blocks), fp.Opts.PrefetchDepth)
+	defer fp.URL.stopPrefetch()
+	order := bwOrder(fp)
+	for i := uint64(0); i < blocks.N; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := bwReadBlock(fp, blocks.Offset[i], blocks.Size[i])
+		if err != nil {
+			return err
+		}
+		hdr, items, err := bwParseDataBlock(data, order)
+		if err != nil {
+			return fmt.Errorf("data block at offset %d: %w", blocks.Offset[i], err)
+		}
+		if hdr.ChromID != tid {
+			continue
+		}
+		k := 0
+		for _, it := range items {
+			if it.End > start && it.Start < end {
+				items[k] = it
+				k++
+			}
+		}
+		if k == 0 {
+			continue
+		}
+		if err := fn(hdr, items[:k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package gobigwig
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// bwLocalPath 把 file:// URL 转换为本地路径，其他名称原样返回
+// 支持 file:///data/x.bw、file://localhost/data/x.bw 和百分号编码；在 Windows 上还支持
+// file:///C:/data/x.bw、file:/C:/data/x.bw 等盘符形式，以及 file://server/share/x.bw（UNC 路径）
+func bwLocalPath(name string) (string, error) {
+	if len(name) < 5 || !strings.EqualFold(name[:5], "file:") {
+		return name, nil
+	}
+	return bwFileURLPath(name, runtime.GOOS == "windows")
+}
+
+// bwFileURLPath 按 windows 是否为 Windows 的规则解析 file URL
+func bwFileURLPath(name string, windows bool) (string, error) {
+	u, err := url.Parse(name)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL %q: %w", name, err)
+	}
+	p := u.Path
+	if u.Opaque != "" {
+		// file:C:/data/x.bw 这种没有斜杠的写法
+		if p, err = url.PathUnescape(u.Opaque); err != nil {
+			return "", fmt.Errorf("invalid file URL %q: %w", name, err)
+		}
+	}
+	if p == "" {
+		return "", fmt.Errorf("file URL %q has no path", name)
+	}
+	host := u.Host
+	if strings.EqualFold(host, "localhost") {
+		host = ""
+	}
+	if !windows {
+		if host != "" {
+			return "", fmt.Errorf("file URL %q refers to remote host %q", name, host)
+		}
+		return p, nil
+	}
+	// file://C:/data/x.bw 把盘符写在了主机的位置
+	if len(host) == 2 && host[1] == ':' && bwIsDriveLetter(host[0]) {
+		return filepath.FromSlash(host + p), nil
+	}
+	if host != "" {
+		return `\\` + host + filepath.FromSlash(p), nil
+	}
+	// /C:/data/x.bw 去掉盘符前的斜杠
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' && bwIsDriveLetter(p[1]) {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p), nil
+}
+
+func bwIsDriveLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
@@ -0,0 +1,169 @@
+//go:build cgo_export
+
+package gobigwig
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bwHandle 是句柄表中的一项；同一文件对象的读取共用一个文件位置，
+// 所以同一句柄上的 C 接口调用由 mu 串行化，不同句柄之间互不影响
+type bwHandle struct {
+	mu     sync.Mutex
+	fp     *Bigwig_file_out
+	cancel atomic.Pointer[int32] // 调用方的取消标志（C 内存），nil 表示未设置；不受 mu 保护，调用进行中也可以设置
+}
+
+// bwHandles 是 C 接口的句柄表：C 侧只拿到不透明的整数编号，Go 对象始终由这里引用，
+// 不会把 Go 指针交给 C（cgo 不允许 C 保存 Go 指针，GC 也可能回收只被 C 引用的对象）
+// 读写两类句柄共用编号，把写入句柄传给读取函数（或反过来）只会得到“句柄无效”
+var bwHandles = struct {
+	sync.Mutex
+	next    uint64
+	files   map[uint64]*bwHandle
+	writers map[uint64]*bwWriterHandle
+}{files: make(map[uint64]*bwHandle), writers: make(map[uint64]*bwWriterHandle)}
+
+// bwRegisterHandle 登记 fp 并返回新的句柄编号，编号从 1 开始且不会重复使用，0 表示无效句柄
+func bwRegisterHandle(fp *Bigwig_file_out) uint64 {
+	bwHandles.Lock()
+	defer bwHandles.Unlock()
+	bwHandles.next++
+	bwHandles.files[bwHandles.next] = &bwHandle{fp: fp}
+	return bwHandles.next
+}
+
+// bwAcquireHandle 取得句柄对应的文件并独占它，调用方用完后必须调用返回的 unlock；
+// 句柄无效或已关闭时返回 nil
+func bwAcquireHandle(h uint64) (fp *Bigwig_file_out, unlock func()) {
+	bwHandles.Lock()
+	e := bwHandles.files[h]
+	bwHandles.Unlock()
+	if e == nil {
+		return nil, nil
+	}
+	e.mu.Lock()
+	if e.fp == nil {
+		// 等待期间句柄被关闭
+		e.mu.Unlock()
+		return nil, nil
+	}
+	return e.fp, e.mu.Unlock
+}
+
+// bwReleaseHandle 从句柄表中移除 h，等待该句柄上进行中的调用结束后返回对应的文件，之后 h 不再有效
+func bwReleaseHandle(h uint64) *Bigwig_file_out {
+	bwHandles.Lock()
+	e := bwHandles.files[h]
+	delete(bwHandles.files, h)
+	bwHandles.Unlock()
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fp := e.fp
+	e.fp = nil
+	return fp
+}
+
+// bwWriterHandle 是句柄表中的一个写入句柄，同一写入句柄上的调用由 mu 串行化
+type bwWriterHandle struct {
+	mu sync.Mutex
+	w  *BigWigWriter
+}
+
+// bwRegisterWriter 登记 w 并返回新的句柄编号
+func bwRegisterWriter(w *BigWigWriter) uint64 {
+	bwHandles.Lock()
+	defer bwHandles.Unlock()
+	bwHandles.next++
+	bwHandles.writers[bwHandles.next] = &bwWriterHandle{w: w}
+	return bwHandles.next
+}
+
+// bwAcquireWriter 与 bwAcquireHandle 相同，但用于写入句柄
+func bwAcquireWriter(h uint64) (w *BigWigWriter, unlock func()) {
+	bwHandles.Lock()
+	e := bwHandles.writers[h]
+	bwHandles.Unlock()
+	if e == nil {
+		return nil, nil
+	}
+	e.mu.Lock()
+	if e.w == nil {
+		e.mu.Unlock()
+		return nil, nil
+	}
+	return e.w, e.mu.Unlock
+}
+
+// bwReleaseWriter 与 bwReleaseHandle 相同，但用于写入句柄
+func bwReleaseWriter(h uint64) *BigWigWriter {
+	bwHandles.Lock()
+	e := bwHandles.writers[h]
+	delete(bwHandles.writers, h)
+	bwHandles.Unlock()
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	w := e.w
+	e.w = nil
+	return w
+}
+
+// bwCancelPoll 是调用进行中检查取消标志的间隔
+const bwCancelPoll = 10 * time.Millisecond
+
+// bwSetCancelFlag 设置句柄 h 的取消标志地址，flag 为 nil 表示取消设置；句柄无效或已关闭时返回 false
+func bwSetCancelFlag(h uint64, flag *int32) bool {
+	bwHandles.Lock()
+	e := bwHandles.files[h]
+	bwHandles.Unlock()
+	if e == nil {
+		return false
+	}
+	e.cancel.Store(flag)
+	return true
+}
+
+// bwHandleContext 返回句柄 h 上一次调用使用的 ctx：设置了取消标志时，标志为非 0 或在调用中变为非 0
+// 都会取消 ctx，读取在下一个检查点停止；调用结束后必须调用返回的 cancel
+func bwHandleContext(h uint64) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bwHandles.Lock()
+	e := bwHandles.files[h]
+	bwHandles.Unlock()
+	if e == nil {
+		return ctx, cancel
+	}
+	flag := e.cancel.Load()
+	if flag == nil {
+		return ctx, cancel
+	}
+	if atomic.LoadInt32(flag) != 0 {
+		cancel()
+		return ctx, cancel
+	}
+	go func() {
+		t := time.NewTicker(bwCancelPoll)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				if atomic.LoadInt32(flag) != 0 {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
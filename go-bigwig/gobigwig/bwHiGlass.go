@@ -0,0 +1,313 @@
+package gobigwig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HiGlassTileSize 是 HiGlass 一维 tile 的 bin 数
+const HiGlassTileSize = 1024
+
+// HiGlassMode 是 HiGlass 的聚合方式或范围模式，tileset_info 中以 {"name", "value"} 列出
+type HiGlassMode struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HiGlassTilesetInfo 是 HiGlass 请求 tileset_info 时的返回内容，与 clodius 的 bigwig tileset 一致：
+// 染色体按自然顺序（chr2 在 chr10 之前）首尾相接成一条坐标轴，
+// 第 z 层的一个 tile 覆盖 TileSize<<(MaxZoom-z) 个碱基
+type HiGlassTilesetInfo struct {
+	MinPos           []uint64               `json:"min_pos"`
+	MaxPos           []uint64               `json:"max_pos"`
+	MaxWidth         uint64                 `json:"max_width"`
+	TileSize         int                    `json:"tile_size"`
+	MaxZoom          int                    `json:"max_zoom"`
+	ChromSizes       [][2]any               `json:"chromsizes"`
+	AggregationModes map[string]HiGlassMode `json:"aggregation_modes"`
+	RangeModes       map[string]HiGlassMode `json:"range_modes"`
+}
+
+// HiGlassTile 是 HiGlass 请求 tiles 时每个 tile 的返回内容
+// Dense 是 base64 编码的小端 float32 数组，没有数据的 bin 为 NaN；
+// 范围模式为 minMax 时先是 HiGlassTileSize 个最小值，再是同样多个最大值
+// MinValue/MaxValue 是忽略 NaN 后的范围，整个 tile 没有数据时为 null
+type HiGlassTile struct {
+	Dense    string      `json:"dense"`
+	DType    string      `json:"dtype"`
+	MinValue bwJSONFloat `json:"min_value"`
+	MaxValue bwJSONFloat `json:"max_value"`
+}
+
+var bwHiGlassAggregations = map[string]HiGlassMode{
+	"mean": {"Mean", "mean"},
+	"min":  {"Min", "min"},
+	"max":  {"Max", "max"},
+	"std":  {"Standard Deviation", "std"},
+}
+
+var bwHiGlassRanges = map[string]HiGlassMode{
+	"minMax": {"Min-Max", "minMax"},
+}
+
+// bwHiGlassChrom 是拼接坐标轴上的一条染色体
+type bwHiGlassChrom struct {
+	name   string
+	length uint32
+	offset uint64
+}
+
+// hiGlassChroms 返回按自然顺序拼接的染色体和总长度
+func (fp *Bigwig_file_out) hiGlassChroms() ([]bwHiGlassChrom, uint64, error) {
+	chroms, err := fp.Chroms()
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.SliceStable(chroms, func(i, j int) bool { return bwNaturalLess(chroms[i].Name, chroms[j].Name) })
+	out := make([]bwHiGlassChrom, len(chroms))
+	var total uint64
+	for i, c := range chroms {
+		out[i] = bwHiGlassChrom{c.Name, c.Length, total}
+		total += uint64(c.Length)
+	}
+	return out, total, nil
+}
+
+// bwHiGlassMaxZoom 返回覆盖 total 个碱基所需的最大层级，即 ceil(log2(total/HiGlassTileSize))
+func bwHiGlassMaxZoom(total uint64) int {
+	tiles := (total + HiGlassTileSize - 1) / HiGlassTileSize
+	if tiles <= 1 {
+		return 0
+	}
+	return bits.Len64(tiles - 1)
+}
+
+// HiGlassTilesetInfo 返回 HiGlass 的 tileset_info
+func (fp *Bigwig_file_out) HiGlassTilesetInfo() (*HiGlassTilesetInfo, error) {
+	chroms, total, err := fp.hiGlassChroms()
+	if err != nil {
+		return nil, err
+	}
+	maxZoom := bwHiGlassMaxZoom(total)
+	info := &HiGlassTilesetInfo{
+		MinPos:           []uint64{0},
+		MaxPos:           []uint64{total},
+		MaxWidth:         uint64(HiGlassTileSize) << maxZoom,
+		TileSize:         HiGlassTileSize,
+		MaxZoom:          maxZoom,
+		ChromSizes:       make([][2]any, len(chroms)),
+		AggregationModes: bwHiGlassAggregations,
+		RangeModes:       bwHiGlassRanges,
+	}
+	for i, c := range chroms {
+		info.ChromSizes[i] = [2]any{c.name, c.length}
+	}
+	return info, nil
+}
+
+// HiGlassTileContext 计算第 zoom 层第 x 个 tile，aggregation 为 mean（默认）、min、max 或 std，
+// rangeMode 为空或 minMax；跨越染色体边界的 bin 合并两侧的数据。ctx 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) HiGlassTileContext(ctx context.Context, zoom int, x uint64, aggregation, rangeMode string) (*HiGlassTile, error) {
+	if aggregation == "" {
+		aggregation = "mean"
+	}
+	if _, ok := bwHiGlassAggregations[aggregation]; !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownStat, aggregation)
+	}
+	if _, ok := bwHiGlassRanges[rangeMode]; rangeMode != "" && !ok {
+		return nil, fmt.Errorf("unknown range mode %q", rangeMode)
+	}
+	chroms, total, err := fp.hiGlassChroms()
+	if err != nil {
+		return nil, err
+	}
+	maxZoom := bwHiGlassMaxZoom(total)
+	if zoom < 0 || zoom > maxZoom {
+		return nil, fmt.Errorf("%w: zoom %d (max zoom %d)", ErrOutOfRange, zoom, maxZoom)
+	}
+	binWidth := uint64(1) << (maxZoom - zoom)
+	tileStart := x * HiGlassTileSize * binWidth
+	if x >= uint64(1)<<zoom || tileStart >= total {
+		return nil, fmt.Errorf("%w: tile %d.%d", ErrOutOfRange, zoom, x)
+	}
+	tileEnd := tileStart + HiGlassTileSize*binWidth
+
+	query := fmt.Sprintf("tile\x00%d\x00%d\x00%s\x00%s", zoom, x, aggregation, rangeMode)
+	return bwCachedResult(fp, query, func() (*HiGlassTile, error) {
+		stats := make([]bwBinStat, HiGlassTileSize)
+		for i := range stats {
+			stats[i].MinVal, stats[i].MaxVal = float32(math.Inf(1)), float32(math.Inf(-1))
+		}
+		// 使用 reduction 不超过 bin 宽度一半的最粗 zoom 层级，没有时读取原始记录
+		bw := fp.bf_fp
+		zoomIdx := -1
+		if len(bw.Hdr.ZoomHdrs) > 0 {
+			zoomIdx = bwSelectBestZoomLevel(bw.Hdr.ZoomHdrs[0], uint32(min64(binWidth/2, math.MaxUint32)))
+		}
+		for _, c := range chroms {
+			// 与 tile 重叠的部分，坐标相对于染色体
+			s := max(tileStart, c.offset) - c.offset
+			e := min64(tileEnd, c.offset+uint64(c.length)) - c.offset
+			if s >= e {
+				continue
+			}
+			summaries, err := bwTrackSummaries(ctx, bw, c.name, uint32(s), uint32(e), zoomIdx)
+			if err != nil {
+				return nil, err
+			}
+			if len(summaries) == 0 {
+				continue
+			}
+			// 逐 bin 累加与染色体重叠的部分
+			first := (c.offset + s - tileStart) / binWidth
+			for i := first; i < HiGlassTileSize; i++ {
+				bs := tileStart + i*binWidth
+				if bs >= c.offset+e {
+					break
+				}
+				ls := max(bs, c.offset+s) - c.offset
+				le := min64(bs+binWidth, c.offset+e) - c.offset
+				part, err := bwBinSummaries(ctx, summaries, uint32(ls), uint32(le), 1)
+				if err != nil {
+					return nil, err
+				}
+				stats[i].merge(part[0])
+			}
+		}
+
+		var values []float32
+		if rangeMode == "minMax" {
+			values = make([]float32, 2*HiGlassTileSize)
+			for i, b := range stats {
+				values[i] = b.value("min")
+				values[HiGlassTileSize+i] = b.value("max")
+			}
+		} else {
+			values = make([]float32, HiGlassTileSize)
+			for i, b := range stats {
+				values[i] = b.value(aggregation)
+			}
+		}
+		return bwHiGlassEncode(values), nil
+	}, func(t *HiGlassTile) *HiGlassTile {
+		c := *t
+		return &c
+	}, func(t *HiGlassTile) int64 { return int64(len(t.Dense) + len(t.DType)) })
+}
+
+// merge 把另一段的统计合并进 b
+func (b *bwBinStat) merge(o bwBinStat) {
+	b.SumData += o.SumData
+	b.SumSquares += o.SumSquares
+	b.ValidCount += o.ValidCount
+	if o.MinVal < b.MinVal {
+		b.MinVal = o.MinVal
+	}
+	if o.MaxVal > b.MaxVal {
+		b.MaxVal = o.MaxVal
+	}
+}
+
+// value 返回 bin 的 mean、min、max 或 std（总体标准差），没有数据时为 NaN
+func (b bwBinStat) value(stat string) float32 {
+	if b.ValidCount == 0 {
+		return float32(math.NaN())
+	}
+	n := b.ValidCount
+	switch stat {
+	case "min":
+		return b.MinVal
+	case "max":
+		return b.MaxVal
+	case "std":
+		mean := b.SumData / n
+		return float32(math.Sqrt(math.Max(b.SumSquares/n-mean*mean, 0)))
+	}
+	return float32(b.SumData / n)
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// bwHiGlassEncode 把 values 编码为 HiGlassTile
+func bwHiGlassEncode(values []float32) *HiGlassTile {
+	buf := make([]byte, 4*len(values))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+		if !math.IsNaN(float64(v)) {
+			lo, hi = math.Min(lo, float64(v)), math.Max(hi, float64(v))
+		}
+	}
+	return &HiGlassTile{
+		Dense:    base64.StdEncoding.EncodeToString(buf),
+		DType:    "float32",
+		MinValue: bwJSONFloat(lo),
+		MaxValue: bwJSONFloat(hi),
+	}
+}
+
+// ParseHiGlassTileID 解析 HiGlass 的 tile 编号 uuid.zoom.x[.aggregation[.rangeMode]]，
+// uuid 取第一个点之前的部分
+func ParseHiGlassTileID(id string) (uuid string, zoom int, x uint64, aggregation, rangeMode string, err error) {
+	parts := strings.Split(id, ".")
+	if len(parts) < 3 || len(parts) > 5 || parts[0] == "" {
+		return "", 0, 0, "", "", fmt.Errorf("invalid tile id %q", id)
+	}
+	if zoom, err = strconv.Atoi(parts[1]); err != nil {
+		return "", 0, 0, "", "", fmt.Errorf("invalid tile id %q", id)
+	}
+	if x, err = strconv.ParseUint(parts[2], 10, 64); err != nil {
+		return "", 0, 0, "", "", fmt.Errorf("invalid tile id %q", id)
+	}
+	if len(parts) > 3 {
+		aggregation = parts[3]
+	}
+	if len(parts) > 4 {
+		rangeMode = parts[4]
+	}
+	return parts[0], zoom, x, aggregation, rangeMode, nil
+}
+
+// bwNaturalLess 按自然顺序比较字符串，其中的数字按数值比较（chr2 < chr10）
+func bwNaturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := bwLeadingDigits(a), bwLeadingDigits(b)
+		if da > 0 && db > 0 {
+			na, nb := strings.TrimLeft(a[:da], "0"), strings.TrimLeft(b[:db], "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[da:], b[db:]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// bwLeadingDigits 返回 s 开头连续数字的个数
+func bwLeadingDigits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,95 @@
+package gobigwig
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BlockInfo 是 InspectBlock 返回的单个数据块信息
+type BlockInfo struct {
+	Offset           uint64
+	CompressedSize   uint64 // 文件中的字节数
+	UncompressedSize int    // 解压后的字节数，未压缩的文件与 CompressedSize 相同
+	Compressed       bool
+	Header           BlockHeader
+	Chrom            string // Header.ChromID 对应的染色体名
+	Encoding         string // bedGraph、variableStep、fixedStep 或 unknown
+	NItems           int
+	ItemSize         int   // 每条记录的字节数，未知类型为 0
+	Err              error // 块头或记录长度不合法时的原因，合法时为 nil
+
+	data  []byte
+	order binary.ByteOrder
+}
+
+// InspectBlock 读取并解压 offset 处大小为 size 的数据块（offset/size 通常来自 DumpIndex 的叶子项），
+// 返回块头、编码类型和大小等信息，用于调试单个数据块；需要具体记录时调用 BlockInfo.Items
+// 只有块无法读取或解压时才返回 error，格式问题记录在 BlockInfo.Err 中
+func (fp *Bigwig_file_out) InspectBlock(offset, size uint64) (*BlockInfo, error) {
+	bw := fp.bf_fp
+	data, err := bwReadBlock(bw, offset, size)
+	if err != nil {
+		return nil, err
+	}
+	order := bwOrder(bw)
+	info := &BlockInfo{
+		Offset:           offset,
+		CompressedSize:   size,
+		UncompressedSize: len(data),
+		Compressed:       bw.Hdr.bufsize > 0,
+		data:             data,
+		order:            order,
+	}
+	hdr := bwDataHeader_t{}
+	if info.Err = bwFillDataHdr(&hdr, data, order); info.Err != nil {
+		info.Encoding = "unknown"
+		return info, nil
+	}
+	info.Header = BlockHeader{hdr.Tid, hdr.Start, hdr.End, hdr.Step, hdr.Span, hdr.Type, hdr.NItems}
+	if bwLoadChromList(bw) == nil {
+		info.Chrom = bwChromName(bw, hdr.Tid)
+	}
+	info.Encoding = bwBlockEncoding(hdr.Type)
+	info.NItems = int(hdr.NItems)
+	info.ItemSize = bwDataItemSize(hdr.Type)
+	info.Err = bwCheckDataHdr(&hdr, len(data))
+	return info, nil
+}
+
+// Items 解码块中的全部记录
+func (b *BlockInfo) Items() ([]Interval, error) {
+	if b.Err != nil {
+		return nil, b.Err
+	}
+	_, items, err := bwParseDataBlock(b.data, b.order)
+	return items, err
+}
+
+// String 返回块的单行摘要
+func (b *BlockInfo) String() string {
+	s := fmt.Sprintf("block @%d: %d bytes", b.Offset, b.CompressedSize)
+	if b.Compressed {
+		s += fmt.Sprintf(" (%d uncompressed)", b.UncompressedSize)
+	}
+	s += fmt.Sprintf(", %s %s:%d-%d, %d items", b.Encoding, b.Chrom, b.Header.Start, b.Header.End, b.NItems)
+	if b.Header.Type != bwTypeBedGraph {
+		s += fmt.Sprintf(", step=%d span=%d", b.Header.Step, b.Header.Span)
+	}
+	if b.Err != nil {
+		s += fmt.Sprintf(", invalid: %v", b.Err)
+	}
+	return s
+}
+
+// bwBlockEncoding 返回数据块类型的名称
+func bwBlockEncoding(typ uint8) string {
+	switch typ {
+	case bwTypeBedGraph:
+		return "bedGraph"
+	case bwTypeVariableStep:
+		return "variableStep"
+	case bwTypeFixedStep:
+		return "fixedStep"
+	}
+	return "unknown"
+}
@@ -0,0 +1,50 @@
+//go:build cgo_export
+
+package gobigwig
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+// 每个调用线程各自保存最近一次失败的信息：导出函数运行在调用它的 C 线程上，
+// 其中调用的 C 函数也在同一线程执行，因此这里的线程局部变量按调用方线程区分
+static _Thread_local char *bw_err_msg;
+static _Thread_local uintptr_t bw_err_handle;
+
+static void bw_set_error(uintptr_t handle, char *msg) {
+	free(bw_err_msg);
+	bw_err_msg = msg;
+	bw_err_handle = handle;
+}
+
+static char *bw_get_error(uintptr_t handle, int any) {
+	static char empty[1];
+	if (bw_err_msg == NULL || (!any && bw_err_handle != handle)) {
+		return empty;
+	}
+	return bw_err_msg;
+}
+*/
+import "C"
+
+import "fmt"
+
+// bwClearError 在导出函数开始时清除调用线程的错误信息
+func bwClearError() {
+	C.bw_set_error(0, nil)
+}
+
+// bwSetError 记录调用线程最近一次失败的信息，handle 为出错的句柄（打开失败等没有句柄时为 0）
+func bwSetError(handle C.uintptr_t, format string, args ...any) {
+	C.bw_set_error(handle, C.CString(fmt.Sprintf(format, args...)))
+}
+
+// bwGetError 返回调用线程最近一次失败的信息；anyHandle 为 false 时只返回 handle 上的失败，
+// 没有时返回空串。返回的字符串在该线程下一次调用导出函数前有效
+func bwGetError(handle C.uintptr_t, anyHandle bool) *C.char {
+	flag := C.int(0)
+	if anyHandle {
+		flag = 1
+	}
+	return C.bw_get_error(handle, flag)
+}
@@ -0,0 +1,95 @@
+package gobigwig
+
+import "sort"
+
+// bwExtensionHeaderSize 版本 4 扩展头的大小
+const bwExtensionHeaderSize = 64
+
+// Section 是文件中的一段连续字节 [Offset, Offset+Size)
+type Section struct {
+	Name   string // header、zoomHeaders、autoSql、totalSummary、extension、chromTree、data、index、zoomData、zoomIndex、unused
+	Level  int    // zoom 层级编号，非 zoom 段为 -1
+	Offset uint64
+	Size   uint64
+}
+
+// End 返回段结束处的偏移
+func (s Section) End() uint64 { return s.Offset + s.Size }
+
+// FileLayout 描述文件各部分所在的字节范围
+type FileLayout struct {
+	Size     int64     // 文件大小，远程文件无法得知时为 -1（此时最后一段的 Size 为 0）
+	Sections []Section // 按偏移排序
+	Unused   []Section // 各段之间未被引用的空隙
+}
+
+// UnusedBytes 返回各段之间未被引用的字节总数
+func (l FileLayout) UnusedBytes() uint64 {
+	var n uint64
+	for _, s := range l.Unused {
+		n += s.Size
+	}
+	return n
+}
+
+// Layout 根据文件头和 zoom 头返回文件头、染色体 B+ 树、主数据与主索引以及每个 zoom 层级的
+// 数据与索引所占的字节范围，可用于按段预读或分片存储，以及发现浪费的空间
+// 大小不固定的段延伸到下一段的起点（最后一段延伸到文件末尾）
+func (fp *Bigwig_file_out) Layout() FileLayout {
+	bw := fp.bf_fp
+	hdr := bw.Hdr
+	l := FileLayout{Size: bwFileSize(bw.URL)}
+
+	type part struct {
+		Section
+		fixed bool // Size 由格式确定，否则延伸到下一段
+	}
+	parts := []part{{Section{"header", -1, 0, bwHeaderSize}, true}}
+	if hdr.nLevels > 0 {
+		parts = append(parts, part{Section{"zoomHeaders", -1, bwHeaderSize, uint64(hdr.nLevels) * bwZoomHeaderSize}, true})
+	}
+	if hdr.sqloffset > 0 {
+		parts = append(parts, part{Section{"autoSql", -1, hdr.sqloffset, 0}, false})
+	}
+	if hdr.summaryoffset > 0 {
+		parts = append(parts, part{Section{"totalSummary", -1, hdr.summaryoffset, bwSummarySize}, true})
+	}
+	if hdr.extensionoffset > 0 {
+		parts = append(parts, part{Section{"extension", -1, hdr.extensionoffset, bwExtensionHeaderSize}, true})
+	}
+	parts = append(parts,
+		part{Section{"chromTree", -1, hdr.ctoffset, 0}, false},
+		part{Section{"data", -1, hdr.dataOffset, 0}, false},
+		part{Section{"index", -1, hdr.indexoffset, 0}, false},
+	)
+	if len(hdr.ZoomHdrs) > 0 {
+		z := hdr.ZoomHdrs[0]
+		for i := range z.Level {
+			parts = append(parts,
+				part{Section{"zoomData", i, z.DataOffset[i], 0}, false},
+				part{Section{"zoomIndex", i, z.IndexOffset[i], 0}, false},
+			)
+		}
+	}
+	sort.SliceStable(parts, func(a, b int) bool { return parts[a].Offset < parts[b].Offset })
+
+	for i := range parts {
+		next := uint64(0)
+		switch {
+		case i+1 < len(parts):
+			next = parts[i+1].Offset
+		case l.Size >= 0:
+			next = uint64(l.Size)
+		}
+		p := &parts[i]
+		if !p.fixed {
+			if next > p.Offset {
+				p.Size = next - p.Offset
+			}
+		} else if next > p.End() {
+			l.Unused = append(l.Unused, Section{"unused", -1, p.End(), next - p.End()})
+		}
+		l.Sections = append(l.Sections, p.Section)
+	}
+	return l
+}
@@ -0,0 +1,287 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// AggOp 表示 Merge 在同一位置上合并多个文件的值的方式
+type AggOp int
+
+const (
+	AggMean AggOp = iota // 有值的文件的平均值
+	AggSum               // 有值的文件之和
+	AggMax               // 最大值
+	AggMin               // 最小值
+)
+
+func (op AggOp) String() string {
+	switch op {
+	case AggMean:
+		return "mean"
+	case AggSum:
+		return "sum"
+	case AggMax:
+		return "max"
+	case AggMin:
+		return "min"
+	}
+	return fmt.Sprintf("AggOp(%d)", int(op))
+}
+
+// ParseAggOp 按名称（mean、sum、max、min，与 String 的结果一致）返回 AggOp
+func ParseAggOp(s string) (AggOp, error) {
+	for op := AggMean; op <= AggMin; op++ {
+		if s == op.String() {
+			return op, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown aggregation %q", s)
+}
+
+// apply 合并 vals（至少一个值）
+func (op AggOp) apply(vals []float32) float32 {
+	switch op {
+	case AggMax, AggMin:
+		r := vals[0]
+		for _, v := range vals[1:] {
+			if (op == AggMax && v > r) || (op == AggMin && v < r) {
+				r = v
+			}
+		}
+		return r
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += float64(v)
+	}
+	if op == AggMean {
+		sum /= float64(len(vals))
+	}
+	return float32(sum)
+}
+
+// bwIntervalCursor 以拉取方式按位置顺序逐条读取一条染色体上的记录
+type bwIntervalCursor struct {
+	ctx  context.Context
+	iter *bwOverlapIterator_t
+	i    uint32
+	cur  Interval
+	done bool
+	err  error
+}
+
+// newBWIntervalCursor 创建 chrom:[start, end) 上的游标并读到第一条记录；文件中没有该染色体时游标直接结束
+func newBWIntervalCursor(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32) *bwIntervalCursor {
+	c := &bwIntervalCursor{ctx: ctx}
+	c.iter = bwOverlappingIntervalsIterator(ctx, fp, chrom, start, end, bwExportBlocksPerIteration)
+	if c.iter != nil && c.iter.Err != nil {
+		c.err, c.done = c.iter.Err, true
+		return c
+	}
+	c.advance()
+	return c
+}
+
+// advance 读取下一条记录，没有更多记录或出错时设置 done
+func (c *bwIntervalCursor) advance() {
+	for !c.done {
+		if c.iter == nil || c.iter.Data == nil {
+			c.done = true
+			break
+		}
+		if o := c.iter.Intervals; o != nil && c.i < o.L {
+			c.cur = Interval{Start: o.Start[c.i], End: o.End[c.i], Value: o.Value[c.i]}
+			c.i++
+			return
+		}
+		next := bwIteratorNext(c.iter)
+		if next == nil {
+			c.err = c.iter.Err
+			if c.err == nil {
+				c.err = c.ctx.Err()
+			}
+			if c.err == nil {
+				c.err = errors.New("failed to read data blocks")
+			}
+			c.done = true
+			break
+		}
+		c.iter, c.i = next, 0
+	}
+	if c.err == nil {
+		c.err = c.ctx.Err()
+	}
+}
+
+func (c *bwIntervalCursor) close() {
+	bwIteratorDestroy(c.iter)
+}
+
+// bwSweep 对多个游标做 k 路归并：把染色体切分为各文件记录边界之间的片段，
+// 对至少一个文件有值的片段以覆盖它的值调用 fn（vals 的顺序与 cursors 一致，缺失的文件被跳过）
+func bwSweep(cursors []*bwIntervalCursor, fn func(s, e uint32, vals []float32) error) error {
+	vals := make([]float32, 0, len(cursors))
+	pos := uint32(0)
+	for {
+		lo := uint32(math.MaxUint32)
+		for _, c := range cursors {
+			for !c.done && c.cur.End <= pos {
+				c.advance()
+			}
+			if c.err != nil {
+				return c.err
+			}
+			if !c.done && max32(c.cur.Start, pos) < lo {
+				lo = max32(c.cur.Start, pos)
+			}
+		}
+		if lo == math.MaxUint32 {
+			return nil
+		}
+		pos = lo
+		end := uint32(math.MaxUint32)
+		vals = vals[:0]
+		for _, c := range cursors {
+			if c.done {
+				continue
+			}
+			if c.cur.Start <= pos {
+				vals = append(vals, c.cur.Value)
+				end = min32(end, c.cur.End)
+			} else {
+				end = min32(end, c.cur.Start)
+			}
+		}
+		if err := fn(pos, end, vals); err != nil {
+			return err
+		}
+		pos = end
+	}
+}
+
+// bwOpenInputs 打开 inputs 并返回染色体长度的并集；同名染色体长度不一致时返回错误
+func bwOpenInputs(inputs []string) ([]*Bigwig_file_out, map[string]uint32, error) {
+	files := make([]*Bigwig_file_out, 0, len(inputs))
+	closeAll := func() {
+		for _, f := range files {
+			CloseBigWig(f)
+		}
+	}
+	sizes := make(map[string]uint32)
+	for _, in := range inputs {
+		fp, err := OpenBigWig(in)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, fp)
+		bw := fp.bf_fp
+		if err := bwLoadChromList(bw); err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("%s: %w", in, err)
+		}
+		for tid, chrom := range bw.Cl.Chrom {
+			if n, ok := sizes[chrom]; ok && n != bw.Cl.Len[tid] {
+				closeAll()
+				return nil, nil, fmt.Errorf("%s: chromosome %s has length %d, but %d in an earlier input", in, chrom, bw.Cl.Len[tid], n)
+			}
+			sizes[chrom] = bw.Cl.Len[tid]
+		}
+	}
+	return files, sizes, nil
+}
+
+// Merge 把多个 bigWig 按位置对齐，用 op 合并同一位置上的值后写入 output，可替代 wiggletools mean/sum
+// 各文件逐条流式读取并做 k 路归并，内存占用与文件大小无关；某位置只有部分文件有值时，
+// 只合并这些文件的值，所有文件都没有值的位置在输出中也没有值。相邻且值相同的片段合并为一条记录
+// 染色体列表为各输入的并集（同名染色体长度必须一致）；opts 用于创建 output
+func Merge(output string, inputs []string, op AggOp, opts ...WriteOption) error {
+	return MergeContext(context.Background(), output, inputs, op, opts...)
+}
+
+// MergeContext 与 Merge 相同，ctx 被取消时返回 ctx.Err() 并删除 output
+func MergeContext(ctx context.Context, output string, inputs []string, op AggOp, opts ...WriteOption) error {
+	if len(inputs) == 0 {
+		return errors.New("no input files")
+	}
+	if op < AggMean || op > AggMin {
+		return fmt.Errorf("unknown aggregation %v", op)
+	}
+	files, sizes, err := bwOpenInputs(inputs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			CloseBigWig(f)
+		}
+	}()
+	c, err := newBWConverter(sizes, output, opts)
+	if err != nil {
+		return err
+	}
+	c.progress = bwTrackProgress(c.w.opts.Progress, files...)
+	return c.finish(c.merge(ctx, files, func(vals []float32) (float32, bool) {
+		return op.apply(vals), true
+	}))
+}
+
+// bwPendingRecord 把首尾相接且值相同的片段合并后写入转换器
+type bwPendingRecord struct {
+	c       *bwConverter
+	chrom   string
+	pending bool
+	s, e    uint32
+	v       float32
+}
+
+func (p *bwPendingRecord) add(s, e uint32, v float32) error {
+	if p.pending && s == p.e && v == p.v {
+		p.e = e
+		return nil
+	}
+	if err := p.flush(); err != nil {
+		return err
+	}
+	p.pending, p.s, p.e, p.v = true, s, e, v
+	return nil
+}
+
+func (p *bwPendingRecord) flush() error {
+	if !p.pending {
+		return nil
+	}
+	p.pending = false
+	return p.c.add(p.chrom, p.s, p.e, p.v)
+}
+
+// merge 逐条染色体归并 files，combine 返回片段的输出值，返回 false 表示该片段不输出
+func (c *bwConverter) merge(ctx context.Context, files []*Bigwig_file_out, combine func(vals []float32) (float32, bool)) error {
+	for tid, chrom := range c.w.bf_fp.Cl.Chrom {
+		length := c.w.bf_fp.Cl.Len[tid]
+		cursors := make([]*bwIntervalCursor, len(files))
+		for i, f := range files {
+			cursors[i] = newBWIntervalCursor(ctx, f.bf_fp, chrom, 0, length)
+		}
+		out := &bwPendingRecord{c: c, chrom: chrom}
+		err := bwSweep(cursors, func(s, e uint32, vals []float32) error {
+			if v, ok := combine(vals); ok {
+				return out.add(s, e, v)
+			}
+			return nil
+		})
+		if err == nil {
+			err = out.flush()
+		}
+		for _, cur := range cursors {
+			cur.close()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", chrom, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package gobigwig
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics 接收文件句柄的 IO 与缓存统计，用于排查服务变慢的原因和调整缓存大小
+// 同一个 Metrics 可以被多个文件句柄共享，实现必须支持并发调用
+type Metrics interface {
+	BytesRead(n int64)          // 从磁盘或网络实际读取的字节数
+	Request()                   // 发出一次 HTTP 请求（HEAD、GET 或 Range）
+	CacheHit()                  // 读取由缓存（预读、缓冲区、块缓存、索引缓存）满足
+	CacheMiss()                 // 读取需要访问磁盘或网络
+	Decompress(d time.Duration) // 解压一个数据块所用的时间
+}
+
+// nopMetrics 未设置 Metrics 时使用，丢弃所有统计
+type nopMetrics struct{}
+
+func (nopMetrics) BytesRead(int64)          {}
+func (nopMetrics) Request()                 {}
+func (nopMetrics) CacheHit()                {}
+func (nopMetrics) CacheMiss()               {}
+func (nopMetrics) Decompress(time.Duration) {}
+
+// Counters 是 Metrics 的默认实现，使用原子计数器
+// 实现了 expvar.Var，可以直接 expvar.Publish("bigwig", c) 发布
+type Counters struct {
+	bytesRead       atomic.Int64
+	requests        atomic.Int64
+	cacheHits       atomic.Int64
+	cacheMisses     atomic.Int64
+	decompressions  atomic.Int64
+	decompressNanos atomic.Int64
+}
+
+// MetricsSnapshot 是 Counters 在某一时刻的取值
+type MetricsSnapshot struct {
+	BytesRead      int64         `json:"bytes_read"`
+	Requests       int64         `json:"requests"`
+	CacheHits      int64         `json:"cache_hits"`
+	CacheMisses    int64         `json:"cache_misses"`
+	Decompressions int64         `json:"decompressions"`
+	DecompressTime time.Duration `json:"decompress_ns"`
+}
+
+func (c *Counters) BytesRead(n int64) { c.bytesRead.Add(n) }
+func (c *Counters) Request()          { c.requests.Add(1) }
+func (c *Counters) CacheHit()         { c.cacheHits.Add(1) }
+func (c *Counters) CacheMiss()        { c.cacheMisses.Add(1) }
+func (c *Counters) Decompress(d time.Duration) {
+	c.decompressions.Add(1)
+	c.decompressNanos.Add(int64(d))
+}
+
+// Snapshot 返回当前的统计值
+func (c *Counters) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		BytesRead:      c.bytesRead.Load(),
+		Requests:       c.requests.Load(),
+		CacheHits:      c.cacheHits.Load(),
+		CacheMisses:    c.cacheMisses.Load(),
+		Decompressions: c.decompressions.Load(),
+		DecompressTime: time.Duration(c.decompressNanos.Load()),
+	}
+}
+
+// Reset 将所有计数器清零
+func (c *Counters) Reset() {
+	c.bytesRead.Store(0)
+	c.requests.Store(0)
+	c.cacheHits.Store(0)
+	c.cacheMisses.Store(0)
+	c.decompressions.Store(0)
+	c.decompressNanos.Store(0)
+}
+
+// String 以 JSON 形式输出快照，满足 expvar.Var
+func (c *Counters) String() string {
+	b, _ := json.Marshal(c.Snapshot())
+	return string(b)
+}
+
+// HitRate 返回缓存命中率，没有任何访问时返回 0
+func (s MetricsSnapshot) HitRate() float64 {
+	total := s.CacheHits + s.CacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(total)
+}
@@ -0,0 +1,345 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// bwMultiSummaryChunk BED 模式下每个并行任务处理的区间数
+const bwMultiSummaryChunk = 256
+
+// bwStatAcc 累计一个 bin 内按碱基加权的统计量
+type bwStatAcc struct {
+	n        float64 // 有数据的碱基数；由 zoom 汇总按重叠比例分摊时可能不是整数
+	sum      float64
+	sumSq    float64
+	min, max float32
+}
+
+func (a *bwStatAcc) add(v float32, bases uint32) {
+	if a.n == 0 || v < a.min {
+		a.min = v
+	}
+	if a.n == 0 || v > a.max {
+		a.max = v
+	}
+	a.n += float64(bases)
+	a.sum += float64(v) * float64(bases)
+	a.sumSq += float64(v) * float64(v) * float64(bases)
+}
+
+// result 返回 stat 对应的值，width 为 bin 宽度，w 为已解析的 CoverageWeighted 或 RegionWeighted；
+// CoverageWeighted 时没有数据的 bin 除 coverage 外返回 NaN
+func (a *bwStatAcc) result(stat string, width uint32, w Weighting) float64 {
+	if stat == "coverage" {
+		return a.n / float64(width)
+	}
+	n, lo, hi := a.n, float64(a.min), float64(a.max)
+	if w == RegionWeighted && width > 0 {
+		// 没有数据的碱基按 0 计入
+		if a.n == 0 {
+			lo, hi = 0, 0
+		} else if a.n < float64(width) {
+			lo, hi = math.Min(lo, 0), math.Max(hi, 0)
+		}
+		n = float64(width)
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	switch stat {
+	case "max", "maximum":
+		return hi
+	case "min", "minimum":
+		return lo
+	case "sum":
+		return a.sum
+	case "std":
+		// 与 libBigWig 相同，使用样本标准差（除以 n-1）
+		if n <= 1 {
+			return 0
+		}
+		return math.Sqrt(math.Max((a.sumSq-a.sum*a.sum/n)/(n-1), 0))
+	}
+	return a.sum / n
+}
+
+// ErrUnknownStat 表示不支持的统计量名称
+var ErrUnknownStat = errors.New("unknown statistic")
+
+// bwValidStat 判断 stat 是否为 MultiBigwigSummary 支持的统计量
+func bwValidStat(stat string) bool {
+	switch stat {
+	case "mean", "average", "max", "maximum", "min", "minimum", "sum", "coverage", "std":
+		return true
+	}
+	return false
+}
+
+// bwCanonicalStat 返回 stat 的规范名称，使别名（average、maximum、minimum）共用缓存结果
+func bwCanonicalStat(stat string) string {
+	switch stat {
+	case "average":
+		return "mean"
+	case "maximum":
+		return "max"
+	case "minimum":
+		return "min"
+	}
+	return stat
+}
+
+// MultiSummaryOptions 是 MultiBigwigSummary 的参数
+type MultiSummaryOptions struct {
+	BinSize uint32   // 全基因组分箱时的 bin 宽度；Regions 非空时忽略
+	Regions []Region // 非空时每个区间作为一个 bin（例如 ReadBED 的结果），坐标从 0 开始
+	Stat    string   // mean（默认）、max、min、sum、std，或 coverage（有数据碱基的比例）
+	Workers int      // 并行数，<=0 时使用 runtime.NumCPU()
+	// Weighting 决定没有数据的碱基是否按 0 计入，WeightDefault 即 CoverageWeighted
+	Weighting Weighting
+	// Progress 接收读取进度，total 为各文件数据区大小之和；按 zoom 层级计算的 bin 不推进进度，
+	// 结束时报告完成。nil 表示不报告
+	Progress ProgressFunc
+}
+
+// MultiSummary 是 MultiBigwigSummary 的结果：N 个文件 × M 个 bin 的矩阵
+type MultiSummary struct {
+	Files  []string
+	Stat   string
+	Bins   []Region    // 坐标从 0 开始
+	Values [][]float64 // Values[i][j] 为第 i 个文件在第 j 个 bin 上的统计值，CoverageWeighted 时没有数据为 NaN
+
+	weighting Weighting // 已解析的 Weighting
+}
+
+// WriteTable 以每个 bin 一行（chrom、start、end 加每个文件一列）写出矩阵，列名为文件路径
+func (s *MultiSummary) WriteTable(w io.Writer, opts ...TableOption) error {
+	rows := make([]TableRow, len(s.Bins))
+	for j, b := range s.Bins {
+		row := TableRow{b.Chrom, b.Start, b.End, make([]float64, len(s.Files))}
+		for i := range s.Files {
+			row.Values[i] = s.Values[i][j]
+		}
+		rows[j] = row
+	}
+	return WriteTable(w, s.Files, rows, opts...)
+}
+
+// bwSummaryTask 是一个并行任务：Bins[lo:hi]，stream 为 true 时这些 bin 在同一条染色体上首尾相接
+type bwSummaryTask struct {
+	lo, hi int
+	stream bool
+}
+
+// MultiBigwigSummary 把基因组按 BinSize 分箱（或使用 Regions 中的区间），计算每个文件在每个 bin 上的统计量，
+// 得到用于相关性分析或 PCA 的矩阵，相当于 deeptools multiBigwigSummary
+// 全基因组模式使用所有文件共有（且长度一致）的染色体，按染色体并行、顺序读取；
+// 文件中没有某个区间所在的染色体时该处为 NaN
+func MultiBigwigSummary(ctx context.Context, files []string, opts MultiSummaryOptions) (*MultiSummary, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no input files")
+	}
+	if opts.Stat == "" {
+		opts.Stat = "mean"
+	}
+	if !bwValidStat(opts.Stat) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownStat, opts.Stat)
+	}
+	if len(opts.Regions) == 0 && opts.BinSize == 0 {
+		return nil, errors.New("bin size must be positive")
+	}
+	fps := make([]*Bigwig_file_out, 0, len(files))
+	defer func() {
+		for _, f := range fps {
+			CloseBigWig(f)
+		}
+	}()
+	for _, path := range files {
+		fp, err := OpenBigWig(path)
+		if err != nil {
+			return nil, err
+		}
+		fps = append(fps, fp)
+		if err := bwLoadChromList(fp.bf_fp); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	progress := bwTrackProgress(opts.Progress, fps...)
+
+	s := &MultiSummary{Files: append([]string(nil), files...), Stat: opts.Stat, weighting: opts.Weighting}
+	if s.weighting == WeightDefault {
+		s.weighting = CoverageWeighted
+	}
+	var tasks []bwSummaryTask
+	if len(opts.Regions) > 0 {
+		for _, r := range opts.Regions {
+			if r.Start < 0 || r.Start >= r.End {
+				return nil, fmt.Errorf("%w: %s:%d-%d", ErrInvalidRange, r.Chrom, r.Start, r.End)
+			}
+		}
+		s.Bins = append([]Region(nil), opts.Regions...)
+		for lo := 0; lo < len(s.Bins); lo += bwMultiSummaryChunk {
+			tasks = append(tasks, bwSummaryTask{lo, min(lo+bwMultiSummaryChunk, len(s.Bins)), false})
+		}
+	} else {
+		for _, chrom := range bwCommonChroms(fps) {
+			length, _ := bwChromLength(fps[0].bf_fp, chrom)
+			lo := len(s.Bins)
+			for start := uint64(0); start < uint64(length); start += uint64(opts.BinSize) {
+				end := start + uint64(opts.BinSize)
+				if end > uint64(length) {
+					end = uint64(length)
+				}
+				s.Bins = append(s.Bins, Region{Chrom: chrom, Start: int(start), End: int(end)})
+			}
+			tasks = append(tasks, bwSummaryTask{lo, len(s.Bins), true})
+		}
+	}
+	s.Values = make([][]float64, len(files))
+	for i := range s.Values {
+		s.Values[i] = make([]float64, len(s.Bins))
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(tasks) {
+		workers = len(tasks)
+	}
+	// 第一个 worker 使用打开的句柄，其余 worker 各自 Clone 一组
+	handles := [][]*Bigwig_file_out{fps}
+	defer func() {
+		for _, hs := range handles[1:] {
+			for _, h := range hs {
+				CloseBigWig(h)
+			}
+		}
+	}()
+	for len(handles) < workers {
+		hs := make([]*Bigwig_file_out, 0, len(fps))
+		for _, fp := range fps {
+			c, err := fp.Clone()
+			if err != nil {
+				break
+			}
+			hs = append(hs, c)
+		}
+		if len(hs) < len(fps) {
+			for _, h := range hs {
+				CloseBigWig(h)
+			}
+			break
+		}
+		handles = append(handles, hs)
+	}
+
+	next := make(chan bwSummaryTask)
+	errs := make([]error, len(handles))
+	var wg sync.WaitGroup
+	for w, hs := range handles {
+		wg.Add(1)
+		go func(w int, hs []*Bigwig_file_out) {
+			defer wg.Done()
+			for t := range next {
+				if errs[w] != nil {
+					continue
+				}
+				errs[w] = s.fill(ctx, hs, t)
+			}
+		}(w, hs)
+	}
+	for _, t := range tasks {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- t
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	progress.finish()
+	return s, nil
+}
+
+// bwCommonChroms 返回所有文件共有且长度一致的染色体，顺序与第一个文件一致
+func bwCommonChroms(fps []*Bigwig_file_out) []string {
+	var out []string
+	first := fps[0].bf_fp
+	for tid, chrom := range first.Cl.Chrom {
+		ok := true
+		for _, fp := range fps[1:] {
+			if n, found := bwChromLength(fp.bf_fp, chrom); !found || n != first.Cl.Len[tid] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			out = append(out, chrom)
+		}
+	}
+	return out
+}
+
+// fill 用 hs 计算任务 t 中各 bin 在每个文件上的统计量
+func (s *MultiSummary) fill(ctx context.Context, hs []*Bigwig_file_out, t bwSummaryTask) error {
+	for i, h := range hs {
+		bw := h.bf_fp
+		if t.stream {
+			first, last := s.Bins[t.lo], s.Bins[t.hi-1]
+			cur := newBWIntervalCursor(ctx, bw, first.Chrom, uint32(first.Start), uint32(last.End))
+			for j := t.lo; j < t.hi; j++ {
+				b := s.Bins[j]
+				var acc bwStatAcc
+				cur.accumulate(&acc, uint32(b.Start), uint32(b.End))
+				s.Values[i][j] = acc.result(s.Stat, uint32(b.End-b.Start), s.weighting)
+			}
+			cur.close()
+			if cur.err != nil {
+				return fmt.Errorf("%s: %s: %w", s.Files[i], first.Chrom, cur.err)
+			}
+			continue
+		}
+		for j := t.lo; j < t.hi; j++ {
+			b := s.Bins[j]
+			if _, ok := bwChromLength(bw, b.Chrom); !ok {
+				s.Values[i][j] = math.NaN()
+				continue
+			}
+			var acc bwStatAcc
+			err := bwEachInterval(ctx, bw, b.Chrom, uint32(b.Start), uint32(b.End), func(is, ie uint32, v float32) error {
+				acc.add(v, ie-is)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %s:%d-%d: %w", s.Files[i], b.Chrom, b.Start, b.End, err)
+			}
+			s.Values[i][j] = acc.result(s.Stat, uint32(b.End-b.Start), s.weighting)
+		}
+	}
+	return nil
+}
+
+// accumulate 把 [s, e) 内的记录累计到 acc；跨过 e 的记录保留给下一个 bin
+func (c *bwIntervalCursor) accumulate(acc *bwStatAcc, s, e uint32) {
+	for !c.done && c.cur.Start < e {
+		if c.cur.End > s {
+			is, ie := max32(c.cur.Start, s), min32(c.cur.End, e)
+			acc.add(c.cur.Value, ie-is)
+		}
+		if c.cur.End > e {
+			break
+		}
+		c.advance()
+	}
+}
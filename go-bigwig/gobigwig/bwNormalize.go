@@ -0,0 +1,87 @@
+package gobigwig
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Normalization 表示写入前对每条记录的值做的归一化，与 deeptools bamCoverage 的 --normalizeUsing 对应
+// 记录的值视为宽 NormBinSize 的 bin 上的原始计数（对应 --binSize，默认 1 即逐碱基覆盖度），
+// 与记录本身的长度无关：相邻 bin 的值相同时会合并为一条更长的记录，但每个 bin 的计数不变
+type Normalization int
+
+const (
+	NormNone Normalization = iota // 不做归一化
+	NormCPM                       // 值 × 1e6 / 总 reads 数
+	NormRPKM                      // 值 × 1e9 / (总 reads 数 × bin 宽度)
+	NormBPM                       // 值 × 1e3 / bin 宽度 × 1e6 / 总 RPK（所有 bin 的每 kb reads 数之和）
+)
+
+func (n Normalization) String() string {
+	switch n {
+	case NormNone:
+		return "none"
+	case NormCPM:
+		return "CPM"
+	case NormRPKM:
+		return "RPKM"
+	case NormBPM:
+		return "BPM"
+	}
+	return fmt.Sprintf("Normalization(%d)", int(n))
+}
+
+// WithNormalization 设置归一化方法；total 对 CPM、RPKM 为总 reads 数（或总计数），
+// 对 BPM 为所有 bin 的每 kb reads 数之和
+func WithNormalization(method Normalization, total float64) WriteOption {
+	return func(o *BWOptions_Write) {
+		o.Normalization = method
+		o.NormTotal = total
+	}
+}
+
+// WithNormBinSize 设置 RPKM、BPM 归一化时每个值对应的 bin 宽度（碱基），
+// 与生成计数时 deeptools 的 --binSize 相同；默认 1，即值为逐碱基覆盖度
+func WithNormBinSize(n uint32) WriteOption {
+	return func(o *BWOptions_Write) { o.NormBinSize = n }
+}
+
+// WithScaleFactor 设置在归一化之后再乘的系数，例如 spike-in 校正系数
+func WithScaleFactor(f float64) WriteOption {
+	return func(o *BWOptions_Write) { o.Scale = f }
+}
+
+// checkNormalization 检查归一化参数
+func (o *BWOptions_Write) checkNormalization() error {
+	if o.Normalization < NormNone || o.Normalization > NormBPM {
+		return fmt.Errorf("unknown normalization %v", o.Normalization)
+	}
+	if o.Normalization != NormNone && (!(o.NormTotal > 0) || math.IsInf(o.NormTotal, 0)) {
+		return fmt.Errorf("invalid total for %v normalization: %v", o.Normalization, o.NormTotal)
+	}
+	if (o.Normalization == NormRPKM || o.Normalization == NormBPM) && o.NormBinSize == 0 {
+		return fmt.Errorf("invalid bin size for %v normalization: 0", o.Normalization)
+	}
+	if math.IsNaN(o.Scale) || math.IsInf(o.Scale, 0) {
+		return errors.New("scale factor must be finite")
+	}
+	return nil
+}
+
+// normalize 返回记录的值归一化并缩放后的值，RPKM、BPM 按 NormBinSize 而不是记录长度计算
+func (o *BWOptions_Write) normalize(v float32) float32 {
+	if o.Normalization == NormNone && o.Scale == 1 {
+		return v
+	}
+	f := o.Scale
+	switch o.Normalization {
+	case NormCPM:
+		f *= 1e6 / o.NormTotal
+	case NormRPKM:
+		f *= 1e9 / (o.NormTotal * float64(o.NormBinSize))
+	case NormBPM:
+		f *= 1e9 / (float64(o.NormBinSize) * o.NormTotal)
+	}
+	return float32(float64(v) * f)
+}
@@ -0,0 +1,46 @@
+package gobigwig_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// RPKM 按 bin 宽度归一化：合并了两个 bin 的记录与单个 bin 的记录得到相同的值
+func TestNormalizeRPKMUsesBinSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpkm.bw")
+	w, err := gb.CreateBigWig(path, []string{"chr1"}, []uint32{1000},
+		gb.WithNormalization(gb.NormRPKM, 1e6), gb.WithNormBinSize(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddIntervals("chr1", []uint32{0, 100}, []uint32{100, 150}, []float32{10, 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gb.CloseBigWig(fp)
+	ivs, err := fp.IntervalsContext(context.Background(), "chr1", 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 × 1e9 / (1e6 × 50)
+	if len(ivs) != 2 || ivs[0].Value != 200 || ivs[1].Value != 200 {
+		t.Errorf("intervals = %+v, want two records with value 200", ivs)
+	}
+}
+
+func TestNormalizeRejectsZeroBinSize(t *testing.T) {
+	_, err := gb.CreateBigWig(filepath.Join(t.TempDir(), "bpm.bw"), []string{"chr1"}, []uint32{1000},
+		gb.WithNormalization(gb.NormBPM, 1e3), gb.WithNormBinSize(0))
+	if err == nil {
+		t.Fatal("CreateBigWig accepted a zero bin size for BPM")
+	}
+}
@@ -0,0 +1,110 @@
+package gobigwig
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// bwNpyMagic 是 .npy 1.0 格式的文件头魔数和版本号
+const bwNpyMagic = "\x93NUMPY\x01\x00"
+
+// bwNpyHeader 返回 dtype 为 little-endian float32、C 顺序、给定形状的 .npy 文件头，
+// 总长度按格式要求补齐为 64 的倍数
+func bwNpyHeader(shape []int) []byte {
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = strconv.Itoa(d)
+	}
+	s := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		s += ","
+	}
+	dict := "{'descr': '<f4', 'fortran_order': False, 'shape': (" + s + "), }"
+	// 魔数 8 字节 + 头长度 2 字节 + dict + 填充空格 + '\n'
+	n := len(bwNpyMagic) + 2 + len(dict) + 1
+	pad := (64 - n%64) % 64
+	hdr := make([]byte, 0, n+pad)
+	hdr = append(hdr, bwNpyMagic...)
+	hdr = binary.LittleEndian.AppendUint16(hdr, uint16(len(dict)+pad+1))
+	hdr = append(hdr, dict...)
+	hdr = append(hdr, strings.Repeat(" ", pad)...)
+	return append(hdr, '\n')
+}
+
+// WriteNpy 把 data 以 NumPy .npy 格式（float32）写入 w，shape 为数组形状（C 顺序），
+// 不给出时为一维；写出的文件可直接用 np.load 读取，NaN 保持为 NaN
+func WriteNpy(w io.Writer, data []float32, shape ...int) error {
+	if len(shape) == 0 {
+		shape = []int{len(data)}
+	}
+	n := 1
+	for _, d := range shape {
+		if d < 0 {
+			return fmt.Errorf("invalid npy shape %v", shape)
+		}
+		n *= d
+	}
+	if n != len(data) {
+		return fmt.Errorf("npy shape %v needs %d values, got %d", shape, n, len(data))
+	}
+	out := bufio.NewWriter(w)
+	if _, err := out.Write(bwNpyHeader(shape)); err != nil {
+		return err
+	}
+	if err := binary.Write(out, binary.LittleEndian, data); err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// WriteNpyMatrix 把等长的 rows 写成形状为 (len(rows), len(rows[0])) 的二维 .npy 数组
+func WriteNpyMatrix(w io.Writer, rows [][]float32) error {
+	cols := 0
+	if len(rows) > 0 {
+		cols = len(rows[0])
+	}
+	data := make([]float32, 0, len(rows)*cols)
+	for i, r := range rows {
+		if len(r) != cols {
+			return fmt.Errorf("npy matrix row %d has %d values, want %d", i, len(r), cols)
+		}
+		data = append(data, r...)
+	}
+	return WriteNpy(w, data, len(rows), cols)
+}
+
+// SaveNpy 与 WriteNpy 相同，但写入文件 path
+func SaveNpy(path string, data []float32, shape ...int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteNpy(f, data, shape...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteMatrixNpy 对每个区间取 numBins 个 zoom 分箱值，写成形状为 (len(regions), numBins) 的
+// .npy 矩阵，行顺序与 regions 一致；与 WriteMatrixTable 取值相同，但不经过文本转换
+func (fp *Bigwig_file_out) WriteMatrixNpy(ctx context.Context, w io.Writer, regions []Region, numBins int) error {
+	if numBins <= 0 {
+		return fmt.Errorf("invalid bin count: %d", numBins)
+	}
+	data := make([]float32, 0, len(regions)*numBins)
+	for _, r := range regions {
+		values, _, _, err := fp.binnedRows(ctx, r, numBins)
+		if err != nil {
+			return fmt.Errorf("%s:%d-%d: %w", r.Chrom, r.Start, r.End, err)
+		}
+		data = append(data, values...)
+	}
+	return WriteNpy(w, data, len(regions), numBins)
+}
@@ -0,0 +1,124 @@
+package gobigwig
+
+import (
+	"math"
+	"sort"
+)
+
+// OverlapPolicy 决定写入文件时互相重叠的区间（部分工具会产生重叠的 bedGraph 记录）
+// 在逐碱基取值和由原始数据分箱时如何合并
+type OverlapPolicy int
+
+const (
+	OverlapRaw      OverlapPolicy = iota // 按查询结果的顺序依次写入，后写入的覆盖先写入的（原有行为，受 SortResults 影响）
+	OverlapLastWins                      // 按 start 稳定排序后，start 较大的区间覆盖较小的
+	OverlapMax                           // 取覆盖该碱基的所有区间中的最大值
+	OverlapMean                          // 取覆盖该碱基的所有区间的平均值
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapRaw:
+		return "raw"
+	case OverlapLastWins:
+		return "last-wins"
+	case OverlapMax:
+		return "max"
+	case OverlapMean:
+		return "mean"
+	}
+	return "unknown"
+}
+
+// bwFillBases 按 policy 把 iv 中的区间写入 values，values[i] 对应碱基 start+i，
+// 调用前 values 应已填充 NaN，没有区间覆盖的碱基保持不变
+func bwFillBases(values []float32, iv *bwOverlappingIntervals_t, start uint32, policy OverlapPolicy) {
+	end := start + uint32(len(values))
+	clip := func(i uint32) (uint32, uint32, bool) {
+		s, e := max32(iv.Start[i], start), min32(iv.End[i], end)
+		return s - start, e - start, s < e
+	}
+
+	switch policy {
+	case OverlapMax:
+		for i := uint32(0); i < iv.L; i++ {
+			s, e, ok := clip(i)
+			if !ok {
+				continue
+			}
+			v := iv.Value[i]
+			for j := s; j < e; j++ {
+				if math.IsNaN(float64(values[j])) || v > values[j] {
+					values[j] = v
+				}
+			}
+		}
+	case OverlapMean:
+		sums := make([]float64, len(values))
+		counts := make([]uint32, len(values))
+		for i := uint32(0); i < iv.L; i++ {
+			s, e, ok := clip(i)
+			if !ok {
+				continue
+			}
+			for j := s; j < e; j++ {
+				sums[j] += float64(iv.Value[i])
+				counts[j]++
+			}
+		}
+		for j, c := range counts {
+			if c > 0 {
+				values[j] = float32(sums[j] / float64(c))
+			}
+		}
+	default:
+		order := make([]uint32, iv.L)
+		for i := range order {
+			order[i] = uint32(i)
+		}
+		if policy == OverlapLastWins {
+			sort.SliceStable(order, func(a, b int) bool { return iv.Start[order[a]] < iv.Start[order[b]] })
+		}
+		for _, i := range order {
+			s, e, ok := clip(i)
+			if !ok {
+				continue
+			}
+			for j := s; j < e; j++ {
+				values[j] = iv.Value[i]
+			}
+		}
+	}
+}
+
+// bwResolveOverlaps 按 policy 合并 iv 中 [start, end) 内的重叠区间，
+// 返回互不重叠、按位置排序的区间（相邻且值相同的碱基合并为一个区间）；
+// OverlapRaw 时原样返回 iv
+func bwResolveOverlaps(iv *bwOverlappingIntervals_t, start, end uint32, policy OverlapPolicy) *bwOverlappingIntervals_t {
+	if policy == OverlapRaw || iv == nil || iv.L == 0 || end <= start {
+		return iv
+	}
+	values := make([]float32, end-start)
+	for i := range values {
+		values[i] = float32(math.NaN())
+	}
+	bwFillBases(values, iv, start, policy)
+
+	out := &bwOverlappingIntervals_t{}
+	for i := 0; i < len(values); {
+		v := values[i]
+		j := i + 1
+		if math.IsNaN(float64(v)) {
+			for j < len(values) && math.IsNaN(float64(values[j])) {
+				j++
+			}
+		} else {
+			for j < len(values) && values[j] == v {
+				j++
+			}
+			out = pushIntervals(out, start+uint32(i), start+uint32(j), v)
+		}
+		i = j
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// PairedSegment 是 IterPaired 给出的一个片段，片段内两个文件的取值都不变
+type PairedSegment struct {
+	Start, End uint32
+	A, B       float32 // 两个文件在片段上的值，没有数据时为 NaN
+}
+
+// IterPaired 同时扫描 a 和 b 在 chrom 上的记录，按两个文件记录边界的并集把染色体切分为片段，
+// 对至少一个文件有数据的片段按坐标顺序调用 fn，不需要逐碱基展开；fn 返回错误时停止并返回该错误
+// 只在一个文件中存在的染色体按另一个文件没有数据处理，两个文件都没有时返回错误
+// 片段坐标按 a 打开时的 Coordinates 约定给出
+func IterPaired(ctx context.Context, a, b *Bigwig_file_out, chrom string, fn func(PairedSegment) error) error {
+	la, oka := bwChromLength(a.bf_fp, chrom)
+	lb, okb := bwChromLength(b.bf_fp, chrom)
+	if !oka && !okb {
+		return fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	ca := newBWIntervalCursor(ctx, a.bf_fp, chrom, 0, la)
+	defer ca.close()
+	cb := newBWIntervalCursor(ctx, b.bf_fp, chrom, 0, lb)
+	defer cb.close()
+
+	cursors := [2]*bwIntervalCursor{ca, cb}
+	coords := a.bf_fp.Opts.Coordinates
+	nan := float32(math.NaN())
+	pos := uint32(0)
+	for {
+		lo := uint32(math.MaxUint32)
+		for _, c := range cursors {
+			for !c.done && c.cur.End <= pos {
+				c.advance()
+			}
+			if c.err != nil {
+				return c.err
+			}
+			if !c.done && max32(c.cur.Start, pos) < lo {
+				lo = max32(c.cur.Start, pos)
+			}
+		}
+		if lo == math.MaxUint32 {
+			return nil
+		}
+		seg := PairedSegment{Start: lo, End: math.MaxUint32, A: nan, B: nan}
+		for i, c := range cursors {
+			if c.done {
+				continue
+			}
+			if c.cur.Start > lo {
+				seg.End = min32(seg.End, c.cur.Start)
+				continue
+			}
+			seg.End = min32(seg.End, c.cur.End)
+			if i == 0 {
+				seg.A = c.cur.Value
+			} else {
+				seg.B = c.cur.Value
+			}
+		}
+		pos = seg.End
+		s, e := coords.FromZeroBased(int(seg.Start), int(seg.End))
+		seg.Start, seg.End = uint32(s), uint32(e)
+		if err := fn(seg); err != nil {
+			return err
+		}
+	}
+}
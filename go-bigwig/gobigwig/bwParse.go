@@ -0,0 +1,270 @@
+package gobigwig
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// bwMaxDataBlockSize 一个数据块解压后的最大字节数：24 字节块头加最多 65535 条 bedGraph 记录
+const bwMaxDataBlockSize = bwDataHeaderSize + math.MaxUint16*12
+
+// 下面的 Parse* / DecodeBlock 直接在字节切片上运行与读取文件时相同的解析代码，
+// 供 go-fuzz / OSS-Fuzz 等对处理不可信远程数据的代码做模糊测试；
+// 所有分配的大小都受输入长度或格式上限约束；万一解析代码仍有越界等 panic，也由 bwRecover 转换为错误返回
+
+// ZoomLevel 描述一个 zoom 层级在文件中的位置
+type ZoomLevel struct {
+	Reduction   uint32
+	DataOffset  uint64
+	IndexOffset uint64
+}
+
+// Header 是 ParseHeader 解析出的文件头、zoom 头和全局 summary
+type Header struct {
+	Endianness        Endianness
+	Version           uint16
+	NLevels           uint16
+	ChromTreeOffset   uint64
+	DataOffset        uint64
+	IndexOffset       uint64
+	FieldCount        uint16
+	DefinedFieldCount uint16
+	AutoSQLOffset     uint64
+	SummaryOffset     uint64
+	BufSize           uint32
+	ExtensionOffset   uint64
+	ZoomLevels        []ZoomLevel
+	NBasesCovered     uint64
+	MinVal            float64
+	MaxVal            float64
+	SumData           float64
+	SumSquared        float64
+}
+
+// ChromInfo 是 chrom B+ 树中的一条记录
+type ChromInfo struct {
+	ID     uint32
+	Name   string
+	Length uint32
+}
+
+// RTreeChild 是 R 树节点的一个子项；叶子节点的 Offset/Size 指向数据块，
+// 非叶子节点的 Offset 指向下一级节点，Size 为 0
+type RTreeChild struct {
+	ChromIdxStart uint32
+	BaseStart     uint32
+	ChromIdxEnd   uint32
+	BaseEnd       uint32
+	Offset        uint64
+	Size          uint64
+}
+
+// RTreeNode 是 ParseRTreeNode 解析出的 R 树节点
+type RTreeNode struct {
+	IsLeaf   bool
+	Children []RTreeChild
+}
+
+// Interval 是一条 bigWig 记录 [Start, End) = Value，坐标从 0 开始
+type Interval struct {
+	Start uint32
+	End   uint32
+	Value float32
+}
+
+// bwFromBytes 构造一个从内存读取 b 的只读文件对象，偏移即 b 中的下标
+func bwFromBytes(b []byte) *bigWigFile_t {
+	return &bigWigFile_t{
+		URL: &URL{
+			rs:      bytes.NewReader(b),
+			Type:    BWG_FILE,
+			FName:   "<bytes>",
+			metrics: newBWTracer(nil),
+		},
+	}
+}
+
+// ErrParsePanic 表示解析代码在畸形输入上发生了 panic 并已被恢复；返回它说明解析器有缺陷，
+// 模糊测试据此把它与普通的格式错误区分开
+var ErrParsePanic = errors.New("panic while parsing bigWig data")
+
+// bwRecover 把解析过程中的 panic 转换为包装 ErrParsePanic 的错误，用法为 defer bwRecover(&err)
+func bwRecover(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("%w: %v", ErrParsePanic, r)
+	}
+}
+
+// bwInflate 解压 zlib 数据，结果超过 limit 字节时返回错误（防止解压炸弹）
+func bwInflate(compBuf []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(compBuf))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > int64(limit) {
+		return nil, fmt.Errorf("decompressed block exceeds %d bytes", limit)
+	}
+	return buf.Bytes(), nil
+}
+
+// bwBlockLimit 返回文件中一个块解压后允许的最大字节数
+func bwBlockLimit(fp *bigWigFile_t) int {
+	if fp.Hdr != nil && int(fp.Hdr.bufsize) > bwMaxDataBlockSize {
+		return int(fp.Hdr.bufsize)
+	}
+	return bwMaxDataBlockSize
+}
+
+// ParseHeader 解析 b 开头的文件头；b 需从文件偏移 0 开始，
+// 并包含 zoom 头以及（如有）summary 所在的位置
+func ParseHeader(b []byte) (h *Header, err error) {
+	defer bwRecover(&err)
+	fp := bwFromBytes(b)
+	if err := bwHdrRead(fp); err != nil {
+		return nil, err
+	}
+	return bwExportHeader(fp), nil
+}
+
+// bwExportHeader 把内部的文件头结构转换为导出的 Header
+func bwExportHeader(fp *bigWigFile_t) *Header {
+	h := fp.Hdr
+	out := &Header{
+		Version:           h.version,
+		NLevels:           h.nLevels,
+		ChromTreeOffset:   h.ctoffset,
+		DataOffset:        h.dataOffset,
+		IndexOffset:       h.indexoffset,
+		FieldCount:        h.fieldCount,
+		DefinedFieldCount: h.definedFieldCount,
+		AutoSQLOffset:     h.sqloffset,
+		SummaryOffset:     h.summaryoffset,
+		BufSize:           h.bufsize,
+		ExtensionOffset:   h.extensionoffset,
+		NBasesCovered:     h.NBasesCovered,
+		MinVal:            h.MinVal,
+		MaxVal:            h.MaxVal,
+		SumData:           h.SumData,
+		SumSquared:        h.SumSquared,
+	}
+	if bwOrder(fp) == binary.BigEndian {
+		out.Endianness = BigEndian
+	}
+	if len(h.ZoomHdrs) > 0 {
+		z := h.ZoomHdrs[0]
+		for i := range z.Level {
+			out.ZoomLevels = append(out.ZoomLevels, ZoomLevel{z.Level[i], z.DataOffset[i], z.IndexOffset[i]})
+		}
+	}
+	return out
+}
+
+// ParseChromTree 解析 b 中偏移 offset 处的 chrom B+ 树，b 中的偏移与文件偏移一致
+func ParseChromTree(b []byte, offset uint64) (chroms []ChromInfo, err error) {
+	defer bwRecover(&err)
+	fp := bwFromBytes(b)
+	fp.Hdr = &bigWigHdr_t{ctoffset: offset}
+	cl, err := bwReadchromList(fp)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ChromInfo, len(cl.Chrom))
+	for i := range cl.Chrom {
+		out[i] = ChromInfo{ID: uint32(i), Name: cl.Chrom[i], Length: cl.Len[i]}
+	}
+	return out, nil
+}
+
+// ParseRTreeNode 解析 b 中偏移 offset 处的一个 R 树节点（不读取子节点）
+func ParseRTreeNode(b []byte, offset uint64) (n *RTreeNode, err error) {
+	defer bwRecover(&err)
+	fp := bwFromBytes(b)
+	fp.Idx = &bwRTree_t{RootOffset: offset}
+	node, err := bwGetRTreeNode(fp, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := &RTreeNode{IsLeaf: node.IsLeaf != 0, Children: make([]RTreeChild, node.NChildren)}
+	for i := range out.Children {
+		c := RTreeChild{
+			ChromIdxStart: node.ChrIdxStart[i],
+			BaseStart:     node.BaseStart[i],
+			ChromIdxEnd:   node.ChrIdxEnd[i],
+			BaseEnd:       node.BaseEnd[i],
+			Offset:        node.DataOffset[i],
+		}
+		if out.IsLeaf {
+			c.Size = node.Size[i]
+		}
+		out.Children[i] = c
+	}
+	return out, nil
+}
+
+// BlockHeader 是数据块开头 24 字节的块头
+type BlockHeader struct {
+	ChromID uint32
+	Start   uint32
+	End     uint32
+	Step    uint32
+	Span    uint32
+	Type    uint8 // 1=bedGraph，2=variableStep，3=fixedStep
+	NItems  uint16
+}
+
+// ParseDataBlock 解析一个已解压的小端数据块，返回块头和全部记录；
+// 不涉及文件句柄，块头不合法或长度与记录数不符时返回错误
+func ParseDataBlock(b []byte) (bh BlockHeader, ivs []Interval, err error) {
+	defer bwRecover(&err)
+	return bwParseDataBlock(b, binary.LittleEndian)
+}
+
+// bwParseDataBlock 按 order 解析一个已解压的数据块
+func bwParseDataBlock(b []byte, order binary.ByteOrder) (BlockHeader, []Interval, error) {
+	hdr := bwDataHeader_t{}
+	if err := bwFillDataHdr(&hdr, b, order); err != nil {
+		return BlockHeader{}, nil, err
+	}
+	bh := BlockHeader{hdr.Tid, hdr.Start, hdr.End, hdr.Step, hdr.Span, hdr.Type, hdr.NItems}
+	o, err := bwDecodeDataBlock(context.Background(), b, order, hdr.Tid, 0, math.MaxUint32, &bwOverlappingIntervals_t{})
+	if err != nil {
+		return bh, nil, err
+	}
+	out := make([]Interval, o.L)
+	for i := range out {
+		out[i] = Interval{o.Start[i], o.End[i], o.Value[i]}
+	}
+	return bh, out, nil
+}
+
+// DecodeBlock 解码一个小端数据块中的全部记录；compressed 为 true 时先做 zlib 解压
+// （解压后的大小上限为 bwMaxDataBlockSize）
+func DecodeBlock(b []byte, compressed bool) ([]Interval, error) {
+	return DecodeBlockOrder(b, compressed, binary.LittleEndian)
+}
+
+// DecodeBlockOrder 与 DecodeBlock 相同，但按 order 解码（大端文件的字节序见 Header.Endianness）
+func DecodeBlockOrder(b []byte, compressed bool, order binary.ByteOrder) (ivs []Interval, err error) {
+	defer bwRecover(&err)
+	data := b
+	if compressed {
+		if data, err = bwInflate(b, bwMaxDataBlockSize); err != nil {
+			return nil, err
+		}
+	}
+	_, out, err := bwParseDataBlock(data, order)
+	return out, err
+}
@@ -0,0 +1,192 @@
+package gobigwig
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// DEFAULT_POOL_HANDLES Pool 默认最多同时保持打开的文件数
+const DEFAULT_POOL_HANDLES = 256
+
+// ErrPoolClosed 在 Pool 关闭后继续使用时返回
+var ErrPoolClosed = errors.New("bigwig pool is closed")
+
+// Region 表示一个查询区间，默认为从 0 开始的 Chrom:[Start, End)；
+// 使用 WithCoordinates(OneBased) 打开的文件按从 1 开始的闭区间 Chrom:[Start, End] 解释
+type Region struct {
+	Chrom  string
+	Start  int
+	End    int
+	Strand byte // '+'、'-'，或 0 表示没有链信息；只有 Profile 等区分方向的计算使用
+}
+
+// Pool 按路径/URL 管理大量打开的 bigWig 文件，适用于同时服务成千上万条轨道的浏览器后端
+// 所有文件共享同一个 MemoryBudget（块缓存与 R 树节点），打开的句柄数量超过上限时
+// 关闭最久未使用的句柄；对同一文件的查询会串行执行，不同文件之间可以并发
+type Pool struct {
+	mu      sync.Mutex
+	maxOpen int
+	budget  *MemoryBudget
+	opts    []OpenOption
+	lru     *list.List // *poolEntry，头部为最近使用
+	items   map[string]*list.Element
+	closed  bool
+}
+
+// poolEntry 是 Pool 中的一个文件句柄
+type poolEntry struct {
+	path    string
+	ready   chan struct{} // 打开完成后关闭
+	fp      *Bigwig_file_out
+	err     error
+	mu      sync.Mutex // 串行化对 fp 的访问
+	refs    int        // 正在使用该句柄的调用数，受 Pool.mu 保护
+	evicted bool       // 已被淘汰，最后一个使用者释放时关闭
+}
+
+// NewPool 创建 Pool：最多保持 maxOpen 个打开的文件（<=0 使用 DEFAULT_POOL_HANDLES），
+// 所有文件共享上限为 memoryLimit 字节的缓存（<=0 表示不缓存数据块）；
+// opts 用于打开每个文件
+func NewPool(maxOpen int, memoryLimit int64, opts ...OpenOption) *Pool {
+	if maxOpen <= 0 {
+		maxOpen = DEFAULT_POOL_HANDLES
+	}
+	p := &Pool{
+		maxOpen: maxOpen,
+		opts:    opts,
+		lru:     list.New(),
+		items:   make(map[string]*list.Element),
+	}
+	if memoryLimit > 0 {
+		p.budget = NewMemoryBudget(memoryLimit)
+		p.opts = append(opts[:len(opts):len(opts)], WithMemoryBudget(p.budget))
+	}
+	return p
+}
+
+// Budget 返回 Pool 共享的内存预算，未设置时为 nil
+func (p *Pool) Budget() *MemoryBudget { return p.budget }
+
+// Len 返回当前打开的文件数
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+// Acquire 返回 path 对应的文件句柄，必要时打开它
+// 在调用 release 之前，调用方独占该句柄；release 必须且只能调用一次
+func (p *Pool) Acquire(path string) (fp *Bigwig_file_out, release func(), err error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, nil, ErrPoolClosed
+	}
+	var e *poolEntry
+	if el, ok := p.items[path]; ok {
+		e = el.Value.(*poolEntry)
+		p.lru.MoveToFront(el)
+		e.refs++
+		p.mu.Unlock()
+		<-e.ready
+	} else {
+		e = &poolEntry{path: path, ready: make(chan struct{}), refs: 1}
+		p.items[path] = p.lru.PushFront(e)
+		victims := p.evictLocked()
+		p.mu.Unlock()
+		closeEntries(victims)
+
+		// 打开文件时不持有 Pool 的锁，避免慢速远程打开阻塞其他文件
+		e.fp, e.err = OpenBigWig(path, p.opts...)
+		close(e.ready)
+	}
+
+	if e.err != nil {
+		p.mu.Lock()
+		if el, ok := p.items[path]; ok && el.Value.(*poolEntry) == e {
+			p.lru.Remove(el)
+			delete(p.items, path)
+		}
+		e.refs--
+		p.mu.Unlock()
+		return nil, nil, e.err
+	}
+
+	e.mu.Lock()
+	// 设置了 WithReopenCheck 时，文件被替换后在原处重新打开
+	e.fp.reopenIfStale()
+	var once sync.Once
+	return e.fp, func() { once.Do(func() { p.release(e) }) }, nil
+}
+
+// release 结束对句柄的使用，已被淘汰且无人使用的句柄在此关闭
+func (p *Pool) release(e *poolEntry) {
+	e.mu.Unlock()
+	p.mu.Lock()
+	e.refs--
+	done := e.evicted && e.refs == 0
+	p.mu.Unlock()
+	if done {
+		CloseBigWig(e.fp)
+	}
+}
+
+// evictLocked 从 LRU 尾部淘汰超出上限的句柄，返回可以立即关闭的句柄
+func (p *Pool) evictLocked() []*poolEntry {
+	var victims []*poolEntry
+	for p.lru.Len() > p.maxOpen {
+		el := p.lru.Back()
+		e := p.lru.Remove(el).(*poolEntry)
+		delete(p.items, e.path)
+		e.evicted = true
+		if e.refs == 0 {
+			victims = append(victims, e)
+		}
+	}
+	return victims
+}
+
+func closeEntries(entries []*poolEntry) {
+	for _, e := range entries {
+		<-e.ready
+		if e.fp != nil {
+			CloseBigWig(e.fp)
+		}
+	}
+}
+
+// Query 读取 path 中 r 区间的信号，等价于对该文件调用 ReadBigWigSignalContext
+func (p *Pool) Query(path string, r Region) ([]float32, error) {
+	return p.QueryContext(context.Background(), path, r)
+}
+
+// QueryContext 与 Query 相同，ctx 被取消时尽快返回 ctx.Err()
+func (p *Pool) QueryContext(ctx context.Context, path string, r Region) ([]float32, error) {
+	fp, release, err := p.Acquire(path)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return fp.ReadBigWigSignalContext(ctx, r.Chrom, r.Start, r.End)
+}
+
+// Close 关闭所有空闲的句柄；正在使用的句柄在释放时关闭，之后的 Acquire 返回 ErrPoolClosed
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var victims []*poolEntry
+	for el := p.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*poolEntry)
+		e.evicted = true
+		if e.refs == 0 {
+			victims = append(victims, e)
+		}
+	}
+	p.lru.Init()
+	p.items = make(map[string]*list.Element)
+	p.mu.Unlock()
+	closeEntries(victims)
+	return nil
+}
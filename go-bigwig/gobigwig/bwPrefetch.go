@@ -0,0 +1,143 @@
+package gobigwig
+
+import (
+	"context"
+	"sort"
+)
+
+// 合并数据块时允许的最大间隙与单个读取范围的最大大小
+const (
+	prefetchMaxGap       = 4096
+	prefetchMaxRangeSize = 1 << 20
+	DEFAULT_PREFETCH     = 4 // 默认预读的范围数
+)
+
+// bwRange 表示文件中一段连续的字节范围 [Start, Start+Size)
+type bwRange struct {
+	Start uint64
+	Size  uint64
+}
+
+// coalesceBlocks 把按偏移排序后相邻（间隙不超过 prefetchMaxGap）的数据块合并为读取范围
+func coalesceBlocks(o *bwOverlapBlock_t) []bwRange {
+	if o == nil || o.N == 0 {
+		return nil
+	}
+	blocks := make([]bwRange, o.N)
+	for i := range blocks {
+		blocks[i] = bwRange{o.Offset[i], o.Size[i]}
+	}
+	sort.Slice(blocks, func(a, b int) bool { return blocks[a].Start < blocks[b].Start })
+
+	ranges := []bwRange{blocks[0]}
+	for _, b := range blocks[1:] {
+		last := &ranges[len(ranges)-1]
+		end := last.Start + last.Size
+		if b.Start <= end+prefetchMaxGap && b.Start+b.Size-last.Start <= prefetchMaxRangeSize {
+			if b.Start+b.Size > end {
+				last.Size = b.Start + b.Size - last.Start
+			}
+			continue
+		}
+		ranges = append(ranges, b)
+	}
+	return ranges
+}
+
+// bwPrefetchEntry 一个已发起的预读请求，done 关闭后 data/err 可读
+type bwPrefetchEntry struct {
+	data []byte
+	err  error
+	done chan struct{}
+}
+
+// bwPrefetcher 在后台按顺序预读接下来的若干个读取范围，
+// 让网络请求与当前块的解压、解析重叠进行
+type bwPrefetcher struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	u       *URL
+	ranges  []bwRange
+	entries []*bwPrefetchEntry // 与 ranges 一一对应，nil 表示尚未发起或已释放
+	next    int                // 下一个待发起的范围
+	cur     int                // 读取方当前所在的范围
+	depth   int                // 最多领先 cur 的范围数
+}
+
+// startPrefetch 为远程文件启动预读，替换之前的预读器；本地文件或 depth<=0 时不做任何事
+func (u *URL) startPrefetch(ctx context.Context, ranges []bwRange, depth int) {
+	if u.isLocal() || depth <= 0 || len(ranges) == 0 {
+		return
+	}
+	u.stopPrefetch()
+	pctx, cancel := context.WithCancel(ctx)
+	pf := &bwPrefetcher{
+		ctx:     pctx,
+		cancel:  cancel,
+		u:       u,
+		ranges:  ranges,
+		entries: make([]*bwPrefetchEntry, len(ranges)),
+		depth:   depth,
+	}
+	pf.fill()
+	u.pf = pf
+}
+
+// stopPrefetch 取消尚未完成的预读请求并释放预读的数据
+func (u *URL) stopPrefetch() {
+	if u.pf != nil {
+		u.pf.cancel()
+		u.pf = nil
+	}
+}
+
+// fill 发起 [cur, cur+depth) 中尚未发起的请求
+func (pf *bwPrefetcher) fill() {
+	for pf.next < len(pf.ranges) && pf.next < pf.cur+pf.depth {
+		r := pf.ranges[pf.next]
+		e := &bwPrefetchEntry{done: make(chan struct{})}
+		pf.entries[pf.next] = e
+		go func() {
+			e.data, e.err = pf.u.fetchRange(pf.ctx, int64(r.Start), int64(r.Size))
+			close(e.done)
+		}()
+		pf.next++
+	}
+}
+
+// read 若 pos 落在某个预读范围内，则等待该范围下载完成并从中读取，ok 为 true；
+// 否则 ok 为 false，调用方走普通读取。读取方前进到新范围时释放之前的范围并继续预读
+func (pf *bwPrefetcher) read(pos int64, p []byte) (n int, ok bool, err error) {
+	upos := uint64(pos)
+	for i := pf.cur; i < len(pf.ranges); i++ {
+		r := pf.ranges[i]
+		if upos < r.Start {
+			return 0, false, nil
+		}
+		if upos >= r.Start+r.Size {
+			continue
+		}
+		for j := pf.cur; j < i; j++ {
+			pf.entries[j] = nil
+		}
+		pf.cur = i
+		pf.fill()
+
+		e := pf.entries[i]
+		select {
+		case <-e.done:
+		case <-pf.ctx.Done():
+			return 0, true, pf.ctx.Err()
+		}
+		if e.err != nil {
+			return 0, true, e.err
+		}
+		off := upos - r.Start
+		if off >= uint64(len(e.data)) {
+			// 文件比索引描述的短，交给普通读取报告错误
+			return 0, false, nil
+		}
+		return copy(p, e.data[off:]), true, nil
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,162 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// ProfileOptions 是 Profile 的参数
+type ProfileOptions struct {
+	Stat    string // 每个区间内各 bin 的统计量，取值同 StatsOptions.Type，默认 mean
+	Exact   bool   // 使用原始记录计算，同 StatsOptions.Exact
+	Matrix  bool   // 在结果中保留每个区间的 bin 值
+	Workers int    // 并行数，<=0 时使用 runtime.NumCPU()
+	// Weighting 决定没有数据的碱基是否按 0 计入，同 StatsOptions.Weighting
+	Weighting Weighting
+}
+
+// ProfileResult 是 Profile 的结果
+type ProfileResult struct {
+	Mean   []float64   // 每个 bin 在有值区间上的平均值，没有区间有值时为 NaN
+	Median []float64   // 每个 bin 在有值区间上的中位数，没有区间有值时为 NaN
+	N      []int       // 每个 bin 有值的区间数
+	Matrix [][]float64 // 设置 ProfileOptions.Matrix 时每个区间一行，顺序与 regions 一致；否则为 nil
+}
+
+// Profile 把每个区间均分为 bins 个 bin 并按 opts.Stat 取值（与 Stats 相同），再对所有区间逐 bin 汇总，
+// 即 TSS 等位点的 metaplot；Strand 为 '-' 的区间按从 End 到 Start 的方向排列，使各行都沿转录方向对齐
+// 区间用 opts.Workers 个并行的句柄计算；文件中没有的染色体上的区间整行为 NaN，不参与汇总，其他错误使计算停止
+func (fp *Bigwig_file_out) Profile(ctx context.Context, regions []Region, bins int, opts ProfileOptions) (*ProfileResult, error) {
+	if bins <= 0 {
+		return nil, fmt.Errorf("invalid number of bins: %d", bins)
+	}
+	if opts.Stat == "" {
+		opts.Stat = "mean"
+	}
+	if !bwValidStat(opts.Stat) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownStat, opts.Stat)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = max(1, min(workers, len(regions)))
+
+	// 与 QueryMany 相同，第一个 worker 直接使用 fp，其余使用 Clone
+	handles := []*Bigwig_file_out{fp}
+	for len(handles) < workers {
+		c, err := fp.Clone()
+		if err != nil {
+			break
+		}
+		handles = append(handles, c)
+	}
+	defer func() {
+		for _, h := range handles[1:] {
+			CloseBigWig(h)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	rows := make([][]float64, len(regions))
+	var (
+		errOnce  sync.Once
+		firstErr error
+	)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for _, h := range handles {
+		wg.Add(1)
+		go func(h *Bigwig_file_out) {
+			defer wg.Done()
+			for i := range next {
+				row, err := h.profileRow(ctx, regions[i], bins, opts)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				rows[i] = row
+			}
+		}(h)
+	}
+	for i := range regions {
+		if ctx.Err() != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res := &ProfileResult{
+		Mean:   make([]float64, bins),
+		Median: make([]float64, bins),
+		N:      make([]int, bins),
+	}
+	col := make([]float64, 0, len(rows))
+	for j := 0; j < bins; j++ {
+		col = col[:0]
+		for _, row := range rows {
+			if !math.IsNaN(row[j]) {
+				col = append(col, row[j])
+			}
+		}
+		res.N[j] = len(col)
+		res.Mean[j], res.Median[j] = bwMeanMedian(col)
+	}
+	if opts.Matrix {
+		res.Matrix = rows
+	}
+	return res, nil
+}
+
+// profileRow 计算 Profile 中区间 r 的一行，负链区间翻转
+func (fp *Bigwig_file_out) profileRow(ctx context.Context, r Region, bins int, opts ProfileOptions) ([]float64, error) {
+	if _, ok := bwChromLength(fp.bf_fp, r.Chrom); !ok {
+		row := make([]float64, bins)
+		for j := range row {
+			row[j] = math.NaN()
+		}
+		return row, nil
+	}
+	row, err := fp.Stats(ctx, r.Chrom, r.Start, r.End, StatsOptions{Type: opts.Stat, NBins: bins, Exact: opts.Exact, Weighting: opts.Weighting})
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d-%d: %w", r.Chrom, r.Start, r.End, err)
+	}
+	if r.Strand == '-' {
+		slices.Reverse(row)
+	}
+	return row, nil
+}
+
+// bwMeanMedian 返回 vals 的平均值和中位数（会对 vals 排序），vals 为空时都为 NaN
+func bwMeanMedian(vals []float64) (mean, median float64) {
+	n := len(vals)
+	if n == 0 {
+		return math.NaN(), math.NaN()
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	slices.Sort(vals)
+	median = vals[n/2]
+	if n%2 == 0 {
+		median = (vals[n/2-1] + vals[n/2]) / 2
+	}
+	return sum / float64(n), median
+}
@@ -0,0 +1,139 @@
+package gobigwig
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// ProgressFunc 接收长时间操作的进度：done 为已处理的字节数，total 为总字节数，0 表示总量未知
+// 读取 bigWig 的操作以输入文件数据区（原始记录所在的数据块）的大小为总量，
+// 转换文本的操作以输入的大小为总量；回调被串行地同步调用（并行的操作可能来自不同 goroutine），应尽快返回
+type ProgressFunc func(done, total uint64)
+
+// WithProgress 设置转换、合并等写入操作的进度回调，进度按读取的输入字节数计算，
+// 操作成功结束时最后报告一次 done == total
+func WithProgress(fn ProgressFunc) WriteOption {
+	return func(o *BWOptions_Write) { o.Progress = fn }
+}
+
+// WithReadProgress 设置读取进度回调，用于 WriteBedGraph、Verify 等整个文件的扫描：
+// 每读取一个数据区中的数据块报告一次，total 为数据区的大小
+func WithReadProgress(fn ProgressFunc) OpenOption {
+	return func(o *BWOptions_Open) { o.Progress = fn }
+}
+
+// bwProgressStep 是两次报告之间至少推进的比例（千分之一），避免逐块回调拖慢扫描
+const bwProgressStep = 1000
+
+// bwProgress 汇总一个或多个输入的处理量并按 bwProgressStep 节流后报告，可被并发调用；nil 表示不报告
+type bwProgress struct {
+	mu       sync.Mutex
+	fn       ProgressFunc
+	done     uint64
+	total    uint64
+	reported uint64
+}
+
+func newBWProgress(fn ProgressFunc, total uint64) *bwProgress {
+	if fn == nil {
+		return nil
+	}
+	return &bwProgress{fn: fn, total: total}
+}
+
+// add 记录新处理的 n 个字节，推进超过总量的千分之一（总量未知时每次）才调用回调
+func (p *bwProgress) add(n uint64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	if p.total > 0 && p.done > p.total {
+		p.done = p.total
+	}
+	if p.done == p.reported || p.total > 0 && p.done-p.reported < p.total/bwProgressStep && p.done < p.total {
+		return
+	}
+	p.reported = p.done
+	p.fn(p.done, p.total)
+}
+
+// finish 报告完成；总量未知时以已处理的字节数作为总量
+func (p *bwProgress) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.total == 0 {
+		p.total = p.done
+	} else if p.reported == p.total {
+		return
+	}
+	p.done, p.reported = p.total, p.total
+	p.fn(p.done, p.total)
+}
+
+// bwDataSectionSize 返回数据区（从数据区起点到主索引）的字节数
+func bwDataSectionSize(bw *bigWigFile_t) uint64 {
+	if bw.Hdr == nil || bw.Hdr.indexoffset <= bw.Hdr.dataOffset {
+		return 0
+	}
+	return bw.Hdr.indexoffset - bw.Hdr.dataOffset
+}
+
+// bwInDataSection 判断 offset 处的块是否属于数据区（而不是 zoom 数据）
+func bwInDataSection(bw *bigWigFile_t, offset uint64) bool {
+	return bw.Hdr != nil && offset >= bw.Hdr.dataOffset && offset < bw.Hdr.indexoffset
+}
+
+// bwTrackProgress 让 files 的数据块读取计入同一个进度，总量为各文件数据区大小之和；fn 为 nil 时返回 nil
+func bwTrackProgress(fn ProgressFunc, files ...*Bigwig_file_out) *bwProgress {
+	if fn == nil {
+		return nil
+	}
+	var total uint64
+	for _, f := range files {
+		total += bwDataSectionSize(f.bf_fp)
+	}
+	p := newBWProgress(fn, total)
+	for _, f := range files {
+		f.bf_fp.progress = p
+	}
+	return p
+}
+
+// bwProgressReader 统计从 r 读出的字节数
+type bwProgressReader struct {
+	r io.Reader
+	p *bwProgress
+}
+
+func (pr *bwProgressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.add(uint64(n))
+	return n, err
+}
+
+// bwTrackReader 返回统计读取量的 r 和对应的进度，总量取自 r 的剩余长度（文件或内存中的数据），
+// 无法确定时为 0；fn 为 nil 时原样返回 r
+func bwTrackReader(fn ProgressFunc, r io.Reader) (io.Reader, *bwProgress) {
+	if fn == nil {
+		return r, nil
+	}
+	var total uint64
+	switch v := r.(type) {
+	case *os.File:
+		if st, err := v.Stat(); err == nil && st.Mode().IsRegular() {
+			if pos, err := v.Seek(0, io.SeekCurrent); err == nil && st.Size() > pos {
+				total = uint64(st.Size() - pos)
+			}
+		}
+	case interface{ Len() int }:
+		total = uint64(v.Len())
+	}
+	p := newBWProgress(fn, total)
+	return &bwProgressReader{r, p}, p
+}
@@ -0,0 +1,114 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// bwQuantileBins 是 Quantiles 使用的等宽直方图的 bin 数，误差不超过区间取值范围的 1/bwQuantileBins
+const bwQuantileBins = 4096
+
+// Histogram 统计 chrom:[start, end) 中取值落在各个 bin 的碱基数
+// edges 为严格递增的 n+1 个边界，第 i 个 bin 为 [edges[i], edges[i+1])，最后一个 bin 包含右端点；
+// 超出 [edges[0], edges[n]] 的值不计入。返回 n 个计数
+func (fp *Bigwig_file_out) Histogram(ctx context.Context, chrom string, start, end int, edges []float64) ([]uint64, error) {
+	if len(edges) < 2 {
+		return nil, fmt.Errorf("histogram needs at least 2 edges, got %d", len(edges))
+	}
+	for i := 1; i < len(edges); i++ {
+		if !(edges[i] > edges[i-1]) {
+			return nil, fmt.Errorf("histogram edges must be strictly increasing: %g after %g", edges[i], edges[i-1])
+		}
+	}
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]uint64, len(edges)-1)
+	err = bwEachInterval(ctx, fp.bf_fp, chrom, uint32(start), uint32(end), func(s, e uint32, v float32) error {
+		i, found := slices.BinarySearch(edges, float64(v))
+		switch {
+		case found && i == len(counts):
+			i--
+		case !found:
+			i--
+		}
+		if i >= 0 && i < len(counts) {
+			counts[i] += uint64(e - s)
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// Quantiles 返回 chrom:[start, end) 中按碱基加权的取值在各个 qs（0 到 1）处的分位数，区间内没有数据时为 NaN
+// 取值范围来自 zoom 汇总（与 Stats 相同的层级选择），再读取一次原始记录建立 bwQuantileBins 个 bin 的等宽直方图，
+// 在 bin 内线性插值，因此结果是近似值，误差不超过取值范围的 1/bwQuantileBins；0 和 1 处为精确的最小值和最大值
+func (fp *Bigwig_file_out) Quantiles(ctx context.Context, chrom string, start, end int, qs []float64) ([]float64, error) {
+	for _, q := range qs {
+		if !(q >= 0 && q <= 1) {
+			return nil, fmt.Errorf("quantile %g is outside [0, 1]", q)
+		}
+	}
+	mins, err := fp.Stats(ctx, chrom, start, end, StatsOptions{Type: "min"})
+	if err != nil {
+		return nil, err
+	}
+	maxs, err := fp.Stats(ctx, chrom, start, end, StatsOptions{Type: "max"})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(qs))
+	if math.IsNaN(mins[0]) {
+		for i := range out {
+			out[i] = math.NaN()
+		}
+		return out, nil
+	}
+	// zoom 汇总可能包含区间外的记录，范围只会偏大；直方图同时记录区间内真实的最小值和最大值
+	lo, width := mins[0], (maxs[0]-mins[0])/bwQuantileBins
+	counts := make([]uint64, bwQuantileBins)
+	var total uint64
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	start, end, err = bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	err = bwEachInterval(ctx, fp.bf_fp, chrom, uint32(start), uint32(end), func(s, e uint32, v float32) error {
+		x := float64(v)
+		if math.IsNaN(x) {
+			return nil
+		}
+		i := 0
+		if width > 0 {
+			i = max(0, min(int((x-lo)/width), bwQuantileBins-1))
+		}
+		counts[i] += uint64(e - s)
+		total += uint64(e - s)
+		minVal, maxVal = math.Min(minVal, x), math.Max(maxVal, x)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for k, q := range qs {
+		if total == 0 {
+			out[k] = math.NaN()
+			continue
+		}
+		target := q * float64(total)
+		var cum float64
+		i := 0
+		for ; i < bwQuantileBins-1 && cum+float64(counts[i]) < target; i++ {
+			cum += float64(counts[i])
+		}
+		v := lo + float64(i)*width
+		if counts[i] > 0 {
+			v += (target - cum) / float64(counts[i]) * width
+		}
+		out[k] = math.Max(minVal, math.Min(maxVal, v))
+	}
+	return out, nil
+}
@@ -0,0 +1,146 @@
+package gobigwig
+
+import "math"
+
+// 量化后表示缺失值（NaN）的保留码
+const (
+	QuantNaNInt16 int16 = math.MinInt16
+	QuantNaNUint8 uint8 = math.MaxUint8
+)
+
+// QuantWindow 是一个量化窗口的参数，窗口内的原值 ≈ float32(q)*Scale + Offset
+type QuantWindow struct {
+	Scale  float32
+	Offset float32
+}
+
+// QuantizedInt16 是按窗口量化为 int16 的信号，码值范围 [-32767, 32767]，
+// QuantNaNInt16 表示缺失值
+type QuantizedInt16 struct {
+	Values     []int16
+	Windows    []QuantWindow // Windows[i] 对应 Values[i*WindowSize : (i+1)*WindowSize]
+	WindowSize int
+}
+
+// QuantizedUint8 是按窗口量化为 uint8 的信号，码值范围 [0, 254]，
+// QuantNaNUint8 表示缺失值
+type QuantizedUint8 struct {
+	Values     []uint8
+	Windows    []QuantWindow
+	WindowSize int
+}
+
+// quantWindowRange 返回窗口内有限值的最小值和最大值，全部为 NaN/Inf 时 ok 为 false
+func quantWindowRange(values []float32) (lo, hi float32, ok bool) {
+	for _, v := range values {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			continue
+		}
+		if !ok {
+			lo, hi, ok = v, v, true
+			continue
+		}
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return lo, hi, ok
+}
+
+// quantize 对每个窗口计算 scale/offset，并把窗口内的值映射到 [qmin, qmax] 的整数码，
+// emit 接收码值；NaN 与 ±Inf 以 nan=true 传给 emit
+func quantize(values []float32, window int, qmin, qmax float64, emit func(i int, q float64, nan bool)) ([]QuantWindow, int) {
+	if window <= 0 || window > len(values) {
+		window = len(values)
+	}
+	if window == 0 {
+		return nil, 0
+	}
+	windows := make([]QuantWindow, 0, (len(values)+window-1)/window)
+	for s := 0; s < len(values); s += window {
+		e := s + window
+		if e > len(values) {
+			e = len(values)
+		}
+		lo, hi, ok := quantWindowRange(values[s:e])
+		w := QuantWindow{Offset: lo}
+		if ok && hi > lo {
+			w.Scale = float32((float64(hi) - float64(lo)) / (qmax - qmin))
+			w.Offset = float32(float64(lo) - qmin*float64(w.Scale))
+		}
+		windows = append(windows, w)
+		for i := s; i < e; i++ {
+			v := float64(values[i])
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				emit(i, 0, true)
+				continue
+			}
+			q := qmin
+			if w.Scale > 0 {
+				q = math.Round((v - float64(w.Offset)) / float64(w.Scale))
+				q = math.Max(qmin, math.Min(qmax, q))
+			}
+			emit(i, q, false)
+		}
+	}
+	return windows, window
+}
+
+// QuantizeInt16 把 values 按每 window 个值一组量化为 int16，window<=0 表示整体一个窗口
+// 每个窗口使用自己的 scale/offset，相对误差不超过窗口值域的 1/65534
+func QuantizeInt16(values []float32, window int) *QuantizedInt16 {
+	out := &QuantizedInt16{Values: make([]int16, len(values))}
+	out.Windows, out.WindowSize = quantize(values, window, -math.MaxInt16, math.MaxInt16, func(i int, q float64, nan bool) {
+		if nan {
+			out.Values[i] = QuantNaNInt16
+		} else {
+			out.Values[i] = int16(q)
+		}
+	})
+	return out
+}
+
+// QuantizeUint8 把 values 按每 window 个值一组量化为 uint8，window<=0 表示整体一个窗口
+// 每个窗口使用自己的 scale/offset，误差不超过窗口值域的 1/508
+func QuantizeUint8(values []float32, window int) *QuantizedUint8 {
+	out := &QuantizedUint8{Values: make([]uint8, len(values))}
+	out.Windows, out.WindowSize = quantize(values, window, 0, math.MaxUint8-1, func(i int, q float64, nan bool) {
+		if nan {
+			out.Values[i] = QuantNaNUint8
+		} else {
+			out.Values[i] = uint8(q)
+		}
+	})
+	return out
+}
+
+// Dequantize 还原为 float32，缺失值还原为 NaN
+func (q *QuantizedInt16) Dequantize() []float32 {
+	out := make([]float32, len(q.Values))
+	for i, v := range q.Values {
+		if v == QuantNaNInt16 {
+			out[i] = float32(math.NaN())
+			continue
+		}
+		w := q.Windows[i/q.WindowSize]
+		out[i] = float32(v)*w.Scale + w.Offset
+	}
+	return out
+}
+
+// Dequantize 还原为 float32，缺失值还原为 NaN
+func (q *QuantizedUint8) Dequantize() []float32 {
+	out := make([]float32, len(q.Values))
+	for i, v := range q.Values {
+		if v == QuantNaNUint8 {
+			out[i] = float32(math.NaN())
+			continue
+		}
+		w := q.Windows[i/q.WindowSize]
+		out[i] = float32(v)*w.Scale + w.Offset
+	}
+	return out
+}
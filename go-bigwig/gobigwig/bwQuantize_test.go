@@ -0,0 +1,50 @@
+package gobigwig_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// 量化的是逐碱基的值：每个碱基一个码值，没有数据的碱基为缺失值
+func TestReadBigWigSignalQuantizedPerBase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "q.bw")
+	w, err := gb.CreateBigWig(path, []string{"chr1"}, []uint32{100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddIntervals("chr1", []uint32{0, 10}, []uint32{10, 20}, []float32{1, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gb.CloseBigWig(fp)
+	ctx := context.Background()
+
+	q16, err := fp.ReadBigWigSignalInt16(ctx, "chr1", 0, 30, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q8, err := fp.ReadBigWigSignalUint8(ctx, "chr1", 0, 30, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q16.Values) != 30 || len(q8.Values) != 30 {
+		t.Fatalf("got %d int16 and %d uint8 values, want 30 each", len(q16.Values), len(q8.Values))
+	}
+	for i := 20; i < 30; i++ {
+		if q16.Values[i] != gb.QuantNaNInt16 || q8.Values[i] != gb.QuantNaNUint8 {
+			t.Errorf("base %d: got %d/%d, want the missing-value codes", i, q16.Values[i], q8.Values[i])
+		}
+	}
+	if d := q16.Dequantize(); d[5] != 1 || d[15] != 3 {
+		t.Errorf("dequantized bases 5 and 15 = %v, %v, want 1, 3", d[5], d[15])
+	}
+}
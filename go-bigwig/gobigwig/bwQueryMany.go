@@ -0,0 +1,85 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// RegionResult 是 QueryMany 中一个区间的查询结果，Err 非 nil 时 Values 为 nil
+type RegionResult struct {
+	Region Region
+	Values []float32
+	Err    error
+}
+
+// QueryMany 用 workers 个并行的句柄查询 regions，结果顺序与 regions 一致
+// workers<=0 时使用 runtime.NumCPU()；各区间的错误保存在对应的 RegionResult.Err 中，
+// 返回的 error 是所有区间错误的合并（errors.Join），全部成功时为 nil
+func (fp *Bigwig_file_out) QueryMany(regions []Region, workers int) ([]RegionResult, error) {
+	return fp.QueryManyContext(context.Background(), regions, workers)
+}
+
+// QueryManyContext 与 QueryMany 相同，ctx 被取消后未开始的区间直接以 ctx.Err() 结束
+func (fp *Bigwig_file_out) QueryManyContext(ctx context.Context, regions []Region, workers int) ([]RegionResult, error) {
+	results := make([]RegionResult, len(regions))
+	for i, r := range regions {
+		results[i].Region = r
+	}
+	if len(regions) == 0 {
+		return results, nil
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(regions) {
+		workers = len(regions)
+	}
+
+	// 第一个 worker 直接使用 fp（调用期间 fp 不会被其他人使用），其余使用 Clone
+	handles := []*Bigwig_file_out{fp}
+	for len(handles) < workers {
+		c, err := fp.Clone()
+		if err != nil {
+			break
+		}
+		handles = append(handles, c)
+	}
+	defer func() {
+		for _, h := range handles[1:] {
+			CloseBigWig(h)
+		}
+	}()
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for _, h := range handles {
+		wg.Add(1)
+		go func(h *Bigwig_file_out) {
+			defer wg.Done()
+			for i := range next {
+				r := &results[i]
+				if err := ctx.Err(); err != nil {
+					r.Err = err
+					continue
+				}
+				r.Values, r.Err = h.ReadBigWigSignalContext(ctx, r.Region.Chrom, r.Region.Start, r.Region.End)
+			}
+		}(h)
+	}
+	for i := range regions {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d-%d: %w", r.Region.Chrom, r.Region.Start, r.Region.End, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package gobigwig
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// QueryStats 是一段查询期间句柄的 IO 诊断信息，用于弄清某个区间为什么慢并提交可复现的性能报告
+// 由 CollectStats 填充；收集期间字段被原子地更新，应在 stop 返回后再读取
+type QueryStats struct {
+	Blocks            int64         `json:"blocks"`             // 访问的数据块（原始记录或 zoom 汇总）数，包括缓存命中的
+	BytesRead         int64         `json:"bytes_read"`         // 从磁盘或网络实际读取的字节数
+	BytesDecompressed int64         `json:"bytes_decompressed"` // 解压得到的字节数
+	Requests          int64         `json:"requests"`           // 发出的 HTTP 请求数
+	CacheHits         int64         `json:"cache_hits"`         // 由缓存（预读、缓冲区、块缓存、索引缓存）满足的读取数
+	CacheMisses       int64         `json:"cache_misses"`       // 需要访问磁盘或网络的读取数
+	IndexTime         time.Duration `json:"index_ns"`           // 遍历 R 树索引（包括读取节点）的时间
+	DecompressTime    time.Duration `json:"decompress_ns"`      // 解压数据块的时间
+	Elapsed           time.Duration `json:"elapsed_ns"`         // 从 CollectStats 到 stop 的总时间
+}
+
+// String 以 JSON 形式输出
+func (s *QueryStats) String() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// CollectStats 开始把句柄上的查询的 IO 统计累加到 s，直到调用返回的 stop；
+// 收集期间由该句柄 Clone 出的句柄（包括 QueryMany、Profile 等内部并行使用的句柄）上的查询也计入 s
+// 同一时刻一个句柄只能收集到一个 QueryStats，再次调用会替换之前的目标。与 WithMetrics 互不影响
+func (fp *Bigwig_file_out) CollectStats(s *QueryStats) (stop func()) {
+	t := fp.bf_fp.URL.metrics
+	t0 := time.Now()
+	t.stats.Store(s)
+	return func() {
+		if t.stats.CompareAndSwap(s, nil) {
+			s.Elapsed = time.Since(t0)
+		}
+	}
+}
+
+// bwTracer 包装 URL 的 Metrics，并在 CollectStats 期间同时累加到 QueryStats
+// nil 的 bwTracer（写入模式的文件）丢弃所有统计
+type bwTracer struct {
+	base   Metrics
+	stats  atomic.Pointer[QueryStats]
+	parent *bwTracer // Clone 出的句柄的 tracer 指向原句柄的 tracer
+}
+
+func newBWTracer(m Metrics) *bwTracer {
+	if m == nil {
+		m = nopMetrics{}
+	}
+	return &bwTracer{base: m}
+}
+
+// fork 返回 Clone 出的句柄使用的 tracer，原句柄收集统计时它也计入
+func (t *bwTracer) fork() *bwTracer {
+	if t == nil {
+		return nil
+	}
+	return &bwTracer{base: t.base, parent: t}
+}
+
+// current 返回正在收集的 QueryStats，没有时为 nil
+func (t *bwTracer) current() *QueryStats {
+	for ; t != nil; t = t.parent {
+		if s := t.stats.Load(); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+func (t *bwTracer) BytesRead(n int64) {
+	if t == nil {
+		return
+	}
+	t.base.BytesRead(n)
+	if s := t.current(); s != nil {
+		atomic.AddInt64(&s.BytesRead, n)
+	}
+}
+
+func (t *bwTracer) Request() {
+	if t == nil {
+		return
+	}
+	t.base.Request()
+	if s := t.current(); s != nil {
+		atomic.AddInt64(&s.Requests, 1)
+	}
+}
+
+func (t *bwTracer) CacheHit() {
+	if t == nil {
+		return
+	}
+	t.base.CacheHit()
+	if s := t.current(); s != nil {
+		atomic.AddInt64(&s.CacheHits, 1)
+	}
+}
+
+func (t *bwTracer) CacheMiss() {
+	if t == nil {
+		return
+	}
+	t.base.CacheMiss()
+	if s := t.current(); s != nil {
+		atomic.AddInt64(&s.CacheMisses, 1)
+	}
+}
+
+func (t *bwTracer) Decompress(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.base.Decompress(d)
+	if s := t.current(); s != nil {
+		atomic.AddInt64((*int64)(&s.DecompressTime), int64(d))
+	}
+}
+
+// block 记录访问了一个数据块
+func (t *bwTracer) block() {
+	if s := t.current(); s != nil {
+		atomic.AddInt64(&s.Blocks, 1)
+	}
+}
+
+// decompressed 记录解压得到 n 字节
+func (t *bwTracer) decompressed(n int) {
+	if s := t.current(); s != nil {
+		atomic.AddInt64(&s.BytesDecompressed, int64(n))
+	}
+}
+
+// indexSince 记录从 t0 开始的索引遍历时间，用法为 defer t.indexSince(time.Now())
+func (t *bwTracer) indexSince(t0 time.Time) {
+	if s := t.current(); s != nil {
+		atomic.AddInt64((*int64)(&s.IndexTime), int64(time.Since(t0)))
+	}
+}
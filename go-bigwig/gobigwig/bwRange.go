@@ -0,0 +1,49 @@
+package gobigwig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOutOfRange 在查询区间超出染色体末端（RangeError 模式）或整个位于染色体之外时返回
+var ErrOutOfRange = errors.New("query out of chromosome range")
+
+// ErrInvalidRange 在查询区间的 start 为负或 start >= end 时返回
+var ErrInvalidRange = errors.New("invalid query range")
+
+// RangePolicy 决定查询区间的 end 超过染色体长度时的处理方式
+type RangePolicy int
+
+const (
+	RangeClamp RangePolicy = iota // 把 end 截断到染色体长度，并调用 ClampHook
+	RangeError                    // 返回 ErrOutOfRange
+)
+
+// ClampHook 在查询区间被截断到染色体长度 length 时调用，start/end 使用打开时设置的坐标约定
+type ClampHook func(chrom string, start, end int, length uint32)
+
+// bwCheckRange 把按 Coordinates 约定给出的查询区间转换为从 0 开始的半开区间，
+// 校验后按 RangePolicy 处理超出染色体末端的部分，返回实际查询的区间；错误信息使用调用方的坐标
+// 染色体长度使用 WithChromLengths 覆盖后的值
+func bwCheckRange(bw *bigWigFile_t, chrom string, start, end int) (int, int, error) {
+	coords := bw.Opts.Coordinates
+	ustart, uend := start, end
+	start, end = coords.ToZeroBased(start, end)
+	if start < 0 || start >= end {
+		return 0, 0, fmt.Errorf("%w: %s:%d-%d (%s)", ErrInvalidRange, chrom, ustart, uend, coords)
+	}
+	length, ok := bwChromLength(bw, chrom)
+	if !ok {
+		return 0, 0, fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	if uint64(end) <= uint64(length) {
+		return start, end, nil
+	}
+	if bw.Opts.RangePolicy == RangeError || uint64(start) >= uint64(length) {
+		return 0, 0, fmt.Errorf("%w: %s:%d-%d (chromosome length %d)", ErrOutOfRange, chrom, ustart, uend, length)
+	}
+	if bw.Opts.ClampHook != nil {
+		bw.Opts.ClampHook(chrom, ustart, uend, length)
+	}
+	return start, int(length), nil
+}
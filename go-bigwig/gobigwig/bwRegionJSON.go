@@ -0,0 +1,158 @@
+package gobigwig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// bwJSONFloat 按 float32 的最短形式编码，NaN 与无穷编码为 null（JSON 不支持它们）
+type bwJSONFloat float32
+
+func (f bwJSONFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return strconv.AppendFloat(nil, v, 'g', -1, 32), nil
+}
+
+// RegionTrack 是 MarshalRegionJSON 输出的结构，字段与 igv.js / HiGlass 等网页基因组轨道组件
+// 常用的分箱数据一致：每个 bin 的起点、平均值，以及由 zoom 数据得到的最小/最大值包络
+// 没有数据的 bin 在 values/min/max 中为 null
+type RegionTrack struct {
+	Chrom   string        `json:"chrom"`
+	Start   int           `json:"start"`
+	End     int           `json:"end"`
+	BinSize float64       `json:"binSize"`
+	Zoom    uint32        `json:"zoom"` // 所用 zoom 层级的 reduction，0 表示直接使用原始数据
+	Starts  []int         `json:"starts"`
+	Values  []bwJSONFloat `json:"values"`
+	Min     []bwJSONFloat `json:"min"`
+	Max     []bwJSONFloat `json:"max"`
+}
+
+// MarshalRegionJSON 把 chrom:[start, end) 分成 bins 个 bin，返回紧凑的 JSON（见 RegionTrack），
+// 可直接交给网页轨道组件绘制；选用 reduction 不超过 bin 宽度的最粗 zoom 层级，
+// 没有这样的层级（或文件没有 zoom 层级）时由原始数据计算。坐标使用打开时设置的约定
+func (fp *Bigwig_file_out) MarshalRegionJSON(chrom string, start, end, bins int) ([]byte, error) {
+	t, err := fp.regionTrack(context.Background(), chrom, start, end, bins)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(t)
+}
+
+// RegionTrackContext 返回 MarshalRegionJSON 编码前的 RegionTrack，ctx 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) RegionTrackContext(ctx context.Context, chrom string, start, end, bins int) (*RegionTrack, error) {
+	return fp.regionTrack(ctx, chrom, start, end, bins)
+}
+
+// regionTrack 计算 MarshalRegionJSON 的内容
+func (fp *Bigwig_file_out) regionTrack(ctx context.Context, chrom string, start, end, bins int) (*RegionTrack, error) {
+	if bins <= 0 {
+		return nil, fmt.Errorf("invalid bin count: %d", bins)
+	}
+	s, e, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("track\x00%s\x00%d\x00%d\x00%d\x00%d\x00%d\x00%d", chrom, start, end, s, e, bins, bwResolveWeighting(fp.bf_fp, WeightDefault))
+	return bwCachedResult(fp, query, func() (*RegionTrack, error) {
+		t, err := fp.binTrack(ctx, chrom, uint32(s), uint32(e), uint32(e), bins)
+		if err != nil {
+			return nil, err
+		}
+		t.Start, t.End = start, end
+		return t, nil
+	}, (*RegionTrack).clone, (*RegionTrack).size)
+}
+
+// clone 深复制 t，供结果缓存使用
+func (t *RegionTrack) clone() *RegionTrack {
+	c := *t
+	c.Starts = slices.Clone(t.Starts)
+	c.Values = slices.Clone(t.Values)
+	c.Min = slices.Clone(t.Min)
+	c.Max = slices.Clone(t.Max)
+	return &c
+}
+
+// size 估计 t 占用的字节数
+func (t *RegionTrack) size() int64 {
+	return int64(len(t.Chrom) + 8*(len(t.Starts)+len(t.Values)+len(t.Min)+len(t.Max)))
+}
+
+// binTrack 把从 0 开始的 [s, e) 分成 bins 个 bin，只读取 [s, dataEnd) 内的数据，
+// 超出 dataEnd 的 bin 为空；zoom 层级按整个 [s, e) 的 bin 宽度选择
+func (fp *Bigwig_file_out) binTrack(ctx context.Context, chrom string, s, e, dataEnd uint32, bins int) (*RegionTrack, error) {
+	bw := fp.bf_fp
+	// 选用 reduction 不超过 bin 宽度的最粗 zoom 层级：更粗的 summary 会跨越 bin 边界，
+	// 被整条计入多个 bin；没有这样的层级时使用原始数据
+	zoomIdx := -1
+	var zoom uint32
+	if len(bw.Hdr.ZoomHdrs) > 0 {
+		zhdr := bw.Hdr.ZoomHdrs[0]
+		if zoomIdx = bwSelectBestZoomLevel(zhdr, (e-s)/uint32(bins)); zoomIdx >= 0 {
+			zoom = zhdr.Level[zoomIdx]
+		}
+	}
+	summaries, err := bwTrackSummaries(ctx, bw, chrom, s, dataEnd, zoomIdx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := bwBinSummaries(ctx, summaries, s, e, bins)
+	if err != nil {
+		return nil, err
+	}
+	coords := bw.Opts.Coordinates
+	t := &RegionTrack{
+		Chrom:   chrom,
+		BinSize: float64(e-s) / float64(bins),
+		Zoom:    zoom,
+		Starts:  make([]int, bins),
+		Values:  make([]bwJSONFloat, bins),
+		Min:     make([]bwJSONFloat, bins),
+		Max:     make([]bwJSONFloat, bins),
+	}
+	t.Start, t.End = coords.FromZeroBased(int(s), int(e))
+	nan := bwJSONFloat(math.NaN())
+	w := bwResolveWeighting(bw, WeightDefault)
+	for i, b := range stats {
+		bs, be := bwBinEdges(int(s), int(e), bins, i)
+		t.Starts[i], _ = coords.FromZeroBased(bs, be)
+		// 超出 dataEnd 的部分不属于染色体，RegionWeighted 时也不按 0 计入
+		width := min(be, int(dataEnd)) - bs
+		if width <= 0 || b.ValidCount == 0 && w == CoverageWeighted {
+			t.Values[i], t.Min[i], t.Max[i] = nan, nan, nan
+			continue
+		}
+		acc := b.acc()
+		t.Values[i] = bwJSONFloat(acc.result("mean", uint32(width), w))
+		t.Min[i] = bwJSONFloat(acc.result("min", uint32(width), w))
+		t.Max[i] = bwJSONFloat(acc.result("max", uint32(width), w))
+	}
+	return t, nil
+}
+
+// bwTrackSummaries 返回 chrom:[s, e) 内第 zoomIdx 个 zoom 层级的 summary，
+// zoomIdx < 0 时把每条原始记录当作一条 summary
+func bwTrackSummaries(ctx context.Context, bw *bigWigFile_t, chrom string, s, e uint32, zoomIdx int) ([]*bwSummary, error) {
+	if zoomIdx >= 0 {
+		return bwGetSummariesInRegion(ctx, bw, zoomIdx, chrom, s, e)
+	}
+	var summaries []*bwSummary
+	err := bwEachInterval(ctx, bw, chrom, s, e, func(is, ie uint32, v float32) error {
+		n := ie - is
+		summaries = append(summaries, &bwSummary{
+			Start: is, End: ie, ValidCount: n,
+			MinVal: v, MaxVal: v,
+			SumData: float64(v) * float64(n), SumSquares: float64(v) * float64(v) * float64(n),
+		})
+		return nil
+	})
+	return summaries, err
+}
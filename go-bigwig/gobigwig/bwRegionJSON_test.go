@@ -0,0 +1,69 @@
+package gobigwig_test
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// writeCoarseZoom 写出 chr1 [0,10)=1、[10,20)=2、[50,100)=5，只有一个 230bp 的 zoom 层级
+func writeCoarseZoom(t *testing.T, opts ...gb.OpenOption) *gb.Bigwig_file_out {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "coarse.bw")
+	w, err := gb.CreateBigWig(path, []string{"chr1"}, []uint32{1000}, gb.WithZoomLadder(230))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddIntervals("chr1", []uint32{0, 10, 50}, []uint32{10, 20, 100}, []float32{1, 2, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := gb.OpenBigWig(path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gb.CloseBigWig(fp) })
+	return fp
+}
+
+// 比 bin 宽的 zoom 层级不能使用，否则一条 summary 会被整条计入每个 bin
+func TestRegionTrackSkipsCoarseZoom(t *testing.T) {
+	fp := writeCoarseZoom(t)
+	tr, err := fp.RegionTrackContext(context.Background(), "chr1", 0, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Zoom != 0 {
+		t.Errorf("zoom = %d, want 0 (raw data)", tr.Zoom)
+	}
+	if want := []float32{1.5, 5}; !slices.Equal(values(tr), want) {
+		t.Errorf("values = %v, want %v", values(tr), want)
+	}
+}
+
+// tile 与 RegionTrack 使用同样的 zoom 选择，从 LRU 缓存再次取得的结果也相同
+func TestTileSkipsCoarseZoom(t *testing.T) {
+	fp := writeCoarseZoom(t, gb.WithMemoryLimit(1<<20))
+	for i := 0; i < 2; i++ {
+		tr, err := fp.TileContext(context.Background(), "chr1", 100, 0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []float32{1.5, 5}; tr.Zoom != 0 || !slices.Equal(values(tr), want) {
+			t.Errorf("request %d: zoom %d values %v, want zoom 0 values %v", i, tr.Zoom, values(tr), want)
+		}
+	}
+}
+
+func values(tr *gb.RegionTrack) []float32 {
+	out := make([]float32, len(tr.Values))
+	for i, v := range tr.Values {
+		out[i] = float32(v)
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package gobigwig
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// WithReopenCheck 让 Pool 在取得句柄时检查文件是否已被替换（本地文件比较路径当前指向的文件与已打开的文件，
+// 远程文件用 HEAD 请求比较 ETag/Last-Modified），被替换时透明地重新打开；两次检查至少间隔 interval，0 表示不检查
+// 不使用 Pool 时可以自行调用 ReopenIfStale
+func WithReopenCheck(interval time.Duration) OpenOption {
+	return func(o *BWOptions_Open) { o.ReopenInterval = interval }
+}
+
+// Stale 判断打开后文件是否已被替换或修改，之后的读取可能得到旧的或不一致的数据
+// 远程服务器既不返回 ETag 也不返回 Last-Modified 时无法判断，返回 false
+func (fp *Bigwig_file_out) Stale() (bool, error) {
+	if fp.bf_fp == nil || fp.bf_fp.URL == nil {
+		return false, errors.New("file is closed")
+	}
+	u := fp.bf_fp.URL
+	if u.Type == BWG_FILE {
+		f, ok := u.rs.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		opened, err := f.Stat()
+		if err != nil {
+			return false, err
+		}
+		current, err := os.Stat(u.FName)
+		if err != nil {
+			return false, err
+		}
+		return !os.SameFile(opened, current) || !opened.ModTime().Equal(current.ModTime()) || opened.Size() != current.Size(), nil
+	}
+	old := u.version.load()
+	if old == "" {
+		return false, nil
+	}
+	req, err := http.NewRequest("HEAD", u.url, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := u.do(req, 0, -1)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("HEAD %s: %s", u.url, resp.Status)
+	}
+	current := bwResponseVersion(resp)
+	return current != "" && current != old, nil
+}
+
+// ReopenIfStale 在文件已被替换时用打开时的选项重新打开，并在原处替换句柄的内容，返回是否重新打开
+// 重新打开失败（例如新文件尚未上传完整）时保留原句柄并返回错误；旧句柄的块缓存被释放，
+// 结果缓存因文件版本变化而失效。之前 Clone 出的句柄仍指向旧文件
+func (fp *Bigwig_file_out) ReopenIfStale() (bool, error) {
+	stale, err := fp.Stale()
+	if err != nil || !stale {
+		return false, err
+	}
+	old := fp.bf_fp
+	opts := old.Opts
+	nf, err := OpenBigWig(old.URL.FName, func(o *BWOptions_Open) { *o = opts })
+	if err != nil {
+		return false, fmt.Errorf("reopen %s: %w", old.URL.FName, err)
+	}
+	CloseBigWig(&Bigwig_file_out{bf_fp: old})
+	*fp = *nf
+	return true, nil
+}
+
+// reopenIfStale 在设置了 ReopenInterval 且距上次检查已超过间隔时调用 ReopenIfStale；
+// 检查或重新打开失败时继续使用原句柄
+func (fp *Bigwig_file_out) reopenIfStale() {
+	bw := fp.bf_fp
+	interval := bw.Opts.ReopenInterval
+	if interval <= 0 {
+		return
+	}
+	now := time.Now()
+	if last := bw.URL.checked; !last.IsZero() && now.Sub(last) < interval {
+		return
+	}
+	bw.URL.checked = now
+	if ok, _ := fp.ReopenIfStale(); ok {
+		fp.bf_fp.URL.checked = now
+	}
+}
@@ -0,0 +1,240 @@
+package gobigwig
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// RepairReport 是 Repair 的结果
+type RepairReport struct {
+	Blocks  uint64 // 扫描恢复出的数据块数
+	Items   uint64 // 写入修复文件的记录数
+	DataEnd uint64 // 扫描停止处的文件偏移，之后的数据无法恢复
+	Stop    error  // 扫描停止的原因；到达数据区末尾时为 nil
+}
+
+// bwCountingReader 统计已读取的字节数；实现 io.ByteReader，
+// 使 zlib 解压不会越过当前块读取后面的数据
+type bwCountingReader struct {
+	r *bufio.Reader
+	n uint64
+}
+
+func (c *bwCountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+func (c *bwCountingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Repair 从 src 的 dataOffset 开始顺序扫描数据块（不使用 R 树索引），
+// 把能完整解析的块重新写入 dst，生成带新索引、zoom 层级和 summary 的文件
+// 用于恢复上传中断、索引（位于文件末尾）丢失的文件；src 的文件头和染色体树必须完好
+// 扫描在第一个无法解析的块处停止，原因记录在 RepairReport.Stop 中；
+// opts 用于创建 dst，默认沿用 src 是否压缩
+func Repair(src, dst string, opts ...WriteOption) (RepairReport, error) {
+	var rep RepairReport
+	u, err := Open(src)
+	if err != nil {
+		return rep, err
+	}
+	defer u.Close()
+
+	fp := &bigWigFile_t{URL: u}
+	if err := bwHdrRead(fp); err != nil {
+		return rep, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	cl, err := bwReadchromList(fp)
+	if err != nil {
+		return rep, fmt.Errorf("读取染色体列表失败: %w", err)
+	}
+
+	w, err := CreateBigWig(dst, cl.Chrom, cl.Len, append([]WriteOption{WithCompression(fp.Hdr.bufsize > 0)}, opts...)...)
+	if err != nil {
+		return rep, err
+	}
+
+	// 数据区以 8 字节的块数量开头，截断的文件中它可能仍是写入时的占位值，只作为扫描上限参考
+	var declared uint64
+	if bwSetPos(fp, fp.Hdr.dataOffset) != 0 {
+		w.Close()
+		return rep, errors.New("failed to seek to data section")
+	}
+	if _, err := bwRead(&declared, 8, 1, fp); err != nil {
+		w.Close()
+		return rep, fmt.Errorf("failed to read block count: %w", err)
+	}
+	// 索引仍在文件中时，数据区到索引为止
+	end := uint64(0)
+	size := bwFileSize(u)
+	if size > 0 && fp.Hdr.indexoffset > fp.Hdr.dataOffset && fp.Hdr.indexoffset < uint64(size) {
+		end = fp.Hdr.indexoffset
+	}
+	// 进度以要扫描的数据区为总量，没有索引时扫描到文件末尾
+	total := end
+	if total == 0 && size > 0 {
+		total = uint64(size)
+	}
+	progress := newBWProgress(w.opts.Progress, total-min64(total, fp.Hdr.dataOffset))
+
+	cr := &bwCountingReader{r: bufio.NewReaderSize(u, 1<<20), n: fp.Hdr.dataOffset + 8}
+	order := bwOrder(fp)
+	limit := bwBlockLimit(fp)
+	for end == 0 || cr.n < end {
+		if declared > 0 && rep.Blocks == declared {
+			break
+		}
+		start := cr.n
+		raw, err := bwScanBlock(cr, fp.Hdr.bufsize > 0, order, limit)
+		if err == nil {
+			var hdr BlockHeader
+			var items []Interval
+			if hdr, items, err = bwParseDataBlock(raw, order); err == nil {
+				err = bwRepairAdd(w, cl, hdr, items)
+				if err == nil {
+					rep.Blocks++
+					rep.Items += uint64(len(items))
+					rep.DataEnd = cr.n
+					progress.add(cr.n - start)
+					continue
+				}
+			}
+		}
+		if !errors.Is(err, io.EOF) {
+			rep.Stop = fmt.Errorf("block at offset %d: %w", start, err)
+		}
+		break
+	}
+	if rep.DataEnd == 0 {
+		rep.DataEnd = fp.Hdr.dataOffset + 8
+	}
+	if err := w.Close(); err != nil {
+		return rep, err
+	}
+	progress.finish()
+	return rep, nil
+}
+
+// bwScanBlock 从 cr 读取下一个数据块并返回解压后的内容
+// 压缩块的长度由 zlib 流自身的结束标记确定，未压缩块的长度由块头中的类型和记录数确定
+func bwScanBlock(cr *bwCountingReader, compressed bool, order binary.ByteOrder, limit int) ([]byte, error) {
+	if _, err := cr.r.Peek(1); err != nil {
+		return nil, err
+	}
+	if compressed {
+		zr, err := zlib.NewReader(cr)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		var buf bytes.Buffer
+		n, err := io.Copy(&buf, io.LimitReader(zr, int64(limit)+1))
+		if err != nil {
+			return nil, err
+		}
+		if n > int64(limit) {
+			return nil, fmt.Errorf("decompressed block exceeds %d bytes", limit)
+		}
+		return buf.Bytes(), nil
+	}
+
+	raw := make([]byte, bwDataHeaderSize)
+	if _, err := io.ReadFull(cr, raw); err != nil {
+		return nil, err
+	}
+	hdr := bwDataHeader_t{}
+	if err := bwFillDataHdr(&hdr, raw, order); err != nil {
+		return nil, err
+	}
+	size := bwDataItemSize(hdr.Type)
+	if size == 0 {
+		return nil, fmt.Errorf("unknown data block type %d", hdr.Type)
+	}
+	raw = append(raw, make([]byte, int(hdr.NItems)*size)...)
+	if _, err := io.ReadFull(cr, raw[bwDataHeaderSize:]); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// bwRepairAdd 按块的原始类型把记录写入 w；items 可以是块内连续的一部分记录
+func bwRepairAdd(w *BigWigWriter, cl *chromList, hdr BlockHeader, items []Interval) error {
+	if int(hdr.ChromID) >= len(cl.Chrom) {
+		return fmt.Errorf("block references chrom %d, file has %d", hdr.ChromID, len(cl.Chrom))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	chrom := cl.Chrom[hdr.ChromID]
+	starts := make([]uint32, len(items))
+	values := make([]float32, len(items))
+	for i, it := range items {
+		starts[i] = it.Start
+		values[i] = it.Value
+	}
+	switch hdr.Type {
+	case bwTypeVariableStep:
+		return w.AddIntervalSpans(chrom, starts, hdr.Span, values)
+	case bwTypeFixedStep:
+		return w.AddIntervalSpanSteps(chrom, items[0].Start, hdr.Span, hdr.Step, values)
+	}
+	ends := make([]uint32, len(items))
+	for i, it := range items {
+		ends[i] = it.End
+	}
+	return w.AddIntervals(chrom, starts, ends, values)
+}
+
+// RecomputeSummary 解压 path 的所有数据块，重算 basesCovered、minVal、maxVal、sumData 与 sumSquares，
+// 与文件中记录的 summary 不符时（即 Verify 报告 IssueSummary）就地改写 summary 段，返回是否改写
+// 除 summary 外还有其他问题的文件不会被修改，应改用 Repair；没有 summary 段（summaryOffset 为 0）的文件无法就地修复
+func RecomputeSummary(path string) (bool, error) {
+	r := Report{Path: path, Level: VerifyData, FileSize: -1}
+	v, err := bwVerify(&r, nil)
+	if err != nil {
+		return false, err
+	}
+	for _, i := range r.Issues {
+		if i.Kind != IssueSummary {
+			return false, fmt.Errorf("cannot recompute summary of %s: %s", path, i)
+		}
+	}
+	if v.summary == nil {
+		return false, fmt.Errorf("cannot recompute summary of %s: data blocks could not be scanned", path)
+	}
+	if !r.Has(IssueSummary) {
+		return false, nil
+	}
+	hdr := v.fp.Hdr
+	if hdr.summaryoffset == 0 {
+		return false, fmt.Errorf("cannot recompute summary of %s: file has no summary section", path)
+	}
+
+	var buf bytes.Buffer
+	sum := v.summary
+	for _, x := range []any{sum.covered, sum.minVal, sum.maxVal, sum.sumData, sum.sumSquares} {
+		binary.Write(&buf, bwOrder(v.fp), x)
+	}
+	f, err := os.OpenFile(v.fp.URL.FName, os.O_WRONLY, 0)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.WriteAt(buf.Bytes(), int64(hdr.summaryoffset)); err != nil {
+		f.Close()
+		return false, err
+	}
+	return true, f.Close()
+}
@@ -0,0 +1,210 @@
+package gobigwig
+
+import (
+	"container/list"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// ResultCache 缓存 Stats、RegionTrackContext（MarshalRegionJSON）和 HiGlassTileContext 的计算结果，
+// 用于反复请求相同区间的仪表盘和 tile 服务；它位于块缓存之上，命中时不再读取和解码任何数据块
+// 键由文件路径/URL、文件版本和规范化后的查询参数组成：本地文件的版本是已打开文件的修改时间和大小，
+// 远程文件的版本是最近一次响应的 ETag（没有时为 Last-Modified），版本变化时该文件的旧结果全部失效；
+// 远程服务器两者都不返回时无法判断文件是否变化，该文件的结果不缓存
+// 同一个 ResultCache 可以通过 WithResultCache 被多个文件（例如 Pool 中的全部文件）共享，可以并发使用
+type ResultCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	lru      *list.List // *bwResultEntry，头部为最近使用
+	items    map[bwResultKey]*list.Element
+	versions map[string]string // 每个文件最近一次见到的版本
+}
+
+// bwResultKey 标识一个缓存结果
+type bwResultKey struct {
+	file    string
+	version string
+	query   string
+}
+
+// bwResultEntry 是 ResultCache 中的一个结果，value 在存入后不再被修改
+type bwResultEntry struct {
+	key   bwResultKey
+	value any
+	size  int64
+}
+
+// NewResultCache 创建最多占用约 maxBytes 字节的结果缓存，超出时淘汰最久未使用的结果
+func NewResultCache(maxBytes int64) *ResultCache {
+	return &ResultCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[bwResultKey]*list.Element),
+		versions: make(map[string]string),
+	}
+}
+
+// WithResultCache 让文件的统计与分箱结果使用共享的结果缓存 c，nil 表示不缓存
+func WithResultCache(c *ResultCache) OpenOption {
+	return func(o *BWOptions_Open) { o.ResultCache = c }
+}
+
+// Len 返回缓存的结果数
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Size 返回缓存结果估计占用的字节数
+func (c *ResultCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+// Purge 清空缓存
+func (c *ResultCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	clear(c.items)
+	clear(c.versions)
+	c.used = 0
+}
+
+// get 返回 key 对应的结果；key.version 与该文件上次见到的版本不同时先丢弃该文件的旧结果
+func (c *ResultCache) get(key bwResultKey) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.versions[key.file]; ok && old != key.version {
+		for el := c.lru.Front(); el != nil; {
+			next := el.Next()
+			if e := el.Value.(*bwResultEntry); e.key.file == key.file {
+				c.remove(el)
+			}
+			el = next
+		}
+	}
+	c.versions[key.file] = key.version
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*bwResultEntry).value, true
+}
+
+// put 存入结果，size 为其估计占用的字节数；超过整个缓存上限的结果不存入
+func (c *ResultCache) put(key bwResultKey, value any, size int64) {
+	size += int64(len(key.file) + len(key.version) + len(key.query))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size > c.maxBytes || c.versions[key.file] != key.version {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.lru.PushFront(&bwResultEntry{key: key, value: value, size: size})
+	c.used += size
+	for c.used > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove 移除 el，调用方持有 c.mu
+func (c *ResultCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*bwResultEntry)
+	delete(c.items, e.key)
+	c.used -= e.size
+}
+
+// bwRemoteVersion 记录远程文件最近一次响应中的版本标识，可被并发的 Range 请求更新
+type bwRemoteVersion struct {
+	v atomic.Value // string
+}
+
+// note 从 resp 的 ETag 或 Last-Modified 更新版本
+func (rv *bwRemoteVersion) note(resp *http.Response) {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return
+	}
+	if v := bwResponseVersion(resp); v != "" {
+		rv.v.Store(v)
+	}
+}
+
+// bwResponseVersion 返回 resp 的 ETag（没有时为 Last-Modified）作为版本标识，两者都没有时为空
+func bwResponseVersion(resp *http.Response) string {
+	if v := resp.Header.Get("ETag"); v != "" {
+		return "etag:" + v
+	}
+	if v := resp.Header.Get("Last-Modified"); v != "" {
+		return "mtime:" + v
+	}
+	return ""
+}
+
+// load 返回记录的版本，没有时为空
+func (rv *bwRemoteVersion) load() string {
+	v, _ := rv.v.Load().(string)
+	return v
+}
+
+// fileVersion 返回判断文件内容是否变化的版本标识，无法判断时返回 false
+// 本地文件对已打开的文件调用 Stat，因此被替换（重命名覆盖）的文件仍对应原来的内容
+func (u *URL) fileVersion() (string, bool) {
+	if u.Type == BWG_FILE {
+		f, ok := u.rs.(*os.File)
+		if !ok {
+			return "", false
+		}
+		st, err := f.Stat()
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%d:%d", st.ModTime().UnixNano(), st.Size()), true
+	}
+	v := u.version.load()
+	return v, v != ""
+}
+
+// bwCachedResult 在 fp 设置了 ResultCache 时按 query 查找结果，未命中时调用 compute 计算并存入；
+// query 必须包含影响结果的全部（规范化后的）参数。clone 复制结果，使调用方拿到的值与缓存互不影响，
+// size 估计结果占用的字节数
+func bwCachedResult[T any](fp *Bigwig_file_out, query string, compute func() (T, error), clone func(T) T, size func(T) int64) (T, error) {
+	bw := fp.bf_fp
+	c := bw.Opts.ResultCache
+	if c == nil {
+		return compute()
+	}
+	version, ok := bw.URL.fileVersion()
+	if !ok {
+		return compute()
+	}
+	// 同一文件用不同的染色体长度覆盖打开时结果不同
+	key := bwResultKey{file: bw.URL.FName, version: version, query: fmt.Sprintf("%p\x00%s", bw.Opts.ChromLengths, query)}
+	if v, ok := c.get(key); ok {
+		return clone(v.(T)), nil
+	}
+	v, err := compute()
+	if err != nil {
+		return v, err
+	}
+	// 计算期间远程文件的版本变化时，结果可能混合了新旧两个版本的数据
+	if now, _ := bw.URL.fileVersion(); now == version {
+		c.put(key, clone(v), size(v))
+	}
+	return v, nil
+}
+
+// bwCloneFloats 复制缓存的 Stats 结果
+func bwCloneFloats(v []float64) []float64 { return append([]float64(nil), v...) }
+
+// bwFloatsSize 估计 Stats 结果占用的字节数
+func bwFloatsSize(v []float64) int64 { return int64(8 * len(v)) }
@@ -3,12 +3,15 @@ package gobigwig
 import (
 	"bytes"
 	"compress/zlib"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"sort"
+	"time"
 )
 
 func decompressZlibDebug(compBuf []byte) ([]byte, error) {
@@ -73,8 +76,7 @@ func readRTreeIdx(fp *bigWigFile_t, offset uint64) (*bwRTree_t, error) {
 		return nil, fmt.Errorf("failed to read magic number: %v", err)
 	}
 	if int(magic) != IDX_MAGIC {
-		fmt.Fprintf(os.Stderr, "[readRTreeIdx] Mismatch in the magic number!\n")
-		return nil, errors.New("invalid magic number")
+		return nil, fmt.Errorf("invalid R-tree index magic number 0x%x", magic)
 	}
 
 	node := &bwRTree_t{}
@@ -285,11 +287,44 @@ func mergeOverlapBlocks(b1, b2 *bwOverlapBlock_t) *bwOverlapBlock_t {
 	return b1
 }
 
+// sortOverlapBlocks 按文件偏移排序并去除重复的数据块（索引叶子重叠时同一块可能出现多次）
+func sortOverlapBlocks(o *bwOverlapBlock_t) {
+	if o == nil || o.N < 2 {
+		return
+	}
+	type block struct{ offset, size uint64 }
+	blocks := make([]block, o.N)
+	for i := range blocks {
+		blocks[i] = block{o.Offset[i], o.Size[i]}
+	}
+	sort.Slice(blocks, func(a, b int) bool { return blocks[a].offset < blocks[b].offset })
+
+	n := 0
+	for i, b := range blocks {
+		if i > 0 && b.offset == blocks[n-1].offset {
+			continue
+		}
+		blocks[n] = b
+		n++
+	}
+	o.N = uint64(n)
+	o.Offset = o.Offset[:n]
+	o.Size = o.Size[:n]
+	for i := 0; i < n; i++ {
+		o.Offset[i] = blocks[i].offset
+		o.Size[i] = blocks[i].size
+	}
+}
+
 // overlapsNonLeaf 在非叶子节点上查找与 [start, end) 区间重叠的数据块
-func overlapsNonLeaf(fp *bigWigFile_t, node *bwRTreeNode_t, tid, start, end uint32) *bwOverlapBlock_t {
+// ctx 被取消时返回 nil，调用方通过 ctx.Err() 区分取消与读取错误
+func overlapsNonLeaf(ctx context.Context, fp *bigWigFile_t, node *bwRTreeNode_t, tid, start, end uint32) (*bwOverlapBlock_t, error) {
 	output := &bwOverlapBlock_t{}
 
 	for i := uint16(0); i < node.NChildren; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		// 如果染色体索引在子节点范围之外，跳过
 		if tid < node.ChrIdxStart[i] || tid > node.ChrIdxEnd[i] {
 			continue
@@ -308,41 +343,47 @@ func overlapsNonLeaf(fp *bigWigFile_t, node *bwRTreeNode_t, tid, start, end uint
 			}
 		}
 
-		if node.Child[i] == nil {
-			child, err := bwGetRTreeNode(fp, node.DataOffset[i])
+		bwLockIndex(fp)
+		child := node.Child[i]
+		bwUnlockIndex(fp)
+		if child == nil {
+			var err error
+			child, err = bwGetRTreeNodeCached(fp, node.DataOffset[i])
 			if err != nil {
-				return nil
+				return nil, fmt.Errorf("R-tree node at offset %d: %w", node.DataOffset[i], err)
+			}
+			// 没有内存预算时子节点常驻内存，否则由 LRU 缓存决定保留多久
+			if fp.budget == nil {
+				bwLockIndex(fp)
+				node.Child[i] = child
+				bwUnlockIndex(fp)
 			}
-			node.Child[i] = child
 		}
 
 		var nodeBlocks *bwOverlapBlock_t
-		if node.Child[i].IsLeaf != 0 {
-			nodeBlocks = overlapsLeaf(node.Child[i], tid, start, end)
+		if child.IsLeaf != 0 {
+			nodeBlocks = overlapsLeaf(child, tid, start, end)
 		} else {
-			nodeBlocks = overlapsNonLeaf(fp, node.Child[i], tid, start, end)
-		}
-
-		if nodeBlocks == nil {
-			return nil
+			var err error
+			if nodeBlocks, err = overlapsNonLeaf(ctx, fp, child, tid, start, end); err != nil {
+				return nil, err
+			}
 		}
 
 		output = mergeOverlapBlocks(output, nodeBlocks)
-		if output == nil {
-			return nil
-		}
 	}
 
-	return output
+	return output, nil
 }
 
 // walkRTreeNodes 遍历 R 树节点，返回重叠的数据块
-// 如果发生错误返回 nil
-func walkRTreeNodes(bw *bigWigFile_t, root *bwRTreeNode_t, tid, start, end uint32) *bwOverlapBlock_t {
+// 子节点读取失败时返回错误，ctx 被取消时返回 ctx.Err()
+func walkRTreeNodes(ctx context.Context, bw *bigWigFile_t, root *bwRTreeNode_t, tid, start, end uint32) (*bwOverlapBlock_t, error) {
+	defer bw.URL.metrics.indexSince(time.Now())
 	if root.IsLeaf != 0 {
-		return overlapsLeaf(root, tid, start, end)
+		return overlapsLeaf(root, tid, start, end), nil
 	}
-	return overlapsNonLeaf(bw, root, tid, start, end)
+	return overlapsNonLeaf(ctx, bw, root, tid, start, end)
 }
 
 // bwGetTid 返回染色体名对应的索引 (tid)
@@ -351,6 +392,10 @@ func bwGetTid(fp *bigWigFile_t, chrom string) uint32 {
 	if chrom == "" {
 		return ^uint32(0) // -1 的无符号表示
 	}
+	if err := bwLoadChromList(fp); err != nil {
+		fmt.Fprintf(os.Stderr, "[bwGetTid] failed to load chromosome list: %v\n", err)
+		return ^uint32(0)
+	}
 	for i := uint32(0); i < uint32(fp.Cl.NKeys); i++ {
 		if fp.Cl.Chrom[i] == chrom {
 			return i
@@ -359,45 +404,41 @@ func bwGetTid(fp *bigWigFile_t, chrom string) uint32 {
 	return ^uint32(0)
 }
 
-func bwGetOverlappingBlocks(fp *bigWigFile_t, chrom string, start, end uint32) *bwOverlapBlock_t {
+// bwGetOverlappingBlocks 返回与 chrom:[start, end) 重叠的数据块
+// 染色体不存在、R 树索引读取失败或 ctx 被取消时返回错误
+func bwGetOverlappingBlocks(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32) (*bwOverlapBlock_t, error) {
 	tid := bwGetTid(fp, chrom)
 	if tid == ^uint32(0) { // 未找到染色体
-		fmt.Fprintf(os.Stderr, "[bwGetOverlappingBlocks] Non-existent contig: %s\n", chrom)
-		return nil
-	}
-	// 如果索引尚未加载，则读取 R 树索引
-	if fp.Idx == nil {
-		var err error
-		fp.Idx, err = readRTreeIdx(fp, fp.Hdr.indexoffset)
-		if err != nil {
-			return nil
-		}
+		return nil, fmt.Errorf("chromosome not found: %s", chrom)
 	}
-	// 如果根节点为空，则读取根节点
-	if fp.Idx.Root == nil {
-		var err error
-		fp.Idx.Root, err = bwGetRTreeNode(fp, 0)
-		if err != nil {
-			return nil
-		}
+	// 如果索引尚未加载（延迟打开模式），则读取 R 树索引及其根节点
+	if err := bwLoadIndex(fp); err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
 	}
 	// 遍历 R 树查找重叠的数据块
-	return walkRTreeNodes(fp, fp.Idx.Root, tid, start, end)
+	blocks, err := walkRTreeNodes(ctx, fp, fp.Idx.Root, tid, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if fp.Opts.SortResults {
+		sortOverlapBlocks(blocks)
+	}
+	return blocks, nil
 }
 
 // bwFillDataHdr 从字节切片 b 填充数据块头信息到 hdr
-func bwFillDataHdr(hdr *bwDataHeader_t, b []byte) error {
-	if len(b) < 24 { // 最少需要 24 字节才能包含所有字段
-		return fmt.Errorf("byte slice too short: %d", len(b))
+func bwFillDataHdr(hdr *bwDataHeader_t, b []byte, order binary.ByteOrder) error {
+	if len(b) < bwDataHeaderSize { // 最少需要 24 字节才能包含所有字段
+		return fmt.Errorf("data block too short for header: %d bytes", len(b))
 	}
 
-	hdr.Tid = binary.LittleEndian.Uint32(b[0:4])
-	hdr.Start = binary.LittleEndian.Uint32(b[4:8])
-	hdr.End = binary.LittleEndian.Uint32(b[8:12])
-	hdr.Step = binary.LittleEndian.Uint32(b[12:16])
-	hdr.Span = binary.LittleEndian.Uint32(b[16:20])
+	hdr.Tid = order.Uint32(b[0:4])
+	hdr.Start = order.Uint32(b[4:8])
+	hdr.End = order.Uint32(b[8:12])
+	hdr.Step = order.Uint32(b[12:16])
+	hdr.Span = order.Uint32(b[16:20])
 	hdr.Type = b[20]                                  // uint8
-	hdr.NItems = binary.LittleEndian.Uint16(b[22:24]) // uint16，注意字节偏移
+	hdr.NItems = order.Uint16(b[22:24]) // uint16，注意字节偏移
 
 	return nil
 }
@@ -463,146 +504,197 @@ func pushBBIntervals(o *bbOverlappingEntries_t, start, end uint32, str string, w
 }
 
 
+// sortIntervals 将区间按 start（相同时按 end）排序，并去除 start/end/value 完全相同的重复区间
+// 已经有序且无重复时直接返回
+func sortIntervals(o *bwOverlappingIntervals_t) {
+	if o == nil || o.L < 2 {
+		return
+	}
+	n := int(o.L)
+	ordered := true
+	for i := 1; i < n; i++ {
+		if o.Start[i] < o.Start[i-1] || (o.Start[i] == o.Start[i-1] && o.End[i] <= o.End[i-1]) {
+			ordered = false
+			break
+		}
+	}
+	if ordered {
+		return
+	}
+
+	type interval struct {
+		start, end uint32
+		value      float32
+	}
+	items := make([]interval, n)
+	for i := range items {
+		items[i] = interval{o.Start[i], o.End[i], o.Value[i]}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		if items[a].start != items[b].start {
+			return items[a].start < items[b].start
+		}
+		return items[a].end < items[b].end
+	})
+
+	k := 0
+	for i, it := range items {
+		if i > 0 && it == items[k-1] {
+			continue
+		}
+		items[k] = it
+		k++
+	}
+	for i := 0; i < k; i++ {
+		o.Start[i] = items[i].start
+		o.End[i] = items[i].end
+		o.Value[i] = items[i].value
+	}
+	o.L = uint32(k)
+}
+
 func bwStrdup(s string) string {
 	return string([]byte(s)) // Go 中直接复制
 }
 
-func bwGetOverlappingIntervalsCore(fp *bigWigFile_t, o *bwOverlapBlock_t, tid, ostart, oend uint32) *bwOverlappingIntervals_t {
+// bwGetOverlappingIntervalsCore 逐块读取、解压并解码 o 中的数据块
+// 每个块的读取与解压前后都会检查 ctx，块内解码每 ctxCheckInterval 条记录检查一次；
+// ctx 被取消时返回 ctx.Err()，块读取、解压或解码失败时返回带块偏移的错误
+func bwGetOverlappingIntervalsCore(ctx context.Context, fp *bigWigFile_t, o *bwOverlapBlock_t, tid, ostart, oend uint32) (*bwOverlappingIntervals_t, error) {
 	if o == nil || o.N == 0 {
-		// fmt.Println("[DEBUG] 没有重叠块")
-		return &bwOverlappingIntervals_t{}
+		return &bwOverlappingIntervals_t{}, nil
 	}
 
-	// fmt.Printf("[DEBUG] 处理 %d 个重叠块\n", o.N)
 	output := &bwOverlappingIntervals_t{}
-	compressed := fp.Hdr.bufsize > 0
 
 	for i := uint64(0); i < o.N; i++ {
-		// fmt.Printf("\n[DEBUG] === 块 %d/%d ===\n", i+1, o.N)
-		// fmt.Printf("[DEBUG] 偏移: %d, 大小: %d\n", o.Offset[i], o.Size[i])
-
-		// 定位到数据块
-		if out := bwSetPos(fp, o.Offset[i]); out != 0 {
-			// fmt.Fprintf(os.Stderr, "[ERROR] 定位失败\n")
-			return nil
-		}
-
-		// 读取数据
-		compBuf := make([]byte, o.Size[i])
-		n, err := fp.URL.Read(compBuf)
-		if err != nil || n != int(o.Size[i]) {
-			// fmt.Fprintf(os.Stderr, "[ERROR] 读取失败: %v\n", err)
-			return nil
-		}
-
-		var uncompressed []byte
-		if compressed {
-			uncompressed, err = decompressZlibDebug(compBuf)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "[ERROR] 解压失败: %v\n", err)
-				return nil
-			}
-		} else {
-			uncompressed = compBuf
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		if len(uncompressed) < 24 {
-			// fmt.Fprintf(os.Stderr, "[ERROR] 数据太短\n")
-			return nil
+		// 读取并解压数据块
+		uncompressed, err := bwReadBlock(fp, o.Offset[i], o.Size[i])
+		if err != nil {
+			return nil, fmt.Errorf("data block at offset %d: %w", o.Offset[i], err)
 		}
-
-		hdr := bwDataHeader_t{}
-		if err := bwFillDataHdr(&hdr, uncompressed); err != nil {
-			// fmt.Fprintf(os.Stderr, "[ERROR] 解析头失败: %v\n", err)
-			return nil
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		// fmt.Printf("[DEBUG] 数据头:\n")
-		// fmt.Printf("  Tid=%d (查询tid=%d)\n", hdr.Tid, tid)
-		// fmt.Printf("  Start=%d, End=%d\n", hdr.Start, hdr.End)
-		// fmt.Printf("  Type=%d (1=bedGraph, 2=variableStep, 3=fixedStep)\n", hdr.Type)
-		// fmt.Printf("  Step=%d, Span=%d\n", hdr.Step, hdr.Span)
-		// fmt.Printf("  NItems=%d\n", hdr.NItems)
-// 		fmt.Printf("raw bytes: %v\n", uncompressed[:24])
-// fmt.Printf("tid=%d start=%d end=%d step=%d span=%d type=%d nItems=%d\n",
-//     binary.LittleEndian.Uint32(uncompressed[0:4]),
-//     binary.LittleEndian.Uint32(uncompressed[4:8]),
-//     binary.LittleEndian.Uint32(uncompressed[8:12]),
-//     binary.LittleEndian.Uint32(uncompressed[12:16]),
-//     binary.LittleEndian.Uint32(uncompressed[16:20]),
-//     uncompressed[20],
-//     binary.LittleEndian.Uint16(uncompressed[22:24]),
-// )
-// fmt.Printf("raw type bytes: %v\n", uncompressed[20:24])
-		if hdr.Tid != tid {
-			// fmt.Printf("[DEBUG] 染色体不匹配，跳过\n")
-			continue
+		output, err = bwDecodeDataBlock(ctx, uncompressed, bwOrder(fp), tid, ostart, oend, output)
+		if err != nil {
+			if cerr := ctx.Err(); cerr != nil {
+				return nil, cerr
+			}
+			return nil, fmt.Errorf("data block at offset %d: %w", o.Offset[i], err)
 		}
+	}
 
-		p := uncompressed[24:]
-		// fmt.Printf("[DEBUG] 数据部分大小: %d 字节\n", len(p))
-		// fmt.Printf("[DEBUG] 前32字节数据: % x\n", p[:min(32, len(p))])
-
-		start := hdr.Start
-		itemsAdded := 0
+	return output, nil
+}
 
-		for j := uint16(0); j < hdr.NItems; j++ {
-			var end uint32
-			var value float32
 
-			switch hdr.Type {
-			case 1: // bedGraph
-				if len(p) < 12 {
-					// fmt.Printf("[DEBUG] bedGraph 数据不足, 结束循环\n")
-					break
-				}
-				start = binary.LittleEndian.Uint32(p[0:4])
-				end = binary.LittleEndian.Uint32(p[4:8])
-				value = math.Float32frombits(binary.LittleEndian.Uint32(p[8:12]))
-				p = p[12:]
-
-			case 2: // variableStep
-				if len(p) < 8 {
-					// fmt.Printf("[DEBUG] variableStep 数据不足, 结束循环\n")
-					break
-				}
-				start = binary.LittleEndian.Uint32(p[0:4])
-				end = start + hdr.Span
-				value = math.Float32frombits(binary.LittleEndian.Uint32(p[4:8]))
-				p = p[8:]
-
-			case 3: // fixedStep
-				if len(p) < 4 {
-					// fmt.Printf("[DEBUG] fixedStep 数据不足, 结束循环\n")
-					break
-				}
-				start += hdr.Step
-				end = start + hdr.Span
-				value = math.Float32frombits(binary.LittleEndian.Uint32(p[0:4]))
-				p = p[4:]
-
-			default:
-				// fmt.Printf("[DEBUG] 未知类型: %d\n", hdr.Type)
-				return nil
-			}
+// bwDecodeDataBlock 解码一个解压后的数据块，把与 [ostart, oend) 重叠的记录追加到 output
+// 块不属于 tid 时原样返回 output；块头不合法、数据长度与记录数不符或记录越界时返回错误，
+// 不会追加任何部分结果；ctx 被取消时返回 ctx.Err()
+func bwDecodeDataBlock(ctx context.Context, uncompressed []byte, order binary.ByteOrder, tid, ostart, oend uint32, output *bwOverlappingIntervals_t) (*bwOverlappingIntervals_t, error) {
+	hdr := bwDataHeader_t{}
+	if err := bwFillDataHdr(&hdr, uncompressed, order); err != nil {
+		return nil, err
+	}
 
-			// 跳过不在查询范围的区间
-			if end <= ostart || start >= oend {
-				continue
-			}
+	if hdr.Tid != tid {
+		return output, nil
+	}
+	if err := bwCheckDataHdr(&hdr, len(uncompressed)); err != nil {
+		return nil, err
+	}
 
-			output = pushIntervals(output, start, end, value)
-			itemsAdded++
-			// if j < 3 {
-			// 	// fmt.Printf("[DEBUG]   Item %d: [%d, %d) = %.4f\n", j, start, end, value)
-			// }
+	p := uncompressed[bwDataHeaderSize:]
+	first := output.L
+	for j := uint32(0); j < uint32(hdr.NItems); j++ {
+		if j%ctxCheckInterval == 0 && j > 0 && ctx.Err() != nil {
+			output.L = first
+			return nil, ctx.Err()
+		}
+		var start, end uint32
+		var value float32
+
+		switch hdr.Type {
+		case bwTypeBedGraph:
+			start = order.Uint32(p[0:4])
+			end = order.Uint32(p[4:8])
+			value = math.Float32frombits(order.Uint32(p[8:12]))
+			p = p[12:]
+		case bwTypeVariableStep:
+			start = order.Uint32(p[0:4])
+			end = start + hdr.Span
+			value = math.Float32frombits(order.Uint32(p[4:8]))
+			p = p[8:]
+		case bwTypeFixedStep:
+			// 第一条记录从块头的 Start 开始，之后每条后移 Step
+			start = hdr.Start + j*hdr.Step
+			end = start + hdr.Span
+			value = math.Float32frombits(order.Uint32(p[0:4]))
+			p = p[4:]
+		}
+
+		if end <= start || uint64(start) < uint64(hdr.Start) || (hdr.End > 0 && end > hdr.End) {
+			output.L = first
+			return nil, fmt.Errorf("data block item %d: interval [%d, %d) is empty or outside block range [%d, %d)",
+				j, start, end, hdr.Start, hdr.End)
+		}
+		// 跳过不在查询范围的区间
+		if end <= ostart || start >= oend {
+			continue
 		}
+		output = pushIntervals(output, start, end, value)
+	}
+	return output, nil
+}
 
-		// fmt.Printf("[DEBUG] 本块添加了 %d 个区间到结果\n", itemsAdded)
+// bwCheckDataHdr 检查块头本身是否可能合法，以及 n 字节的块是否恰好容纳 NItems 条记录
+func bwCheckDataHdr(hdr *bwDataHeader_t, n int) error {
+	size := bwDataItemSize(hdr.Type)
+	if size == 0 {
+		return fmt.Errorf("unknown data block type %d", hdr.Type)
+	}
+	if hdr.End < hdr.Start {
+		return fmt.Errorf("data block end %d before start %d", hdr.End, hdr.Start)
+	}
+	if hdr.Type != bwTypeBedGraph {
+		if hdr.Span == 0 {
+			return fmt.Errorf("data block of type %d has zero span", hdr.Type)
+		}
+		if hdr.Type == bwTypeFixedStep && hdr.NItems > 1 && hdr.Step == 0 {
+			return errors.New("fixedStep data block has zero step")
+		}
+		if hdr.Type == bwTypeFixedStep && hdr.NItems > 0 &&
+			uint64(hdr.Start)+uint64(hdr.NItems-1)*uint64(hdr.Step)+uint64(hdr.Span) > math.MaxUint32 {
+			return errors.New("fixedStep data block overflows coordinate range")
+		}
+	}
+	need := bwDataHeaderSize + int(hdr.NItems)*size
+	if n < need {
+		return fmt.Errorf("data block truncated: %d items of type %d need %d bytes, have %d", hdr.NItems, hdr.Type, need, n)
 	}
+	if n > need {
+		return fmt.Errorf("data block has %d trailing bytes after %d items", n-need, hdr.NItems)
+	}
+	return nil
+}
 
-	// fmt.Printf("[DEBUG] 总共返回 %d 个区间\n", output.L)
-	return output
+// bwDataItemSize 返回一种数据块类型中每条记录的字节数，未知类型返回 0
+func bwDataItemSize(typ uint8) int {
+	switch typ {
+	case bwTypeBedGraph:
+		return 12
+	case bwTypeVariableStep:
+		return 8
+	case bwTypeFixedStep:
+		return 4
+	}
+	return 0
 }
 
 
@@ -615,21 +707,31 @@ func bytesToUint32Slice(b []byte) []uint32 {
     return u32s
 }
 
-func bwGetOverlappingIntervals(fp *bigWigFile_t, chrom string, start, end uint32) *bwOverlappingIntervals_t {
+// bwGetOverlappingIntervals 返回 chrom:[start, end) 内的全部记录（不截断）
+// 染色体不存在时返回 nil；索引或数据块读取、解码失败时返回错误
+func bwGetOverlappingIntervals(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32) (*bwOverlappingIntervals_t, error) {
 	tid := bwGetTid(fp, chrom)
 	if tid == ^uint32(0) { // tid == -1 的情况
-		return nil
+		return nil, nil
 	}
 
-	blocks := bwGetOverlappingBlocks(fp, chrom, start, end)
-	if blocks == nil {
-		return nil
+	blocks, err := bwGetOverlappingBlocks(ctx, fp, chrom, start, end)
+	if err != nil {
+		return nil, err
 	}
-	output := bwGetOverlappingIntervalsCore(fp, blocks, tid, start, end)
-	return output
+	fp.URL.startPrefetch(ctx, coalesceBlocks(blocks), fp.Opts.PrefetchDepth)
+	output, err := bwGetOverlappingIntervalsCore(ctx, fp, blocks, tid, start, end)
+	fp.URL.stopPrefetch()
+	if err != nil {
+		return nil, err
+	}
+	if fp.Opts.SortResults {
+		sortIntervals(output)
+	}
+	return output, nil
 }
 
-func bwOverlappingIntervalsIterator(fp *bigWigFile_t, chrom string, start, end, blocksPerIteration uint32) *bwOverlapIterator_t {
+func bwOverlappingIntervalsIterator(ctx context.Context, fp *bigWigFile_t, chrom string, start, end, blocksPerIteration uint32) *bwOverlapIterator_t {
 	var output *bwOverlapIterator_t
 	tid := bwGetTid(fp, chrom)
 	if tid == ^uint32(0) { // tid == -1
@@ -637,6 +739,7 @@ func bwOverlappingIntervalsIterator(fp *bigWigFile_t, chrom string, start, end,
 	}
 
 	output = &bwOverlapIterator_t{
+		Ctx:                ctx,
 		Bw:                 fp,
 		Tid:                tid,
 		Start:              start,
@@ -644,15 +747,21 @@ func bwOverlappingIntervalsIterator(fp *bigWigFile_t, chrom string, start, end,
 		BlocksPerIteration: blocksPerIteration,
 	}
 
-	blocks := bwGetOverlappingBlocks(fp, chrom, start, end)
+	blocks, err := bwGetOverlappingBlocks(ctx, fp, chrom, start, end)
+	if err != nil {
+		output.Err = err
+		return output
+	}
 	output.Blocks = blocks
 
 	if blocks != nil {
+		// 迭代器会按顺序读取全部数据块，远程文件在后台预读
+		fp.URL.startPrefetch(ctx, coalesceBlocks(blocks), fp.Opts.PrefetchDepth)
 		n := blocks.N
 		if n > uint64(blocksPerIteration) {
 			blocks.N = uint64(blocksPerIteration)
 		}
-		output.Intervals = bwGetOverlappingIntervalsCore(fp, blocks, tid, start, end)
+		output.Intervals, output.Err = bwGetOverlappingIntervalsCore(ctx, fp, blocks, tid, start, end)
 		blocks.N = n
 		output.Offset = uint64(blocksPerIteration)
 	}
@@ -661,6 +770,20 @@ func bwOverlappingIntervalsIterator(fp *bigWigFile_t, chrom string, start, end,
 	return output
 }
 
+// bwIteratorDestroy 结束迭代，停止尚未完成的预读
+func bwIteratorDestroy(iter *bwOverlapIterator_t) {
+	if iter == nil || iter.Bw == nil {
+		return
+	}
+	iter.Bw.URL.stopPrefetch()
+	iter.Blocks = nil
+	iter.Intervals = nil
+	iter.Entries = nil
+	iter.Data = nil
+}
+
+// bwIteratorNext 读取下一批数据块；没有更多数据块时返回的迭代器 Data 为 nil，
+// 读取或解码失败时把错误记录在 iter.Err 并返回 nil
 func bwIteratorNext(iter *bwOverlapIterator_t) *bwOverlapIterator_t {
 	if iter == nil || iter.Blocks == nil {
 		return nil
@@ -696,7 +819,7 @@ func bwIteratorNext(iter *bwOverlapIterator_t) *bwOverlapIterator_t {
 		}
 		// 获取区间或条目
 		if iter.Bw.Type == 0 {
-			iter.Intervals = bwGetOverlappingIntervalsCore(iter.Bw, currentBlocks, iter.Tid, iter.Start, iter.End)
+			iter.Intervals, iter.Err = bwGetOverlappingIntervalsCore(iter.Ctx, iter.Bw, currentBlocks, iter.Tid, iter.Start, iter.End)
 			iter.Data = iter.Intervals
 		} 
 		iter.Offset += uint64(iter.BlocksPerIteration)
@@ -708,14 +831,16 @@ func bwIteratorNext(iter *bwOverlapIterator_t) *bwOverlapIterator_t {
 	return iter
 }
 
-func bwGetValues(fp *bigWigFile_t, chrom string, start, end uint32, includeNA bool) *bwOverlappingIntervals_t {
-	intermediate := bwGetOverlappingIntervals(fp, chrom, start, end)
-	if intermediate == nil {
-		return nil
+// bwGetValues 返回 [start, end) 内的值；includeNA 时每个碱基一个值（无数据为 NaN），
+// 重叠区间按 policy 合并；数据块读取或解码失败时返回错误
+func bwGetValues(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32, includeNA bool, policy OverlapPolicy) (*bwOverlappingIntervals_t, error) {
+	intermediate, err := bwGetOverlappingIntervals(ctx, fp, chrom, start, end)
+	if err != nil || intermediate == nil {
+		return nil, err
 	}
 	output := &bwOverlappingIntervals_t{}
 	if output == nil {
-		return nil
+		return nil, nil
 	}
 	if includeNA {
 		// 每个位点都返回一个值
@@ -724,14 +849,7 @@ func bwGetValues(fp *bigWigFile_t, chrom string, start, end uint32, includeNA bo
 		for i := range output.Value {
 			output.Value[i] = float32(math.NaN())
 		}
-		for i := uint32(0); i < intermediate.L; i++ {
-			for j := intermediate.Start[i]; j < intermediate.End[i]; j++ {
-				if j < start || j >= end {
-					continue
-				}
-				output.Value[j-start] = intermediate.Value[i]
-			}
-		}
+		bwFillBases(output.Value, intermediate, start, policy)
 		output.L = length
 	} else {
 		// 只返回实际有值的位置
@@ -761,7 +879,7 @@ func bwGetValues(fp *bigWigFile_t, chrom string, start, end uint32, includeNA bo
 			}
 		}
 	}
-	return output
+	return output, nil
 }
 
 // bwReadIndex 读取指定 offset 的 RTree 索引，如果 offset 为 0，则读取值的索引
@@ -0,0 +1,44 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+)
+
+// SampleValues 在整个基因组上按 binSize 分箱，用 seed 均匀、不放回地随机抽取 n 个 bin，
+// 返回抽中的 bin（按染色体和坐标排序，坐标从 0 开始，与打开文件时的坐标约定无关）及其平均值，
+// 用于构建背景分布和质控作图；染色体末端不足 binSize 的部分不参与抽样，基因组的 bin 数不超过 n 时返回全部 bin
+// 与 Stats 相同，有合适的 zoom 层级时用汇总计算（近似值），没有数据的 bin 为 NaN
+func (fp *Bigwig_file_out) SampleValues(ctx context.Context, n int, binSize uint32, seed int64) ([]Region, []float64, error) {
+	if n <= 0 || binSize == 0 {
+		return nil, nil, fmt.Errorf("invalid sample of %d bins of %d bases", n, binSize)
+	}
+	bw := fp.bf_fp
+	if err := bwLoadChromList(bw); err != nil {
+		return nil, nil, err
+	}
+	bins := bwSampleBins(fp, bw.Cl.Chrom, binSize, n, uint64(seed))
+	zoomIdx := -1
+	if len(bw.Hdr.ZoomHdrs) > 0 {
+		zoomIdx = bwSelectBestZoomLevel(bw.Hdr.ZoomHdrs[0], binSize/2)
+	}
+	w := bwResolveWeighting(bw, WeightDefault)
+	values := make([]float64, len(bins))
+	for i, b := range bins {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		accs := make([]bwStatAcc, 1)
+		var err error
+		if zoomIdx >= 0 {
+			err = bwZoomStats(ctx, bw, zoomIdx, b.Chrom, uint32(b.Start), uint32(b.End), accs)
+		} else {
+			err = bwExactStats(ctx, bw, b.Chrom, b.Start, b.End, accs)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%d-%d: %w", b.Chrom, b.Start, b.End, err)
+		}
+		values[i] = accs[0].result("mean", binSize, w)
+	}
+	return bins, values, nil
+}
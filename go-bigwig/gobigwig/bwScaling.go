@@ -0,0 +1,221 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// ScalingMethod 表示 EstimateScaling 估计缩放系数的方法
+type ScalingMethod int
+
+const (
+	ScaleSES         ScalingMethod = iota // signal extraction scaling（Diaz et al. 2012），用背景 bin 的信号总量之比
+	ScaleMedianRatio                      // 两个文件都有信号的 bin 上 a/b 的中位数
+)
+
+func (m ScalingMethod) String() string {
+	switch m {
+	case ScaleSES:
+		return "ses"
+	case ScaleMedianRatio:
+		return "median"
+	}
+	return fmt.Sprintf("ScalingMethod(%d)", int(m))
+}
+
+// ParseScalingMethod 按名称（ses、median，与 String 的结果一致）返回 ScalingMethod
+func ParseScalingMethod(s string) (ScalingMethod, error) {
+	for m := ScaleSES; m <= ScaleMedianRatio; m++ {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown scaling method %q", s)
+}
+
+// DEFAULT_SCALING_BIN_SIZE 和 DEFAULT_SCALING_SAMPLES 是 ScalingOptions 未设置时的 bin 宽度与抽样 bin 数
+const (
+	DEFAULT_SCALING_BIN_SIZE = 10000
+	DEFAULT_SCALING_SAMPLES  = 100000
+)
+
+// ScalingOptions 是 EstimateScaling 的参数
+type ScalingOptions struct {
+	BinSize uint32 // 抽样 bin 的宽度，0 表示 DEFAULT_SCALING_BIN_SIZE
+	Samples int    // 抽样的 bin 数，0 表示 DEFAULT_SCALING_SAMPLES；全基因组的 bin 数不超过它时使用全部 bin
+	Seed    uint64 // 随机抽样的种子，相同的种子得到相同的结果
+	Workers int    // 并行数，<=0 时使用 runtime.NumCPU()
+}
+
+// ScalingFactors 是 EstimateScaling 的结果：a*A 与 b*B 可以直接比较
+// 两个系数中一个为 1，另一个不大于 1，即总是把信号较强的一侧缩小
+type ScalingFactors struct {
+	Method ScalingMethod
+	A, B   float64
+	Bins   int // 参与估计的 bin 数
+}
+
+// CompareOption 返回把系数应用到 Compare 输入上的选项
+func (s *ScalingFactors) CompareOption() CompareOption {
+	return WithScale(s.A, s.B)
+}
+
+// EstimateScaling 在 a、b 共有（名称和长度都相同）的染色体上随机抽取 bin，按每个 bin 的信号总和估计缩放系数，
+// 用于比较处理组与对照组（a 为处理组，b 为对照组）；没有数据的 bin 按 0 计算
+func EstimateScaling(ctx context.Context, a, b string, method ScalingMethod, opts ScalingOptions) (*ScalingFactors, error) {
+	if method != ScaleSES && method != ScaleMedianRatio {
+		return nil, fmt.Errorf("unknown scaling method %v", method)
+	}
+	if opts.BinSize == 0 {
+		opts.BinSize = DEFAULT_SCALING_BIN_SIZE
+	}
+	if opts.Samples <= 0 {
+		opts.Samples = DEFAULT_SCALING_SAMPLES
+	}
+	files, _, err := bwOpenInputs([]string{a, b})
+	if err != nil {
+		return nil, err
+	}
+	regions := bwSampleBins(files[0], bwCommonChroms(files), opts.BinSize, opts.Samples, opts.Seed)
+	for _, f := range files {
+		CloseBigWig(f)
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("%s and %s have no chromosome in common", a, b)
+	}
+
+	s, err := MultiBigwigSummary(ctx, []string{a, b}, MultiSummaryOptions{Regions: regions, Stat: "sum", Workers: opts.Workers})
+	if err != nil {
+		return nil, err
+	}
+	va, vb := s.Values[0], s.Values[1]
+	for i := range va {
+		if math.IsNaN(va[i]) {
+			va[i] = 0
+		}
+		if math.IsNaN(vb[i]) {
+			vb[i] = 0
+		}
+	}
+	var ratio float64
+	if method == ScaleSES {
+		ratio, err = bwSESRatio(va, vb)
+	} else {
+		ratio, err = bwMedianRatio(va, vb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := &ScalingFactors{Method: method, A: 1, B: ratio, Bins: len(regions)}
+	if ratio > 1 {
+		f.A, f.B = 1/ratio, 1
+	}
+	return f, nil
+}
+
+// bwSampleBins 把 chroms 按 binSize 分箱，bin 总数超过 n 时用 seed 不放回地随机抽取 n 个，
+// 结果按染色体（chroms 中的顺序）和坐标排序，坐标从 0 开始；染色体末端不足一个 bin 宽度的部分不参与抽样
+func bwSampleBins(fp *Bigwig_file_out, chroms []string, binSize uint32, n int, seed uint64) []Region {
+	type chromBins struct {
+		chrom string
+		n     uint64
+	}
+	var all []chromBins
+	var total uint64
+	for _, c := range chroms {
+		l, _ := bwChromLength(fp.bf_fp, c)
+		if k := uint64(l / binSize); k > 0 {
+			all = append(all, chromBins{c, k})
+			total += k
+		}
+	}
+	var picks []uint64
+	if total <= uint64(n) {
+		picks = make([]uint64, total)
+		for i := range picks {
+			picks[i] = uint64(i)
+		}
+	} else {
+		// Floyd 算法：不放回地抽取 n 个全局 bin 编号
+		rng := rand.New(rand.NewPCG(seed, seed))
+		seen := make(map[uint64]bool, n)
+		for j := total - uint64(n); j < total; j++ {
+			t := rng.Uint64N(j + 1)
+			if seen[t] {
+				t = j
+			}
+			seen[t] = true
+			picks = append(picks, t)
+		}
+		slices.Sort(picks)
+	}
+
+	regions := make([]Region, 0, len(picks))
+	ci, base := 0, uint64(0)
+	for _, p := range picks {
+		for p >= base+all[ci].n {
+			base += all[ci].n
+			ci++
+		}
+		s := int((p - base) * uint64(binSize))
+		regions = append(regions, Region{Chrom: all[ci].chrom, Start: s, End: s + int(binSize)})
+	}
+	return regions
+}
+
+// bwSESRatio 按 a 的信号从小到大累加两个文件的信号占比，在两者占比差最大处（a 的富集区之前）
+// 取累计信号之比 a/b 作为背景上的缩放比例
+func bwSESRatio(a, b []float64) (float64, error) {
+	idx := make([]int, len(a))
+	for i := range idx {
+		idx[i] = i
+	}
+	slices.SortFunc(idx, func(i, j int) int {
+		switch {
+		case a[i] < a[j]:
+			return -1
+		case a[i] > a[j]:
+			return 1
+		}
+		return 0
+	})
+	var totalA, totalB float64
+	for i := range a {
+		totalA += a[i]
+		totalB += b[i]
+	}
+	if totalA <= 0 || totalB <= 0 {
+		return 0, errors.New("SES needs positive signal in both files")
+	}
+	var cumA, cumB, bestA, bestB, bestDiff float64
+	for _, i := range idx {
+		cumA += a[i]
+		cumB += b[i]
+		if d := cumB/totalB - cumA/totalA; d > bestDiff {
+			bestDiff, bestA, bestB = d, cumA, cumB
+		}
+	}
+	if bestA <= 0 || bestB <= 0 {
+		return 0, errors.New("SES found no background bins with signal in both files")
+	}
+	return bestA / bestB, nil
+}
+
+// bwMedianRatio 返回两个文件都有信号的 bin 上 a/b 的中位数
+func bwMedianRatio(a, b []float64) (float64, error) {
+	var ratios []float64
+	for i := range a {
+		if a[i] > 0 && b[i] > 0 {
+			ratios = append(ratios, a[i]/b[i])
+		}
+	}
+	if len(ratios) == 0 {
+		return 0, errors.New("median ratio needs bins with signal in both files")
+	}
+	_, median := bwMeanMedian(ratios)
+	return median, nil
+}
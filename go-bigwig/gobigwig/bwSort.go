@@ -0,0 +1,231 @@
+package gobigwig
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bwSortChunk 外部排序时每段在内存中排序的记录数
+const bwSortChunk = 1 << 20
+
+// UnsortedError 表示转换输入中某条记录与上一条记录顺序颠倒或互相重叠
+type UnsortedError struct {
+	Line       int    // 出错记录所在的行号
+	Record     string // 出错的记录
+	PrevLine   int    // 上一条记录所在的行号
+	PrevRecord string // 上一条记录
+	Overlap    bool   // true 表示两条记录重叠，false 表示顺序颠倒
+}
+
+func (e *UnsortedError) Error() string {
+	if e.Overlap {
+		return fmt.Sprintf("record %q overlaps %q on line %d", e.Record, e.PrevRecord, e.PrevLine)
+	}
+	return fmt.Sprintf("record %q sorts before %q on line %d: sort the input with sort -k1,1 -k2,2n or convert with WithExternalSort",
+		e.Record, e.PrevRecord, e.PrevLine)
+}
+
+// bwConvertRecord 是转换时的一条记录及其输入行号
+type bwConvertRecord struct {
+	chrom      string
+	start, end uint32
+	value      float32
+	line       int
+}
+
+func (r bwConvertRecord) String() string {
+	return fmt.Sprintf("%s %d %d %s", r.chrom, r.start, r.end, bwFormatValue(r.value))
+}
+
+// bwRecordLess 按 sort -k1,1 -k2,2n 的顺序比较，位置相同时保持输入顺序
+func bwRecordLess(a, b *bwConvertRecord) bool {
+	if a.chrom != b.chrom {
+		return a.chrom < b.chrom
+	}
+	if a.start != b.start {
+		return a.start < b.start
+	}
+	if a.end != b.end {
+		return a.end < b.end
+	}
+	return a.line < b.line
+}
+
+// bwSortRun 是外部排序中一个已排序的临时文件
+type bwSortRun struct {
+	sc  *bufio.Scanner
+	cur bwConvertRecord
+}
+
+// next 读取下一条记录，到达末尾时返回 false
+func (r *bwSortRun) next() (bool, error) {
+	if !r.sc.Scan() {
+		return false, r.sc.Err()
+	}
+	f := strings.Split(r.sc.Text(), "\t")
+	if len(f) != 5 {
+		return false, fmt.Errorf("corrupt sort run line %q", r.sc.Text())
+	}
+	line, err1 := strconv.Atoi(f[0])
+	start, err2 := strconv.ParseUint(f[2], 10, 32)
+	end, err3 := strconv.ParseUint(f[3], 10, 32)
+	value, err4 := strconv.ParseFloat(f[4], 32)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return false, fmt.Errorf("corrupt sort run line %q", r.sc.Text())
+	}
+	r.cur = bwConvertRecord{f[1], uint32(start), uint32(end), float32(value), line}
+	return true, nil
+}
+
+// bwSortHeap 按各 run 当前记录排序的最小堆
+type bwSortHeap []*bwSortRun
+
+func (h bwSortHeap) Len() int           { return len(h) }
+func (h bwSortHeap) Less(i, j int) bool { return bwRecordLess(&h[i].cur, &h[j].cur) }
+func (h bwSortHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *bwSortHeap) Push(x any)        { *h = append(*h, x.(*bwSortRun)) }
+func (h *bwSortHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// readBedGraphSorted 读取未排序的 bedGraph，按 bwSortChunk 条一段排序后写入 tmpDir 下的临时文件，
+// 再多路归并写入；输入不超过一段时直接在内存中排序。错误信息中的行号仍指向原始输入
+func (c *bwConverter) readBedGraphSorted(r io.Reader, tmpDir string) error {
+	r, err := bwTextInput(r)
+	if err != nil {
+		return err
+	}
+	var runs []*os.File
+	defer func() {
+		for _, f := range runs {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var recs []bwConvertRecord
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if bwSkipTextLine(line) {
+			continue
+		}
+		chrom, start, end, value, err := bwParseBedGraphLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		recs = append(recs, bwConvertRecord{chrom, start, end, value, lineNo})
+		if len(recs) == bwSortChunk {
+			f, err := bwWriteSortRun(recs, tmpDir)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, f)
+			recs = recs[:0]
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		sort.Slice(recs, func(i, j int) bool { return bwRecordLess(&recs[i], &recs[j]) })
+		for i := range recs {
+			if err := c.addRecord(&recs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(recs) > 0 {
+		f, err := bwWriteSortRun(recs, tmpDir)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, f)
+	}
+	recs = nil
+
+	h := make(bwSortHeap, 0, len(runs))
+	for _, f := range runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		run := &bwSortRun{sc: bufio.NewScanner(bufio.NewReaderSize(f, 256*1024))}
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h = append(h, run)
+		}
+	}
+	heap.Init(&h)
+	for h.Len() > 0 {
+		run := h[0]
+		if err := c.addRecord(&run.cur); err != nil {
+			return err
+		}
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(&h, 0)
+		} else {
+			heap.Pop(&h)
+		}
+	}
+	return nil
+}
+
+// addRecord 写入排序后的一条记录，错误带上它在原始输入中的行号
+func (c *bwConverter) addRecord(rec *bwConvertRecord) error {
+	c.line = rec.line
+	if err := c.add(rec.chrom, rec.start, rec.end, rec.value); err != nil {
+		return fmt.Errorf("line %d: %w", rec.line, err)
+	}
+	return nil
+}
+
+// bwWriteSortRun 把 recs 排序后写入 tmpDir 下的临时文件，返回仍处于打开状态的文件
+func bwWriteSortRun(recs []bwConvertRecord, tmpDir string) (*os.File, error) {
+	sort.Slice(recs, func(i, j int) bool { return bwRecordLess(&recs[i], &recs[j]) })
+	f, err := os.CreateTemp(tmpDir, "bwsort-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriterSize(f, 256*1024)
+	var line []byte
+	for _, r := range recs {
+		line = strconv.AppendInt(line[:0], int64(r.line), 10)
+		line = append(line, '\t')
+		line = append(line, r.chrom...)
+		line = append(line, '\t')
+		line = strconv.AppendUint(line, uint64(r.start), 10)
+		line = append(line, '\t')
+		line = strconv.AppendUint(line, uint64(r.end), 10)
+		line = append(line, '\t')
+		line = strconv.AppendFloat(line, float64(r.value), 'g', -1, 32)
+		line = append(line, '\n')
+		w.Write(line) // bufio.Writer 的写错误会保留到 Flush 时返回
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
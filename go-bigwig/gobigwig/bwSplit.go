@@ -0,0 +1,142 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// bwHasData 判断第 tid 条染色体在文件中是否有数据块
+func bwHasData(ctx context.Context, bw *bigWigFile_t, tid int) (bool, error) {
+	blocks, err := bwGetOverlappingBlocks(ctx, bw, bw.Cl.Chrom[tid], 0, bw.Cl.Len[tid])
+	if err != nil {
+		return false, err
+	}
+	return blocks.N > 0, nil
+}
+
+// bwCopyChrom 把 bw 中第 tid 条染色体的全部记录按原来的块类型写入 w
+func bwCopyChrom(ctx context.Context, w *BigWigWriter, bw *bigWigFile_t, tid int) error {
+	chrom := bw.Cl.Chrom[tid]
+	return bwEachBlock(ctx, bw, chrom, 0, bw.Cl.Len[tid], func(hdr BlockHeader, items []Interval) error {
+		return bwRepairAdd(w, bw.Cl, hdr, items)
+	})
+}
+
+// Split 把 src 按染色体拆分为多个 bigWig 文件，写入目录 dir，文件名为 <src 文件名>.<染色体>.bw，
+// 每个文件只包含一条染色体；没有数据的染色体不生成文件。dir 不存在时自动创建。返回生成的文件路径（按染色体顺序）
+// opts 用于创建输出文件，默认沿用 src 是否压缩
+func Split(src, dir string, opts ...WriteOption) ([]string, error) {
+	fp, err := OpenBigWig(src)
+	if err != nil {
+		return nil, err
+	}
+	defer CloseBigWig(fp)
+	bw := fp.bf_fp
+	if err := bwLoadChromList(bw); err != nil {
+		return nil, err
+	}
+	opts = append([]WriteOption{WithCompression(bw.Hdr.bufsize > 0)}, opts...)
+	progress := bwTrackProgress(newWriteOptions(opts).Progress, fp)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	base := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+	var outs []string
+	for tid, chrom := range bw.Cl.Chrom {
+		ok, err := bwHasData(ctx, bw, tid)
+		if err != nil {
+			return outs, err
+		}
+		if !ok {
+			continue
+		}
+		// 染色体名中的路径分隔符不能出现在文件名里
+		name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(chrom)
+		out := filepath.Join(dir, base+"."+name+".bw")
+		w, err := CreateBigWig(out, []string{chrom}, []uint32{bw.Cl.Len[tid]}, opts...)
+		if err != nil {
+			return outs, err
+		}
+		if err := bwCopyChrom(ctx, w, bw, tid); err != nil {
+			w.Close()
+			os.Remove(out)
+			return outs, fmt.Errorf("%s: %w", chrom, err)
+		}
+		if err := w.Close(); err != nil {
+			return outs, err
+		}
+		outs = append(outs, out)
+	}
+	progress.finish()
+	return outs, nil
+}
+
+// Concat 把多个 bigWig 合并为 dst，重新生成染色体树、索引和 zoom 层级
+// 染色体列表为各输入的并集（同名染色体长度必须一致），每条染色体的数据只能来自一个输入，
+// 例如 Split 的输出或按染色体并行生成的文件；opts 用于创建 dst，默认沿用第一个输入是否压缩
+func Concat(inputs []string, dst string, opts ...WriteOption) error {
+	if len(inputs) == 0 {
+		return errors.New("no input files")
+	}
+	ctx := context.Background()
+	files, lengths, err := bwOpenInputs(inputs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, f := range files {
+			CloseBigWig(f)
+		}
+	}()
+
+	type source struct {
+		file int // inputs 中的下标
+		tid  int
+	}
+	sources := make(map[string]source)
+	for i, fp := range files {
+		bw := fp.bf_fp
+		for tid, chrom := range bw.Cl.Chrom {
+			ok, err := bwHasData(ctx, bw, tid)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if prev, dup := sources[chrom]; dup {
+				return fmt.Errorf("chromosome %s has data in both %s and %s", chrom, inputs[prev.file], inputs[i])
+			}
+			sources[chrom] = source{i, tid}
+		}
+	}
+
+	opts = append([]WriteOption{WithCompression(files[0].bf_fp.Hdr.bufsize > 0)}, opts...)
+	w, err := CreateBigWigFromSizes(dst, lengths, opts...)
+	if err != nil {
+		return err
+	}
+	progress := bwTrackProgress(w.opts.Progress, files...)
+	// 按 tid（染色体名排序）的顺序写入
+	for _, chrom := range w.bf_fp.Cl.Chrom {
+		s, ok := sources[chrom]
+		if !ok {
+			continue
+		}
+		if err := bwCopyChrom(ctx, w, files[s.file].bf_fp, s.tid); err != nil {
+			w.Close()
+			return fmt.Errorf("%s: %s: %w", inputs[s.file], chrom, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	progress.finish()
+	return nil
+}
@@ -0,0 +1,34 @@
+package gobigwig
+
+import "fmt"
+
+// statReadAhead Stat 读取远程文件时每次请求的字节数，足够覆盖文件头和 zoom 头
+const statReadAhead = 4096
+
+// FileInfo 是 Stat 返回的文件元数据
+type FileInfo struct {
+	Header
+	Path       string
+	Compressed bool // 数据块是否经过 zlib 压缩
+}
+
+// Stat 只读取文件头、zoom 头和全局 summary（远程文件通常只需一两个几 KB 的请求），
+// 不读取染色体树和索引，适合对成千上万个文件做编目
+func Stat(path string, opts ...OpenOption) (FileInfo, error) {
+	u, err := Open(path, append(opts[:len(opts):len(opts)], WithWholeFileThreshold(0))...)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer u.Close()
+	u.readAhead = statReadAhead
+
+	fp := &bigWigFile_t{URL: u}
+	if err := bwHdrRead(fp); err != nil {
+		return FileInfo{}, fmt.Errorf("读取文件头失败: %w", err)
+	}
+	return FileInfo{
+		Header:     *bwExportHeader(fp),
+		Path:       path,
+		Compressed: fp.Hdr.bufsize > 0,
+	}, nil
+}
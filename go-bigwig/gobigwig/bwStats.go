@@ -0,0 +1,138 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+)
+
+// Weighting 决定 bin 内没有数据的碱基如何参与统计
+type Weighting int
+
+const (
+	WeightDefault    Weighting = iota // 使用打开文件时 WithWeighting 的设置，未设置时为 CoverageWeighted
+	CoverageWeighted                  // 只统计有数据的碱基，没有数据的 bin 为 NaN（pyBigWig 的默认行为）
+	RegionWeighted                    // 没有数据的碱基按 0 计入：mean 为 sum 除以 bin 宽度，min/max/std 包含这些 0
+)
+
+func (w Weighting) String() string {
+	switch w {
+	case WeightDefault:
+		return "default"
+	case CoverageWeighted:
+		return "coverage"
+	case RegionWeighted:
+		return "region"
+	}
+	return fmt.Sprintf("Weighting(%d)", int(w))
+}
+
+// bwResolveWeighting 把 WeightDefault 解析为 bw 打开时的设置
+func bwResolveWeighting(bw *bigWigFile_t, w Weighting) Weighting {
+	if w == WeightDefault {
+		w = bw.Opts.Weighting
+	}
+	if w == WeightDefault {
+		w = CoverageWeighted
+	}
+	return w
+}
+
+// StatsOptions 是 Stats 的参数
+type StatsOptions struct {
+	Type      string    // mean（默认）、min、max、sum、std，或 coverage（有数据碱基的比例）
+	NBins     int       // 把区间均分为多少个 bin，<=0 时为 1
+	Exact     bool      // 使用原始记录计算精确值，而不是 zoom 层级的汇总
+	ZoomLevel int       // 使用第 ZoomLevel 个 zoom 层级（从 1 开始，与 Header.ZoomLevels 的顺序一致），0 表示自动选择
+	Weighting Weighting // 没有数据的碱基是否按 0 计入，默认使用打开文件时的设置
+}
+
+// Stats 把 chrom:[start, end) 均分为 opts.NBins 个 bin，返回每个 bin 的统计量，相当于 pyBigWig 的 bw.stats
+// 默认选择缩放倍数不超过每个 bin 宽度一半的最粗 zoom 层级，没有合适的层级或设置 Exact 时读取原始记录；
+// zoom 汇总按重叠比例分摊到 bin 中，因此是近似值。CoverageWeighted 时没有数据的 bin 除 coverage 外为 NaN
+func (fp *Bigwig_file_out) Stats(ctx context.Context, chrom string, start, end int, opts StatsOptions) ([]float64, error) {
+	if opts.Type == "" {
+		opts.Type = "mean"
+	}
+	if !bwValidStat(opts.Type) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownStat, opts.Type)
+	}
+	if opts.NBins <= 0 {
+		opts.NBins = 1
+	}
+	start, end, err := bwCheckRange(fp.bf_fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if opts.NBins > end-start {
+		return nil, fmt.Errorf("%d bins exceed the %d bases of %s:%d-%d", opts.NBins, end-start, chrom, start, end)
+	}
+	bw := fp.bf_fp
+	zoomIdx := -1
+	if !opts.Exact && len(bw.Hdr.ZoomHdrs) > 0 {
+		zhdr := bw.Hdr.ZoomHdrs[0]
+		if opts.ZoomLevel > 0 {
+			if opts.ZoomLevel > len(zhdr.Level) {
+				return nil, fmt.Errorf("zoom level %d does not exist (file has %d)", opts.ZoomLevel, len(zhdr.Level))
+			}
+			zoomIdx = opts.ZoomLevel - 1
+		} else {
+			zoomIdx = bwSelectBestZoomLevel(zhdr, uint32((end-start)/opts.NBins/2))
+		}
+	}
+	w := bwResolveWeighting(bw, opts.Weighting)
+	query := fmt.Sprintf("stats\x00%s\x00%d\x00%d\x00%d\x00%s\x00%d\x00%d", chrom, start, end, opts.NBins, bwCanonicalStat(opts.Type), zoomIdx, w)
+	return bwCachedResult(fp, query, func() ([]float64, error) {
+		accs := make([]bwStatAcc, opts.NBins)
+		var err error
+		if zoomIdx >= 0 {
+			err = bwZoomStats(ctx, bw, zoomIdx, chrom, uint32(start), uint32(end), accs)
+		} else {
+			err = bwExactStats(ctx, bw, chrom, start, end, accs)
+		}
+		if err != nil {
+			return nil, err
+		}
+		out := make([]float64, opts.NBins)
+		for i := range accs {
+			s, e := bwBinEdges(start, end, opts.NBins, i)
+			out[i] = accs[i].result(opts.Type, uint32(e-s), w)
+		}
+		return out, nil
+	}, bwCloneFloats, bwFloatsSize)
+}
+
+// bwExactStats 顺序读取 [start, end) 内的记录，累计到 accs 对应的 bin 中
+func bwExactStats(ctx context.Context, bw *bigWigFile_t, chrom string, start, end int, accs []bwStatAcc) error {
+	cur := newBWIntervalCursor(ctx, bw, chrom, uint32(start), uint32(end))
+	defer cur.close()
+	for i := range accs {
+		s, e := bwBinEdges(start, end, len(accs), i)
+		cur.accumulate(&accs[i], uint32(s), uint32(e))
+	}
+	return cur.err
+}
+
+// bwZoomStats 用第 zoomIdx 个 zoom 层级的汇总填充 accs
+func bwZoomStats(ctx context.Context, bw *bigWigFile_t, zoomIdx int, chrom string, start, end uint32, accs []bwStatAcc) error {
+	summaries, err := bwGetSummariesInRegion(ctx, bw, zoomIdx, chrom, start, end)
+	if err != nil {
+		return err
+	}
+	bins, err := bwBinSummaries(ctx, summaries, start, end, len(accs))
+	if err != nil {
+		return err
+	}
+	for i, b := range bins {
+		if b.ValidCount == 0 {
+			continue
+		}
+		accs[i] = bwStatAcc{
+			n:     b.ValidCount,
+			sum:   b.SumData,
+			sumSq: b.SumSquares,
+			min:   b.MinVal,
+			max:   b.MaxVal,
+		}
+	}
+	return nil
+}
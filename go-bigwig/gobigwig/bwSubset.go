@@ -0,0 +1,96 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Subset 把 src 中与 regions（坐标从 0 开始）重叠的数据写入新的 bigWig 文件 dst，
+// 用于截取某个位点附近的小文件以便分享；dst 沿用 src 的染色体列表，并重新生成索引、zoom 层级和 summary
+// 完全落在区间内的数据块按原来的类型（bedGraph/variableStep/fixedStep）写入，
+// 跨越区间边界的块截断到区间内后以 bedGraph 形式写入；重叠或相邻的区间会被合并
+// opts 用于创建 dst，默认沿用 src 是否压缩
+func Subset(src, dst string, regions []Region, opts ...WriteOption) error {
+	fp, err := OpenBigWig(src)
+	if err != nil {
+		return err
+	}
+	defer CloseBigWig(fp)
+	bw := fp.bf_fp
+	if err := bwLoadChromList(bw); err != nil {
+		return err
+	}
+
+	rs, err := bwSubsetRegions(bw, regions)
+	if err != nil {
+		return err
+	}
+	w, err := CreateBigWig(dst, bw.Cl.Chrom, bw.Cl.Len, append([]WriteOption{WithCompression(bw.Hdr.bufsize > 0)}, opts...)...)
+	if err != nil {
+		return err
+	}
+	progress := bwTrackProgress(w.opts.Progress, fp)
+	for _, r := range rs {
+		s, e := uint32(r.Start), uint32(r.End)
+		err = bwEachBlock(context.Background(), bw, r.Chrom, s, e, func(hdr BlockHeader, items []Interval) error {
+			inside := true
+			for _, it := range items {
+				if it.Start < s || it.End > e {
+					inside = false
+					break
+				}
+			}
+			if inside {
+				return bwRepairAdd(w, bw.Cl, hdr, items)
+			}
+			clipped := make([]Interval, len(items))
+			for i, it := range items {
+				clipped[i] = Interval{Start: max32(it.Start, s), End: min32(it.End, e), Value: it.Value}
+			}
+			return bwRepairAdd(w, bw.Cl, BlockHeader{ChromID: hdr.ChromID, Type: bwTypeBedGraph}, clipped)
+		})
+		if err != nil {
+			w.Close()
+			return fmt.Errorf("%s:%d-%d: %w", r.Chrom, r.Start, r.End, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	progress.finish()
+	return nil
+}
+
+// bwSubsetRegions 校验 regions 并按文件中的染色体顺序和起点排序，合并重叠或相邻的区间
+func bwSubsetRegions(bw *bigWigFile_t, regions []Region) ([]Region, error) {
+	type tidRegion struct {
+		tid uint32
+		Region
+	}
+	rs := make([]tidRegion, 0, len(regions))
+	for _, r := range regions {
+		s, e, err := bwCheckRange(bw, r.Chrom, r.Start, r.End)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, tidRegion{bwGetTid(bw, r.Chrom), Region{Chrom: r.Chrom, Start: s, End: e}})
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].tid != rs[j].tid {
+			return rs[i].tid < rs[j].tid
+		}
+		return rs[i].Start < rs[j].Start
+	})
+	var out []Region
+	for _, r := range rs {
+		if n := len(out); n > 0 && out[n-1].Chrom == r.Chrom && r.Start <= out[n-1].End {
+			if r.End > out[n-1].End {
+				out[n-1].End = r.End
+			}
+			continue
+		}
+		out = append(out, r.Region)
+	}
+	return out, nil
+}
@@ -0,0 +1,158 @@
+package gobigwig
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// TableOptions 表示 TSV/CSV 导出的格式
+type TableOptions struct {
+	Delimiter byte   // 列分隔符，默认制表符
+	Header    bool   // 是否写出表头行（默认开启）
+	NA        string // 缺失值（NaN）的写法，默认 "NaN"，pandas 可直接识别
+}
+
+// TableOption 用于修改 TableOptions 的函数式选项
+type TableOption func(*TableOptions)
+
+// WithDelimiter 设置列分隔符，例如 ',' 输出 CSV
+func WithDelimiter(d byte) TableOption {
+	return func(o *TableOptions) { o.Delimiter = d }
+}
+
+// WithHeader 控制是否写出表头行
+func WithHeader(enabled bool) TableOption {
+	return func(o *TableOptions) { o.Header = enabled }
+}
+
+// WithNA 设置缺失值的写法，例如 "" 或 "NA"（便于 Excel 或 R 读取）
+func WithNA(s string) TableOption {
+	return func(o *TableOptions) { o.NA = s }
+}
+
+func newTableOptions(opts []TableOption) TableOptions {
+	o := TableOptions{Delimiter: '\t', Header: true, NA: "NaN"}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// TableRow 是导出表中的一行：区间加上一个或多个数值列
+type TableRow struct {
+	Chrom  string
+	Start  int
+	End    int
+	Values []float64
+}
+
+// WriteTable 把 rows 写成 chrom/start/end 加 columns 各列的分隔文本表，
+// columns 为数值列的列名，每行的 Values 长度应与之相同
+func WriteTable(w io.Writer, columns []string, rows []TableRow, opts ...TableOption) error {
+	o := newTableOptions(opts)
+	out := bufio.NewWriter(w)
+	var line []byte
+	if o.Header {
+		line = append(line, "chrom"...)
+		for _, c := range append([]string{"start", "end"}, columns...) {
+			line = append(line, o.Delimiter)
+			line = append(line, c...)
+		}
+		line = append(line, '\n')
+		if _, err := out.Write(line); err != nil {
+			return err
+		}
+	}
+	for _, r := range rows {
+		line = append(line[:0], r.Chrom...)
+		line = append(line, o.Delimiter)
+		line = strconv.AppendInt(line, int64(r.Start), 10)
+		line = append(line, o.Delimiter)
+		line = strconv.AppendInt(line, int64(r.End), 10)
+		for _, v := range r.Values {
+			line = append(line, o.Delimiter)
+			if math.IsNaN(v) {
+				line = append(line, o.NA...)
+			} else if float64(float32(v)) == v {
+				// 来自 float32 的值按 float32 的最短形式输出，避免 0.30000001192092896 这类尾数
+				line = strconv.AppendFloat(line, v, 'g', -1, 32)
+			} else {
+				line = strconv.AppendFloat(line, v, 'g', -1, 64)
+			}
+		}
+		line = append(line, '\n')
+		if _, err := out.Write(line); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// bwBinEdges 返回 [start, end) 均分为 numBins 份时第 i 个 bin 的边界，与 zoom 分箱的计算方式一致
+func bwBinEdges(start, end, numBins, i int) (int, int) {
+	binSize := float64(end-start) / float64(numBins)
+	return start + int(uint32(float64(i)*binSize)), start + int(uint32(float64(i+1)*binSize))
+}
+
+// binnedRows 查询 r 的 numBins 个 zoom 分箱值，同时返回校验后从 0 开始的区间
+func (fp *Bigwig_file_out) binnedRows(ctx context.Context, r Region, numBins int) ([]float32, int, int, error) {
+	if numBins <= 0 {
+		return nil, 0, 0, fmt.Errorf("invalid bin count: %d", numBins)
+	}
+	start, end, err := bwCheckRange(fp.bf_fp, r.Chrom, r.Start, r.End)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	reduction := (end - start) / numBins
+	values, err := fp.zoomValues(ctx, r.Chrom, start, end, numBins, true, reduction)
+	return values, start, end, err
+}
+
+// WriteBinnedTable 把 chrom:[start, end) 分成 numBins 个 bin，以每个 bin 一行
+// （chrom、start、end、value）的形式写出 zoom 分箱的平均值，可直接用 pandas.read_csv 读取
+// 坐标使用打开时设置的约定
+func (fp *Bigwig_file_out) WriteBinnedTable(ctx context.Context, w io.Writer, chrom string, start, end, numBins int, opts ...TableOption) error {
+	values, s, e, err := fp.binnedRows(ctx, Region{Chrom: chrom, Start: start, End: end}, numBins)
+	if err != nil {
+		return err
+	}
+	coords := fp.bf_fp.Opts.Coordinates
+	rows := make([]TableRow, len(values))
+	for i, v := range values {
+		bs, be := bwBinEdges(s, e, numBins, i)
+		bs, be = coords.FromZeroBased(bs, be)
+		rows[i] = TableRow{chrom, bs, be, []float64{float64(v)}}
+	}
+	return WriteTable(w, []string{"value"}, rows, opts...)
+}
+
+// WriteMatrixTable 对每个区间取 numBins 个 zoom 分箱值，每个区间写成一行
+// （chrom、start、end、bin_0 … bin_{numBins-1}），构成区间 × bin 的矩阵
+func (fp *Bigwig_file_out) WriteMatrixTable(ctx context.Context, w io.Writer, regions []Region, numBins int, opts ...TableOption) error {
+	if numBins <= 0 {
+		return fmt.Errorf("invalid bin count: %d", numBins)
+	}
+	columns := make([]string, numBins)
+	for i := range columns {
+		columns[i] = "bin_" + strconv.Itoa(i)
+	}
+	rows := make([]TableRow, len(regions))
+	for i, r := range regions {
+		values, _, _, err := fp.binnedRows(ctx, r, numBins)
+		if err != nil {
+			return err
+		}
+		row := TableRow{r.Chrom, r.Start, r.End, make([]float64, len(values))}
+		for j, v := range values {
+			row.Values[j] = float64(v)
+		}
+		rows[i] = row
+	}
+	return WriteTable(w, columns, rows, opts...)
+}
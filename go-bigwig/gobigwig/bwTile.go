@@ -0,0 +1,66 @@
+package gobigwig
+
+import (
+	"context"
+	"fmt"
+)
+
+// bwTileKey 区分同一文件中不同染色体、宽度和分箱数的 tile
+type bwTileKey struct {
+	tid   uint32
+	width uint32
+	bins  int
+}
+
+// bwTileOverhead 估算一个缓存的 RegionTrack 除逐 bin 数组以外占用的字节数
+const bwTileOverhead = 160
+
+// Tile 返回染色体 chrom 上第 tileIndex 个宽 tileWidth 的 tile（从 0 开始的 [i*w, (i+1)*w)）
+// 分成 bins 个 bin 后的结果，对应网页基因组浏览器按固定 tile 请求数据的方式
+// 每个 bin 宽度相同：最后一个 tile 超出染色体末端的 bin 为 null，不受 RangePolicy 影响；
+// 与 RegionTrack 相同，只使用 reduction 不超过 bin 宽度的 zoom 层级，否则读取原始数据。设置了内存预算时结果进入 LRU 缓存，
+// 重复请求同一 tile 不再读取文件，因此调用方不能修改返回值
+func (fp *Bigwig_file_out) Tile(chrom string, tileWidth uint32, tileIndex uint64, bins int) (*RegionTrack, error) {
+	return fp.TileContext(context.Background(), chrom, tileWidth, tileIndex, bins)
+}
+
+// TileContext 与 Tile 相同，ctx 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) TileContext(ctx context.Context, chrom string, tileWidth uint32, tileIndex uint64, bins int) (*RegionTrack, error) {
+	if tileWidth == 0 {
+		return nil, fmt.Errorf("%w: tile width 0", ErrInvalidRange)
+	}
+	if bins <= 0 || uint32(bins) > tileWidth {
+		return nil, fmt.Errorf("invalid bin count %d for tile width %d", bins, tileWidth)
+	}
+	bw := fp.bf_fp
+	length, ok := bwChromLength(bw, chrom)
+	if !ok {
+		return nil, fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	start := tileIndex * uint64(tileWidth)
+	if tileIndex >= (uint64(length)+uint64(tileWidth)-1)/uint64(tileWidth) || start+uint64(tileWidth) > 1<<32-1 {
+		return nil, fmt.Errorf("%w: tile %d of width %d on %s (chromosome length %d)", ErrOutOfRange, tileIndex, tileWidth, chrom, length)
+	}
+
+	key := bwCacheKey{
+		owner:  bw.cacheOwner,
+		kind:   bwCacheTile,
+		offset: tileIndex,
+		tile:   bwTileKey{tid: bwGetTid(bw, chrom), width: tileWidth, bins: bins},
+	}
+	if v, ok := bw.budget.get(key); ok {
+		bw.URL.metrics.CacheHit()
+		return v.(*RegionTrack), nil
+	}
+	if bw.budget != nil {
+		bw.URL.metrics.CacheMiss()
+	}
+	s := uint32(start)
+	e := s + tileWidth
+	t, err := fp.binTrack(ctx, chrom, s, e, min32(e, length), bins)
+	if err != nil {
+		return nil, err
+	}
+	bw.budget.put(key, t, bwTileOverhead+int64(bins)*20)
+	return t, nil
+}
@@ -0,0 +1,59 @@
+package gobigwig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// TransformFunc 返回记录 chrom:[start, end) 变换后的值，返回 NaN 表示删除该记录
+type TransformFunc func(chrom string, start, end uint32, v float32) float32
+
+// Transform 逐条读取 src 的记录，用 fn 变换值后写入 dst，重新生成数据块、索引、zoom 层级和汇总信息，
+// 可用于取对数、截断或把黑名单区域置 0，而不必先导出为 bedGraph
+// 相邻且变换后值相同的记录合并为一条；opts 用于创建 dst，默认沿用 src 是否压缩
+func Transform(src, dst string, fn TransformFunc, opts ...WriteOption) error {
+	return TransformContext(context.Background(), src, dst, fn, opts...)
+}
+
+// TransformContext 与 Transform 相同，ctx 被取消时返回 ctx.Err() 并删除 dst
+func TransformContext(ctx context.Context, src, dst string, fn TransformFunc, opts ...WriteOption) error {
+	if fn == nil {
+		return errors.New("nil transform function")
+	}
+	files, sizes, err := bwOpenInputs([]string{src})
+	if err != nil {
+		return err
+	}
+	defer CloseBigWig(files[0])
+	bw := files[0].bf_fp
+	opts = append([]WriteOption{WithCompression(bw.Hdr.bufsize > 0)}, opts...)
+	c, err := newBWConverter(sizes, dst, opts)
+	if err != nil {
+		return err
+	}
+	c.progress = bwTrackProgress(c.w.opts.Progress, files...)
+	return c.finish(c.transform(ctx, bw, fn))
+}
+
+// transform 逐条染色体写出 fn 变换后的记录
+func (c *bwConverter) transform(ctx context.Context, bw *bigWigFile_t, fn TransformFunc) error {
+	for tid, chrom := range c.w.bf_fp.Cl.Chrom {
+		out := &bwPendingRecord{c: c, chrom: chrom}
+		err := bwEachInterval(ctx, bw, chrom, 0, c.w.bf_fp.Cl.Len[tid], func(s, e uint32, v float32) error {
+			v = fn(chrom, s, e, v)
+			if math.IsNaN(float64(v)) {
+				return nil
+			}
+			return out.add(s, e, v)
+		})
+		if err == nil {
+			err = out.flush()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", chrom, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,502 @@
+package gobigwig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// VerifyLevel 决定 Verify 检查的深度，每一级都包含前一级的检查
+type VerifyLevel int
+
+const (
+	VerifyHeader VerifyLevel = iota // magic、文件头与 zoom 头中的偏移、染色体树
+	VerifyIndex                     // 主索引和每个 zoom 索引的全部节点与叶子
+	VerifyData                      // 解压并解析每个数据块，重算 summary 并与文件中记录的比较
+)
+
+// verifyMaxIssues 报告中最多记录的问题数，达到后停止检查
+const verifyMaxIssues = 1000
+
+// IssueKind 是 VerifyIssue 的类别
+type IssueKind int
+
+const (
+	IssueFormat    IssueKind = iota // 文件头、zoom 头或染色体树不合法
+	IssueTruncated                  // 文件被截断：引用的结构超出文件末尾或读取时遇到 EOF
+	IssueIndex                      // 主索引或 zoom 索引损坏
+	IssueData                       // 数据块或 zoom 块无法解压、解析或与索引不符
+	IssueSummary                    // 文件中记录的 summary 与数据不符
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case IssueFormat:
+		return "format"
+	case IssueTruncated:
+		return "truncated"
+	case IssueIndex:
+		return "index"
+	case IssueData:
+		return "data"
+	case IssueSummary:
+		return "summary"
+	}
+	return fmt.Sprintf("IssueKind(%d)", int(k))
+}
+
+// MarshalText 使 IssueKind 在 JSON 中以名称输出
+func (k IssueKind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+// VerifyIssue 是 Verify 发现的一个问题
+type VerifyIssue struct {
+	Section string // header、zoom、chromTree、index、zoomIndex、data、summary
+	Offset  uint64 // 问题所在结构的文件偏移，未知时为 0
+	Message string
+	Kind    IssueKind
+}
+
+func (i VerifyIssue) String() string {
+	if i.Offset > 0 {
+		return fmt.Sprintf("%s@%d: %s", i.Section, i.Offset, i.Message)
+	}
+	return i.Section + ": " + i.Message
+}
+
+// Report 是 Verify 的检查结果
+type Report struct {
+	Path         string
+	Level        VerifyLevel
+	FileSize     int64   // 文件大小，远程文件无法得知时为 -1
+	Header       *Header // 文件头无法解析时为 nil
+	Chroms       int     // 染色体树中的染色体数
+	Blocks       uint64  // 主索引叶子指向的数据块数
+	ZoomBlocks   uint64  // 所有 zoom 索引叶子指向的块数
+	Items        uint64  // 数据块中的记录数（仅 VerifyData）
+	Issues       []VerifyIssue
+	IssuesCapped bool // 问题数达到 verifyMaxIssues，检查提前结束（与文件截断 IssueTruncated 无关）
+}
+
+// OK 报告是否没有发现任何问题
+func (r *Report) OK() bool { return len(r.Issues) == 0 }
+
+// Has 报告是否发现了 kind 类别的问题
+func (r *Report) Has(kind IssueKind) bool {
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// bwLeaf 是 R 树叶子中的一项
+type bwLeaf struct {
+	chrStart, baseStart, chrEnd, baseEnd uint32
+	offset, size                         uint64
+}
+
+// bwVerifier 保存一次 Verify 的状态
+type bwVerifier struct {
+	fp      *bigWigFile_t
+	r       *Report
+	chroms  *chromList
+	eof     bool           // 上一次 inFile 因超出文件末尾而失败，下一条问题归为 IssueTruncated
+	summary *bwFileSummary // data 从所有数据块重算的 summary，有块无法读取时为 nil
+}
+
+// bwFileSummary 是文件 summary 段的内容
+type bwFileSummary struct {
+	covered             uint64
+	minVal, maxVal      float64
+	sumData, sumSquares float64
+}
+
+func (v *bwVerifier) issue(section string, offset uint64, format string, args ...any) {
+	if v.r.IssuesCapped {
+		return
+	}
+	kind := IssueFormat
+	switch section {
+	case "index", "zoomIndex":
+		kind = IssueIndex
+	case "data", "zoomData":
+		kind = IssueData
+	case "summary":
+		kind = IssueSummary
+	}
+	if v.eof {
+		kind, v.eof = IssueTruncated, false
+	}
+	for _, a := range args {
+		if err, ok := a.(error); ok && (errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)) {
+			kind = IssueTruncated
+		}
+	}
+	v.r.Issues = append(v.r.Issues, VerifyIssue{section, offset, fmt.Sprintf(format, args...), kind})
+	if len(v.r.Issues) >= verifyMaxIssues {
+		v.r.IssuesCapped = true
+	}
+}
+
+// inFile 判断 [offset, offset+size) 是否位于文件内，文件大小未知时只检查文件头之后
+func (v *bwVerifier) inFile(offset, size uint64) bool {
+	if offset < bwHeaderSize || offset+size < offset {
+		return false
+	}
+	if v.r.FileSize >= 0 && offset+size > uint64(v.r.FileSize) {
+		v.eof = true
+		return false
+	}
+	return true
+}
+
+// Verify 检查 path 指向的 bigWig 文件的完整性，相当于 bigWigInfo 的校验模式
+// 格式问题记录在 Report.Issues 中，只有文件无法打开时才返回 error
+func Verify(path string, level VerifyLevel, opts ...OpenOption) (Report, error) {
+	r := Report{Path: path, Level: level, FileSize: -1}
+	_, err := bwVerify(&r, opts)
+	return r, err
+}
+
+// bwVerify 按 r.Path 和 r.Level 做检查并把结果写入 r，返回检查使用的 bwVerifier
+func bwVerify(r *Report, opts []OpenOption) (*bwVerifier, error) {
+	u, err := Open(r.Path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer u.Close()
+	r.FileSize = bwFileSize(u)
+
+	v := &bwVerifier{fp: &bigWigFile_t{URL: u, Opts: newOpenOptions(opts)}, r: r}
+	if !v.header() || r.IssuesCapped {
+		return v, nil
+	}
+	v.chromTree()
+	if r.Level < VerifyIndex || r.IssuesCapped {
+		return v, nil
+	}
+
+	hdr := v.fp.Hdr
+	leaves := v.index("index", hdr.indexoffset, hdr.dataOffset, hdr.indexoffset)
+	r.Blocks = uint64(len(leaves))
+	v.blockCount(uint64(len(leaves)))
+
+	var zoomLeaves [][]bwLeaf
+	if len(hdr.ZoomHdrs) > 0 {
+		z := hdr.ZoomHdrs[0]
+		for i := range z.Level {
+			if r.IssuesCapped {
+				return v, nil
+			}
+			zl := v.index("zoomIndex", z.IndexOffset[i], z.DataOffset[i], z.IndexOffset[i])
+			r.ZoomBlocks += uint64(len(zl))
+			zoomLeaves = append(zoomLeaves, zl)
+		}
+	}
+	if r.Level < VerifyData || r.IssuesCapped {
+		return v, nil
+	}
+
+	v.data(leaves)
+	for _, zl := range zoomLeaves {
+		v.zoomData(zl)
+	}
+	return v, nil
+}
+
+// bwFileSize 返回文件大小，无法得知时返回 -1
+func bwFileSize(u *URL) int64 {
+	if u.data != nil {
+		return int64(len(u.data))
+	}
+	if f, ok := u.rs.(*os.File); ok {
+		if st, err := f.Stat(); err == nil {
+			return st.Size()
+		}
+	}
+	return -1
+}
+
+// header 检查 magic、文件头中的偏移和 zoom 头，文件头无法解析时返回 false
+func (v *bwVerifier) header() bool {
+	fp := v.fp
+	if err := bwHdrRead(fp); err != nil {
+		v.eof = v.r.FileSize >= 0 && v.r.FileSize < bwHeaderSize
+		v.issue("header", 0, "%v", err)
+		return false
+	}
+	v.r.Header = bwExportHeader(fp)
+	hdr := fp.Hdr
+
+	offsets := []struct {
+		name     string
+		off      uint64
+		size     uint64
+		required bool
+	}{
+		{"chromTreeOffset", hdr.ctoffset, 32, true},
+		{"dataOffset", hdr.dataOffset, 8, true},
+		{"indexOffset", hdr.indexoffset, 48, true},
+		{"autoSqlOffset", hdr.sqloffset, 1, false},
+		{"totalSummaryOffset", hdr.summaryoffset, bwSummarySize, false},
+		{"extensionOffset", hdr.extensionoffset, 2, false},
+	}
+	for _, o := range offsets {
+		if o.off == 0 && !o.required {
+			continue
+		}
+		if !v.inFile(o.off, o.size) {
+			v.issue("header", 0, "%s %d is outside the file", o.name, o.off)
+		}
+	}
+	if hdr.ctoffset >= hdr.dataOffset {
+		v.issue("header", 0, "chromTreeOffset %d is not before dataOffset %d", hdr.ctoffset, hdr.dataOffset)
+	}
+	if hdr.dataOffset >= hdr.indexoffset {
+		v.issue("header", 0, "dataOffset %d is not before indexOffset %d", hdr.dataOffset, hdr.indexoffset)
+	}
+	if hdr.fieldCount != 0 || hdr.definedFieldCount != 0 {
+		v.issue("header", 0, "bigWig file has fieldCount %d / definedFieldCount %d, expected 0", hdr.fieldCount, hdr.definedFieldCount)
+	}
+
+	if len(hdr.ZoomHdrs) == 0 {
+		return true
+	}
+	z := hdr.ZoomHdrs[0]
+	for i := range z.Level {
+		off := uint64(bwHeaderSize + i*bwZoomHeaderSize)
+		if i > 0 && z.Level[i] <= z.Level[i-1] {
+			v.issue("zoom", off, "level %d reduction %d is not larger than the previous level's %d", i, z.Level[i], z.Level[i-1])
+		}
+		if !v.inFile(z.DataOffset[i], 4) {
+			v.issue("zoom", off, "level %d dataOffset %d is outside the file", i, z.DataOffset[i])
+		}
+		if !v.inFile(z.IndexOffset[i], 48) {
+			v.issue("zoom", off, "level %d indexOffset %d is outside the file", i, z.IndexOffset[i])
+		}
+		if z.DataOffset[i] < hdr.indexoffset {
+			v.issue("zoom", off, "level %d dataOffset %d lies before the main index", i, z.DataOffset[i])
+		}
+		if z.DataOffset[i] >= z.IndexOffset[i] {
+			v.issue("zoom", off, "level %d dataOffset %d is not before its indexOffset %d", i, z.DataOffset[i], z.IndexOffset[i])
+		}
+	}
+	return true
+}
+
+// chromTree 读取染色体树并检查名称与长度
+func (v *bwVerifier) chromTree() {
+	cl, err := bwReadchromList(v.fp)
+	if err != nil {
+		v.issue("chromTree", v.fp.Hdr.ctoffset, "%v", err)
+		return
+	}
+	v.chroms = cl
+	v.r.Chroms = len(cl.Chrom)
+	seen := make(map[string]bool, len(cl.Chrom))
+	for i, name := range cl.Chrom {
+		switch {
+		case name == "":
+			v.issue("chromTree", v.fp.Hdr.ctoffset, "chrom %d has an empty name", i)
+		case seen[name]:
+			v.issue("chromTree", v.fp.Hdr.ctoffset, "duplicate chrom name %q", name)
+		}
+		seen[name] = true
+		if cl.Len[i] == 0 {
+			v.issue("chromTree", v.fp.Hdr.ctoffset, "chrom %q has zero length", name)
+		}
+	}
+}
+
+// index 遍历 offset 处的 R 树，检查每个节点并返回全部叶子项
+// 叶子指向的块必须位于 [lo, hi) 之内
+func (v *bwVerifier) index(section string, offset, lo, hi uint64) []bwLeaf {
+	idx, err := readRTreeIdx(v.fp, offset)
+	if err != nil {
+		v.issue(section, offset, "%v", err)
+		return nil
+	}
+	var leaves []bwLeaf
+	visited := make(map[uint64]bool)
+	var walk func(off uint64, depth int)
+	walk = func(off uint64, depth int) {
+		if v.r.IssuesCapped {
+			return
+		}
+		if visited[off] || depth > 64 {
+			v.issue(section, off, "R-tree node is reachable more than once or nested too deep")
+			return
+		}
+		visited[off] = true
+		if !v.inFile(off, 4) {
+			v.issue(section, off, "R-tree node is outside the file")
+			return
+		}
+		node, err := bwGetRTreeNode(v.fp, off)
+		if err != nil {
+			v.issue(section, off, "%v", err)
+			return
+		}
+		if node.NChildren == 0 {
+			v.issue(section, off, "R-tree node has no children")
+		}
+		for i := 0; i < int(node.NChildren); i++ {
+			v.checkRange(section, off, node.ChrIdxStart[i], node.BaseStart[i], node.ChrIdxEnd[i], node.BaseEnd[i])
+			if node.IsLeaf == 0 {
+				walk(node.DataOffset[i], depth+1)
+				continue
+			}
+			l := bwLeaf{node.ChrIdxStart[i], node.BaseStart[i], node.ChrIdxEnd[i], node.BaseEnd[i], node.DataOffset[i], node.Size[i]}
+			switch {
+			case l.size == 0:
+				v.issue(section, off, "leaf %d points to an empty block at %d", i, l.offset)
+			case l.offset < lo || l.offset+l.size > hi || !v.inFile(l.offset, l.size):
+				v.issue(section, off, "leaf %d block [%d, %d) is outside the data section [%d, %d)", i, l.offset, l.offset+l.size, lo, hi)
+			case l.size > uint64(bwBlockLimit(v.fp))+1024:
+				v.issue(section, off, "leaf %d block size %d exceeds the buffer size", i, l.size)
+			default:
+				leaves = append(leaves, l)
+			}
+		}
+	}
+	walk(idx.RootOffset, 0)
+	if section == "index" && idx.NItems != uint64(len(leaves)) && !v.r.IssuesCapped {
+		v.issue(section, offset, "index header declares %d blocks, leaves reference %d valid blocks", idx.NItems, len(leaves))
+	}
+	return leaves
+}
+
+// checkRange 检查索引项中的染色体编号和坐标
+func (v *bwVerifier) checkRange(section string, off uint64, chrStart, baseStart, chrEnd, baseEnd uint32) {
+	if chrStart > chrEnd || (chrStart == chrEnd && baseStart > baseEnd) {
+		v.issue(section, off, "range %d:%d-%d:%d is reversed", chrStart, baseStart, chrEnd, baseEnd)
+	}
+	if v.chroms == nil {
+		return
+	}
+	n := uint32(len(v.chroms.Chrom))
+	if chrEnd >= n {
+		v.issue(section, off, "range references chrom %d, file has %d", chrEnd, n)
+		return
+	}
+	if baseEnd > v.chroms.Len[chrEnd] {
+		v.issue(section, off, "range end %d is past the end of %s (%d)", baseEnd, v.chroms.Chrom[chrEnd], v.chroms.Len[chrEnd])
+	}
+}
+
+// blockCount 比较 dataOffset 处记录的数据块数与索引中的块数
+func (v *bwVerifier) blockCount(n uint64) {
+	var count uint64
+	if bwSetPos(v.fp, v.fp.Hdr.dataOffset) != 0 {
+		v.issue("data", v.fp.Hdr.dataOffset, "cannot seek to the data section")
+		return
+	}
+	if _, err := bwRead(&count, 8, 1, v.fp); err != nil {
+		v.issue("data", v.fp.Hdr.dataOffset, "cannot read the block count: %v", err)
+		return
+	}
+	if count != n {
+		v.issue("data", v.fp.Hdr.dataOffset, "data section declares %d blocks, index references %d", count, n)
+	}
+}
+
+// data 解压并解析每个数据块，检查记录与索引范围一致，并重算 summary
+func (v *bwVerifier) data(leaves []bwLeaf) {
+	fp := v.fp
+	order := bwOrder(fp)
+	var covered uint64
+	var sum, sumSq float64
+	minVal, maxVal := math.Inf(1), math.Inf(-1)
+	bad := false
+	for _, l := range leaves {
+		if v.r.IssuesCapped {
+			return
+		}
+		b, err := bwReadBlock(fp, l.offset, l.size)
+		if err != nil {
+			v.issue("data", l.offset, "%v", err)
+			bad = true
+			continue
+		}
+		bh, items, err := bwParseDataBlock(b, order)
+		if err != nil {
+			v.issue("data", l.offset, "%v", err)
+			bad = true
+			continue
+		}
+		if bh.ChromID < l.chrStart || bh.ChromID > l.chrEnd {
+			v.issue("data", l.offset, "block chrom %d is outside its index range %d-%d", bh.ChromID, l.chrStart, l.chrEnd)
+		}
+		v.r.Items += uint64(len(items))
+		for _, it := range items {
+			if l.chrStart == l.chrEnd && (it.Start < l.baseStart || it.End > l.baseEnd) {
+				v.issue("data", l.offset, "interval [%d, %d) is outside its index range [%d, %d)", it.Start, it.End, l.baseStart, l.baseEnd)
+				break
+			}
+			w := float64(it.End - it.Start)
+			val := float64(it.Value)
+			covered += uint64(it.End - it.Start)
+			sum += val * w
+			sumSq += val * val * w
+			minVal = math.Min(minVal, val)
+			maxVal = math.Max(maxVal, val)
+		}
+	}
+	// 有块无法读取时重算的 summary 必然不一致，不再重复报告
+	if bad || v.r.IssuesCapped {
+		return
+	}
+	if covered == 0 {
+		minVal, maxVal = 0, 0
+	}
+	v.summary = &bwFileSummary{covered, minVal, maxVal, sum, sumSq}
+	if fp.Hdr.summaryoffset == 0 {
+		return
+	}
+
+	hdr := fp.Hdr
+	off := hdr.summaryoffset
+	if covered != hdr.NBasesCovered {
+		v.issue("summary", off, "basesCovered is %d, data covers %d", hdr.NBasesCovered, covered)
+	}
+	if covered == 0 {
+		return
+	}
+	if minVal != hdr.MinVal {
+		v.issue("summary", off, "minVal is %g, data minimum is %g", hdr.MinVal, minVal)
+	}
+	if maxVal != hdr.MaxVal {
+		v.issue("summary", off, "maxVal is %g, data maximum is %g", hdr.MaxVal, maxVal)
+	}
+	if !bwClose(sum, hdr.SumData) {
+		v.issue("summary", off, "sumData is %g, data sum is %g", hdr.SumData, sum)
+	}
+	if !bwClose(sumSq, hdr.SumSquared) {
+		v.issue("summary", off, "sumSquares is %g, data sum of squares is %g", hdr.SumSquared, sumSq)
+	}
+}
+
+// zoomData 检查每个 zoom 块能否解压，且由完整的 32 字节记录组成
+func (v *bwVerifier) zoomData(leaves []bwLeaf) {
+	for _, l := range leaves {
+		if v.r.IssuesCapped {
+			return
+		}
+		b, err := bwReadBlock(v.fp, l.offset, l.size)
+		if err != nil {
+			v.issue("zoomData", l.offset, "%v", err)
+			continue
+		}
+		if len(b) == 0 || len(b)%bwZoomRecordSize != 0 {
+			v.issue("zoomData", l.offset, "zoom block of %d bytes is not a whole number of %d-byte records", len(b), bwZoomRecordSize)
+		}
+	}
+}
+
+// bwClose 判断按 float32 值累加出的两个总和是否在舍入误差内相等
+func bwClose(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-6*math.Max(math.Abs(a), math.Abs(b))+1e-9
+}
@@ -0,0 +1,76 @@
+package gobigwig
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// bwWigWriter 记录当前 wiggle 段的声明，后续记录能接续时不再重复写声明行
+type bwWigWriter struct {
+	w     *bufio.Writer
+	mode  uint8 // 0=尚无声明，bwTypeVariableStep 或 bwTypeFixedStep
+	chrom string
+	span  uint32
+	step  uint32
+	next  uint32 // fixedStep 段中下一条记录应有的 start
+}
+
+// add 写入一条记录；step 为所在块的 fixedStep 步长，其他类型的块为 0
+func (ww *bwWigWriter) add(chrom string, typ uint8, step uint32, it Interval) error {
+	span := it.End - it.Start
+	same := ww.chrom == chrom && ww.span == span
+	switch {
+	case ww.mode == bwTypeFixedStep && same && it.Start == ww.next:
+		ww.next += ww.step
+		_, err := fmt.Fprintf(ww.w, "%s\n", bwFormatValue(it.Value))
+		return err
+	case ww.mode == bwTypeVariableStep && same && typ != bwTypeFixedStep:
+		_, err := fmt.Fprintf(ww.w, "%d\t%s\n", it.Start+1, bwFormatValue(it.Value))
+		return err
+	}
+
+	// 开始新的一段：fixedStep 块保持 fixedStep，bedGraph 与 variableStep 块写成 variableStep
+	ww.chrom, ww.span = chrom, span
+	if typ == bwTypeFixedStep && step > 0 {
+		ww.mode, ww.step, ww.next = bwTypeFixedStep, step, it.Start+step
+		_, err := fmt.Fprintf(ww.w, "fixedStep chrom=%s start=%d step=%d span=%d\n%s\n",
+			chrom, it.Start+1, step, span, bwFormatValue(it.Value))
+		return err
+	}
+	ww.mode = bwTypeVariableStep
+	_, err := fmt.Fprintf(ww.w, "variableStep chrom=%s span=%d\n%d\t%s\n", chrom, span, it.Start+1, bwFormatValue(it.Value))
+	return err
+}
+
+// WriteWiggle 把 regions 内的记录以 wiggle 格式写入 w，不给出 regions 时导出整个文件
+// 来自 fixedStep 数据块的连续记录写成 fixedStep 段，其余写成按 span 分段的 variableStep，
+// 供只接受 .wig 输入的旧工具使用；wiggle 坐标从 1 开始，与区间重叠的记录整条输出而不截断
+func (fp *Bigwig_file_out) WriteWiggle(w io.Writer, regions ...Region) error {
+	return fp.WriteWiggleContext(context.Background(), w, regions...)
+}
+
+// WriteWiggleContext 与 WriteWiggle 相同，ctx 被取消时返回 ctx.Err()
+func (fp *Bigwig_file_out) WriteWiggleContext(ctx context.Context, w io.Writer, regions ...Region) error {
+	bw := fp.bf_fp
+	rs, err := bwExportRegions(bw, regions)
+	if err != nil {
+		return err
+	}
+	ww := &bwWigWriter{w: bufio.NewWriter(w)}
+	for _, r := range rs {
+		err := bwEachBlock(ctx, bw, r.Chrom, uint32(r.Start), uint32(r.End), func(hdr BlockHeader, items []Interval) error {
+			for _, it := range items {
+				if err := ww.add(r.Chrom, hdr.Type, hdr.Step, it); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return ww.w.Flush()
+}
@@ -0,0 +1,155 @@
+package gobigwig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// bwWigSection 是 wiggle 中当前 variableStep/fixedStep 声明行的参数，坐标已转换为从 0 开始
+type bwWigSection struct {
+	typ   uint8 // 0 表示尚未遇到声明行，此时数据行按 bedGraph 解析
+	chrom string
+	next  uint32 // fixedStep 下一条记录的起点
+	span  uint32
+	step  uint32
+}
+
+// ConvertWiggle 读取 r 中的 wiggle（fixedStep、variableStep 段，以及声明行之前的 bedGraph 行），
+// 写成 bigWig 文件 out；fixedStep/variableStep 段按原有的 span/step 写成对应类型的数据块，
+// 可替代 UCSC 的 wigToBigWig。chromSizes、排序要求和压缩输入的处理与 ConvertBedGraph 相同
+func ConvertWiggle(r io.Reader, chromSizes map[string]uint32, out string, opts ...WriteOption) error {
+	if len(chromSizes) == 0 {
+		return errors.New("chromosome sizes are empty")
+	}
+	c, err := newBWConverter(chromSizes, out, opts)
+	if err != nil {
+		return err
+	}
+	r, c.progress = bwTrackReader(c.w.opts.Progress, r)
+	return c.finish(c.readWiggle(r))
+}
+
+// readWiggle 逐行解析 wiggle 并写入
+func (c *bwConverter) readWiggle(r io.Reader) error {
+	r, err := bwTextInput(r)
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var sec bwWigSection
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if bwSkipTextLine(line) {
+			continue
+		}
+		c.line = lineNo
+		switch {
+		case strings.HasPrefix(line, "variableStep") || strings.HasPrefix(line, "fixedStep"):
+			sec, err = bwParseWigDeclaration(line)
+		case sec.typ == bwTypeVariableStep:
+			err = c.addVariableStep(&sec, line)
+		case sec.typ == bwTypeFixedStep:
+			err = c.addFixedStep(&sec, line)
+		default:
+			var chrom string
+			var start, end uint32
+			var value float32
+			if chrom, start, end, value, err = bwParseBedGraphLine(line); err == nil {
+				err = c.add(chrom, start, end, value)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+	return sc.Err()
+}
+
+// bwParseWigDeclaration 解析 "variableStep chrom=... [span=...]" 或
+// "fixedStep chrom=... start=... [step=...] [span=...]"，span 和 step 默认为 1
+func bwParseWigDeclaration(line string) (bwWigSection, error) {
+	f := strings.Fields(line)
+	sec := bwWigSection{typ: bwTypeVariableStep, span: 1, step: 1}
+	if f[0] == "fixedStep" {
+		sec.typ = bwTypeFixedStep
+	} else if f[0] != "variableStep" {
+		return sec, fmt.Errorf("unknown declaration %q", f[0])
+	}
+	var start uint64
+	hasStart := false
+	for _, kv := range f[1:] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return sec, fmt.Errorf("invalid %s parameter %q", f[0], kv)
+		}
+		var n uint64
+		var err error
+		switch k {
+		case "chrom":
+			sec.chrom = v
+			continue
+		case "start", "step", "span":
+			if n, err = strconv.ParseUint(v, 10, 32); err != nil || n == 0 {
+				return sec, fmt.Errorf("invalid %s %q", k, v)
+			}
+		default:
+			return sec, fmt.Errorf("unknown %s parameter %q", f[0], k)
+		}
+		switch k {
+		case "start":
+			start, hasStart = n, true
+		case "step":
+			sec.step = uint32(n)
+		case "span":
+			sec.span = uint32(n)
+		}
+	}
+	if sec.chrom == "" {
+		return sec, fmt.Errorf("%s without chrom", f[0])
+	}
+	if sec.typ == bwTypeFixedStep {
+		if !hasStart {
+			return sec, errors.New("fixedStep without start")
+		}
+		sec.next = uint32(start - 1)
+	} else if hasStart {
+		return sec, errors.New("variableStep does not take start")
+	}
+	return sec, nil
+}
+
+// addVariableStep 解析 variableStep 段中的 "position value" 行，position 从 1 开始
+func (c *bwConverter) addVariableStep(sec *bwWigSection, line string) error {
+	f := strings.Fields(line)
+	if len(f) != 2 {
+		return fmt.Errorf("expected 2 fields in variableStep data, got %d", len(f))
+	}
+	pos, err := strconv.ParseUint(f[0], 10, 32)
+	if err != nil || pos == 0 {
+		return fmt.Errorf("invalid position %q", f[0])
+	}
+	value, err := strconv.ParseFloat(f[1], 32)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", f[1])
+	}
+	start := uint32(pos - 1)
+	return c.addItem(bwTypeVariableStep, sec.span, 0, sec.chrom, start, start+sec.span, float32(value))
+}
+
+// addFixedStep 解析 fixedStep 段中的单值行
+func (c *bwConverter) addFixedStep(sec *bwWigSection, line string) error {
+	value, err := strconv.ParseFloat(line, 32)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", line)
+	}
+	start := sec.next
+	sec.next += sec.step
+	return c.addItem(bwTypeFixedStep, sec.span, sec.step, sec.chrom, start, start+sec.span, float32(value))
+}
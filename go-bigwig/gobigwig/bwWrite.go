@@ -0,0 +1,989 @@
+package gobigwig
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// 写入时各固定结构在文件中的大小（字节）
+const (
+	bwHeaderSize        = 64
+	bwZoomHeaderSize    = 24
+	bwSummarySize       = 40
+	bwDataHeaderSize    = 24
+	bwZoomRecordSize    = 32
+	bwWriteVersion      = 4
+	DEFAULT_ZOOM_LEVELS = 10 // 默认最多生成的缩放层级数
+	bwZoomIncrement     = 4  // 相邻缩放层级的 reduction 倍数
+)
+
+// BWOptions_Write 表示创建 bigWig 文件时的参数
+type BWOptions_Write struct {
+	BlockSize     uint32        // R 树每个节点的最大子节点数
+	BufSize       uint32        // 每个数据块未压缩时的最大字节数
+	MaxZoomLevels int           // 最多生成的缩放层级数，0 表示不生成
+	Compress      bool          // 是否使用 zlib 压缩数据块
+	CompressLevel int           // zlib 压缩级别，-1（zlib.DefaultCompression）到 9
+	ZoomLadder    []uint32      // 指定各缩放层级的 reduction，为空时自动确定
+	SyncOnClose   bool          // Close 完成后是否 fsync
+	SyncEveryN    int           // 每写入 N 个数据块执行一次 fsync，0 表示不按块同步
+	SortInput     bool          // ConvertBedGraph 是否先对输入做外部排序
+	SortTempDir   string        // 外部排序临时文件所在目录，空表示系统临时目录
+	Normalization Normalization // 写入前对每条记录的值做的归一化
+	NormTotal     float64       // 归一化所用的总数，含义见 Normalization
+	NormBinSize   uint32        // RPKM、BPM 归一化时每个值对应的 bin 宽度，默认 1
+	Scale         float64       // 归一化之后再乘的系数，默认 1
+	Blacklist     *Blacklist    // 写入时值被置为 0 的区域，nil 表示不屏蔽
+	Progress      ProgressFunc  // 转换、合并等操作读取输入的进度，nil 表示不报告
+}
+
+// WriteOption 用于修改 BWOptions_Write 的函数式选项
+type WriteOption func(*BWOptions_Write)
+
+// WithBlockSize 设置 R 树节点的最大子节点数
+func WithBlockSize(n uint32) WriteOption {
+	return func(o *BWOptions_Write) { o.BlockSize = n }
+}
+
+// WithBufSize 设置单个数据块未压缩时的最大字节数
+func WithBufSize(n uint32) WriteOption {
+	return func(o *BWOptions_Write) { o.BufSize = n }
+}
+
+// WithMaxZoomLevels 设置最多生成的缩放层级数，0 表示不生成缩放数据
+func WithMaxZoomLevels(n int) WriteOption {
+	return func(o *BWOptions_Write) { o.MaxZoomLevels = n }
+}
+
+// WithCompression 控制是否压缩数据块
+func WithCompression(enabled bool) WriteOption {
+	return func(o *BWOptions_Write) { o.Compress = enabled }
+}
+
+// WithCompressionLevel 设置 zlib 压缩级别（1 最快、9 最小，-1 为默认），并开启压缩
+func WithCompressionLevel(level int) WriteOption {
+	return func(o *BWOptions_Write) {
+		o.Compress = true
+		o.CompressLevel = level
+	}
+}
+
+// WithZoomLadder 指定缩放层级的 reduction，从细到粗排列，每一级必须是上一级的整数倍，
+// 例如 WithZoomLadder(100, 400, 1600)；默认最细层级取平均记录宽度的 10 倍，之后每级乘 4
+// 会同时把 MaxZoomLevels 设为层级数
+func WithZoomLadder(reductions ...uint32) WriteOption {
+	return func(o *BWOptions_Write) {
+		o.ZoomLadder = reductions
+		o.MaxZoomLevels = len(reductions)
+	}
+}
+
+// WithSyncOnClose 让 Close 在写完文件后执行 fsync
+func WithSyncOnClose(enabled bool) WriteOption {
+	return func(o *BWOptions_Write) { o.SyncOnClose = enabled }
+}
+
+// WithSyncEvery 每写入 nBlocks 个数据块执行一次 fsync，以吞吐量换取崩溃时的数据安全
+func WithSyncEvery(nBlocks int) WriteOption {
+	return func(o *BWOptions_Write) { o.SyncEveryN = nBlocks }
+}
+
+// WithExternalSort 让 ConvertBedGraph 接受未排序的输入：先在 tmpDir 中分段排序再归并，
+// tmpDir 为空时使用系统临时目录
+func WithExternalSort(tmpDir string) WriteOption {
+	return func(o *BWOptions_Write) {
+		o.SortInput = true
+		o.SortTempDir = tmpDir
+	}
+}
+
+// WithZeroedBlacklist 让写入的记录中落在 b 内的部分值为 0；部分重叠的记录被拆分为 bedGraph 记录
+func WithZeroedBlacklist(b *Blacklist) WriteOption {
+	return func(o *BWOptions_Write) { o.Blacklist = b }
+}
+
+func newWriteOptions(opts []WriteOption) BWOptions_Write {
+	o := BWOptions_Write{
+		BlockSize:     DEFAULT_nCHILDREN,
+		BufSize:       DEFAULT_BLOCKSIZE,
+		MaxZoomLevels: DEFAULT_ZOOM_LEVELS,
+		Compress:      true,
+		CompressLevel: zlib.DefaultCompression,
+		Scale:         1,
+		NormBinSize:   1,
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// BigWigWriter 以流式方式写入 bigWig 文件
+// 条目必须按染色体列表顺序、同一染色体内按位置有序且互不重叠地添加
+type BigWigWriter struct {
+	bf_fp  *bigWigFile_t
+	opts   BWOptions_Write
+	f      *os.File
+	w      *bufio.Writer
+	pos    uint64            // 当前写入位置
+	tids   map[string]uint32 // 染色体名到 tid 的映射
+	closed bool
+}
+
+// CreateBigWig 创建 bigWig 文件，chroms/lengths 给出染色体名及长度，顺序即 tid
+func CreateBigWig(fname string, chroms []string, lengths []uint32, opts ...WriteOption) (*BigWigWriter, error) {
+	if len(chroms) == 0 || len(chroms) != len(lengths) {
+		return nil, errors.New("chroms and lengths must be non-empty and of equal length")
+	}
+	o := newWriteOptions(opts)
+	if o.BlockSize < 2 || o.BlockSize > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid block size: %d", o.BlockSize)
+	}
+	if o.BufSize < bwDataHeaderSize+bwZoomRecordSize {
+		return nil, fmt.Errorf("invalid buffer size: %d", o.BufSize)
+	}
+	if o.MaxZoomLevels < 0 || o.MaxZoomLevels > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid zoom level count: %d", o.MaxZoomLevels)
+	}
+	if o.CompressLevel < zlib.HuffmanOnly || o.CompressLevel > zlib.BestCompression {
+		return nil, fmt.Errorf("invalid compression level: %d", o.CompressLevel)
+	}
+	for i, r := range o.ZoomLadder {
+		if r < 2 || (i > 0 && (r <= o.ZoomLadder[i-1] || r%o.ZoomLadder[i-1] != 0)) {
+			return nil, fmt.Errorf("invalid zoom ladder %v: each reduction must be at least 2 and a larger multiple of the previous one", o.ZoomLadder)
+		}
+	}
+	if err := o.checkNormalization(); err != nil {
+		return nil, err
+	}
+
+	tids := make(map[string]uint32, len(chroms))
+	for i, c := range chroms {
+		if c == "" {
+			return nil, errors.New("empty chromosome name")
+		}
+		if _, ok := tids[c]; ok {
+			return nil, fmt.Errorf("duplicate chromosome: %s", c)
+		}
+		tids[c] = uint32(i)
+	}
+
+	fname, err := bwLocalPath(fname)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(fname)
+	if err != nil {
+		return nil, err
+	}
+	fp := &bigWigFile_t{
+		URL:     &URL{rs: f, Type: BWG_FILE, FName: fname},
+		IsWrite: true,
+		Type:    0,
+		Hdr: &bigWigHdr_t{
+			version: bwWriteVersion,
+			MinVal:  math.Inf(1),
+			MaxVal:  math.Inf(-1),
+		},
+		Cl: &chromList{
+			NKeys: int64(len(chroms)),
+			Chrom: append([]string(nil), chroms...),
+			Len:   append([]uint32(nil), lengths...),
+		},
+		WriteBuffer: &bwWriteBuffer_t{
+			BlockSize: o.BlockSize,
+			BufSize:   o.BufSize,
+		},
+	}
+	bw := &BigWigWriter{
+		bf_fp: fp,
+		opts:  o,
+		f:     f,
+		w:     bufio.NewWriter(f),
+		tids:  tids,
+	}
+
+	// 文件头、zoom 头和 summary 先写占位，Close 时回填
+	hdr := fp.Hdr
+	hdr.summaryoffset = uint64(bwHeaderSize + bwZoomHeaderSize*o.MaxZoomLevels)
+	hdr.ctoffset = hdr.summaryoffset + bwSummarySize
+	if err := bw.write(make([]byte, hdr.ctoffset)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := bw.writeChromList(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// 数据区以块数量开头，同样在 Close 时回填
+	hdr.dataOffset = bw.pos
+	if err := bw.write(make([]byte, 8)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return bw, nil
+}
+
+func (bw *BigWigWriter) write(b []byte) error {
+	n, err := bw.w.Write(b)
+	bw.pos += uint64(n)
+	return err
+}
+
+// writeChromList 写入染色体 B+ 树，键按名称排序，值为 (tid, 长度)
+func (bw *BigWigWriter) writeChromList() error {
+	cl := bw.bf_fp.Cl
+	n := len(cl.Chrom)
+	keySize := 1
+	for _, c := range cl.Chrom {
+		if len(c) > keySize {
+			keySize = len(c)
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return cl.Chrom[order[a]] < cl.Chrom[order[b]] })
+
+	bs := int(bw.opts.BlockSize)
+	if bs > n {
+		bs = n
+	}
+
+	// 自底向上分层：第 0 层的 lo/hi 指向排序后的条目，其余层指向下一层的节点
+	type bptNode struct{ lo, hi int }
+	var levels [][]bptNode
+	var lvl []bptNode
+	for i := 0; i < n; i += bs {
+		lvl = append(lvl, bptNode{i, min(i+bs, n)})
+	}
+	levels = append(levels, lvl)
+	for len(lvl) > 1 {
+		var up []bptNode
+		for i := 0; i < len(lvl); i += bs {
+			up = append(up, bptNode{i, min(i+bs, len(lvl))})
+		}
+		levels = append(levels, up)
+		lvl = up
+	}
+
+	// 自顶向下计算每个节点的偏移（叶子与非叶子条目大小都是 keySize+8）
+	itemSize := uint64(keySize + 8)
+	offs := make([][]uint64, len(levels))
+	off := bw.pos + 32
+	for k := len(levels) - 1; k >= 0; k-- {
+		offs[k] = make([]uint64, len(levels[k]))
+		for j, nd := range levels[k] {
+			offs[k][j] = off
+			off += 4 + uint64(nd.hi-nd.lo)*itemSize
+		}
+	}
+	firstKey := func(k, j int) string {
+		for ; k > 0; k-- {
+			j = levels[k][j].lo
+		}
+		return cl.Chrom[order[levels[0][j].lo]]
+	}
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&buf, le, uint32(CIRTREE_MAGIC))
+	binary.Write(&buf, le, uint32(bs))
+	binary.Write(&buf, le, uint32(keySize))
+	binary.Write(&buf, le, uint32(8))
+	binary.Write(&buf, le, uint64(n))
+	binary.Write(&buf, le, uint64(0))
+	key := make([]byte, keySize)
+	putKey := func(s string) {
+		clear(key)
+		copy(key, s)
+		buf.Write(key)
+	}
+	for k := len(levels) - 1; k >= 0; k-- {
+		for _, nd := range levels[k] {
+			isLeaf := uint8(0)
+			if k == 0 {
+				isLeaf = 1
+			}
+			buf.WriteByte(isLeaf)
+			buf.WriteByte(0)
+			binary.Write(&buf, le, uint16(nd.hi-nd.lo))
+			for c := nd.lo; c < nd.hi; c++ {
+				if k == 0 {
+					tid := order[c]
+					putKey(cl.Chrom[tid])
+					binary.Write(&buf, le, uint32(tid))
+					binary.Write(&buf, le, cl.Len[tid])
+				} else {
+					putKey(firstKey(k-1, c))
+					binary.Write(&buf, le, offs[k-1][c])
+				}
+			}
+		}
+	}
+	return bw.write(buf.Bytes())
+}
+
+// maxItems 返回指定类型的数据块最多能容纳的条目数
+func (bw *BigWigWriter) maxItems(ltype uint8) uint32 {
+	itemSize := uint32(4)
+	switch ltype {
+	case 1:
+		itemSize = 12
+	case 2:
+		itemSize = 8
+	}
+	n := (bw.opts.BufSize - bwDataHeaderSize) / itemSize
+	if n > math.MaxUint16 {
+		n = math.MaxUint16
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+func (bw *BigWigWriter) lookupTid(chrom string) (uint32, error) {
+	if bw.closed {
+		return 0, errors.New("writer already closed")
+	}
+	tid, ok := bw.tids[chrom]
+	if !ok {
+		return 0, fmt.Errorf("chromosome not found: %s", chrom)
+	}
+	return tid, nil
+}
+
+// checkOrder 检查条目是否合法且按顺序添加
+func (bw *BigWigWriter) checkOrder(tid, start, end uint32) error {
+	wb := bw.bf_fp.WriteBuffer
+	if start >= end {
+		return fmt.Errorf("invalid interval %s:%d-%d", bw.bf_fp.Cl.Chrom[tid], start, end)
+	}
+	if end > bw.bf_fp.Cl.Len[tid] {
+		return fmt.Errorf("interval %s:%d-%d exceeds chromosome length %d", bw.bf_fp.Cl.Chrom[tid], start, end, bw.bf_fp.Cl.Len[tid])
+	}
+	if tid < wb.LastTid || (tid == wb.LastTid && start < wb.LastEnd) {
+		return fmt.Errorf("interval %s:%d-%d is out of order or overlaps the previous entry", bw.bf_fp.Cl.Chrom[tid], start, end)
+	}
+	return nil
+}
+
+// pushItem 检查条目顺序，做归一化和黑名单屏蔽后加入当前块
+func (bw *BigWigWriter) pushItem(ltype uint8, tid, span, step, start, end uint32, value float32) error {
+	if err := bw.checkOrder(tid, start, end); err != nil {
+		return err
+	}
+	value = bw.opts.normalize(value)
+	if b := bw.opts.Blacklist; b != nil && b.Overlaps(bw.bf_fp.Cl.Chrom[tid], start, end) {
+		return bw.pushMasked(b, ltype, tid, span, step, start, end, value)
+	}
+	return bw.appendItem(ltype, tid, span, step, start, end, value)
+}
+
+// pushMasked 写入与黑名单重叠的条目：整条在黑名单内时保持类型、值为 0，
+// 否则拆分为 bedGraph 条目，黑名单内的部分值为 0
+func (bw *BigWigWriter) pushMasked(b *Blacklist, ltype uint8, tid, span, step, start, end uint32, value float32) error {
+	chrom := bw.bf_fp.Cl.Chrom[tid]
+	if b.Covered(chrom, start, end) == end-start {
+		return bw.appendItem(ltype, tid, span, step, start, end, 0)
+	}
+	pos := start
+	var err error
+	b.each(chrom, start, end, func(s, e uint32) {
+		if err == nil && s > pos {
+			err = bw.appendItem(1, tid, 0, 0, pos, s, value)
+		}
+		if err == nil {
+			err = bw.appendItem(1, tid, 0, 0, s, e, 0)
+		}
+		pos = e
+	})
+	if err == nil && pos < end {
+		err = bw.appendItem(1, tid, 0, 0, pos, end, value)
+	}
+	return err
+}
+
+// appendItem 把已检查过顺序的条目加入当前块，必要时先写出当前块
+func (bw *BigWigWriter) appendItem(ltype uint8, tid, span, step, start, end uint32, value float32) error {
+	wb := bw.bf_fp.WriteBuffer
+	cont := wb.L > 0 && wb.LType == ltype && wb.Tid == tid && wb.L < bw.maxItems(ltype)
+	switch ltype {
+	case 2:
+		cont = cont && wb.Span == span
+	case 3:
+		cont = cont && wb.Span == span && wb.Step == step && start == wb.Start+wb.L*step
+	}
+	if !cont {
+		if err := bw.flushBuffer(); err != nil {
+			return err
+		}
+		wb.LType = ltype
+		wb.Tid = tid
+		wb.Start = start
+		wb.Span = span
+		wb.Step = step
+	}
+	wb.P = append(wb.P, bwWriteItem{Start: start, End: end, Value: value})
+	wb.L++
+	if end > wb.End || wb.L == 1 {
+		wb.End = end
+	}
+	wb.NEntries++
+	wb.RunningWidthSum += uint64(end - start)
+	wb.LastTid = tid
+	wb.LastEnd = end
+	return nil
+}
+
+// AddIntervals 添加 bedGraph 形式的条目 [starts[i], ends[i]) = values[i]
+func (bw *BigWigWriter) AddIntervals(chrom string, starts, ends []uint32, values []float32) error {
+	if len(starts) != len(ends) || len(starts) != len(values) {
+		return errors.New("starts, ends and values must have equal length")
+	}
+	tid, err := bw.lookupTid(chrom)
+	if err != nil {
+		return err
+	}
+	for i := range starts {
+		if err := bw.pushItem(1, tid, 0, 0, starts[i], ends[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddIntervalSpans 添加 variableStep 形式的条目，每个条目从 starts[i] 开始、长度为 span
+func (bw *BigWigWriter) AddIntervalSpans(chrom string, starts []uint32, span uint32, values []float32) error {
+	if len(starts) != len(values) {
+		return errors.New("starts and values must have equal length")
+	}
+	tid, err := bw.lookupTid(chrom)
+	if err != nil {
+		return err
+	}
+	for i := range starts {
+		if err := bw.pushItem(2, tid, span, 0, starts[i], starts[i]+span, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddIntervalSpanSteps 添加 fixedStep 形式的条目，第 i 个条目从 start+i*step 开始、长度为 span
+func (bw *BigWigWriter) AddIntervalSpanSteps(chrom string, start, span, step uint32, values []float32) error {
+	if step == 0 {
+		return errors.New("step must be positive")
+	}
+	tid, err := bw.lookupTid(chrom)
+	if err != nil {
+		return err
+	}
+	for i, v := range values {
+		s := start + uint32(i)*step
+		if err := bw.pushItem(3, tid, span, step, s, s+span, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeBlock 把当前块编码为未压缩的数据块
+func (bw *BigWigWriter) encodeBlock() []byte {
+	wb := bw.bf_fp.WriteBuffer
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	end := wb.End
+	if wb.LType == 3 {
+		end = wb.Start + (wb.L-1)*wb.Step + wb.Span
+	}
+	binary.Write(&buf, le, wb.Tid)
+	binary.Write(&buf, le, wb.Start)
+	binary.Write(&buf, le, end)
+	binary.Write(&buf, le, wb.Step)
+	binary.Write(&buf, le, wb.Span)
+	buf.WriteByte(wb.LType)
+	buf.WriteByte(0)
+	binary.Write(&buf, le, uint16(wb.L))
+	for _, it := range wb.P {
+		switch wb.LType {
+		case 1:
+			binary.Write(&buf, le, it.Start)
+			binary.Write(&buf, le, it.End)
+		case 2:
+			binary.Write(&buf, le, it.Start)
+		}
+		binary.Write(&buf, le, math.Float32bits(it.Value))
+	}
+	return buf.Bytes()
+}
+
+// writeBlock 压缩（如需要）并写出一个数据块，返回其偏移和大小
+func (bw *BigWigWriter) writeBlock(raw []byte) (uint64, uint64, error) {
+	wb := bw.bf_fp.WriteBuffer
+	if uint32(len(raw)) > wb.MaxBlockSize {
+		wb.MaxBlockSize = uint32(len(raw))
+	}
+	out := raw
+	if bw.opts.Compress {
+		var zbuf bytes.Buffer
+		zw, err := zlib.NewWriterLevel(&zbuf, bw.opts.CompressLevel)
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err := zw.Write(raw); err != nil {
+			return 0, 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, 0, err
+		}
+		out = zbuf.Bytes()
+	}
+	offset := bw.pos
+	if err := bw.write(out); err != nil {
+		return 0, 0, err
+	}
+	return offset, uint64(len(out)), nil
+}
+
+// flushBuffer 把当前块写入文件，并更新索引、summary 与缩放缓冲
+func (bw *BigWigWriter) flushBuffer() error {
+	wb := bw.bf_fp.WriteBuffer
+	if wb.L == 0 {
+		return nil
+	}
+	raw := bw.encodeBlock()
+	offset, size, err := bw.writeBlock(raw)
+	if err != nil {
+		return err
+	}
+	end := binary.LittleEndian.Uint32(raw[8:12])
+	bwIndexAppend(&wb.FirstIndexNode, &wb.CurrentIndexNode, wb.BlockSize, wb.Tid, wb.Start, wb.Tid, end, offset, size)
+
+	// 第一次写出数据块时根据平均条目宽度确定最细的缩放层级
+	if wb.Zoom == nil && bw.opts.MaxZoomLevels > 0 {
+		reduction := uint64(10)
+		if wb.NEntries > 0 && wb.RunningWidthSum/wb.NEntries*10 > reduction {
+			reduction = wb.RunningWidthSum / wb.NEntries * 10
+		}
+		if reduction > math.MaxUint32 {
+			reduction = math.MaxUint32
+		}
+		if len(bw.opts.ZoomLadder) > 0 {
+			reduction = uint64(bw.opts.ZoomLadder[0])
+		}
+		wb.Zoom = &bwZoomBuffer_t{Reduction: uint32(reduction)}
+	}
+	hdr := bw.bf_fp.Hdr
+	for _, it := range wb.P {
+		n := float64(it.End - it.Start)
+		v := float64(it.Value)
+		hdr.NBasesCovered += uint64(it.End - it.Start)
+		hdr.MinVal = math.Min(hdr.MinVal, v)
+		hdr.MaxVal = math.Max(hdr.MaxVal, v)
+		hdr.SumData += v * n
+		hdr.SumSquared += v * v * n
+		if wb.Zoom != nil {
+			wb.Zoom.add(wb.Tid, it.Start, it.End, it.Value)
+		}
+	}
+
+	wb.NBlocks++
+	wb.BlocksSinceSync++
+	wb.L = 0
+	wb.P = wb.P[:0]
+	wb.LType = 0
+	if bw.opts.SyncEveryN > 0 && wb.BlocksSinceSync >= bw.opts.SyncEveryN {
+		return bw.Sync()
+	}
+	return nil
+}
+
+// Flush 把缓冲中尚未写出的条目作为一个数据块写入文件，并把写缓冲交给操作系统
+// 不执行 fsync；需要落盘时使用 Sync
+func (bw *BigWigWriter) Flush() error {
+	if bw.closed {
+		return errors.New("writer already closed")
+	}
+	if err := bw.flushBuffer(); err != nil {
+		return err
+	}
+	return bw.w.Flush()
+}
+
+// Sync 执行 Flush 并 fsync 到磁盘
+func (bw *BigWigWriter) Sync() error {
+	if err := bw.flushBuffer(); err != nil {
+		return err
+	}
+	if err := bw.w.Flush(); err != nil {
+		return err
+	}
+	bw.bf_fp.WriteBuffer.BlocksSinceSync = 0
+	return bw.f.Sync()
+}
+
+// Close 写出剩余数据、主索引和缩放层级，回填文件头后关闭文件
+func (bw *BigWigWriter) Close() error {
+	if bw.closed {
+		return nil
+	}
+	err := bw.finalize()
+	bw.closed = true
+	if cerr := bw.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (bw *BigWigWriter) finalize() error {
+	fp := bw.bf_fp
+	wb := fp.WriteBuffer
+	hdr := fp.Hdr
+	if err := bw.flushBuffer(); err != nil {
+		return err
+	}
+
+	// 主索引
+	hdr.indexoffset = bw.pos
+	if err := bw.writeRTree(wb.FirstIndexNode, wb.NBlocks); err != nil {
+		return err
+	}
+
+	// 缩放层级：最细层级已在写入时累积，更粗的层级逐级合并得到
+	var levels []*bwZoomBuffer_t
+	if wb.Zoom != nil {
+		wb.Zoom.finish()
+		if len(wb.Zoom.Records) > 0 {
+			levels = append(levels, wb.Zoom)
+		}
+		for len(levels) > 0 && len(levels) < bw.opts.MaxZoomLevels {
+			prev := levels[len(levels)-1]
+			reduction := uint64(prev.Reduction) * bwZoomIncrement
+			if ladder := bw.opts.ZoomLadder; len(ladder) > 0 {
+				reduction = uint64(ladder[len(levels)])
+			}
+			if reduction > math.MaxUint32 {
+				break
+			}
+			next := prev.reduce(uint32(reduction))
+			// 自动确定的层级在记录数不再减少时停止，指定的层级全部写出
+			if len(next.Records) >= len(prev.Records) && len(bw.opts.ZoomLadder) == 0 {
+				break
+			}
+			levels = append(levels, next)
+		}
+	}
+	zhdr := &bwZoomHdr_t{}
+	for _, z := range levels {
+		dataOffset, indexOffset, err := bw.writeZoomLevel(z)
+		if err != nil {
+			return err
+		}
+		zhdr.Level = append(zhdr.Level, z.Reduction)
+		zhdr.DataOffset = append(zhdr.DataOffset, dataOffset)
+		zhdr.IndexOffset = append(zhdr.IndexOffset, indexOffset)
+	}
+	hdr.nLevels = uint16(len(levels))
+
+	// 文件末尾同样写入魔数
+	var magic [4]byte
+	binary.LittleEndian.PutUint32(magic[:], BIGWIG_MAGIC)
+	if err := bw.write(magic[:]); err != nil {
+		return err
+	}
+	if err := bw.w.Flush(); err != nil {
+		return err
+	}
+
+	// 回填文件头、zoom 头、summary 与数据块数量
+	if bw.opts.Compress {
+		hdr.bufsize = wb.MaxBlockSize
+	}
+	if hdr.NBasesCovered == 0 {
+		hdr.MinVal, hdr.MaxVal = 0, 0
+	}
+	var buf bytes.Buffer
+	bwHdrWrite(&buf, hdr)
+	for i := range zhdr.Level {
+		binary.Write(&buf, binary.LittleEndian, zhdr.Level[i])
+		binary.Write(&buf, binary.LittleEndian, uint32(0))
+		binary.Write(&buf, binary.LittleEndian, zhdr.DataOffset[i])
+		binary.Write(&buf, binary.LittleEndian, zhdr.IndexOffset[i])
+	}
+	if _, err := bw.f.WriteAt(buf.Bytes(), 0); err != nil {
+		return err
+	}
+	buf.Reset()
+	for _, v := range []any{hdr.NBasesCovered, hdr.MinVal, hdr.MaxVal, hdr.SumData, hdr.SumSquared} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	if _, err := bw.f.WriteAt(buf.Bytes(), int64(hdr.summaryoffset)); err != nil {
+		return err
+	}
+	var count [8]byte
+	binary.LittleEndian.PutUint64(count[:], wb.NBlocks)
+	if _, err := bw.f.WriteAt(count[:], int64(hdr.dataOffset)); err != nil {
+		return err
+	}
+	hdr.ZoomHdrs = []*bwZoomHdr_t{zhdr}
+
+	if bw.opts.SyncOnClose {
+		return bw.f.Sync()
+	}
+	return nil
+}
+
+// bwHdrWrite 按磁盘格式写出 64 字节的文件头，与 bwHdrRead 对应
+func bwHdrWrite(buf *bytes.Buffer, hdr *bigWigHdr_t) {
+	le := binary.LittleEndian
+	binary.Write(buf, le, uint32(BIGWIG_MAGIC))
+	binary.Write(buf, le, hdr.version)
+	binary.Write(buf, le, hdr.nLevels)
+	binary.Write(buf, le, hdr.ctoffset)
+	binary.Write(buf, le, hdr.dataOffset)
+	binary.Write(buf, le, hdr.indexoffset)
+	binary.Write(buf, le, hdr.fieldCount)
+	binary.Write(buf, le, hdr.definedFieldCount)
+	binary.Write(buf, le, hdr.sqloffset)
+	binary.Write(buf, le, hdr.summaryoffset)
+	binary.Write(buf, le, hdr.bufsize)
+	binary.Write(buf, le, hdr.extensionoffset)
+}
+
+// add 把 [start, end) = value 累积进按 Reduction 对齐的窗口，跨窗口的条目按碱基拆分
+func (z *bwZoomBuffer_t) add(tid, start, end uint32, value float32) {
+	r := uint64(z.Reduction)
+	for pos := uint64(start); pos < uint64(end); {
+		segEnd := (pos/r + 1) * r
+		if segEnd > uint64(end) {
+			segEnd = uint64(end)
+		}
+		cur := z.Cur
+		if cur == nil || cur.ChromId != tid || uint64(cur.Start)/r != pos/r {
+			z.finish()
+			cur = &bwSummary{ChromId: tid, Start: uint32(pos), MinVal: value, MaxVal: value}
+			z.Cur = cur
+		}
+		n := float64(segEnd - pos)
+		cur.End = uint32(segEnd)
+		cur.ValidCount += uint32(segEnd - pos)
+		if value < cur.MinVal {
+			cur.MinVal = value
+		}
+		if value > cur.MaxVal {
+			cur.MaxVal = value
+		}
+		cur.SumData += float64(value) * n
+		cur.SumSquares += float64(value) * float64(value) * n
+		pos = segEnd
+	}
+}
+
+// finish 结束当前正在累积的窗口
+func (z *bwZoomBuffer_t) finish() {
+	if z.Cur != nil {
+		z.Records = append(z.Records, *z.Cur)
+		z.Cur = nil
+	}
+}
+
+// reduce 把当前层级的记录合并为 reduction 更大的层级（reduction 必须是当前层级的整数倍）
+func (z *bwZoomBuffer_t) reduce(reduction uint32) *bwZoomBuffer_t {
+	out := &bwZoomBuffer_t{Reduction: reduction}
+	for _, rec := range z.Records {
+		n := len(out.Records)
+		if n > 0 && out.Records[n-1].ChromId == rec.ChromId && out.Records[n-1].Start/reduction == rec.Start/reduction {
+			last := &out.Records[n-1]
+			last.End = rec.End
+			last.ValidCount += rec.ValidCount
+			if rec.MinVal < last.MinVal {
+				last.MinVal = rec.MinVal
+			}
+			if rec.MaxVal > last.MaxVal {
+				last.MaxVal = rec.MaxVal
+			}
+			last.SumData += rec.SumData
+			last.SumSquares += rec.SumSquares
+			continue
+		}
+		out.Records = append(out.Records, rec)
+	}
+	return out
+}
+
+// writeZoomLevel 写出一个缩放层级的数据与索引，返回数据和索引的偏移
+// 每个块只包含同一条染色体的记录
+func (bw *BigWigWriter) writeZoomLevel(z *bwZoomBuffer_t) (uint64, uint64, error) {
+	dataOffset := bw.pos
+	var count [4]byte
+	binary.LittleEndian.PutUint32(count[:], uint32(len(z.Records)))
+	if err := bw.write(count[:]); err != nil {
+		return 0, 0, err
+	}
+
+	perBlock := int(bw.opts.BufSize / bwZoomRecordSize)
+	var first, cur *bwLL
+	nBlocks := uint64(0)
+	le := binary.LittleEndian
+	for i := 0; i < len(z.Records); {
+		j := i
+		var buf bytes.Buffer
+		for j < len(z.Records) && j-i < perBlock && z.Records[j].ChromId == z.Records[i].ChromId {
+			rec := z.Records[j]
+			binary.Write(&buf, le, rec.ChromId)
+			binary.Write(&buf, le, rec.Start)
+			binary.Write(&buf, le, rec.End)
+			binary.Write(&buf, le, rec.ValidCount)
+			binary.Write(&buf, le, math.Float32bits(rec.MinVal))
+			binary.Write(&buf, le, math.Float32bits(rec.MaxVal))
+			binary.Write(&buf, le, math.Float32bits(float32(rec.SumData)))
+			binary.Write(&buf, le, math.Float32bits(float32(rec.SumSquares)))
+			j++
+		}
+		offset, size, err := bw.writeBlock(buf.Bytes())
+		if err != nil {
+			return 0, 0, err
+		}
+		bwIndexAppend(&first, &cur, bw.opts.BlockSize, z.Records[i].ChromId, z.Records[i].Start,
+			z.Records[j-1].ChromId, z.Records[j-1].End, offset, size)
+		nBlocks++
+		i = j
+	}
+
+	indexOffset := bw.pos
+	if err := bw.writeRTree(first, nBlocks); err != nil {
+		return 0, 0, err
+	}
+	return dataOffset, indexOffset, nil
+}
+
+// bwIndexAppend 向叶子节点链表追加一个数据块的索引条目，当前叶子满时新建叶子
+func bwIndexAppend(first, cur **bwLL, blockSize uint32, chrStart, baseStart, chrEnd, baseEnd uint32, offset, size uint64) {
+	if *cur == nil || uint32((*cur).Node.NChildren) >= blockSize {
+		ll := &bwLL{Node: &bwRTreeNode_t{IsLeaf: 1}}
+		if *cur == nil {
+			*first = ll
+		} else {
+			(*cur).Next = ll
+		}
+		*cur = ll
+	}
+	n := (*cur).Node
+	n.ChrIdxStart = append(n.ChrIdxStart, chrStart)
+	n.BaseStart = append(n.BaseStart, baseStart)
+	n.ChrIdxEnd = append(n.ChrIdxEnd, chrEnd)
+	n.BaseEnd = append(n.BaseEnd, baseEnd)
+	n.DataOffset = append(n.DataOffset, offset)
+	n.Size = append(n.Size, size)
+	n.NChildren++
+}
+
+// bwNodeBounds 返回节点覆盖范围：最小的 (chr, base) 起点与最大的 (chr, base) 终点
+func bwNodeBounds(n *bwRTreeNode_t) (chrStart, baseStart, chrEnd, baseEnd uint32) {
+	if n.NChildren == 0 {
+		return 0, 0, 0, 0
+	}
+	chrStart, baseStart = n.ChrIdxStart[0], n.BaseStart[0]
+	chrEnd, baseEnd = n.ChrIdxEnd[0], n.BaseEnd[0]
+	for i := 1; i < int(n.NChildren); i++ {
+		if n.ChrIdxStart[i] < chrStart || (n.ChrIdxStart[i] == chrStart && n.BaseStart[i] < baseStart) {
+			chrStart, baseStart = n.ChrIdxStart[i], n.BaseStart[i]
+		}
+		if n.ChrIdxEnd[i] > chrEnd || (n.ChrIdxEnd[i] == chrEnd && n.BaseEnd[i] > baseEnd) {
+			chrEnd, baseEnd = n.ChrIdxEnd[i], n.BaseEnd[i]
+		}
+	}
+	return
+}
+
+// writeRTree 由叶子节点链表自底向上构造 R 树，并从根节点开始逐层写出
+func (bw *BigWigWriter) writeRTree(leaves *bwLL, nItems uint64) error {
+	bs := int(bw.opts.BlockSize)
+	var lvl []*bwRTreeNode_t
+	for l := leaves; l != nil; l = l.Next {
+		lvl = append(lvl, l.Node)
+	}
+	if len(lvl) == 0 {
+		lvl = []*bwRTreeNode_t{{IsLeaf: 1}}
+	}
+	levels := [][]*bwRTreeNode_t{lvl}
+	for len(lvl) > 1 {
+		var up []*bwRTreeNode_t
+		for i := 0; i < len(lvl); i += bs {
+			node := &bwRTreeNode_t{}
+			for _, c := range lvl[i:min(i+bs, len(lvl))] {
+				cs, bst, ce, be := bwNodeBounds(c)
+				node.ChrIdxStart = append(node.ChrIdxStart, cs)
+				node.BaseStart = append(node.BaseStart, bst)
+				node.ChrIdxEnd = append(node.ChrIdxEnd, ce)
+				node.BaseEnd = append(node.BaseEnd, be)
+				node.DataOffset = append(node.DataOffset, 0)
+				node.Child = append(node.Child, c)
+			}
+			node.NChildren = uint16(len(node.Child))
+			up = append(up, node)
+		}
+		levels = append(levels, up)
+		lvl = up
+	}
+
+	// 根节点紧跟在 48 字节的索引头之后，其余节点逐层排列
+	offsets := make(map[*bwRTreeNode_t]uint64)
+	off := bw.pos + 48
+	for k := len(levels) - 1; k >= 0; k-- {
+		for _, nd := range levels[k] {
+			offsets[nd] = off
+			itemSize := uint64(24)
+			if nd.IsLeaf != 0 {
+				itemSize = 32
+			}
+			off += 4 + uint64(nd.NChildren)*itemSize
+		}
+	}
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	cs, bst, ce, be := bwNodeBounds(lvl[0])
+	binary.Write(&buf, le, uint32(IDX_MAGIC))
+	binary.Write(&buf, le, uint32(bs))
+	binary.Write(&buf, le, nItems)
+	binary.Write(&buf, le, cs)
+	binary.Write(&buf, le, bst)
+	binary.Write(&buf, le, ce)
+	binary.Write(&buf, le, be)
+	binary.Write(&buf, le, bw.pos)
+	binary.Write(&buf, le, uint32(1))
+	binary.Write(&buf, le, uint32(0))
+	for k := len(levels) - 1; k >= 0; k-- {
+		for _, nd := range levels[k] {
+			buf.WriteByte(nd.IsLeaf)
+			buf.WriteByte(0)
+			binary.Write(&buf, le, nd.NChildren)
+			for i := 0; i < int(nd.NChildren); i++ {
+				binary.Write(&buf, le, nd.ChrIdxStart[i])
+				binary.Write(&buf, le, nd.BaseStart[i])
+				binary.Write(&buf, le, nd.ChrIdxEnd[i])
+				binary.Write(&buf, le, nd.BaseEnd[i])
+				if nd.IsLeaf != 0 {
+					binary.Write(&buf, le, nd.DataOffset[i])
+					binary.Write(&buf, le, nd.Size[i])
+				} else {
+					binary.Write(&buf, le, offsets[nd.Child[i]])
+				}
+			}
+		}
+	}
+	return bw.write(buf.Bytes())
+}
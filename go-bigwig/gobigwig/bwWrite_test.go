@@ -0,0 +1,383 @@
+package gobigwig_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// testTrack 是一条染色体上的测试数据：记录互不相邻，值为 0.25 的整数倍，
+// 因而和与平方和都能精确表示，与读回的结果可以逐位比较
+type testTrack struct {
+	chrom  string
+	length uint32
+	kind   string // bedGraph、variableStep 或 fixedStep，决定写入时的块类型
+	span   uint32 // variableStep/fixedStep 的记录宽度
+	step   uint32 // fixedStep 的步长
+	recs   []gb.Interval
+}
+
+// testTracks 返回三种块类型各一条染色体，外加一条没有数据的染色体
+func testTracks() []testTrack {
+	r := rand.New(rand.NewPCG(1, 2))
+	value := func() float32 { return float32(r.IntN(41)-20) / 4 }
+	bed := testTrack{chrom: "chr1", length: 5000, kind: "bedGraph"}
+	for pos := uint32(r.IntN(50)); ; {
+		end := pos + 1 + uint32(r.IntN(60))
+		if end > bed.length {
+			break
+		}
+		bed.recs = append(bed.recs, gb.Interval{Start: pos, End: end, Value: value()})
+		pos = end + 1 + uint32(r.IntN(40))
+	}
+	vs := testTrack{chrom: "chr2", length: 3000, kind: "variableStep", span: 5}
+	for pos := uint32(3); pos+vs.span <= vs.length; pos += vs.span + 1 + uint32(r.IntN(20)) {
+		vs.recs = append(vs.recs, gb.Interval{Start: pos, End: pos + vs.span, Value: value()})
+	}
+	fs := testTrack{chrom: "chr3", length: 2000, kind: "fixedStep", span: 3, step: 10}
+	for i := uint32(0); i < 150; i++ {
+		s := 100 + i*fs.step
+		fs.recs = append(fs.recs, gb.Interval{Start: s, End: s + fs.span, Value: value()})
+	}
+	return []testTrack{bed, vs, fs, {chrom: "chr4", length: 1000}}
+}
+
+// writeTracks 按各自的块类型把 tracks 写入 path
+func writeTracks(t *testing.T, path string, tracks []testTrack, opts ...gb.WriteOption) {
+	t.Helper()
+	var chroms []string
+	var lengths []uint32
+	for _, tr := range tracks {
+		chroms = append(chroms, tr.chrom)
+		lengths = append(lengths, tr.length)
+	}
+	w, err := gb.CreateBigWig(path, chroms, lengths, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range tracks {
+		if len(tr.recs) == 0 {
+			continue
+		}
+		var starts, ends []uint32
+		var values []float32
+		for _, iv := range tr.recs {
+			starts, ends, values = append(starts, iv.Start), append(ends, iv.End), append(values, iv.Value)
+		}
+		switch tr.kind {
+		case "bedGraph":
+			err = w.AddIntervals(tr.chrom, starts, ends, values)
+		case "variableStep":
+			err = w.AddIntervalSpans(tr.chrom, starts, tr.span, values)
+		case "fixedStep":
+			err = w.AddIntervalSpanSteps(tr.chrom, starts[0], tr.span, tr.step, values)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openTest(t *testing.T, path string) *gb.Bigwig_file_out {
+	t.Helper()
+	fp, err := gb.OpenBigWig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gb.CloseBigWig(fp) })
+	return fp
+}
+
+// readAll 返回文件中每条染色体的全部记录，没有记录的染色体不出现在结果中
+func readAll(t *testing.T, path string) map[string][]gb.Interval {
+	t.Helper()
+	fp := openTest(t, path)
+	chroms, err := fp.Chroms()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string][]gb.Interval)
+	for _, c := range chroms {
+		ivs, err := fp.IntervalsContext(context.Background(), c.Name, 0, int(c.Length))
+		if err != nil {
+			t.Fatalf("%s: %v", c.Name, err)
+		}
+		if len(ivs) > 0 {
+			out[c.Name] = ivs
+		}
+	}
+	return out
+}
+
+// wantAll 是 readAll 对 tracks 应得的结果
+func wantAll(tracks []testTrack) map[string][]gb.Interval {
+	out := make(map[string][]gb.Interval)
+	for _, tr := range tracks {
+		if len(tr.recs) > 0 {
+			out[tr.chrom] = tr.recs
+		}
+	}
+	return out
+}
+
+func checkIntervals(t *testing.T, got, want map[string][]gb.Interval) {
+	t.Helper()
+	for chrom := range want {
+		if _, ok := got[chrom]; !ok {
+			t.Errorf("%s: no intervals read back", chrom)
+		}
+	}
+	for chrom, ivs := range got {
+		if !slices.Equal(ivs, want[chrom]) {
+			t.Errorf("%s: read back %d intervals %v, want %d %v", chrom, len(ivs), head(ivs), len(want[chrom]), head(want[chrom]))
+		}
+	}
+}
+
+func head(ivs []gb.Interval) []gb.Interval {
+	return ivs[:min(len(ivs), 3)]
+}
+
+// bases 展开为逐碱基的值，没有数据的碱基为 NaN
+func (tr testTrack) bases() []float32 {
+	out := make([]float32, tr.length)
+	for i := range out {
+		out[i] = float32(math.NaN())
+	}
+	for _, iv := range tr.recs {
+		for p := iv.Start; p < iv.End; p++ {
+			out[p] = iv.Value
+		}
+	}
+	return out
+}
+
+// stats 计算 [s, e) 的统计量，std 为样本标准差
+func stats(vals []float32, typ string, s, e int) float64 {
+	var n, sum, sumSq float64
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range vals[s:e] {
+		if v != v {
+			continue
+		}
+		f := float64(v)
+		n, sum, sumSq = n+1, sum+f, sumSq+f*f
+		lo, hi = math.Min(lo, f), math.Max(hi, f)
+	}
+	switch {
+	case typ == "coverage":
+		return n / float64(e-s)
+	case n == 0:
+		return math.NaN()
+	case typ == "mean":
+		return sum / n
+	case typ == "min":
+		return lo
+	case typ == "max":
+		return hi
+	case typ == "sum":
+		return sum
+	case n == 1:
+		return 0
+	}
+	return math.Sqrt((sumSq - sum*sum/n) / (n - 1))
+}
+
+func sameFloat(a, b float64) bool {
+	return a == b || a != a && b != b
+}
+
+// 写入三种块类型的记录后读回：记录、逐碱基值、精确统计量和文件头中的全局 summary 都与写入的数据一致
+func TestWriteRoundTrip(t *testing.T) {
+	tracks := testTracks()
+	for _, compress := range []bool{true, false} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rt.bw")
+			writeTracks(t, path, tracks, gb.WithCompression(compress), gb.WithBlockSize(4), gb.WithBufSize(256), gb.WithZoomLadder(40, 160))
+			checkIntervals(t, readAll(t, path), wantAll(tracks))
+
+			fp := openTest(t, path)
+			ctx := context.Background()
+			var all []float32
+			for _, tr := range tracks {
+				want := tr.bases()
+				all = append(all, want...)
+				got, err := fp.GetValuesContext(ctx, tr.chrom, 0, int(tr.length))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range want {
+					if !sameFloat(float64(got[i]), float64(want[i])) {
+						t.Fatalf("%s:%d: value %v, want %v", tr.chrom, i, got[i], want[i])
+					}
+				}
+				for _, typ := range []string{"mean", "min", "max", "sum", "coverage", "std"} {
+					const nBins = 5
+					got, err := fp.Stats(ctx, tr.chrom, 0, int(tr.length), gb.StatsOptions{Type: typ, NBins: nBins, Exact: true})
+					if err != nil {
+						t.Fatal(err)
+					}
+					w := int(tr.length) / nBins
+					for i, v := range got {
+						want := stats(want, typ, i*w, (i+1)*w)
+						if !sameFloat(v, want) && !(typ == "std" && math.Abs(v-want) <= 1e-12*want) {
+							t.Errorf("%s bin %d %s: %v, want %v", tr.chrom, i, typ, v, want)
+						}
+					}
+				}
+			}
+
+			h := fp.Header()
+			n := stats(all, "coverage", 0, len(all)) * float64(len(all))
+			var sumSq float64
+			for _, v := range all {
+				if v == v {
+					sumSq += float64(v) * float64(v)
+				}
+			}
+			if float64(h.NBasesCovered) != n || h.MinVal != stats(all, "min", 0, len(all)) || h.MaxVal != stats(all, "max", 0, len(all)) ||
+				h.SumData != stats(all, "sum", 0, len(all)) || h.SumSquared != sumSq {
+				t.Errorf("header summary: bases %d min %v max %v sum %v sumSq %v, want %v %v %v %v %v",
+					h.NBasesCovered, h.MinVal, h.MaxVal, h.SumData, h.SumSquared,
+					n, stats(all, "min", 0, len(all)), stats(all, "max", 0, len(all)), stats(all, "sum", 0, len(all)), sumSq)
+			}
+			if len(h.ZoomLevels) != 2 {
+				t.Errorf("%d zoom levels, want 2", len(h.ZoomLevels))
+			}
+		})
+	}
+}
+
+// Split 的输出每个文件只含一条染色体，Concat 合并后与原文件的记录相同
+func TestSplitConcat(t *testing.T) {
+	tracks := testTracks()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bw")
+	writeTracks(t, src, tracks, gb.WithBlockSize(4), gb.WithBufSize(256))
+	parts, err := gb.Split(src, filepath.Join(dir, "parts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("split into %d files, want 3 (one per chromosome with data)", len(parts))
+	}
+	for _, p := range parts {
+		if got := readAll(t, p); len(got) != 1 {
+			t.Errorf("%s: %d chromosomes with data, want 1", p, len(got))
+		}
+	}
+	dst := filepath.Join(dir, "concat.bw")
+	if err := gb.Concat(parts, dst); err != nil {
+		t.Fatal(err)
+	}
+	checkIntervals(t, readAll(t, dst), wantAll(tracks))
+}
+
+// Merge 按片段合并：只有部分文件有值的片段只合并这些文件的值
+func TestMerge(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.bw"), filepath.Join(dir, "b.bw")
+	writeTracks(t, a, []testTrack{{chrom: "chr1", length: 100, kind: "bedGraph", recs: []gb.Interval{{0, 10, 1}, {20, 30, 2}}}})
+	writeTracks(t, b, []testTrack{{chrom: "chr1", length: 100, kind: "bedGraph", recs: []gb.Interval{{5, 25, 4}}}})
+	for _, c := range []struct {
+		op   gb.AggOp
+		want []gb.Interval
+	}{
+		{gb.AggSum, []gb.Interval{{0, 5, 1}, {5, 10, 5}, {10, 20, 4}, {20, 25, 6}, {25, 30, 2}}},
+		{gb.AggMean, []gb.Interval{{0, 5, 1}, {5, 10, 2.5}, {10, 20, 4}, {20, 25, 3}, {25, 30, 2}}},
+		{gb.AggMax, []gb.Interval{{0, 5, 1}, {5, 25, 4}, {25, 30, 2}}},
+		{gb.AggMin, []gb.Interval{{0, 10, 1}, {10, 20, 4}, {20, 30, 2}}},
+	} {
+		out := filepath.Join(dir, fmt.Sprintf("merged%d.bw", c.op))
+		if err := gb.Merge(out, []string{a, b}, c.op); err != nil {
+			t.Fatal(err)
+		}
+		if got := readAll(t, out)["chr1"]; !slices.Equal(got, c.want) {
+			t.Errorf("%v: %v, want %v", c.op, got, c.want)
+		}
+	}
+}
+
+// Subset 只保留与区间重叠的记录，跨越区间边界的记录被截断
+func TestSubset(t *testing.T) {
+	tracks := testTracks()
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.bw"), filepath.Join(dir, "subset.bw")
+	writeTracks(t, src, tracks, gb.WithBlockSize(4), gb.WithBufSize(256))
+	if err := gb.Subset(src, dst, []gb.Region{{Chrom: "chr1", Start: 1000, End: 2000}, {Chrom: "chr3", Start: 0, End: 2000}}); err != nil {
+		t.Fatal(err)
+	}
+	var clipped []gb.Interval
+	for _, iv := range tracks[0].recs {
+		if iv.End > 1000 && iv.Start < 2000 {
+			clipped = append(clipped, gb.Interval{Start: max(iv.Start, 1000), End: min(iv.End, 2000), Value: iv.Value})
+		}
+	}
+	checkIntervals(t, readAll(t, dst), map[string][]gb.Interval{"chr1": clipped, "chr3": tracks[2].recs})
+}
+
+// Transform 以 fn 变换每条记录的值，记录的位置不变
+func TestTransform(t *testing.T) {
+	tracks := testTracks()
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.bw"), filepath.Join(dir, "doubled.bw")
+	writeTracks(t, src, tracks)
+	if err := gb.Transform(src, dst, func(chrom string, start, end uint32, v float32) float32 { return 2 * v }); err != nil {
+		t.Fatal(err)
+	}
+	want := wantAll(tracks)
+	for chrom, ivs := range want {
+		doubled := slices.Clone(ivs)
+		for i := range doubled {
+			doubled[i].Value *= 2
+		}
+		want[chrom] = doubled
+	}
+	checkIntervals(t, readAll(t, dst), want)
+}
+
+// ConvertBedGraph 和 ConvertWiggle 写出的文件与文本中的记录相同
+func TestConvert(t *testing.T) {
+	tracks := testTracks()
+	sizes := make(map[string]uint32)
+	for _, tr := range tracks {
+		sizes[tr.chrom] = tr.length
+	}
+	num := func(v float32) string { return strconv.FormatFloat(float64(v), 'g', -1, 32) }
+	var bg, wig strings.Builder
+	for _, iv := range tracks[0].recs {
+		fmt.Fprintf(&bg, "chr1\t%d\t%d\t%s\n", iv.Start, iv.End, num(iv.Value))
+	}
+	fmt.Fprintf(&wig, "variableStep chrom=chr2 span=%d\n", tracks[1].span)
+	for _, iv := range tracks[1].recs {
+		fmt.Fprintf(&wig, "%d\t%s\n", iv.Start+1, num(iv.Value))
+	}
+	fs := tracks[2]
+	fmt.Fprintf(&wig, "fixedStep chrom=chr3 start=%d step=%d span=%d\n", fs.recs[0].Start+1, fs.step, fs.span)
+	for _, iv := range fs.recs {
+		fmt.Fprintln(&wig, num(iv.Value))
+	}
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "bg.bw")
+	if err := gb.ConvertBedGraph(strings.NewReader(bg.String()), sizes, out, gb.WithBlockSize(4), gb.WithBufSize(256)); err != nil {
+		t.Fatal(err)
+	}
+	checkIntervals(t, readAll(t, out), map[string][]gb.Interval{"chr1": tracks[0].recs})
+	out = filepath.Join(dir, "wig.bw")
+	if err := gb.ConvertWiggle(strings.NewReader(wig.String()), sizes, out, gb.WithBlockSize(4), gb.WithBufSize(256)); err != nil {
+		t.Fatal(err)
+	}
+	checkIntervals(t, readAll(t, out), map[string][]gb.Interval{"chr2": tracks[1].recs, "chr3": fs.recs})
+}
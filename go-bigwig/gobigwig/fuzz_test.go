@@ -0,0 +1,74 @@
+package gobigwig_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gb "go-bigwig/gobigwig"
+	"go-bigwig/internal/bwtest"
+)
+
+// fuzzFiles 返回作为种子的真实文件，即一致性测试用例中由 wig/bedGraph 文本转换得到的 bigWig
+func fuzzFiles(f *testing.F) [][]byte {
+	paths, err := filepath.Glob("../internal/bwtest/testdata/conformance/*.bw")
+	if err != nil {
+		f.Fatal(err)
+	}
+	var files [][]byte
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			f.Fatal(err)
+		}
+		files = append(files, b)
+	}
+	return files
+}
+
+// fuzzTarget 用真实文件和合成文件得到的种子运行 bwtest 中名为 name 的目标；
+// 解析器的 panic 被 Parse* 转换为 gb.ErrParsePanic，这里把它当作失败
+func fuzzTarget(f *testing.F, name string) {
+	var fn func([]byte) error
+	for _, t := range bwtest.FuzzTargets() {
+		if t.Name == name {
+			fn = t.Fn
+		}
+	}
+	if fn == nil {
+		f.Fatalf("unknown fuzz target %q", name)
+	}
+	seeds, err := bwtest.FuzzSeeds(name, fuzzFiles(f)...)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if err := fn(data); errors.Is(err, gb.ErrParsePanic) {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzHeader(f *testing.F) {
+	fuzzTarget(f, "Header")
+}
+
+func FuzzChromTree(f *testing.F) {
+	fuzzTarget(f, "ChromTree")
+}
+
+func FuzzRTree(f *testing.F) {
+	fuzzTarget(f, "RTree")
+}
+
+func FuzzBlock(f *testing.F) {
+	fuzzTarget(f, "Block")
+}
+
+func FuzzCompressedBlock(f *testing.F) {
+	fuzzTarget(f, "CompressedBlock")
+}
@@ -0,0 +1,12 @@
+package gobigwig
+
+// LibVersion 是 gobigwig 的版本号（语义化版本），共享库通过 BigWigLibVersion 导出；
+// 新增导出函数时增加次版本号，改变已有导出函数的签名或行为时增加主版本号，
+// 调用方可以据此判断哪些函数可用。各版本新增的导出：
+//
+//	0.3.0 BigWigOpenEx
+//	0.4.0 BigWigSetCancelFlag
+//	0.5.0 BigWigOpenW
+//	0.6.0 32 位 Windows 上的 stdcall 包装（函数名加 Std）
+//	0.7.0 BigWigOpenFlat、BigWigInfoInt、BigWigInfoDouble
+const LibVersion = "0.7.0"
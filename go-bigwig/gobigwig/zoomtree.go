@@ -3,7 +3,7 @@ package gobigwig
 import (
 	"bytes"
 	"compress/zlib"
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -22,7 +22,8 @@ type bwSummaryOnDisk struct {
 	SumSquares float32
 }
 
-// bwSummary 内存中的 summary 结构
+// bwSummary 内存中的 summary 结构；SumData 与 SumSquares 在内存中用 float64 累加，
+// 只在写入磁盘时转换为 float32，避免深度测序轨道逐条累加的舍入误差
 type bwSummary struct {
 	ChromId    uint32
 	Start      uint32
@@ -30,8 +31,8 @@ type bwSummary struct {
 	ValidCount uint32
 	MinVal     float32
 	MaxVal     float32
-	SumData    float32
-	SumSquares float32
+	SumData    float64
+	SumSquares float64
 }
 
 // bwGetBestZoom 选择最合适的zoom level
@@ -99,8 +100,125 @@ func bwReadZoomIndex(fp *bigWigFile_t, indexOffset uint64) (*bwRTree_t, error) {
 	return idx, nil
 }
 
+// bwZoomIndex 返回第 zoomIdx 个 zoom 层级的索引，首次使用时加载
+// 每个层级有独立的锁：并发使用同一层级的句柄（包括 Clone 出的句柄）只有一个读取索引，
+// 其余等待并共享它；不同层级的加载互不阻塞
+func bwZoomIndex(fp *bigWigFile_t, zoomIdx int) (*bwRTree_t, error) {
+	zhdr := fp.Hdr.ZoomHdrs[0]
+	mu := &zhdr.loadMu[zoomIdx]
+	mu.Lock()
+	defer mu.Unlock()
+	if idx := zhdr.Idx[zoomIdx]; idx != nil {
+		fp.URL.metrics.CacheHit()
+		return idx, nil
+	}
+	fp.URL.metrics.CacheMiss()
+	idx, err := bwReadZoomIndex(fp, zhdr.IndexOffset[zoomIdx])
+	if err != nil {
+		return nil, err
+	}
+	zhdr.Idx[zoomIdx] = idx
+	return idx, nil
+}
+
+// bwZoomExtent 是 zoom 层级 R 树头部记录的数据范围：从 (chrStart, baseStart) 到 (chrEnd, baseEnd)
+type bwZoomExtent struct {
+	known                                bool // 已经读取
+	empty                                bool // 层级没有任何汇总
+	chrStart, baseStart, chrEnd, baseEnd uint32
+}
+
+// overlaps 判断染色体 tid 上的 [start, end) 是否可能与层级的数据重叠
+func (x bwZoomExtent) overlaps(tid, start, end uint32) bool {
+	if x.empty {
+		return false
+	}
+	if tid < x.chrStart || tid == x.chrStart && end <= x.baseStart {
+		return false
+	}
+	if tid > x.chrEnd || tid == x.chrEnd && start >= x.baseEnd {
+		return false
+	}
+	return true
+}
+
+// bwZoomLevelExtent 返回第 zoomIdx 个 zoom 层级的数据范围并缓存；
+// 索引尚未加载时只读取 R 树头部，不读取任何节点
+func bwZoomLevelExtent(fp *bigWigFile_t, zoomIdx int) (bwZoomExtent, error) {
+	zhdr := fp.Hdr.ZoomHdrs[0]
+	mu := &zhdr.loadMu[zoomIdx]
+	mu.Lock()
+	defer mu.Unlock()
+	ext := &zhdr.extents[zoomIdx]
+	if ext.known {
+		return *ext, nil
+	}
+	idx := zhdr.Idx[zoomIdx]
+	if idx == nil {
+		if zhdr.IndexOffset[zoomIdx] == 0 {
+			return bwZoomExtent{}, errors.New("invalid index offset")
+		}
+		var err error
+		if idx, err = readRTreeIdx(fp, zhdr.IndexOffset[zoomIdx]); err != nil {
+			return bwZoomExtent{}, err
+		}
+	}
+	*ext = bwZoomExtent{
+		known:     true,
+		empty:     idx.NItems == 0,
+		chrStart:  idx.ChrIdxStart,
+		baseStart: idx.BaseStart,
+		chrEnd:    idx.ChrIdxEnd,
+		baseEnd:   idx.BaseEnd,
+	}
+	return *ext, nil
+}
+
+// bwLoadZoomIndexes 读取所有 zoom 层级的完整 R 树，子节点挂在父节点上常驻内存，不经过块缓存
+func bwLoadZoomIndexes(fp *bigWigFile_t) error {
+	if len(fp.Hdr.ZoomHdrs) == 0 {
+		return nil
+	}
+	for i := range fp.Hdr.ZoomHdrs[0].Level {
+		idx, err := bwZoomIndex(fp, i)
+		if err != nil {
+			return fmt.Errorf("zoom level %d: %w", i, err)
+		}
+		if err := bwLoadRTreeChildren(fp, idx.Root); err != nil {
+			return fmt.Errorf("zoom level %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// bwLoadRTreeChildren 递归读取 node 下所有尚未加载的子节点
+func bwLoadRTreeChildren(fp *bigWigFile_t, node *bwRTreeNode_t) error {
+	if node.IsLeaf != 0 {
+		return nil
+	}
+	for i := range node.Child {
+		bwLockIndex(fp)
+		child := node.Child[i]
+		bwUnlockIndex(fp)
+		if child == nil {
+			var err error
+			if child, err = bwGetRTreeNode(fp, node.DataOffset[i]); err != nil {
+				return err
+			}
+			bwLockIndex(fp)
+			node.Child[i] = child
+			bwUnlockIndex(fp)
+		}
+		if err := bwLoadRTreeChildren(fp, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // bwGetSummariesInRegion 从指定zoom level获取区间内的summaries
-func bwGetSummariesInRegion(fp *bigWigFile_t, zoomIdx int, chrom string, start, end uint32) ([]*bwSummary, error) {
+// 每个块的读取与解压后都会检查 ctx，被取消时返回 ctx.Err()
+func bwGetSummariesInRegion(ctx context.Context, fp *bigWigFile_t, zoomIdx int, chrom string, start, end uint32) ([]*bwSummary, error) {
 	if fp.Hdr == nil || len(fp.Hdr.ZoomHdrs) == 0 {
 		return nil, errors.New("no zoom headers available")
 	}
@@ -115,73 +233,74 @@ func bwGetSummariesInRegion(fp *bigWigFile_t, zoomIdx int, chrom string, start,
 		return nil, fmt.Errorf("chromosome not found: %s", chrom)
 	}
 
+	// 区间在该层级的数据范围之外时不读取任何节点（稀疏轨道上很常见）
+	ext, err := bwZoomLevelExtent(fp, zoomIdx)
+	if err != nil {
+		return nil, err
+	}
+	if !ext.overlaps(tid, start, end) {
+		return nil, nil
+	}
+
 	// 读取或使用缓存的索引
-	var zoomTree *bwRTree_t
-	var err error
-	
-	if zhdr.Idx[zoomIdx] == nil {
-		zoomTree, err = bwReadZoomIndex(fp, zhdr.IndexOffset[zoomIdx])
-		if err != nil {
-			return nil, err
-		}
-		zhdr.Idx[zoomIdx] = zoomTree
-	} else {
-		zoomTree = zhdr.Idx[zoomIdx]
+	zoomTree, err := bwZoomIndex(fp, zoomIdx)
+	if err != nil {
+		return nil, err
 	}
 
 	// 查找重叠的数据块
-	blocks := walkRTreeNodes(fp, zoomTree.Root, tid, start, end)
+	blocks, err := walkRTreeNodes(ctx, fp, zoomTree.Root, tid, start, end)
+	if err != nil {
+		return nil, err
+	}
 	if blocks == nil || blocks.N == 0 {
 		return nil, nil
 	}
 
 	// 读取并解析summaries
 	summaries := []*bwSummary{}
-	compressed := fp.Hdr.bufsize > 0
+	order := bwOrder(fp)
+	fp.URL.startPrefetch(ctx, coalesceBlocks(blocks), fp.Opts.PrefetchDepth)
+	defer fp.URL.stopPrefetch()
 
 	for i := uint64(0); i < blocks.N; i++ {
-		// 定位到数据块
-		if bwSetPos(fp, blocks.Offset[i]) != 0 {
-			return nil, errors.New("failed to seek to data block")
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
-
-		// 读取数据
-		compBuf := make([]byte, blocks.Size[i])
-		n, err := fp.URL.Read(compBuf)
-		if err != nil || n != int(blocks.Size[i]) {
-			return nil, fmt.Errorf("failed to read data block: %v", err)
+		// 读取并解压数据块
+		data, err := bwReadBlock(fp, blocks.Offset[i], blocks.Size[i])
+		if err != nil {
+			return nil, err
 		}
-
-		var data []byte
-		if compressed {
-			data, err = decompressZlibSimple(compBuf)
-			if err != nil {
-				return nil, fmt.Errorf("failed to decompress: %v", err)
-			}
-		} else {
-			data = compBuf
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		// 解析summaries
+		// 解析summaries（按文件的字节序）
 		// 每个summary的大小是32字节
 		summarySize := 32
 		numSummaries := len(data) / summarySize
 
 		for j := 0; j < numSummaries; j++ {
+			if j%ctxCheckInterval == 0 && j > 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
 			offset := j * summarySize
 			if offset+summarySize > len(data) {
 				break
 			}
 
 			sum := &bwSummary{
-				ChromId:    binary.LittleEndian.Uint32(data[offset : offset+4]),
-				Start:      binary.LittleEndian.Uint32(data[offset+4 : offset+8]),
-				End:        binary.LittleEndian.Uint32(data[offset+8 : offset+12]),
-				ValidCount: binary.LittleEndian.Uint32(data[offset+12 : offset+16]),
-				MinVal:     math.Float32frombits(binary.LittleEndian.Uint32(data[offset+16 : offset+20])),
-				MaxVal:     math.Float32frombits(binary.LittleEndian.Uint32(data[offset+20 : offset+24])),
-				SumData:    math.Float32frombits(binary.LittleEndian.Uint32(data[offset+24 : offset+28])),
-				SumSquares: math.Float32frombits(binary.LittleEndian.Uint32(data[offset+28 : offset+32])),
+				ChromId:    order.Uint32(data[offset : offset+4]),
+				Start:      order.Uint32(data[offset+4 : offset+8]),
+				End:        order.Uint32(data[offset+8 : offset+12]),
+				ValidCount: order.Uint32(data[offset+12 : offset+16]),
+				MinVal:     math.Float32frombits(order.Uint32(data[offset+16 : offset+20])),
+				MaxVal:     math.Float32frombits(order.Uint32(data[offset+20 : offset+24])),
+				SumData:    float64(math.Float32frombits(order.Uint32(data[offset+24 : offset+28]))),
+				SumSquares: float64(math.Float32frombits(order.Uint32(data[offset+28 : offset+32]))),
 			}
 
 			// 过滤出在查询范围内且染色体匹配的summaries
@@ -194,29 +313,36 @@ func bwGetSummariesInRegion(fp *bigWigFile_t, zoomIdx int, chrom string, start,
 	return summaries, nil
 }
 
-// bwGetValuesFromZoom 使用指定的zoom level获取区间的值（带详细调试输出）
-// summaryType: "mean", "max", "min", "coverage", "sum"
-func bwGetValuesFromZoom(fp *bigWigFile_t, zoomIdx int, chrom string, start, end uint32, numBins int, summaryType string) ([]float32, error) {
-	summaries, err := bwGetSummariesInRegion(fp, zoomIdx, chrom, start, end)
-	if err != nil {
-		return nil, err
-	}
-	values := make([]float32, numBins)
-	for i := range values {
-		values[i] = float32(math.NaN())
-	}
-	if len(summaries) == 0 {
-		return values, nil
-	}
+// bwBinStat 是一个 bin 内按重叠比例累加的 zoom 统计
+type bwBinStat struct {
+	SumData    float64
+	SumSquares float64
+	ValidCount float64 // 按重叠比例分摊的碱基数，不取整
+	MinVal     float32
+	MaxVal     float32
+}
+
+// acc 把 bin 的 zoom 统计转换为 bwStatAcc，以便按 Weighting 求值
+func (b bwBinStat) acc() bwStatAcc {
+	return bwStatAcc{n: b.ValidCount, sum: b.SumData, sumSq: b.SumSquares, min: b.MinVal, max: b.MaxVal}
+}
+
+// bwBinSummaries 把 [start, end) 均分为 numBins 个 bin，按与每个 bin 的重叠比例累加 summaries
+// 每 ctxCheckInterval 个 bin 检查一次 ctx
+func bwBinSummaries(ctx context.Context, summaries []*bwSummary, start, end uint32, numBins int) ([]bwBinStat, error) {
+	bins := make([]bwBinStat, numBins)
 	binSize := float64(end-start) / float64(numBins)
 	for i := 0; i < numBins; i++ {
+		if i%ctxCheckInterval == 0 && i > 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
 		binStart := start + uint32(float64(i)*binSize)
 		binEnd := start + uint32(float64(i+1)*binSize)
-		var sumData float64
-		var validCount uint32
-		var minVal float32 = float32(math.Inf(1))
-		var maxVal float32 = float32(math.Inf(-1))
-		overlapCount := 0
+		b := &bins[i]
+		b.MinVal = float32(math.Inf(1))
+		b.MaxVal = float32(math.Inf(-1))
 		// 找到与当前bin重叠的summaries
 		for _, sum := range summaries {
 			if sum.End <= binStart || sum.Start >= binEnd {
@@ -231,45 +357,67 @@ func bwGetValuesFromZoom(fp *bigWigFile_t, zoomIdx int, chrom string, start, end
 			sumWidth := sum.End - sum.Start
 			overlapFactor := float64(overlap) / float64(sumWidth)
 
-			validCount += uint32(float64(sum.ValidCount) * overlapFactor)
-			sumData += float64(sum.SumData) * overlapFactor
-			if sum.MaxVal > maxVal {
-				maxVal = sum.MaxVal
+			b.ValidCount += float64(sum.ValidCount) * overlapFactor
+			b.SumData += sum.SumData * overlapFactor
+			b.SumSquares += sum.SumSquares * overlapFactor
+			if sum.MaxVal > b.MaxVal {
+				b.MaxVal = sum.MaxVal
 			}
-			if sum.MinVal < minVal {
-				minVal = sum.MinVal
+			if sum.MinVal < b.MinVal {
+				b.MinVal = sum.MinVal
 			}
-			overlapCount++
 		}
+	}
+	return bins, nil
+}
 
-		// 根据summaryType计算最终值
-		if validCount > 0 {
-			switch summaryType {
-			case "mean", "average":
-				values[i] = float32(sumData / float64(validCount))
-			case "max", "maximum":
-				values[i] = maxVal
-			case "min", "minimum":
-				values[i] = minVal
-			case "coverage":
-				covFactor := float64(numBins) / float64(end-start)
-				values[i] = float32(covFactor * float64(validCount))
-			case "sum":
-				values[i] = float32(sumData)
-			default:
-				values[i] = float32(sumData / float64(validCount))
-			}
-		}
+// bwGetValuesFromZoom 使用指定的zoom level获取区间的值（带详细调试输出）
+// summaryType: "mean", "max", "min", "coverage", "sum"
+func bwGetValuesFromZoom(ctx context.Context, fp *bigWigFile_t, zoomIdx int, chrom string, start, end uint32, numBins int, summaryType string) ([]float32, error) {
+	values64, err := bwGetValuesFromZoom64(ctx, fp, zoomIdx, chrom, start, end, numBins, summaryType)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float32, len(values64))
+	for i, v := range values64 {
+		values[i] = float32(v)
+	}
+	return values, nil
+}
+
+// bwGetValuesFromZoom64 与 bwGetValuesFromZoom 相同，但全程以 float64 计算并返回
+func bwGetValuesFromZoom64(ctx context.Context, fp *bigWigFile_t, zoomIdx int, chrom string, start, end uint32, numBins int, summaryType string) ([]float64, error) {
+	summaries, err := bwGetSummariesInRegion(ctx, fp, zoomIdx, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, numBins)
+	for i := range values {
+		values[i] = math.NaN()
+	}
+	if len(summaries) == 0 {
+		return values, nil
+	}
+	bins, err := bwBinSummaries(ctx, summaries, start, end, numBins)
+	if err != nil {
+		return nil, err
+	}
+	// 根据summaryType和Weighting计算最终值
+	w := bwResolveWeighting(fp, WeightDefault)
+	for i, b := range bins {
+		s, e := bwBinEdges(int(start), int(end), numBins, i)
+		acc := b.acc()
+		values[i] = acc.result(summaryType, uint32(e-s), w)
 	}
 
 	return values, nil
 }
 
 // bwGetValuesAutoZoom 自动选择合适的zoom level并获取值
-func bwGetValuesAutoZoom(fp *bigWigFile_t, chrom string, start, end uint32, numBins int, summaryType string) ([]float32, error) {
+func bwGetValuesAutoZoom(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32, numBins int, summaryType string) ([]float32, error) {
 	if fp.Hdr == nil || len(fp.Hdr.ZoomHdrs) == 0 || fp.Hdr.ZoomHdrs[0] == nil {
 		// 没有zoom数据，使用原始数据
-		return bwGetValuesFromRaw(fp, chrom, start, end, numBins, summaryType)
+		return bwGetValuesFromRaw(ctx, fp, chrom, start, end, numBins, summaryType)
 	}
 
 	// 计算期望的reduction level
@@ -285,16 +433,20 @@ func bwGetValuesAutoZoom(fp *bigWigFile_t, chrom string, start, end uint32, numB
 
 	if bestIdx >= 0 {
 		// 使用zoom level
-		return bwGetValuesFromZoom(fp, bestIdx, chrom, start, end, numBins, summaryType)
+		return bwGetValuesFromZoom(ctx, fp, bestIdx, chrom, start, end, numBins, summaryType)
 	}
 
 	// 如果没有合适的zoom level，使用原始数据
-	return bwGetValuesFromRaw(fp, chrom, start, end, numBins, summaryType)
+	return bwGetValuesFromRaw(ctx, fp, chrom, start, end, numBins, summaryType)
 }
 
-// bwGetValuesFromRaw 从原始数据获取值（无zoom）
-func bwGetValuesFromRaw(fp *bigWigFile_t, chrom string, start, end uint32, numBins int, summaryType string) ([]float32, error) {
-	intervals := bwGetOverlappingIntervals(fp, chrom, start, end)
+// bwGetValuesFromRaw 从原始数据获取值（无zoom），重叠区间按 OverlapPolicy 合并后再分箱
+func bwGetValuesFromRaw(ctx context.Context, fp *bigWigFile_t, chrom string, start, end uint32, numBins int, summaryType string) ([]float32, error) {
+	intervals, err := bwGetOverlappingIntervals(ctx, fp, chrom, start, end)
+	if err != nil {
+		return nil, err
+	}
+	intervals = bwResolveOverlaps(intervals, start, end, fp.Opts.OverlapPolicy)
 	if intervals == nil || intervals.L == 0 {
 		values := make([]float32, numBins)
 		for i := range values {
@@ -311,6 +463,11 @@ func bwGetValuesFromRaw(fp *bigWigFile_t, chrom string, start, end uint32, numBi
 	binSize := float64(end-start) / float64(numBins)
 
 	for i := 0; i < numBins; i++ {
+		if i%ctxCheckInterval == 0 && i > 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
 		binStart := start + uint32(float64(i)*binSize)
 		binEnd := start + uint32(float64(i+1)*binSize)
 
@@ -1,3 +1,12 @@
+//go:build cgo_export
+
+// 共享库入口：C 接口只在 cgo_export 标签下编译，纯 Go 使用者不需要 C 工具链
+//
+//	go build -tags cgo_export -buildmode=c-shared -o winbbi.dll .
+//
+// 32 位 Windows（导出额外带有 stdcall 版本，见 gobigwig/api_stdcall_windows_386.c）：
+//
+//	GOARCH=386 CGO_ENABLED=1 CC=i686-w64-mingw32-gcc go build -tags cgo_export -buildmode=c-shared -o winbbi32.dll .
 package main
 
 // 导入你的 gobigwig 包（触发 C 绑定接口的导出）
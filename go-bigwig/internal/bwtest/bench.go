@@ -0,0 +1,125 @@
+package bwtest
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// Files 是基准测试使用的一个合成文件
+type Files struct {
+	Spec Spec
+	Path string // 本地文件路径
+	Data []byte // 文件内容，模拟远程文件时由内存提供
+}
+
+// Prepare 按 spec 在 dir 下生成文件并读入内存
+func Prepare(dir string, spec Spec) (*Files, error) {
+	path, err := Synthesize(dir, spec)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Files{Spec: spec.withDefaults(), Path: path, Data: data}, nil
+}
+
+// Benchmark 是一个命名的基准测试。Setup 打开文件、启动服务器等，返回第 i 次迭代执行的 run
+// 和释放资源的 cleanup；只有 run 计入耗时。bench_test.go 中的 Benchmark* 函数（go test -bench）
+// 和 cmd/bwbench 用同样的循环运行它，本包因此不依赖 testing
+type Benchmark struct {
+	Name  string
+	Setup func() (run func(i int) error, cleanup func(), err error)
+}
+
+// Suite 返回针对 f 的基准测试：打开文件、逐碱基与区间查询、zoom 和精确的分箱统计，
+// 以及每个请求延迟 latency 的远程文件的打开与查询；查询区间由固定种子随机选取，各次运行可比较
+func Suite(f *Files, latency time.Duration) []Benchmark {
+	values := func(ctx context.Context, fp *gb.Bigwig_file_out, r gb.Region) error {
+		_, err := fp.GetValuesContext(ctx, r.Chrom, r.Start, r.End)
+		return err
+	}
+	return []Benchmark{
+		{"Open", func() (func(int) error, func(), error) { return benchOpen(f.Path), func() {}, nil }},
+		{"OpenLazy", func() (func(int) error, func(), error) {
+			return benchOpen(f.Path, gb.WithLazyLoad()), func() {}, nil
+		}},
+		{"Values/10kb", func() (func(int) error, func(), error) {
+			return benchQuery(f, f.Path, 10_000, values)
+		}},
+		{"Intervals/100kb", func() (func(int) error, func(), error) {
+			return benchQuery(f, f.Path, 100_000, func(ctx context.Context, fp *gb.Bigwig_file_out, r gb.Region) error {
+				_, err := fp.IntervalsContext(ctx, r.Chrom, r.Start, r.End)
+				return err
+			})
+		}},
+		{"Stats/zoom/1Mb/100bins", func() (func(int) error, func(), error) {
+			return benchQuery(f, f.Path, 1_000_000, func(ctx context.Context, fp *gb.Bigwig_file_out, r gb.Region) error {
+				_, err := fp.Stats(ctx, r.Chrom, r.Start, r.End, gb.StatsOptions{NBins: 100})
+				return err
+			})
+		}},
+		{"Stats/exact/100kb/10bins", func() (func(int) error, func(), error) {
+			return benchQuery(f, f.Path, 100_000, func(ctx context.Context, fp *gb.Bigwig_file_out, r gb.Region) error {
+				_, err := fp.Stats(ctx, r.Chrom, r.Start, r.End, gb.StatsOptions{NBins: 10, Exact: true})
+				return err
+			})
+		}},
+		{"Remote/Open", func() (func(int) error, func(), error) {
+			srv := Serve(f.Data, latency)
+			return benchOpen(srv.URL+"/synth.bw", gb.WithWholeFileThreshold(0)), srv.Close, nil
+		}},
+		{"Remote/Values/10kb", func() (func(int) error, func(), error) {
+			srv := Serve(f.Data, latency)
+			run, cleanup, err := benchQuery(f, srv.URL+"/synth.bw", 10_000, values, gb.WithWholeFileThreshold(0))
+			if err != nil {
+				srv.Close()
+				return nil, nil, err
+			}
+			return run, func() { cleanup(); srv.Close() }, nil
+		}},
+	}
+}
+
+// benchOpen 返回每次打开并关闭 path 的迭代
+func benchOpen(path string, opts ...gb.OpenOption) func(int) error {
+	return func(int) error {
+		fp, err := gb.OpenBigWig(path, opts...)
+		if err != nil {
+			return err
+		}
+		gb.CloseBigWig(fp)
+		return nil
+	}
+}
+
+// benchQuery 打开 path 一次，返回的迭代 i 对第 i 个随机选取的宽度为 width 的区间调用 query
+func benchQuery(f *Files, path string, width int, query func(context.Context, *gb.Bigwig_file_out, gb.Region) error, opts ...gb.OpenOption) (func(int) error, func(), error) {
+	fp, err := gb.OpenBigWig(path, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	regions := RandomRegions(f.Spec, width, 1024, 1)
+	ctx := context.Background()
+	run := func(i int) error { return query(ctx, fp, regions[i%len(regions)]) }
+	return run, func() { gb.CloseBigWig(fp) }, nil
+}
+
+// RandomRegions 用 seed 在 spec 描述的基因组上均匀选取 n 个宽度为 width 的区间（坐标从 0 开始）
+func RandomRegions(spec Spec, width, n int, seed uint64) []gb.Region {
+	spec = spec.withDefaults()
+	width = min(width, int(spec.ChromLen))
+	names := spec.ChromNames()
+	rng := rand.New(rand.NewPCG(seed, seed))
+	regions := make([]gb.Region, n)
+	for i := range regions {
+		start := rng.IntN(int(spec.ChromLen) - width + 1)
+		regions[i] = gb.Region{Chrom: names[rng.IntN(len(names))], Start: start, End: start + width}
+	}
+	return regions
+}
@@ -0,0 +1,90 @@
+package bwtest
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+// benchFiles 是基准测试共用的合成文件，第一个基准测试运行时生成，TestMain 结束时删除
+var (
+	benchOnce  sync.Once
+	benchDir   string
+	benchFiles *Files
+	benchErr   error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if benchDir != "" {
+		os.RemoveAll(benchDir)
+	}
+	os.Exit(code)
+}
+
+// runSuite 用默认 Spec 的合成文件运行 Suite 中名为 name 的基准测试
+func runSuite(b *testing.B, name string) {
+	benchOnce.Do(func() {
+		if benchDir, benchErr = os.MkdirTemp("", "bwtest-bench-*"); benchErr == nil {
+			benchFiles, benchErr = Prepare(benchDir, Spec{Seed: 1})
+		}
+	})
+	if benchErr != nil {
+		b.Fatal(benchErr)
+	}
+	for _, bm := range Suite(benchFiles, 0) {
+		if bm.Name == name {
+			runBenchmark(b, bm)
+			return
+		}
+	}
+	b.Fatalf("unknown benchmark %q", name)
+}
+
+func BenchmarkOpen(b *testing.B) {
+	runSuite(b, "Open")
+}
+
+func BenchmarkOpenLazy(b *testing.B) {
+	runSuite(b, "OpenLazy")
+}
+
+func BenchmarkValues10kb(b *testing.B) {
+	runSuite(b, "Values/10kb")
+}
+
+func BenchmarkIntervals100kb(b *testing.B) {
+	runSuite(b, "Intervals/100kb")
+}
+
+func BenchmarkStatsZoom1Mb100Bins(b *testing.B) {
+	runSuite(b, "Stats/zoom/1Mb/100bins")
+}
+
+func BenchmarkStatsExact100kb10Bins(b *testing.B) {
+	runSuite(b, "Stats/exact/100kb/10bins")
+}
+
+func BenchmarkRemoteOpen(b *testing.B) {
+	runSuite(b, "Remote/Open")
+}
+
+func BenchmarkRemoteValues10kb(b *testing.B) {
+	runSuite(b, "Remote/Values/10kb")
+}
+
+// runBenchmark 在 bm.Setup 之后计时，调用 b.N 次 run
+func runBenchmark(b *testing.B, bm Benchmark) {
+	run, cleanup, err := bm.Setup()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer cleanup()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := run(i); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package bwtest 生成可控密度和块大小的合成 bigWig 文件，并提供覆盖打开、区间查询、分箱统计
+// 和远程读取的基准测试集，供 go test -bench 和 cmd/bwbench 运行，使性能回归可以被测量和比较；
+// 还提供按 libBigWig 的定义逐位比较查询、zoom 和统计结果的一致性测试（CheckConformance），供 go test 和 cmd/bwconform 运行，
+// 以及各解析阶段的模糊测试目标（FuzzTargets），供 gobigwig 的 Fuzz* 函数（go test -fuzz）和 cmd/bwfuzz 运行
+package bwtest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// Spec 描述要生成的合成文件，零值字段使用括号中的默认值
+type Spec struct {
+	Chroms    int     // 染色体数（3）
+	ChromLen  uint32  // 每条染色体的长度（10,000,000）
+	Density   float64 // 有数据的碱基比例，0 到 1（0.5）
+	Span      uint32  // 每条记录覆盖的碱基数（10）
+	BlockSize uint32  // R 树节点的最大子节点数，即每个数据块的记录数（写入器默认值）
+	Seed      uint64  // 随机数种子，相同的 Spec 生成相同的文件
+}
+
+func (s Spec) withDefaults() Spec {
+	if s.Chroms <= 0 {
+		s.Chroms = 3
+	}
+	if s.ChromLen == 0 {
+		s.ChromLen = 10_000_000
+	}
+	if s.Density <= 0 || s.Density > 1 {
+		s.Density = 0.5
+	}
+	if s.Span == 0 {
+		s.Span = 10
+	}
+	return s
+}
+
+// String 返回 Spec 的简短描述，用作基准测试名称的一部分
+func (s Spec) String() string {
+	s = s.withDefaults()
+	return fmt.Sprintf("chroms=%d,len=%d,density=%g,span=%d,block=%d", s.Chroms, s.ChromLen, s.Density, s.Span, s.BlockSize)
+}
+
+// ChromNames 返回 Spec 生成的染色体名 chr1、chr2……
+func (s Spec) ChromNames() []string {
+	s = s.withDefaults()
+	names := make([]string, s.Chroms)
+	for i := range names {
+		names[i] = fmt.Sprintf("chr%d", i+1)
+	}
+	return names
+}
+
+// Synthesize 按 spec 用 gb.CreateBigWig 在 dir 下生成文件并返回路径：每条染色体由长度为 Span 的记录组成，
+// 记录之间的间隔服从指数分布，使有数据的碱基比例接近 Density；值在 [0, 100) 上均匀分布
+func Synthesize(dir string, spec Spec) (string, error) {
+	spec = spec.withDefaults()
+	name := strings.NewReplacer(",", "_", "=", "").Replace(spec.String())
+	path := filepath.Join(dir, fmt.Sprintf("synth_%s_%d.bw", name, spec.Seed))
+	names := spec.ChromNames()
+	lengths := make([]uint32, len(names))
+	for i := range lengths {
+		lengths[i] = spec.ChromLen
+	}
+	var opts []gb.WriteOption
+	if spec.BlockSize > 0 {
+		opts = append(opts, gb.WithBlockSize(spec.BlockSize))
+	}
+	w, err := gb.CreateBigWig(path, names, lengths, opts...)
+	if err != nil {
+		return "", err
+	}
+	rng := rand.New(rand.NewPCG(spec.Seed, spec.Seed))
+	// 记录之间的平均间隔为 Span*(1-Density)/Density
+	meanGap := float64(spec.Span) * (1 - spec.Density) / spec.Density
+	const batch = 4096
+	starts := make([]uint32, 0, batch)
+	values := make([]float32, 0, batch)
+	for _, c := range names {
+		pos := uint64(0)
+		for {
+			if meanGap > 0 {
+				pos += uint64(rng.ExpFloat64() * meanGap)
+			}
+			if pos+uint64(spec.Span) > uint64(spec.ChromLen) {
+				break
+			}
+			starts = append(starts, uint32(pos))
+			values = append(values, rng.Float32()*100)
+			pos += uint64(spec.Span)
+			if len(starts) == batch {
+				if err := w.AddIntervalSpans(c, starts, spec.Span, values); err != nil {
+					w.Close()
+					return "", err
+				}
+				starts, values = starts[:0], values[:0]
+			}
+		}
+		if len(starts) > 0 {
+			if err := w.AddIntervalSpans(c, starts, spec.Span, values); err != nil {
+				w.Close()
+				return "", err
+			}
+			starts, values = starts[:0], values[:0]
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// SynthesizeBytes 与 Synthesize 相同，但返回文件内容，生成时使用的临时文件随即删除
+func SynthesizeBytes(spec Spec) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "bwtest-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path, err := Synthesize(dir, spec)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Serve 在本机启动一个从内存提供 data 的 HTTP 服务器（支持 Range 和 ETag），每个请求先等待 latency，
+// 用于模拟远程文件；文件的 URL 为 srv.URL + "/synth.bw"，用完后调用 srv.Close
+func Serve(data []byte, latency time.Duration) *httptest.Server {
+	etag := fmt.Sprintf(`"%x"`, len(data))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "synth.bw", time.Time{}, bytes.NewReader(data))
+	}))
+}
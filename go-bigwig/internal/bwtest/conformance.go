@@ -0,0 +1,149 @@
+package bwtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// Fixture 是一个一致性测试用例：bigWig 文件 File（相对于 .expected.json 所在目录）和一组查询，
+// 期望结果由 testdata/conformance/make_fixtures.sh 生成一次后随仓库提交：默认直接从 wig/bedGraph 文本源
+// 按 libBigWig 的定义计算，REFERENCE=1 时由 UCSC 工具生成文件、pyBigWig（libBigWig）读取得到
+type Fixture struct {
+	File    string             `json:"file"`
+	Queries []ConformanceQuery `json:"queries"`
+}
+
+// ConformanceQuery 是一个查询及其期望结果，坐标从 0 开始、左闭右开
+// Kind 为 intervals（区间内的原始记录）、values（逐碱基的值）或 stats（与 pyBigWig 的 bw.stats 相同，
+// Exact 为 false 时走 zoom 汇总路径）
+type ConformanceQuery struct {
+	Kind      string       `json:"kind"`
+	Chrom     string       `json:"chrom"`
+	Start     int          `json:"start"`
+	End       int          `json:"end"`
+	Type      string       `json:"type,omitempty"`
+	NBins     int          `json:"nBins,omitempty"`
+	Exact     bool         `json:"exact,omitempty"`
+	Intervals [][3]float64 `json:"intervals,omitempty"`
+	Values    []*float64   `json:"values,omitempty"` // null 表示 NaN
+}
+
+// Mismatch 是一处与期望结果不一致的地方
+type Mismatch struct {
+	Fixture string // .expected.json 的路径
+	Query   int    // 查询在 Queries 中的下标
+	Msg     string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s: query %d: %s", m.Fixture, m.Query, m.Msg)
+}
+
+// CheckConformance 读取 dir 下所有 *.expected.json，执行其中的查询并与期望结果逐位比较：
+// 坐标必须相等，记录值和逐碱基的值按 float32、统计量按 float64 比较二进制表示（NaN 与 null 对应）
+// 返回不一致之处和执行的查询数；dir 中没有用例时返回 0 个查询
+func CheckConformance(ctx context.Context, dir string) ([]Mismatch, int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.expected.json"))
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(paths)
+	var mismatches []Mismatch
+	n := 0
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, n, err
+		}
+		var fx Fixture
+		if err := json.Unmarshal(b, &fx); err != nil {
+			return nil, n, fmt.Errorf("%s: %w", p, err)
+		}
+		fp, err := gb.OpenBigWig(filepath.Join(filepath.Dir(p), fx.File))
+		if err != nil {
+			return nil, n, fmt.Errorf("%s: %w", p, err)
+		}
+		for i, q := range fx.Queries {
+			if msg := checkQuery(ctx, fp, q); msg != "" {
+				mismatches = append(mismatches, Mismatch{Fixture: p, Query: i, Msg: fmt.Sprintf("%s %s:%d-%d: %s", q.Kind, q.Chrom, q.Start, q.End, msg)})
+			}
+			n++
+		}
+		gb.CloseBigWig(fp)
+	}
+	return mismatches, n, nil
+}
+
+// checkQuery 执行 q，与期望一致时返回空字符串
+func checkQuery(ctx context.Context, fp *gb.Bigwig_file_out, q ConformanceQuery) string {
+	switch q.Kind {
+	case "intervals":
+		got, err := fp.IntervalsContext(ctx, q.Chrom, q.Start, q.End)
+		if err != nil {
+			return err.Error()
+		}
+		if len(got) != len(q.Intervals) {
+			return fmt.Sprintf("got %d intervals, want %d", len(got), len(q.Intervals))
+		}
+		for i, iv := range got {
+			want := q.Intervals[i]
+			if float64(iv.Start) != want[0] || float64(iv.End) != want[1] || !sameFloat32(iv.Value, float32(want[2])) {
+				return fmt.Sprintf("interval %d: got [%d, %d) %v, want [%g, %g) %v", i, iv.Start, iv.End, iv.Value, want[0], want[1], float32(want[2]))
+			}
+		}
+	case "values":
+		got, err := fp.GetValuesContext(ctx, q.Chrom, q.Start, q.End)
+		if err != nil {
+			return err.Error()
+		}
+		if len(got) != len(q.Values) {
+			return fmt.Sprintf("got %d values, want %d", len(got), len(q.Values))
+		}
+		for i, v := range got {
+			if want := expected(q.Values[i]); !sameFloat32(v, float32(want)) {
+				return fmt.Sprintf("base %d: got %v, want %v", q.Start+i, v, float32(want))
+			}
+		}
+	case "stats":
+		got, err := fp.Stats(ctx, q.Chrom, q.Start, q.End, gb.StatsOptions{Type: q.Type, NBins: q.NBins, Exact: q.Exact})
+		if err != nil {
+			return err.Error()
+		}
+		if len(got) != len(q.Values) {
+			return fmt.Sprintf("got %d bins, want %d", len(got), len(q.Values))
+		}
+		for i, v := range got {
+			want := expected(q.Values[i])
+			if math.Float64bits(v) != math.Float64bits(want) && !(math.IsNaN(v) && math.IsNaN(want)) {
+				return fmt.Sprintf("%s bin %d: got %v, want %v", q.Type, i, v, want)
+			}
+		}
+	default:
+		return fmt.Sprintf("unknown query kind %q (want %s)", q.Kind, strings.Join([]string{"intervals", "values", "stats"}, ", "))
+	}
+	return ""
+}
+
+// expected 把 null 转换为 NaN
+func expected(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
+
+// sameFloat32 按二进制表示比较，所有 NaN 视为相等
+func sameFloat32(a, b float32) bool {
+	if a != a || b != b {
+		return a != a && b != b
+	}
+	return math.Float32bits(a) == math.Float32bits(b)
+}
@@ -0,0 +1,20 @@
+package bwtest
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	mismatches, n, err := CheckConformance(context.Background(), "testdata/conformance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no conformance queries found in testdata/conformance (run make_fixtures.sh)")
+	}
+	for _, m := range mismatches {
+		t.Error(m)
+	}
+	t.Logf("%d queries, %d mismatches", n, len(mismatches))
+}
@@ -0,0 +1,149 @@
+package bwtest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// bwRTreeHeaderSize 是 R 树索引头的字节数，根节点紧随其后
+const bwRTreeHeaderSize = 48
+
+// FuzzTarget 是一个解析阶段的模糊测试目标：Fn 把任意字节交给对应的 gb.Parse* 函数，
+// 输入不合法时返回错误；解析器的 panic 被转换为包装 gb.ErrParsePanic 的错误，应视为失败
+type FuzzTarget struct {
+	Name string
+	Fn   func(data []byte) error
+}
+
+// FuzzTargets 返回文件头、chrom B+ 树、R 树节点和数据块四个解析阶段的目标
+// chrom 树和 R 树的目标以整个文件为输入，按其中的文件头定位要解析的结构，
+// 使变异既能落在结构本身，也能落在指向它的偏移上
+func FuzzTargets() []FuzzTarget {
+	return []FuzzTarget{
+		{"Header", func(data []byte) error {
+			_, err := gb.ParseHeader(data)
+			return err
+		}},
+		{"ChromTree", func(data []byte) error {
+			h, err := gb.ParseHeader(data)
+			if err != nil {
+				return err
+			}
+			_, err = gb.ParseChromTree(data, h.ChromTreeOffset)
+			return err
+		}},
+		{"RTree", func(data []byte) error {
+			h, err := gb.ParseHeader(data)
+			if err != nil {
+				return err
+			}
+			// 解析根节点及其（非叶子时的）子节点，数量有上限以免输入构造出巨大的遍历
+			offsets := []uint64{h.IndexOffset + bwRTreeHeaderSize}
+			for i := 0; i < len(offsets) && i < 64; i++ {
+				n, err := gb.ParseRTreeNode(data, offsets[i])
+				if err != nil {
+					return err
+				}
+				if !n.IsLeaf {
+					for _, c := range n.Children {
+						offsets = append(offsets, c.Offset)
+					}
+				}
+			}
+			return nil
+		}},
+		{"Block", func(data []byte) error {
+			// 同样的字节也按大端解码一次；种子是小端的，大端解码的普通错误不报告
+			_, _, err := gb.ParseDataBlock(data)
+			if _, berr := gb.DecodeBlockOrder(data, false, binary.BigEndian); errors.Is(berr, gb.ErrParsePanic) {
+				return berr
+			}
+			return err
+		}},
+		{"CompressedBlock", func(data []byte) error {
+			_, err := gb.DecodeBlock(data, true)
+			return err
+		}},
+	}
+}
+
+// FuzzSeeds 返回目标 name 的种子语料：Header/ChromTree/RTree 为 files（例如 testdata 中的真实文件）
+// 和几个小的合成文件，Block/CompressedBlock 为从这些文件中取出的（压缩或解压后的）数据块
+func FuzzSeeds(name string, files ...[]byte) ([][]byte, error) {
+	specs := []Spec{
+		{Chroms: 1, ChromLen: 2_000, Density: 0.5, Span: 1, BlockSize: 4, Seed: 1},
+		{Chroms: 3, ChromLen: 10_000, Density: 0.2, Span: 25, BlockSize: 8, Seed: 2},
+		{Chroms: 2, ChromLen: 50_000, Density: 0.9, Span: 5, Seed: 3},
+	}
+	files = append([][]byte(nil), files...)
+	for _, spec := range specs {
+		data, err := SynthesizeBytes(spec)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, data)
+	}
+	var seeds [][]byte
+	for _, data := range files {
+		switch name {
+		case "Header", "ChromTree", "RTree":
+			seeds = append(seeds, data)
+		case "Block", "CompressedBlock":
+			blocks, err := fuzzBlocks(data, name == "CompressedBlock")
+			if err != nil {
+				return nil, err
+			}
+			seeds = append(seeds, blocks...)
+		default:
+			return nil, fmt.Errorf("unknown fuzz target %q", name)
+		}
+	}
+	return seeds, nil
+}
+
+// fuzzBlocks 返回文件 data 中前几个叶子节点指向的数据块，compressed 为 false 时解压后返回
+func fuzzBlocks(data []byte, compressed bool) ([][]byte, error) {
+	h, err := gb.ParseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	var blocks [][]byte
+	offsets := []uint64{h.IndexOffset + bwRTreeHeaderSize}
+	for len(offsets) > 0 && len(blocks) < 4 {
+		n, err := gb.ParseRTreeNode(data, offsets[0])
+		if err != nil {
+			return nil, err
+		}
+		offsets = offsets[1:]
+		for _, c := range n.Children {
+			if !n.IsLeaf {
+				offsets = append(offsets, c.Offset)
+				continue
+			}
+			b := data[c.Offset : c.Offset+c.Size]
+			if !compressed && h.BufSize > 0 {
+				r, err := zlib.NewReader(bytes.NewReader(b))
+				if err != nil {
+					return nil, err
+				}
+				var buf bytes.Buffer
+				_, err = buf.ReadFrom(r)
+				r.Close()
+				if err != nil {
+					return nil, err
+				}
+				b = buf.Bytes()
+			}
+			blocks = append(blocks, b)
+			if len(blocks) == 4 {
+				break
+			}
+		}
+	}
+	return blocks, nil
+}
@@ -0,0 +1,309 @@
+#!/usr/bin/env python3
+"""按 bwtest.Fixture 的格式输出一个一致性测试用例的查询和期望结果
+
+    python3 gen_expected.py SOURCE chrom.sizes file.bw              # 期望结果直接由文本源计算
+    python3 gen_expected.py --pybigwig SOURCE chrom.sizes file.bw   # 期望结果由 pyBigWig（libBigWig）读取 file.bw 得到
+
+SOURCE 是生成 file.bw 的 wig 或 bedGraph 文件。两种模式使用同一组查询，查询区间由 SOURCE 中的记录决定：
+intervals 查询的边界都落在记录边界上（不依赖记录是否被截断到查询区间），stats 查询的宽度能被 bin 数整除。
+zoom 路径（exact 为 false）除整条染色体一个 bin 的 min/max/coverage 外，还在有数据的窗口上以 1、4、10 个 bin
+检查全部六种统计量，这些窗口的每个 bin 至少含两个不同的值，不依赖空 bin 和单碱基 bin 的约定
+
+文本源模式按 libBigWig 的定义计算：逐碱基值为 float32，统计量按记录顺序以 float64 累加
+（mean = sum/n，std 为样本标准差 sqrt((sumSq - sum*sum/n)/(n-1))），没有数据的 bin 为 null。
+zoom 路径与 libBigWig 的 bwStats 相同：按每 bin 碱基数的一半选择 reduction 不超过它的最接近层级
+（没有时使用原始数据），读取 file.bw 中该层级的 summary，与 bin 部分重叠的 summary 按重叠比例计入。
+浮点数以 repr 输出，可以无损地还原
+"""
+import json
+import math
+import os
+import random
+import struct
+import sys
+import zlib
+
+STATS = ("mean", "min", "max", "sum", "std", "coverage")
+WHOLE_CHROM_ZOOM_STATS = ("min", "max", "coverage")
+ZOOM_BINS = (1, 4, 10)
+
+
+def f32(s):
+    return struct.unpack("f", struct.pack("f", float(s)))[0]
+
+
+def read_sizes(path):
+    sizes = {}
+    for line in open(path):
+        f = line.split()
+        if f:
+            sizes[f[0]] = int(f[1])
+    return sizes
+
+
+def read_source(path):
+    """返回 {chrom: [(start, end, value), ...]}，坐标从 0 开始、左闭右开，按 start 排序"""
+    recs = {}
+    mode = None
+    for line in open(path):
+        f = line.split()
+        if not f or f[0] in ("track", "browser") or f[0].startswith("#"):
+            continue
+        if f[0] in ("fixedStep", "variableStep"):
+            mode = f[0]
+            kv = dict(x.split("=", 1) for x in f[1:])
+            chrom, span = kv["chrom"], int(kv.get("span", 1))
+            if mode == "fixedStep":
+                pos, step = int(kv["start"]) - 1, int(kv.get("step", 1))
+            continue
+        if mode == "fixedStep":
+            recs.setdefault(chrom, []).append((pos, pos + span, f32(f[0])))
+            pos += step
+        elif mode == "variableStep":
+            s = int(f[0]) - 1
+            recs.setdefault(chrom, []).append((s, s + span, f32(f[1])))
+        else:
+            recs.setdefault(f[0], []).append((int(f[1]), int(f[2]), f32(f[3])))
+    for r in recs.values():
+        r.sort()
+    return recs
+
+
+def queries(sizes, recs, rng):
+    """生成 (kind, chrom, start, end, 额外字段) 的查询列表"""
+    out = []
+    for chrom, length in sorted(sizes.items()):
+        rs = recs.get(chrom, [])
+        windows = [(0, length)]
+        for _ in range(4 if rs else 0):
+            i = rng.randrange(len(rs))
+            j = rng.randrange(i, min(len(rs), i + 40))
+            windows.append((rs[i][0], rs[j][1]))
+        for s, e in windows:
+            out.append(("intervals", chrom, s, e, {}))
+        for _ in range(4):
+            w = rng.randint(1, min(length, 2000))
+            s = rng.randint(0, length - w)
+            out.append(("values", chrom, s, s + w, {}))
+        stat_windows = [(0, length)]
+        for _ in range(3):
+            w = 10 * rng.randint(1, min(length, 5000) // 10)
+            s = rng.randint(0, length - w)
+            stat_windows.append((s, s + w))
+        for s, e in stat_windows:
+            for stat in STATS:
+                for nbins in (1, 10):
+                    out.append(("stats", chrom, s, e, {"type": stat, "nBins": nbins, "exact": True}))
+        for stat in WHOLE_CHROM_ZOOM_STATS:
+            out.append(("stats", chrom, 0, length, {"type": stat, "nBins": 1, "exact": False}))
+        for s, e in zoom_windows(rs, length, rng):
+            for nbins in ZOOM_BINS:
+                if distinct_values_per_bin(rs, s, e, nbins):
+                    for stat in STATS:
+                        out.append(("stats", chrom, s, e, {"type": stat, "nBins": nbins, "exact": False}))
+    return out
+
+
+def zoom_windows(rs, length, rng):
+    """在有数据的范围内取三个宽度为 20 的倍数（能被每种 bin 数整除）的窗口，不超出染色体"""
+    if not rs:
+        return []
+    lo, hi = rs[0][0], max(r[1] for r in rs)
+    out = []
+    for _ in range(3):
+        w = 20 * rng.randint(10, max(10, min(hi - lo, 8000) // 20))
+        s = rng.randint(lo, max(lo, hi - w))
+        if s + w <= length:
+            out.append((s, s + w))
+    return out
+
+
+def distinct_values_per_bin(rs, s, e, nbins):
+    width = (e - s) // nbins
+    for i in range(nbins):
+        bs, be = s + i * width, s + (i + 1) * width
+        if len({v for a, b, v in rs if a < be and b > bs}) < 2:
+            return False
+    return True
+
+
+def source_stat(rs, stat, s, e):
+    n = 0
+    total = sum_sq = 0.0
+    lo = hi = None
+    for rs_, re_, v in rs:
+        o = min(re_, e) - max(rs_, s)
+        if o <= 0:
+            continue
+        n += o
+        total += v * o
+        sum_sq += v * v * o
+        lo = v if lo is None else min(lo, v)
+        hi = v if hi is None else max(hi, v)
+    if stat == "coverage":
+        return n / (e - s)
+    if n == 0:
+        return None
+    if stat == "mean":
+        return total / n
+    if stat == "min":
+        return lo
+    if stat == "max":
+        return hi
+    if stat == "sum":
+        return total
+    if n <= 1:
+        return 0.0
+    return math.sqrt(max((sum_sq - total * total / n) / (n - 1), 0.0))
+
+
+def from_source(recs, zoom, kind, chrom, s, e, extra):
+    rs = recs.get(chrom, [])
+    if kind == "intervals":
+        return {"intervals": [[max(a, s), min(b, e), v] for a, b, v in rs if a < e and b > s]}
+    if kind == "values":
+        vals = [None] * (e - s)
+        for a, b, v in rs:
+            for p in range(max(a, s), min(b, e)):
+                vals[p - s] = v
+        return {"values": vals}
+    nbins = extra["nBins"]
+    width = (e - s) // nbins
+    if not extra["exact"]:
+        level = zoom.level(width)
+        if level is not None:
+            sums = zoom.summaries(level, chrom)
+            return {"values": [zoom_stat(sums, extra["type"], s + i * width, s + (i + 1) * width) for i in range(nbins)]}
+    return {"values": [source_stat(rs, extra["type"], s + i * width, s + (i + 1) * width) for i in range(nbins)]}
+
+
+def zoom_stat(sums, stat, s, e):
+    """按 libBigWig 由 zoom summary 计算 [s, e) 的统计量，sums 为按文件顺序排列的
+    (start, end, validCount, min, max, sumData, sumSquares)"""
+    n = total = sum_sq = 0.0
+    lo = hi = None
+    for zs, ze, cnt, zmin, zmax, zsum, zsq in sums:
+        o = min(ze, e) - max(zs, s)
+        if o <= 0:
+            continue
+        f = o / (ze - zs)
+        n += cnt * f
+        total += zsum * f
+        sum_sq += zsq * f
+        lo = zmin if lo is None else min(lo, zmin)
+        hi = zmax if hi is None else max(hi, zmax)
+    if stat == "coverage":
+        return n / (e - s)
+    if lo is None:
+        return None
+    if stat == "mean":
+        return total / n
+    if stat == "min":
+        return lo
+    if stat == "max":
+        return hi
+    if stat == "sum":
+        return total
+    if n <= 1:
+        return 0.0
+    return math.sqrt(max((sum_sq - total * total / n) / (n - 1), 0.0))
+
+
+class ZoomData:
+    """读取 bigWig 文件中的 zoom 层级（只支持小端文件）"""
+
+    def __init__(self, path):
+        self.data = open(path, "rb").read()
+        magic, _, n_levels, ct_offset = struct.unpack_from("<IHHQ", self.data, 0)
+        if magic != 0x888FFC26:
+            raise ValueError("%s: not a little-endian bigWig file" % path)
+        self.compressed = struct.unpack_from("<I", self.data, 52)[0] > 0
+        self.levels = [struct.unpack_from("<IIQQ", self.data, 64 + 24 * i) for i in range(n_levels)]
+        self.tids = {}
+        _, _, key_size = struct.unpack_from("<III", self.data, ct_offset)
+        self._read_chrom_node(ct_offset + 32, key_size)
+
+    def _read_chrom_node(self, off, key_size):
+        is_leaf, _, count = struct.unpack_from("<BBH", self.data, off)
+        off += 4
+        for _ in range(count):
+            key = self.data[off:off + key_size].rstrip(b"\0").decode()
+            if is_leaf:
+                self.tids[key] = struct.unpack_from("<I", self.data, off + key_size)[0]
+                off += key_size + 8
+            else:
+                self._read_chrom_node(struct.unpack_from("<Q", self.data, off + key_size)[0], key_size)
+                off += key_size + 8
+
+    def level(self, bases_per_bin):
+        """与 libBigWig 的 determineZoomLevel 相同，返回层级下标，没有合适的层级时返回 None"""
+        best = None
+        for i, (reduction, _, _, _) in enumerate(self.levels):
+            diff = bases_per_bin // 2 - reduction
+            if diff >= 0 and (best is None or diff < best[0]):
+                best = (diff, i)
+        return None if best is None else best[1]
+
+    def summaries(self, level, chrom):
+        tid = self.tids.get(chrom)
+        out = []
+        for off, size in self._leaf_blocks(self.levels[level][3] + 48):
+            block = self.data[off:off + size]
+            if self.compressed:
+                block = zlib.decompress(block)
+            for j in range(0, len(block), 32):
+                ctid, zs, ze, cnt, zmin, zmax, zsum, zsq = struct.unpack_from("<IIIIffff", block, j)
+                if ctid == tid:
+                    out.append((zs, ze, cnt, zmin, zmax, zsum, zsq))
+        return out
+
+    def _leaf_blocks(self, off):
+        is_leaf, _, count = struct.unpack_from("<BBH", self.data, off)
+        off += 4
+        for _ in range(count):
+            if is_leaf:
+                yield struct.unpack_from("<QQ", self.data, off + 16)
+                off += 32
+            else:
+                yield from self._leaf_blocks(struct.unpack_from("<Q", self.data, off + 16)[0])
+                off += 24
+
+
+def from_pybigwig(bw, kind, chrom, s, e, extra):
+    def num(v):
+        return None if v is None or (isinstance(v, float) and math.isnan(v)) else v
+
+    if kind == "intervals":
+        return {"intervals": [[a, b, v] for a, b, v in (bw.intervals(chrom, s, e) or ())]}
+    if kind == "values":
+        return {"values": [num(v) for v in bw.values(chrom, s, e)]}
+    vals = bw.stats(chrom, s, e, type=extra["type"], nBins=extra["nBins"], exact=extra["exact"])
+    return {"values": [num(v) for v in vals]}
+
+
+def main(argv):
+    use_pybigwig = argv[:1] == ["--pybigwig"]
+    if use_pybigwig:
+        argv = argv[1:]
+    source, sizes_path, bw_path = argv
+    sizes, recs = read_sizes(sizes_path), read_source(source)
+    if use_pybigwig:
+        import pyBigWig
+
+        bw = pyBigWig.open(bw_path)
+        evaluate = lambda *q: from_pybigwig(bw, *q)
+    else:
+        zoom = ZoomData(bw_path)
+        evaluate = lambda *q: from_source(recs, zoom, *q)
+    out = []
+    for kind, chrom, s, e, extra in queries(sizes, recs, random.Random(os.path.basename(bw_path))):
+        q = {"kind": kind, "chrom": chrom, "start": s, "end": e}
+        q.update(extra)
+        q.update(evaluate(kind, chrom, s, e, extra))
+        out.append(q)
+    json.dump({"file": os.path.basename(bw_path), "queries": out}, sys.stdout, separators=(",", ":"))
+    sys.stdout.write("\n")
+
+
+if __name__ == "__main__":
+    main(sys.argv[1:])
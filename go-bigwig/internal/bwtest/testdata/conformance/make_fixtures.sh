@@ -0,0 +1,27 @@
+#!/bin/sh
+# 由文本源生成一致性测试使用的 bigWig 文件和期望结果，只在修改源文件或 gen_expected.py 时运行，并提交生成的文件
+#
+# 默认用本仓库的 wig2bw/bedgraph2bw 生成 bigWig，期望结果由 gen_expected.py 直接从文本源计算；
+# REFERENCE=1 时改用 UCSC 的 wigToBigWig/bedGraphToBigWig 生成 bigWig，并用 pyBigWig（libBigWig）读取得到期望结果
+# 块大小和 zoom 层级取较小的值，使小文件也有多个数据块和 zoom 层级
+set -e
+cd "$(dirname "$0")"
+root=../../../..
+build() { # in out
+	case "$1" in
+	*.wig) ucsc=wigToBigWig go=wig2bw ;;
+	*) ucsc=bedGraphToBigWig go=bedgraph2bw ;;
+	esac
+	if [ -n "$REFERENCE" ]; then
+		"$ucsc" -blockSize=16 -itemsPerSlot=32 "$1" chrom.sizes "$2"
+	else
+		(cd "$root" && go run "./cmd/$go" -block-size 16 -buf-size 256 -zooms 40,160,640 \
+			"internal/bwtest/testdata/conformance/$1" internal/bwtest/testdata/conformance/chrom.sizes \
+			"internal/bwtest/testdata/conformance/$2")
+	fi
+}
+for src in fixed_step.wig variable_step.wig bedgraph.bedGraph; do
+	name=${src%.*}
+	build "$src" "$name.bw"
+	python3 gen_expected.py ${REFERENCE:+--pybigwig} "$src" chrom.sizes "$name.bw" > "$name.expected.json"
+done
@@ -0,0 +1,100 @@
+// Package cliutil 是 cmd 下各命令共用的参数解析与输入输出辅助函数
+package cliutil
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// Strings 是可以重复出现的字符串参数，例如 -region a -region b
+type Strings []string
+
+func (s *Strings) String() string { return strings.Join(*s, ",") }
+
+func (s *Strings) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// Regions 把区间参数（chrom:start-end，或只写染色体名表示整条染色体）和 BED 文件中的区间
+// 合并为查询列表，顺序为先参数后 BED；都没有给出时返回 nil
+func Regions(fp *gb.Bigwig_file_out, specs []string, bedPath string) ([]gb.Region, error) {
+	var regions []gb.Region
+	for _, s := range specs {
+		if !strings.Contains(s, ":") {
+			n, ok := fp.ChromLength(s)
+			if !ok {
+				return nil, fmt.Errorf("chromosome not found: %s", s)
+			}
+			regions = append(regions, gb.Region{Chrom: s, Start: 0, End: int(n)})
+			continue
+		}
+		r, err := gb.ParseRegion(s)
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, r)
+	}
+	if bedPath != "" {
+		f, err := os.Open(bedPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		bed, err := gb.ReadBED(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", bedPath, err)
+		}
+		regions = append(regions, bed...)
+	}
+	return regions, nil
+}
+
+// Output 是带缓冲的输出，Close 时依次刷新缓冲、结束 gzip 流并关闭文件
+type Output struct {
+	*bufio.Writer
+	closers []io.Closer
+}
+
+// Create 打开输出：path 为空或 "-" 时写到标准输出，以 .gz 结尾时使用 gzip 压缩
+func Create(path string) (*Output, error) {
+	var w io.Writer = os.Stdout
+	o := &Output{}
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+		o.closers = append(o.closers, f)
+		if strings.HasSuffix(path, ".gz") {
+			zw := gzip.NewWriter(f)
+			w = zw
+			o.closers = append([]io.Closer{zw}, o.closers...)
+		}
+	}
+	o.Writer = bufio.NewWriterSize(w, 256*1024)
+	return o, nil
+}
+
+func (o *Output) Close() error {
+	err := o.Flush()
+	for _, c := range o.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Fatal 以 "prog: err" 的形式输出错误并以状态码 1 退出
+func Fatal(prog string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+	os.Exit(1)
+}
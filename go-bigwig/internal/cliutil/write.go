@@ -0,0 +1,118 @@
+package cliutil
+
+import (
+	"compress/zlib"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	gb "go-bigwig/gobigwig"
+)
+
+// WriteFlags 是生成 bigWig 的命令共用的输出参数
+type WriteFlags struct {
+	Genome    string
+	Level     int
+	BlockSize uint
+	BufSize   uint
+	MaxZooms  int
+	Zooms     string
+	Unc       bool
+	Progress  bool
+}
+
+// AddWriteFlags 在 fs 上注册 -level、-block-size、-buf-size、-max-zooms、-zooms、-unc 和 -progress
+func AddWriteFlags(fs *flag.FlagSet) *WriteFlags {
+	f := &WriteFlags{}
+	fs.IntVar(&f.Level, "level", zlib.DefaultCompression, "zlib 压缩级别，1 最快、9 最小，-1 为默认")
+	fs.UintVar(&f.BlockSize, "block-size", gb.DEFAULT_nCHILDREN, "R 树每个节点的子节点数")
+	fs.UintVar(&f.BufSize, "buf-size", gb.DEFAULT_BLOCKSIZE, "每个数据块未压缩时的最大字节数")
+	fs.IntVar(&f.MaxZooms, "max-zooms", gb.DEFAULT_ZOOM_LEVELS, "最多生成的缩放层级数，0 表示不生成")
+	fs.StringVar(&f.Zooms, "zooms", "", "以逗号分隔的缩放层级 reduction，例如 100,400,1600，优先于 -max-zooms")
+	fs.BoolVar(&f.Unc, "unc", false, "不压缩数据块")
+	fs.BoolVar(&f.Progress, "progress", false, "在标准错误上显示读取输入的进度")
+	return f
+}
+
+// AddConvertFlags 在 AddWriteFlags 的基础上注册转换命令使用的 -genome
+func AddConvertFlags(fs *flag.FlagSet) *WriteFlags {
+	f := AddWriteFlags(fs)
+	fs.StringVar(&f.Genome, "genome", "", "使用内置（或从 UCSC 下载的）基因组染色体长度，例如 hg38，此时不需要 chrom.sizes 参数")
+	return f
+}
+
+// Options 把参数转换为 WriteOption
+func (f *WriteFlags) Options() ([]gb.WriteOption, error) {
+	opts := []gb.WriteOption{
+		gb.WithBlockSize(uint32(f.BlockSize)),
+		gb.WithBufSize(uint32(f.BufSize)),
+		gb.WithMaxZoomLevels(f.MaxZooms),
+	}
+	if f.Unc {
+		opts = append(opts, gb.WithCompression(false))
+	} else {
+		opts = append(opts, gb.WithCompressionLevel(f.Level))
+	}
+	if f.Zooms != "" {
+		var ladder []uint32
+		for _, s := range strings.Split(f.Zooms, ",") {
+			r, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -zooms %q", f.Zooms)
+			}
+			ladder = append(ladder, uint32(r))
+		}
+		opts = append(opts, gb.WithZoomLadder(ladder...))
+	}
+	if f.Progress {
+		opts = append(opts, gb.WithProgress(Progress(os.Stderr)))
+	}
+	return opts, nil
+}
+
+// Progress 返回在 w 上以单行刷新显示百分比的 ProgressFunc，完成时换行；总量未知时只显示字节数
+func Progress(w io.Writer) gb.ProgressFunc {
+	return func(done, total uint64) {
+		if total == 0 {
+			fmt.Fprintf(w, "\r%.1f MB", float64(done)/(1<<20))
+			return
+		}
+		fmt.Fprintf(w, "\r%5.1f%% (%.1f / %.1f MB)", 100*float64(done)/float64(total), float64(done)/(1<<20), float64(total)/(1<<20))
+		if done >= total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// ChromSizes 返回 -genome 指定的基因组（优先内置表，否则从 UCSC 下载）或 path 中的染色体长度
+func (f *WriteFlags) ChromSizes(path string) (map[string]uint32, error) {
+	if f.Genome == "" {
+		return gb.LoadChromSizes(path)
+	}
+	if sizes, err := gb.BuiltinChromSizes(f.Genome); err == nil {
+		return sizes, nil
+	}
+	return gb.FetchChromSizes(f.Genome)
+}
+
+// ConvertArgs 解析 "输入 [chrom.sizes] 输出" 形式的位置参数，设置了 -genome 时不需要 chrom.sizes
+func (f *WriteFlags) ConvertArgs(args []string) (in, sizes, out string, ok bool) {
+	switch {
+	case f.Genome != "" && len(args) == 2:
+		return args[0], "", args[1], true
+	case f.Genome == "" && len(args) == 3:
+		return args[0], args[1], args[2], true
+	}
+	return "", "", "", false
+}
+
+// Open 打开输入文件，"-" 表示标准输入
+func Open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}